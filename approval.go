@@ -0,0 +1,85 @@
+package pg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultApprovalTable holds the approvals TableApprover checks.
+const DefaultApprovalTable = "migration_approvals"
+
+// PendingMigration is a migration about to be applied, as presented to a MigrationApprover.
+// Version is empty for repeatable migrations.
+type PendingMigration struct {
+	Version  string
+	Name     string
+	Filename string
+	Script   string
+	// Checksum is the hex SHA-256 of the script, so an approval covers exactly the reviewed text.
+	Checksum string
+}
+
+// MigrationApprover is consulted before each migration is applied. q is the migration
+// transaction.
+type MigrationApprover interface {
+	Approve(ctx context.Context, q Querier, m PendingMigration) (bool, error)
+}
+
+// ApproverFunc adapts a function to a MigrationApprover, e.g. one asking an external service.
+type ApproverFunc func(ctx context.Context, q Querier, m PendingMigration) (bool, error)
+
+func (f ApproverFunc) Approve(ctx context.Context, q Querier, m PendingMigration) (bool, error) {
+	return f(ctx, q, m)
+}
+
+// TableApprover approves the migrations with a row of the filename and checksum in Table,
+// created with ApprovalMigration.
+type TableApprover struct {
+	Table string
+}
+
+func (a TableApprover) Approve(ctx context.Context, q Querier, m PendingMigration) (bool, error) {
+	//goland:noinspection SqlResolve
+	return Exists(ctx, q, "SELECT FROM "+QuoteIdentifier(a.Table)+" WHERE filename = $1 AND checksum = $2", m.Filename, m.Checksum)
+}
+
+// ApprovalMigration returns the script creating the table TableApprover checks, for the
+// approvers' tooling to insert into.
+func ApprovalMigration(table string) string {
+	return `
+		CREATE TABLE IF NOT EXISTS ` + QuoteIdentifier(table) + `
+		(
+			filename TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			approved_by TEXT NOT NULL,
+			approved_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (filename, checksum)
+		);
+	`
+}
+
+func scriptChecksum(script []byte) string {
+	sum := sha256.Sum256(script)
+	return hex.EncodeToString(sum[:])
+}
+
+// approve consults the configured approver. Once a migration is held, the ones after it are held
+// too, as they may depend on it.
+func (dbm *databaseMigrator) approve(ctx context.Context, q Querier, m PendingMigration) (bool, error) {
+	if dbm.Configuration.Approver == nil {
+		return true, nil
+	}
+	if !dbm.holding {
+		approved, err := dbm.Configuration.Approver.Approve(ctx, q, m)
+		if err != nil {
+			return false, err
+		}
+		dbm.holding = !approved
+	}
+	if dbm.holding {
+		log.Warnf("Migration %v held until approved (checksum %v)", m.Filename, m.Checksum)
+	}
+	return !dbm.holding, nil
+}
@@ -0,0 +1,58 @@
+package pg_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTableApprover(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, pg.ApprovalMigration(pg.DefaultApprovalTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	directory := t.TempDir()
+	scripts := map[string]string{
+		"0_create.sql": "CREATE TABLE a (id INT);",
+		"1_alter.sql":  "ALTER TABLE a ADD name TEXT;",
+		"2_index.sql":  "CREATE INDEX a_name ON a (name);",
+	}
+	approve := func(filename string) {
+		sum := sha256.Sum256([]byte(scripts[filename]))
+		_, err := db.Pool.Exec(ctx, "INSERT INTO migration_approvals (filename, checksum, approved_by) VALUES ($1, $2, 'reviewer')",
+			filename, hex.EncodeToString(sum[:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, script := range scripts {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(script), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	approve("0_create.sql")
+	approve("2_index.sql")
+	c := db.Configuration
+	c.MigrationsDirectory = directory
+	c.Approver = pg.TableApprover{Table: pg.DefaultApprovalTable}
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id FROM changelog", [][]any{{"0"}})
+
+	approve("1_alter.sql")
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id FROM changelog ORDER BY id", [][]any{{"0"}, {"1"}, {"2"}})
+}
@@ -0,0 +1,49 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Array prepares a slice for use as an array parameter, e.g. with In or "= ANY($1)". A nil slice
+// becomes an empty array so the comparison matches nothing instead of yielding NULL.
+func Array[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
+
+// ScanArray scans the single array column of the first row into a typed slice. Nested slice
+// types such as [][]int scan multidimensional arrays. A NULL array scans to nil.
+func ScanArray[T any](ctx context.Context, q Querier, sql string, args ...any) ([]T, error) {
+	var values []T
+	err := q.QueryRow(ctx, sql, args...).Scan(&values)
+	if err != nil {
+		return nil, fmt.Errorf("scanning array into %v: %w", reflect.TypeFor[[]T](), err)
+	}
+	return values, nil
+}
+
+// ScanColumn collects the first column of every row into a typed slice.
+func ScanColumn[T any](ctx context.Context, q Querier, sql string, args ...any) ([]T, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make([]T, 0)
+	for rows.Next() {
+		var value T
+		err = rows.Scan(&value)
+		if err != nil {
+			return nil, fmt.Errorf("scanning column into %v: %w", reflect.TypeFor[T](), err)
+		}
+		values = append(values, value)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,81 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"reflect"
+	"testing"
+)
+
+func TestScanArray(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	names, err := pg.ScanArray[string](ctx, db.Pool, "SELECT ARRAY['a', 'b']::text[]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("unexpected names %v", names)
+	}
+	ids, err := pg.ScanArray[int64](ctx, db.Pool, "SELECT $1::int8[]", pg.Array([]int64{3, 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int64{3, 1}) {
+		t.Errorf("unexpected ids %v", ids)
+	}
+	matrix, err := pg.ScanArray[[]int](ctx, db.Pool, "SELECT '{{1,2},{3,4}}'::int[]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matrix, [][]int{{1, 2}, {3, 4}}) {
+		t.Errorf("unexpected matrix %v", matrix)
+	}
+
+	// NULL elements need pointer elements, and a NULL array scans to nil.
+	optional, err := pg.ScanArray[*string](ctx, db.Pool, "SELECT ARRAY['a', NULL]::text[]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(optional) != 2 || *optional[0] != "a" || optional[1] != nil {
+		t.Errorf("unexpected optional names %v", optional)
+	}
+	_, err = pg.ScanArray[string](ctx, db.Pool, "SELECT ARRAY['a', NULL]::text[]")
+	if err == nil {
+		t.Error("expected NULL elements to fail scanning into strings")
+	}
+	missing, err := pg.ScanArray[string](ctx, db.Pool, "SELECT NULL::text[]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != nil {
+		t.Errorf("expected a NULL array to scan to nil, got %v", missing)
+	}
+
+	// An empty array from Array matches nothing instead of yielding NULL.
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT 1 = ANY($1::int8[]) IS NOT NULL", [][]any{{true}}, pg.Array[int64](nil))
+}
+
+func TestScanColumn(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	names, err := pg.ScanColumn[string](ctx, db.Pool, "SELECT name FROM testtable ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"name1"}) {
+		t.Errorf("unexpected names %v", names)
+	}
+	ids, err := pg.ScanColumn[int64](ctx, db.Pool, "SELECT id FROM testtable WHERE id = ANY($1)", pg.Array([]int64{2, 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids == nil || len(ids) != 0 {
+		t.Errorf("expected an empty slice, got %v", ids)
+	}
+	_, err = pg.ScanColumn[int64](ctx, db.Pool, "SELECT name FROM testtable")
+	if err == nil {
+		t.Error("expected text to fail scanning into integers")
+	}
+}
@@ -0,0 +1,13 @@
+package pg
+
+import "testing"
+
+func TestArray(t *testing.T) {
+	var ids []int
+	if Array(ids) == nil {
+		t.Error("nil slice should become an empty array")
+	}
+	if len(Array([]int{1, 2})) != 2 {
+		t.Error("non-nil slice should be passed through")
+	}
+}
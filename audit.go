@@ -0,0 +1,198 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditEntry describes one statement executed by the migrator.
+type AuditEntry struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Statement string        `json:"statement"`
+	Args      []any         `json:"args,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// AuditSink receives an entry for every statement the migrator executes, including changelog reads
+// and writes. Set it through Configuration.AuditSink.
+type AuditSink interface {
+	Record(entry AuditEntry) error
+}
+
+// JSONFileAuditSink appends entries as JSON lines to a file.
+type JSONFileAuditSink struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (s *JSONFileAuditSink) Record(entry AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	return json.NewEncoder(file).Encode(entry)
+}
+
+// TableAuditSink inserts entries into a table, creating it on first use. Entries are written outside
+// the migration transaction, so statements of a failed migration remain recorded.
+type TableAuditSink struct {
+	Pool *pgxpool.Pool
+	// Table is the schema qualified table name, e.g. "public.migration_audit".
+	Table   string
+	mu      sync.Mutex
+	created bool
+}
+
+func (s *TableAuditSink) Record(entry AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.created {
+		_, err := s.Pool.Exec(context.Background(), `
+			CREATE TABLE IF NOT EXISTS `+QuoteIdentifier(s.Table)+`
+			(
+				timestamp TIMESTAMPTZ NOT NULL,
+				statement TEXT NOT NULL,
+				args JSONB,
+				duration INTERVAL NOT NULL,
+				error TEXT
+			);
+		`)
+		if err != nil {
+			return err
+		}
+		s.created = true
+	}
+	args, err := json.Marshal(entry.Args)
+	if err != nil {
+		return err
+	}
+	var entryError *string
+	if entry.Error != "" {
+		entryError = &entry.Error
+	}
+	//goland:noinspection SqlResolve
+	_, err = s.Pool.Exec(context.Background(), "INSERT INTO "+QuoteIdentifier(s.Table)+" (timestamp, statement, args, duration, error) VALUES ($1, $2, $3, $4, $5)",
+		entry.Timestamp, entry.Statement, args, entry.Duration, entryError)
+	return err
+}
+
+func (dbm *databaseMigrator) audit(start time.Time, sql string, args []any, err error) {
+	sink := dbm.Configuration.AuditSink
+	if sink == nil {
+		return
+	}
+	entry := AuditEntry{
+		Timestamp: start,
+		Statement: sql,
+		Args:      args,
+		Duration:  time.Since(start),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	auditErr := sink.Record(entry)
+	if auditErr != nil {
+		log.Warnf("Error recording audit entry: %v", auditErr)
+	}
+}
+
+func (dbm *databaseMigrator) exec(q Querier, sql string, args ...any) (pgconn.CommandTag, error) {
+	start := time.Now()
+	tag, err := q.Exec(context.Background(), sql, args...)
+	dbm.audit(start, sql, args, err)
+	return tag, err
+}
+
+func (dbm *databaseMigrator) queryRow(q Querier, sql string, args ...any) pgx.Row {
+	return &auditedRow{dbm: dbm, row: q.QueryRow(context.Background(), sql, args...), start: time.Now(), sql: sql, args: args}
+}
+
+type auditedRow struct {
+	dbm   *databaseMigrator
+	row   pgx.Row
+	start time.Time
+	sql   string
+	args  []any
+}
+
+func (r *auditedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	r.dbm.audit(r.start, r.sql, r.args, err)
+	return err
+}
+
+// audited returns a Querier recording the statements run through q, for helpers the migrator
+// calls with its pool or transaction.
+func (dbm *databaseMigrator) audited(q Querier) Querier {
+	return &auditedQuerier{dbm: dbm, q: q}
+}
+
+type auditedQuerier struct {
+	dbm *databaseMigrator
+	q   Querier
+}
+
+func (a *auditedQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	start := time.Now()
+	tag, err := a.q.Exec(ctx, sql, args...)
+	a.dbm.audit(start, sql, args, err)
+	return tag, err
+}
+
+func (a *auditedQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	start := time.Now()
+	rows, err := a.q.Query(ctx, sql, args...)
+	if err != nil {
+		a.dbm.audit(start, sql, args, err)
+		return nil, err
+	}
+	return &auditedRows{Rows: rows, dbm: a.dbm, start: start, sql: sql, args: args}, nil
+}
+
+func (a *auditedQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &auditedRow{dbm: a.dbm, row: a.q.QueryRow(ctx, sql, args...), start: time.Now(), sql: sql, args: args}
+}
+
+// auditedRows records the statement once its rows are closed, with the error reading them.
+type auditedRows struct {
+	pgx.Rows
+	dbm      *databaseMigrator
+	start    time.Time
+	sql      string
+	args     []any
+	recorded bool
+}
+
+func (r *auditedRows) Next() bool {
+	next := r.Rows.Next()
+	if !next {
+		r.record()
+	}
+	return next
+}
+
+func (r *auditedRows) Close() {
+	r.Rows.Close()
+	r.record()
+}
+
+func (r *auditedRows) record() {
+	if r.recorded {
+		return
+	}
+	r.recorded = true
+	r.dbm.audit(r.start, r.sql, r.args, r.Rows.Err())
+}
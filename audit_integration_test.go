@@ -0,0 +1,87 @@
+package pg_test
+
+import (
+	"encoding/json"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// auditedStatements returns the statements recorded in the JSON lines audit file at path.
+func auditedStatements(t *testing.T, path string) []string {
+	t.Helper()
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statements []string
+	for _, line := range strings.Split(strings.TrimSpace(string(bytes)), "\n") {
+		var entry pg.AuditEntry
+		err = json.Unmarshal([]byte(line), &entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statements = append(statements, entry.Statement)
+	}
+	return statements
+}
+
+func containsStatement(statements []string, prefix string) bool {
+	for _, statement := range statements {
+		if strings.HasPrefix(strings.TrimSpace(statement), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAuditedExtensions(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	c := db.Configuration
+	c.MigrationsDirectory = "testdb"
+	c.Extensions = []string{"pgcrypto"}
+	c.AuditSink = &pg.JSONFileAuditSink{Path: path}
+	err := pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := auditedStatements(t, path)
+	if !containsStatement(statements, `CREATE EXTENSION IF NOT EXISTS "pgcrypto"`) {
+		t.Errorf("expected the extension to be audited, got %v", statements)
+	}
+}
+
+func TestAuditedGrants(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	directory := t.TempDir()
+	files := map[string]string{
+		"0_init.sql": "CREATE TABLE items (id SERIAL PRIMARY KEY);",
+		"grants.yaml": "roles:\n  - name: pgutils_audited\ngrants:\n  - role: pgutils_audited\n    schema: public\n" +
+			"    privileges: [SELECT]\n    default: true\n",
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	c := db.Configuration
+	c.MigrationsDirectory = directory
+	c.GrantsFile = "grants.yaml"
+	c.AuditSink = &pg.JSONFileAuditSink{Path: path}
+	err := pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := auditedStatements(t, path)
+	for _, prefix := range []string{"CREATE ROLE", "GRANT SELECT", "ALTER DEFAULT PRIVILEGES"} {
+		if !containsStatement(statements, prefix) {
+			t.Errorf("expected %v to be audited, got %v", prefix, statements)
+		}
+	}
+}
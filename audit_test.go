@@ -0,0 +1,35 @@
+package pg
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	dbm := createDatabaseMigrator(nil, Configuration{AuditSink: &JSONFileAuditSink{Path: path}})
+	dbm.audit(time.Now(), "CREATE TABLE a (id INT)", nil, nil)
+	dbm.audit(time.Now(), "INSERT INTO a VALUES ($1)", []any{1}, errors.New("boom"))
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(bytes)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 entries, got %v", len(lines))
+	}
+	var entry AuditEntry
+	err = json.Unmarshal([]byte(lines[1]), &entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Statement != "INSERT INTO a VALUES ($1)" || entry.Error != "boom" || len(entry.Args) != 1 {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+}
@@ -0,0 +1,188 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const DefaultBackfillTable = "backfill_progress"
+
+var ErrBackfillNotFound = errors.New("backfill not found")
+
+// BackfillSpec describes an update of a large table run by Backfill.
+type BackfillSpec struct {
+	// Name identifies the progress; running a backfill of the same name again resumes it.
+	Name  string
+	Table string
+	// Key orders the batches and must be unique and not null, usually the primary key.
+	Key string
+	// Set is the SET clause, e.g. "email_lower = lower(email)".
+	Set string
+	// Where optionally restricts the rows to update; Args are its parameters, $1 onwards.
+	Where string
+	Args  []any
+	// BatchSize is the number of rows per batch, 1000 when zero.
+	BatchSize int
+	// Pause is slept between batches.
+	Pause time.Duration
+	// MaxRuntime stops the backfill after this long, to be resumed later; no limit when zero.
+	MaxRuntime time.Duration
+	// ProgressTable records the progress, DefaultBackfillTable when empty. It is created when
+	// missing.
+	ProgressTable string
+}
+
+// BackfillProgress is the recorded state of a backfill. LastKey is the text form of the last key
+// updated, nil before the first batch, so every key, the empty text included, can be resumed after.
+type BackfillProgress struct {
+	Name      string
+	LastKey   *string
+	Rows      int64
+	Completed bool
+	UpdatedAt time.Time
+}
+
+func (s BackfillSpec) progressTable() string {
+	if s.ProgressTable == "" {
+		return DefaultBackfillTable
+	}
+	return s.ProgressTable
+}
+
+// Backfill updates the table in batches of rows ordered by Key, each batch committed together
+// with the progress, so an interrupted backfill resumes after the last committed batch. It
+// returns when all rows are done, or with Completed unset when MaxRuntime is reached. A completed
+// backfill is not run again.
+func Backfill(ctx context.Context, pool *pgxpool.Pool, spec BackfillSpec) (BackfillProgress, error) {
+	if spec.Name == "" || spec.Table == "" || spec.Key == "" || spec.Set == "" {
+		return BackfillProgress{}, errors.New("backfill needs a name, table, key and set clause")
+	}
+	batchSize := spec.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBackfillBatchSize
+	}
+	progressTable := QuoteIdentifier(spec.progressTable())
+	//goland:noinspection SqlResolve
+	_, err := pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS `+progressTable+` (
+			name TEXT PRIMARY KEY,
+			last_key TEXT,
+			rows BIGINT NOT NULL DEFAULT 0,
+			completed BOOLEAN NOT NULL DEFAULT false,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+	progress, err := GetBackfillProgress(ctx, pool, spec.progressTable(), spec.Name)
+	if errors.Is(err, ErrBackfillNotFound) {
+		progress, err = BackfillProgress{Name: spec.Name}, nil
+	}
+	if err != nil || progress.Completed {
+		return progress, err
+	}
+
+	var keyType string
+	err = pool.QueryRow(ctx, "SELECT format_type(atttypid, atttypmod) FROM pg_attribute WHERE attrelid = to_regclass($1) AND attname = $2 AND NOT attisdropped",
+		QuoteIdentifier(spec.Table), spec.Key).Scan(&keyType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return progress, fmt.Errorf("backfill %v: no key column %v in %v", spec.Name, spec.Key, spec.Table)
+	}
+	if err != nil {
+		return progress, err
+	}
+	table, key := QuoteIdentifier(spec.Table), QuoteIdentifier(spec.Key)
+	after := len(spec.Args) + 1
+	where := fmt.Sprintf("($%v::text IS NULL OR %v > $%v::text::%v)", after, key, after, keyType)
+	if spec.Where != "" {
+		where += " AND (" + spec.Where + ")"
+	}
+	//goland:noinspection SqlResolve
+	batch := fmt.Sprintf(`
+		WITH batch AS (
+			SELECT %v AS backfill_key FROM %v WHERE %v ORDER BY %v LIMIT %v
+		), updated AS (
+			UPDATE %v SET %v WHERE %v IN (SELECT backfill_key FROM batch) RETURNING 1
+		)
+		SELECT (SELECT max(backfill_key)::text FROM batch), (SELECT count(*) FROM updated)`,
+		key, table, where, key, batchSize, table, spec.Set, key)
+	//goland:noinspection SqlResolve
+	record := `
+		INSERT INTO ` + progressTable + ` (name, last_key, rows, completed, updated_at) VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (name) DO UPDATE SET last_key = $2, rows = $3, completed = $4, updated_at = now()
+		RETURNING updated_at`
+
+	var deadline <-chan time.Time
+	if spec.MaxRuntime > 0 {
+		deadline = time.After(spec.MaxRuntime)
+	}
+	for {
+		err = runBackfillBatch(ctx, pool, batch, record, spec.Args, &progress)
+		if err != nil || progress.Completed {
+			return progress, err
+		}
+		select {
+		case <-ctx.Done():
+			return progress, ctx.Err()
+		case <-deadline:
+			log.Infof("Backfill %v stopped after %v at key %v, %v rows so far", spec.Name, spec.MaxRuntime, *progress.LastKey, progress.Rows)
+			return progress, nil
+		case <-time.After(spec.Pause):
+		}
+	}
+}
+
+// runBackfillBatch updates the rows after progress.LastKey and records the new progress in the same
+// transaction.
+func runBackfillBatch(ctx context.Context, pool *pgxpool.Pool, batch string, record string, args []any, progress *BackfillProgress) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	lastKey := progress.LastKey
+	var batchKey *string
+	var updated int64
+	err = tx.QueryRow(ctx, batch, append(args[:len(args):len(args)], lastKey)...).Scan(&batchKey, &updated)
+	if err != nil {
+		return err
+	}
+	next := *progress
+	next.Rows += updated
+	if batchKey == nil {
+		next.Completed = true
+	} else {
+		next.LastKey = batchKey
+		lastKey = batchKey
+	}
+	err = tx.QueryRow(ctx, record, next.Name, lastKey, next.Rows, next.Completed).Scan(&next.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	*progress = next
+	return nil
+}
+
+// GetBackfillProgress returns the progress recorded for the backfill name in table, or
+// ErrBackfillNotFound.
+func GetBackfillProgress(ctx context.Context, q Querier, table string, name string) (BackfillProgress, error) {
+	//goland:noinspection SqlResolve
+	row := q.QueryRow(ctx, "SELECT name, last_key, rows, completed, updated_at FROM "+QuoteIdentifier(table)+" WHERE name = $1", name)
+	var p BackfillProgress
+	err := row.Scan(&p.Name, &p.LastKey, &p.Rows, &p.Completed, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return BackfillProgress{}, fmt.Errorf("%w: %v", ErrBackfillNotFound, name)
+	}
+	return p, err
+}
@@ -0,0 +1,93 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestBackfill(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE users (id INT PRIMARY KEY, email TEXT, email_lower TEXT);
+		INSERT INTO users SELECT i, 'User' || i || '@Example.com' FROM generate_series(1, 1000) i;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := pg.BackfillSpec{
+		Name:       "email_lower",
+		Table:      "users",
+		Key:        "id",
+		Set:        "email_lower = lower(email)",
+		Where:      "email LIKE $1",
+		Args:       []any{"User%"},
+		BatchSize:  100,
+		Pause:      20 * time.Millisecond,
+		MaxRuntime: 50 * time.Millisecond,
+	}
+	progress, err := pg.Backfill(ctx, db.Pool, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Completed || progress.Rows == 0 || progress.Rows == 1000 {
+		t.Fatalf("expected the backfill to stop midway, got %+v", progress)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT count(*) FROM users WHERE email_lower IS NOT NULL", [][]any{{progress.Rows}})
+	// Rows before the last key are not visited again.
+	_, err = db.Pool.Exec(ctx, "UPDATE users SET email_lower = 'changed' WHERE id = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec.MaxRuntime = 0
+	progress, err = pg.Backfill(ctx, db.Pool, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !progress.Completed || progress.Rows != 1000 || *progress.LastKey != "1000" {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT email_lower FROM users WHERE id = 1", [][]any{{"changed"}})
+	pgtest.AssertNoRows(t, db.Pool, "SELECT 1 FROM users WHERE id > 1 AND email_lower IS DISTINCT FROM lower(email)")
+
+	recorded, err := pg.GetBackfillProgress(ctx, db.Pool, pg.DefaultBackfillTable, "email_lower")
+	if err != nil || recorded.Rows != 1000 || !recorded.Completed {
+		t.Errorf("unexpected recorded progress %+v: %v", recorded, err)
+	}
+	_, err = pg.GetBackfillProgress(ctx, db.Pool, pg.DefaultBackfillTable, "missing")
+	if !errors.Is(err, pg.ErrBackfillNotFound) {
+		t.Errorf("expected ErrBackfillNotFound, got %v", err)
+	}
+}
+
+func TestBackfillResumesAfterEmptyKey(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE codes (code TEXT PRIMARY KEY, visits INT NOT NULL DEFAULT 0);
+		INSERT INTO codes (code) VALUES (''), ('a'), ('b');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress, err := pg.Backfill(ctx, db.Pool, pg.BackfillSpec{
+		Name:       "visits",
+		Table:      "codes",
+		Key:        "code",
+		Set:        "visits = visits + 1",
+		BatchSize:  1,
+		MaxRuntime: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !progress.Completed || progress.Rows != 3 || *progress.LastKey != "b" {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM codes WHERE visits <> 1")
+}
@@ -0,0 +1,143 @@
+package pg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+type BackupFormat string
+
+const (
+	// BackupCustom is pg_dump's compressed archive format, restorable selectively with pg_restore.
+	BackupCustom BackupFormat = "custom"
+	BackupTar    BackupFormat = "tar"
+	// BackupPlain is a SQL script; restore it with psql rather than RestoreBackup.
+	BackupPlain BackupFormat = "plain"
+)
+
+type BackupOptions struct {
+	// Format defaults to BackupCustom.
+	Format        BackupFormat
+	Schemas       []string
+	Tables        []string
+	ExcludeTables []string
+	SchemaOnly    bool
+	DataOnly      bool
+	// PgDump is the pg_dump executable, found on PATH by default.
+	PgDump string
+}
+
+type RestoreOptions struct {
+	// Clean drops database objects before recreating them.
+	Clean      bool
+	NoOwner    bool
+	SchemaOnly bool
+	DataOnly   bool
+	// SingleTransaction restores all or nothing.
+	SingleTransaction bool
+	// PgRestore is the pg_restore executable, found on PATH by default.
+	PgRestore string
+}
+
+// connectionArgs renders c as pg_dump / pg_restore flags. The password is passed through the
+// environment so it does not show up in the process list.
+func connectionArgs(c Configuration) ([]string, []string) {
+	host, port, err := net.SplitHostPort(c.Address)
+	if err != nil {
+		host, port = c.Address, "5432"
+	}
+	args := []string{"--host", host, "--port", port, "--username", c.Username, "--dbname", c.Name, "--no-password"}
+	env := append(os.Environ(), "PGPASSWORD="+c.Password)
+	return args, env
+}
+
+func backupArgs(opts BackupOptions) []string {
+	format := opts.Format
+	if format == "" {
+		format = BackupCustom
+	}
+	args := []string{"--format", string(format)}
+	for _, schema := range opts.Schemas {
+		args = append(args, "--schema", schema)
+	}
+	for _, table := range opts.Tables {
+		args = append(args, "--table", table)
+	}
+	for _, table := range opts.ExcludeTables {
+		args = append(args, "--exclude-table", table)
+	}
+	if opts.SchemaOnly {
+		args = append(args, "--schema-only")
+	}
+	if opts.DataOnly {
+		args = append(args, "--data-only")
+	}
+	return args
+}
+
+func restoreArgs(opts RestoreOptions) []string {
+	args := []string{"--exit-on-error"}
+	if opts.Clean {
+		args = append(args, "--clean", "--if-exists")
+	}
+	if opts.NoOwner {
+		args = append(args, "--no-owner")
+	}
+	if opts.SchemaOnly {
+		args = append(args, "--schema-only")
+	}
+	if opts.DataOnly {
+		args = append(args, "--data-only")
+	}
+	if opts.SingleTransaction {
+		args = append(args, "--single-transaction")
+	}
+	return args
+}
+
+// Backup streams a pg_dump of the database described by c to w.
+func Backup(ctx context.Context, c Configuration, w io.Writer, opts BackupOptions) error {
+	executable := opts.PgDump
+	if executable == "" {
+		executable = "pg_dump"
+	}
+	connArgs, env := connectionArgs(c)
+	cmd := exec.CommandContext(ctx, executable, append(connArgs, backupArgs(opts)...)...)
+	cmd.Env = env
+	cmd.Stdout = w
+	return runTool(cmd)
+}
+
+// RestoreBackup feeds a custom or tar format archive from r to pg_restore against the database
+// described by c. It is not named Restore, which undoes a soft delete.
+func RestoreBackup(ctx context.Context, c Configuration, r io.Reader, opts RestoreOptions) error {
+	executable := opts.PgRestore
+	if executable == "" {
+		executable = "pg_restore"
+	}
+	connArgs, env := connectionArgs(c)
+	cmd := exec.CommandContext(ctx, executable, append(connArgs, restoreArgs(opts)...)...)
+	cmd.Env = env
+	cmd.Stdin = r
+	return runTool(cmd)
+}
+
+func runTool(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return fmt.Errorf("%v: %w", cmd.Path, err)
+		}
+		return fmt.Errorf("%v: %w: %v", cmd.Path, err, message)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package pg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBackupArgs(t *testing.T) {
+	connArgs, env := connectionArgs(Configuration{Address: "db:6543", Username: "u", Password: "secret", Name: "app"})
+	if strings.Join(connArgs, " ") != "--host db --port 6543 --username u --dbname app --no-password" {
+		t.Errorf("unexpected connection arguments %v", connArgs)
+	}
+	if env[len(env)-1] != "PGPASSWORD=secret" {
+		t.Error("the password should be passed through the environment")
+	}
+	args := backupArgs(BackupOptions{Tables: []string{"users"}, SchemaOnly: true})
+	if strings.Join(args, " ") != "--format custom --table users --schema-only" {
+		t.Errorf("unexpected backup arguments %v", args)
+	}
+	args = restoreArgs(RestoreOptions{Clean: true, SingleTransaction: true})
+	if strings.Join(args, " ") != "--exit-on-error --clean --if-exists --single-transaction" {
+		t.Errorf("unexpected restore arguments %v", args)
+	}
+}
+
+func TestBackupReportsToolErrors(t *testing.T) {
+	var out bytes.Buffer
+	err := Backup(context.Background(), Configuration{Address: "localhost"}, &out, BackupOptions{PgDump: "pgutils-missing-pg-dump"})
+	if err == nil {
+		t.Error("expected an error for a missing executable")
+	}
+}
@@ -0,0 +1,98 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Baseline records the migrations of c up to and including version as completed without running
+// them, e.g. when adopting the migrator for a database whose schema was managed by another tool. An
+// empty version marks all migrations. The rows are loaded with COPY, so thousands of versions take
+// one round trip; migrations already in the changelog are left as they are. It returns the number
+// of migrations recorded.
+func Baseline(pool *pgxpool.Pool, c Configuration, version string) (int64, error) {
+	return createDatabaseMigrator(pool, c).Baseline(version)
+}
+
+func parseMigrationId(version string) ([]int, error) {
+	parts := strings.Split(version, ".")
+	id := make([]int, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q", version)
+		}
+		id = append(id, v)
+	}
+	return id, nil
+}
+
+func (dbm *databaseMigrator) Baseline(version string) (int64, error) {
+	var through []int
+	if version != "" {
+		var err error
+		through, err = parseMigrationId(version)
+		if err != nil {
+			return 0, err
+		}
+	}
+	err := dbm.initChangelogTable()
+	if err != nil {
+		return 0, err
+	}
+	migrations, err := dbm.getMigrations()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	rows := make([][]any, 0, len(migrations))
+	for _, migration := range migrations {
+		if migration.Repeatable {
+			continue
+		}
+		if through != nil && compareMigrationIds(migration.Id, through) > 0 {
+			break
+		}
+		id := strings.Join(Map(migration.Id, strconv.Itoa), ".")
+		rows = append(rows, []any{id, migration.Name, migration.Filename, statusCompleted, now})
+	}
+	ctx := context.Background()
+	tx, err := dbm.PgxPool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	_, err = dbm.exec(tx, dbm.replaceEnv("LOCK TABLE {SCHEMA_TABLE} IN ACCESS EXCLUSIVE MODE"))
+	if err != nil {
+		return 0, err
+	}
+	_, err = dbm.exec(tx, dbm.replaceEnv("CREATE TEMP TABLE pgutils_baseline (LIKE {SCHEMA_TABLE}) ON COMMIT DROP"))
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"pgutils_baseline"}, []string{"id", "name", "filename", "status", "timestamp"}, pgx.CopyFromRows(rows))
+	dbm.audit(start, "COPY pgutils_baseline (id, name, filename, status, timestamp) FROM STDIN", nil, err)
+	if err != nil {
+		return 0, err
+	}
+	//goland:noinspection SqlResolve
+	tag, err := dbm.exec(tx, dbm.replaceEnv("INSERT INTO {SCHEMA_TABLE} (id, name, filename, status, timestamp) SELECT id, name, filename, status, timestamp FROM pgutils_baseline ON CONFLICT (id) DO NOTHING"))
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Recorded %v of %v migrations as baseline", tag.RowsAffected(), len(rows))
+	return tag.RowsAffected(), nil
+}
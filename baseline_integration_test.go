@@ -0,0 +1,35 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE testtable (id INT PRIMARY KEY, name TEXT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := db.Configuration
+	c.MigrationsDirectory = "testdb"
+	recorded, err := pg.Baseline(db.Pool, c, "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorded != 1 {
+		t.Errorf("expected only the initial migration to be recorded, got %v", recorded)
+	}
+	recorded, err = pg.Baseline(db.Pool, c, "0")
+	if err != nil || recorded != 0 {
+		t.Errorf("recorded migrations should be skipped, got %v, %v", recorded, err)
+	}
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id, description FROM testtable", [][]any{{1, "name1"}})
+}
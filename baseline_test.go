@@ -0,0 +1,29 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMigrationId(t *testing.T) {
+	id, err := parseMigrationId("1.20.3")
+	if err != nil || !reflect.DeepEqual(id, []int{1, 20, 3}) {
+		t.Errorf("unexpected id %v, %v", id, err)
+	}
+	_, err = parseMigrationId("1.x")
+	if err == nil {
+		t.Error("expected an error for a non numeric version")
+	}
+}
+
+func TestCompareMigrationIds(t *testing.T) {
+	ordered := [][]int{{0}, {0, 1}, {1}, {1, 2}, {2}, {10}}
+	for i := 1; i < len(ordered); i++ {
+		if compareMigrationIds(ordered[i-1], ordered[i]) >= 0 || compareMigrationIds(ordered[i], ordered[i-1]) <= 0 {
+			t.Errorf("expected %v < %v", ordered[i-1], ordered[i])
+		}
+	}
+	if compareMigrationIds([]int{1, 2}, []int{1, 2}) != 0 {
+		t.Error("equal ids should compare equal")
+	}
+}
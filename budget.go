@@ -0,0 +1,141 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"sync"
+	"time"
+)
+
+var ErrQueryBudgetExceeded = errors.New("query budget exceeded")
+
+// QueryBudget limits the statements run on behalf of a single request, e.g. to catch N+1 query
+// patterns. Zero fields are unlimited.
+type QueryBudget struct {
+	MaxQueries int
+	// MaxDuration bounds the total time spent in statements, not the wall time of the request.
+	MaxDuration time.Duration
+}
+
+// QueryBudgetError fails the statements started after the budget was used up. It matches
+// ErrQueryBudgetExceeded.
+type QueryBudgetError struct {
+	Budget   QueryBudget
+	Queries  int
+	Duration time.Duration
+}
+
+func (e *QueryBudgetError) Error() string {
+	return fmt.Sprintf("%v: %v queries taking %v, budget is %v queries taking %v",
+		ErrQueryBudgetExceeded, e.Queries, e.Duration, e.Budget.MaxQueries, e.Budget.MaxDuration)
+}
+
+func (e *QueryBudgetError) Is(target error) bool {
+	return target == ErrQueryBudgetExceeded
+}
+
+type queryBudgetContextKey struct{}
+
+type queryBudgetStartContextKey struct{}
+
+// queryBudget is shared by everything running with the request's context, possibly concurrently.
+type queryBudget struct {
+	QueryBudget
+	mu       sync.Mutex
+	queries  int
+	duration time.Duration
+}
+
+// WithQueryBudget returns a context whose statements are limited by budget. It is enforced by
+// QueryBudgetTracer, which ConnectWithConfig installs; statements of batches and COPY are not
+// counted.
+func WithQueryBudget(ctx context.Context, budget QueryBudget) context.Context {
+	return context.WithValue(ctx, queryBudgetContextKey{}, &queryBudget{QueryBudget: budget})
+}
+
+// QueryBudgetUsage returns the statements run and the time spent in them under the budget of ctx,
+// e.g. to log them at the end of a request.
+func QueryBudgetUsage(ctx context.Context) (int, time.Duration, bool) {
+	budget, ok := ctx.Value(queryBudgetContextKey{}).(*queryBudget)
+	if !ok {
+		return 0, 0, false
+	}
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	return budget.queries, budget.duration, true
+}
+
+// start counts a statement and returns an error when the budget was already used up.
+func (b *queryBudget) start() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if (b.MaxQueries > 0 && b.queries >= b.MaxQueries) || (b.MaxDuration > 0 && b.duration >= b.MaxDuration) {
+		return &QueryBudgetError{Budget: b.QueryBudget, Queries: b.queries, Duration: b.duration}
+	}
+	b.queries++
+	return nil
+}
+
+func (b *queryBudget) end(duration time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.duration += duration
+}
+
+// exceededContext is done from the start and reports err, so pgx fails the statement with it
+// before sending anything to the server.
+type exceededContext struct {
+	context.Context
+	err error
+}
+
+var closedChannel = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+func (c exceededContext) Done() <-chan struct{} {
+	return closedChannel
+}
+
+func (c exceededContext) Err() error {
+	return c.err
+}
+
+// QueryBudgetTracer enforces the budgets of WithQueryBudget. Install it in pools not created by
+// ConnectWithConfig.
+type QueryBudgetTracer struct{}
+
+func (QueryBudgetTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	budget, ok := ctx.Value(queryBudgetContextKey{}).(*queryBudget)
+	if !ok {
+		return ctx
+	}
+	err := budget.start()
+	if err != nil {
+		return exceededContext{Context: ctx, err: err}
+	}
+	return context.WithValue(ctx, queryBudgetStartContextKey{}, time.Now())
+}
+
+func (QueryBudgetTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	budget, ok := ctx.Value(queryBudgetContextKey{}).(*queryBudget)
+	start, started := ctx.Value(queryBudgetStartContextKey{}).(time.Time)
+	if ok && started {
+		budget.end(time.Since(start))
+	}
+}
+
+// addQueryTracer installs tracer next to the tracers already configured.
+func addQueryTracer(config *pgxpool.Config, tracer pgx.QueryTracer) {
+	if config.ConnConfig.Tracer == nil {
+		config.ConnConfig.Tracer = tracer
+		return
+	}
+	config.ConnConfig.Tracer = multitracer.New(config.ConnConfig.Tracer, tracer)
+}
@@ -0,0 +1,35 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestQueryBudget(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	pool, err := pg.ConnectWithConfig(db.Configuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := pg.WithQueryBudget(context.Background(), pg.QueryBudget{MaxQueries: 2})
+	var name string
+	for range 2 {
+		err = pool.QueryRow(ctx, "SELECT name FROM testtable WHERE id = 1").Scan(&name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = pool.Exec(ctx, "SELECT 1")
+	var budgetErr *pg.QueryBudgetError
+	if !errors.As(err, &budgetErr) || budgetErr.Queries != 2 {
+		t.Fatalf("expected the budget to be exceeded, got %v", err)
+	}
+	err = pool.QueryRow(context.Background(), "SELECT name FROM testtable WHERE id = 1").Scan(&name)
+	if err != nil {
+		t.Errorf("expected the connection to stay usable, got %v", err)
+	}
+}
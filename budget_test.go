@@ -0,0 +1,59 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"testing"
+	"time"
+)
+
+func TestQueryBudgetTracer(t *testing.T) {
+	tracer := QueryBudgetTracer{}
+	ctx := WithQueryBudget(context.Background(), QueryBudget{MaxQueries: 2})
+	for range 2 {
+		queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+		if queryCtx.Err() != nil {
+			t.Fatalf("expected the statement to be within budget, got %v", queryCtx.Err())
+		}
+		tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{})
+	}
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	var budgetErr *QueryBudgetError
+	if !errors.Is(queryCtx.Err(), ErrQueryBudgetExceeded) || !errors.As(queryCtx.Err(), &budgetErr) || budgetErr.Queries != 2 {
+		t.Fatalf("expected the budget to be exceeded, got %v", queryCtx.Err())
+	}
+	select {
+	case <-queryCtx.Done():
+	default:
+		t.Error("expected the statement context to be done")
+	}
+	if queries, _, ok := QueryBudgetUsage(ctx); !ok || queries != 2 {
+		t.Errorf("unexpected usage %v", queries)
+	}
+}
+
+func TestQueryBudgetDuration(t *testing.T) {
+	tracer := QueryBudgetTracer{}
+	ctx := WithQueryBudget(context.Background(), QueryBudget{MaxDuration: time.Millisecond})
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{})
+	time.Sleep(2 * time.Millisecond)
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{})
+	queryCtx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{})
+	if !errors.Is(queryCtx.Err(), ErrQueryBudgetExceeded) {
+		t.Fatalf("expected the time budget to be exceeded, got %v", queryCtx.Err())
+	}
+	if _, duration, _ := QueryBudgetUsage(ctx); duration < time.Millisecond {
+		t.Errorf("unexpected duration %v", duration)
+	}
+}
+
+func TestQueryBudgetTracerWithoutBudget(t *testing.T) {
+	ctx := context.Background()
+	if (QueryBudgetTracer{}).TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{}) != ctx {
+		t.Error("expected contexts without budget to be left alone")
+	}
+	if _, _, ok := QueryBudgetUsage(ctx); ok {
+		t.Error("expected no usage without budget")
+	}
+}
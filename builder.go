@@ -0,0 +1,393 @@
+package pg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryArgs collects positional arguments while a statement is being rendered.
+type queryArgs struct {
+	values []any
+}
+
+func (a *queryArgs) add(value any) string {
+	a.values = append(a.values, value)
+	return "$" + strconv.Itoa(len(a.values))
+}
+
+// Condition renders a boolean SQL expression, binding its values as positional arguments.
+type Condition func(args *queryArgs) string
+
+func compare(column string, operator string, value any) Condition {
+	return func(args *queryArgs) string {
+		return QuoteIdentifier(column) + " " + operator + " " + args.add(value)
+	}
+}
+
+func Eq(column string, value any) Condition    { return compare(column, "=", value) }
+func Ne(column string, value any) Condition    { return compare(column, "<>", value) }
+func Lt(column string, value any) Condition    { return compare(column, "<", value) }
+func Le(column string, value any) Condition    { return compare(column, "<=", value) }
+func Gt(column string, value any) Condition    { return compare(column, ">", value) }
+func Ge(column string, value any) Condition    { return compare(column, ">=", value) }
+func Like(column string, value any) Condition  { return compare(column, "LIKE", value) }
+func ILike(column string, value any) Condition { return compare(column, "ILIKE", value) }
+
+// In matches column against any element of values, which is passed as a single array parameter.
+func In(column string, values any) Condition {
+	return func(args *queryArgs) string {
+		return QuoteIdentifier(column) + " = ANY(" + args.add(values) + ")"
+	}
+}
+
+func IsNull(column string) Condition {
+	return func(args *queryArgs) string {
+		return QuoteIdentifier(column) + " IS NULL"
+	}
+}
+
+func IsNotNull(column string) Condition {
+	return func(args *queryArgs) string {
+		return QuoteIdentifier(column) + " IS NOT NULL"
+	}
+}
+
+// Raw embeds a hand-written expression. Each ? in sql is bound to the next value in args; ?
+// inside literals, quoted identifiers, dollar quoted strings and comments is left alone, and ?? is
+// written as a single ?, so the jsonb operators are spelled ??, ??| and ??&. Raw panics when the
+// number of placeholders and args differ.
+func Raw(sql string, args ...any) Condition {
+	parts := splitPlaceholders(sql)
+	if len(parts)-1 != len(args) {
+		panic(fmt.Sprintf("pg.Raw: %v placeholders but %v args in %q", len(parts)-1, len(args), sql))
+	}
+	return func(a *queryArgs) string {
+		var sb strings.Builder
+		sb.WriteString(parts[0])
+		for i, arg := range args {
+			sb.WriteString(a.add(arg))
+			sb.WriteString(parts[i+1])
+		}
+		return sb.String()
+	}
+}
+
+// splitPlaceholders splits sql at its ? placeholders, skipping literals, quoted identifiers,
+// dollar quoted strings and comments as splitScript does, and unescaping ??.
+func splitPlaceholders(sql string) []string {
+	var parts []string
+	var sb strings.Builder
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '?' && strings.HasPrefix(sql[i+1:], "?"):
+			sb.WriteByte('?')
+			i++
+		case c == '?':
+			parts = append(parts, sb.String())
+			sb.Reset()
+		case c == '\'' || c == '"':
+			backslash := c == '\'' && i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e')
+			j := i + 1
+			for ; j < len(sql) && sql[j] != c; j++ {
+				if backslash && sql[j] == '\\' {
+					j++
+				}
+			}
+			sb.WriteString(sql[i:min(j+1, len(sql))])
+			i = j
+		case c == '$' && dollarTag.MatchString(sql[i:]):
+			tag := dollarTag.FindString(sql[i:])
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				end = len(sql) - i - len(tag)
+			}
+			end = min(i+len(tag)+end+len(tag), len(sql))
+			sb.WriteString(sql[i:end])
+			i = end - 1
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			sb.WriteString(sql[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			depth, j := 1, i+2
+			for ; j < len(sql) && depth > 0; j++ {
+				switch {
+				case strings.HasPrefix(sql[j:], "/*"):
+					depth++
+					j++
+				case strings.HasPrefix(sql[j:], "*/"):
+					depth--
+					j++
+				}
+			}
+			sb.WriteString(sql[i:min(j, len(sql))])
+			i = j - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return append(parts, sb.String())
+}
+
+func join(operator string, conditions []Condition) Condition {
+	return func(args *queryArgs) string {
+		parts := make([]string, 0, len(conditions))
+		for _, condition := range conditions {
+			if condition != nil {
+				parts = append(parts, condition(args))
+			}
+		}
+		if len(parts) == 0 {
+			return "TRUE"
+		}
+		if len(parts) == 1 {
+			return parts[0]
+		}
+		return "(" + strings.Join(parts, " "+operator+" ") + ")"
+	}
+}
+
+// And combines conditions; nil conditions are skipped so optional filters can be passed directly.
+func And(conditions ...Condition) Condition { return join("AND", conditions) }
+
+// Or combines conditions; nil conditions are skipped so optional filters can be passed directly.
+func Or(conditions ...Condition) Condition { return join("OR", conditions) }
+
+func Not(condition Condition) Condition {
+	return func(args *queryArgs) string {
+		return "NOT (" + condition(args) + ")"
+	}
+}
+
+func renderWhere(sb *strings.Builder, args *queryArgs, conditions []Condition) {
+	if len(conditions) == 0 {
+		return
+	}
+	sb.WriteString(" WHERE ")
+	sb.WriteString(And(conditions...)(args))
+}
+
+func renderReturning(sb *strings.Builder, returning []string) {
+	if len(returning) == 0 {
+		return
+	}
+	sb.WriteString(" RETURNING ")
+	sb.WriteString(quoteIdentifiers(returning))
+}
+
+type SelectBuilder struct {
+	columns []string
+	table   string
+	where   []Condition
+	orderBy []string
+	limit   int
+	offset  int
+	asOf    *time.Time
+}
+
+// Select starts a SELECT statement. No columns selects *.
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.table = table
+	return b
+}
+
+func (b *SelectBuilder) Where(conditions ...Condition) *SelectBuilder {
+	b.where = append(b.where, conditions...)
+	return b
+}
+
+// OrderBy adds sort columns; prefix a column with - to sort it descending.
+func (b *SelectBuilder) OrderBy(columns ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, columns...)
+	return b
+}
+
+func (b *SelectBuilder) Limit(limit int) *SelectBuilder {
+	b.limit = limit
+	return b
+}
+
+func (b *SelectBuilder) Offset(offset int) *SelectBuilder {
+	b.offset = offset
+	return b
+}
+
+func (b *SelectBuilder) Build() (string, []any) {
+	args := &queryArgs{}
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		sb.WriteString("*")
+	} else {
+		sb.WriteString(quoteIdentifiers(b.columns))
+	}
+	sb.WriteString(" FROM ")
+	if b.asOf != nil {
+		sb.WriteString(temporalSource(b.table, *b.asOf, args))
+	} else {
+		sb.WriteString(QuoteIdentifier(b.table))
+	}
+	renderWhere(&sb, args, b.where)
+	if len(b.orderBy) > 0 {
+		orderBy := Map(b.orderBy, func(column string) string {
+			if strings.HasPrefix(column, "-") {
+				return QuoteIdentifier(strings.TrimPrefix(column, "-")) + " DESC"
+			}
+			return QuoteIdentifier(column)
+		})
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(orderBy, ", "))
+	}
+	if b.limit > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(args.add(b.limit))
+	}
+	if b.offset > 0 {
+		sb.WriteString(" OFFSET ")
+		sb.WriteString(args.add(b.offset))
+	}
+	return sb.String(), args.values
+}
+
+type InsertBuilder struct {
+	table     string
+	columns   []string
+	rows      [][]any
+	returning []string
+}
+
+func InsertInto(table string) *InsertBuilder {
+	return &InsertBuilder{table: table}
+}
+
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = columns
+	return b
+}
+
+// Values adds a row; call it repeatedly to insert several rows in one statement.
+func (b *InsertBuilder) Values(values ...any) *InsertBuilder {
+	b.rows = append(b.rows, values)
+	return b
+}
+
+func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	b.returning = columns
+	return b
+}
+
+func (b *InsertBuilder) Build() (string, []any) {
+	args := &queryArgs{}
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(QuoteIdentifier(b.table))
+	if len(b.columns) == 0 {
+		// A row of defaults only, as VALUES () is not valid SQL.
+		if len(b.rows) > 1 {
+			panic(fmt.Sprintf("pg.InsertInto: %v rows without columns, DEFAULT VALUES inserts one", len(b.rows)))
+		}
+		sb.WriteString(" DEFAULT VALUES")
+		renderReturning(&sb, b.returning)
+		return sb.String(), args.values
+	}
+	sb.WriteString(" (")
+	sb.WriteString(quoteIdentifiers(b.columns))
+	sb.WriteString(") VALUES ")
+	for i, row := range b.rows {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		sb.WriteString(strings.Join(Map(row, args.add), ", "))
+		sb.WriteString(")")
+	}
+	renderReturning(&sb, b.returning)
+	return sb.String(), args.values
+}
+
+type UpdateBuilder struct {
+	table     string
+	columns   []string
+	values    []any
+	where     []Condition
+	returning []string
+}
+
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+func (b *UpdateBuilder) Set(column string, value any) *UpdateBuilder {
+	b.columns = append(b.columns, column)
+	b.values = append(b.values, value)
+	return b
+}
+
+func (b *UpdateBuilder) Where(conditions ...Condition) *UpdateBuilder {
+	b.where = append(b.where, conditions...)
+	return b
+}
+
+func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
+	b.returning = columns
+	return b
+}
+
+func (b *UpdateBuilder) Build() (string, []any) {
+	args := &queryArgs{}
+	var sb strings.Builder
+	sb.WriteString("UPDATE ")
+	sb.WriteString(QuoteIdentifier(b.table))
+	sb.WriteString(" SET ")
+	for i, column := range b.columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(QuoteIdentifier(column))
+		sb.WriteString(" = ")
+		sb.WriteString(args.add(b.values[i]))
+	}
+	renderWhere(&sb, args, b.where)
+	renderReturning(&sb, b.returning)
+	return sb.String(), args.values
+}
+
+type DeleteBuilder struct {
+	table     string
+	where     []Condition
+	returning []string
+}
+
+func DeleteFrom(table string) *DeleteBuilder {
+	return &DeleteBuilder{table: table}
+}
+
+func (b *DeleteBuilder) Where(conditions ...Condition) *DeleteBuilder {
+	b.where = append(b.where, conditions...)
+	return b
+}
+
+func (b *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
+	b.returning = columns
+	return b
+}
+
+func (b *DeleteBuilder) Build() (string, []any) {
+	args := &queryArgs{}
+	var sb strings.Builder
+	sb.WriteString("DELETE FROM ")
+	sb.WriteString(QuoteIdentifier(b.table))
+	renderWhere(&sb, args, b.where)
+	renderReturning(&sb, b.returning)
+	return sb.String(), args.values
+}
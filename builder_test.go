@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	var nameFilter Condition
+	sql, args := Select("id", "name").
+		From("public.users").
+		Where(Eq("status", "active"), Or(Gt("age", 18), IsNull("age")), nameFilter).
+		OrderBy("-created_at", "id").
+		Limit(10).
+		Build()
+	expected := `SELECT "id", "name" FROM "public"."users" WHERE ("status" = $1 AND ("age" > $2 OR "age" IS NULL)) ORDER BY "created_at" DESC, "id" LIMIT $3`
+	if sql != expected {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if !reflect.DeepEqual(args, []any{"active", 18, 10}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestSelectBuilderQuotesIdentifiers(t *testing.T) {
+	sql, _ := Select().From(`users"; DROP TABLE users; --`).Build()
+	if sql != `SELECT * FROM "users""; DROP TABLE users; --"` {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+}
+
+func TestInsertBuilder(t *testing.T) {
+	sql, args := InsertInto("users").Columns("id", "name").Values(1, "a").Values(2, "b").Returning("id").Build()
+	if sql != `INSERT INTO "users" ("id", "name") VALUES ($1, $2), ($3, $4) RETURNING "id"` {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if !reflect.DeepEqual(args, []any{1, "a", 2, "b"}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected several rows without columns to panic")
+		}
+	}()
+	InsertInto("users").Values().Values().Build()
+}
+
+func TestUpdateBuilder(t *testing.T) {
+	sql, args := Update("users").Set("name", "a").Where(In("id", []int{1, 2}), Raw("age > ? + ?", 1, 2)).Returning("id").Build()
+	if sql != `UPDATE "users" SET "name" = $1 WHERE ("id" = ANY($2) AND age > $3 + $4) RETURNING "id"` {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if len(args) != 4 {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestRawPlaceholders(t *testing.T) {
+	args := &queryArgs{}
+	sql := Raw(`data ?? ? AND tags ??| ? AND name <> '?' AND "a?" = $x$?$x$ -- ?
+AND e = E'\'?' /* ? /* nested ? */ ? */ AND n = ?`, "key", []string{"a"}, 1)(args)
+	expected := `data ? $1 AND tags ?| $2 AND name <> '?' AND "a?" = $x$?$x$ -- ?
+AND e = E'\'?' /* ? /* nested ? */ ? */ AND n = $3`
+	if sql != expected {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if !reflect.DeepEqual(args.values, []any{"key", []string{"a"}, 1}) {
+		t.Errorf("unexpected args: %v", args.values)
+	}
+}
+
+func TestRawArgumentMismatch(t *testing.T) {
+	for _, args := range [][]any{{1}, {1, 2, 3}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %v args for two placeholders to panic", len(args))
+				}
+			}()
+			Raw("a = ? AND b = ?", args...)
+		}()
+	}
+}
+
+func TestDeleteBuilder(t *testing.T) {
+	sql, args := DeleteFrom("users").Where(Not(Eq("id", 1))).Build()
+	if sql != `DELETE FROM "users" WHERE NOT ("id" = $1)` {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if !reflect.DeepEqual(args, []any{1}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	pg "github.com/msumera/pgutils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Channel carries the names of changed tables from the triggers created by Trigger.
+const Channel = "pgutils_cache_invalidate"
+
+type entry[T any] struct {
+	value   T
+	tables  []string
+	expires time.Time
+}
+
+// Cache is a read-through cache whose entries depend on tables. When one of those tables changes
+// on any instance, its trigger notifies Channel and every instance drops the dependent entries.
+type Cache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]entry[T]
+	byTable map[string]map[string]struct{}
+	// generation counts invalidations, so a load racing an invalidation is not cached.
+	generation uint64
+
+	// TTL bounds how long entries live even without invalidations; zero keeps them until invalidated.
+	TTL time.Duration
+}
+
+func New[T any](ttl time.Duration) *Cache[T] {
+	return &Cache[T]{
+		entries: make(map[string]entry[T]),
+		byTable: make(map[string]map[string]struct{}),
+		TTL:     ttl,
+	}
+}
+
+// Get returns the cached value for key, or calls load and caches its result as depending on
+// tables, e.g. "users" or "billing.invoices". Errors are not cached.
+func (c *Cache[T]) Get(ctx context.Context, key string, tables []string, load func(ctx context.Context) (T, error)) (T, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && (e.expires.IsZero() || time.Now().Before(e.expires)) {
+		return e.value, nil
+	}
+	value, err := load(ctx)
+	if err != nil {
+		return value, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return value, nil
+	}
+	c.remove(key)
+	e = entry[T]{value: value, tables: tables}
+	if c.TTL > 0 {
+		e.expires = time.Now().Add(c.TTL)
+	}
+	c.entries[key] = e
+	for _, table := range tables {
+		if c.byTable[table] == nil {
+			c.byTable[table] = make(map[string]struct{})
+		}
+		c.byTable[table][key] = struct{}{}
+	}
+	return value, nil
+}
+
+func (c *Cache[T]) remove(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	for _, table := range e.tables {
+		delete(c.byTable[table], key)
+		if len(c.byTable[table]) == 0 {
+			delete(c.byTable, table)
+		}
+	}
+}
+
+// Invalidate drops the entries depending on table. A schema qualified name also drops entries
+// registered under the bare table name.
+func (c *Cache[T]) Invalidate(table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	names := []string{table}
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		names = append(names, table[i+1:])
+	}
+	for _, name := range names {
+		for key := range c.byTable[name] {
+			c.remove(key)
+		}
+	}
+}
+
+func (c *Cache[T]) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = make(map[string]entry[T])
+	c.byTable = make(map[string]map[string]struct{})
+}
+
+// Register subscribes the cache to Channel on l and flushes it whenever l reconnects, as
+// invalidations may have been missed in the meantime. Call it before l.Listen.
+func (c *Cache[T]) Register(l *pg.Listener) {
+	l.Handle(Channel, func(ctx context.Context, notification *pgconn.Notification) {
+		c.Invalidate(notification.Payload)
+	})
+	onConnect := l.OnConnect
+	l.OnConnect = func() {
+		if onConnect != nil {
+			onConnect()
+		}
+		c.Flush()
+	}
+}
+
+// Trigger returns the script installing the notification trigger on table, for inclusion in a
+// migrations directory. The payload is the schema qualified table name.
+func Trigger(table string) string {
+	name := pgx.Identifier{"pgutils_cache_" + strings.ReplaceAll(table, ".", "_")}.Sanitize()
+	quoted := pg.QuoteIdentifier(table)
+	return `
+		CREATE OR REPLACE FUNCTION pgutils_cache_notify() RETURNS trigger AS $$
+		BEGIN
+			PERFORM pg_notify('` + Channel + `', TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME);
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+		DROP TRIGGER IF EXISTS ` + name + ` ON ` + quoted + `;
+		CREATE TRIGGER ` + name + ` AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON ` + quoted + `
+			FOR EACH STATEMENT EXECUTE FUNCTION pgutils_cache_notify();
+	`
+}
+
+// InstallTrigger runs the script returned by Trigger.
+func InstallTrigger(ctx context.Context, q pg.Querier, table string) error {
+	_, err := q.Exec(ctx, Trigger(table))
+	return err
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestInvalidate(t *testing.T) {
+	c := New[int](0)
+	loads := 0
+	load := func(ctx context.Context) (int, error) {
+		loads++
+		return loads, nil
+	}
+	ctx := context.Background()
+	_, _ = c.Get(ctx, "user:1", []string{"users"}, load)
+	_, _ = c.Get(ctx, "order:1", []string{"orders"}, load)
+	value, _ := c.Get(ctx, "user:1", []string{"users"}, load)
+	if value != 1 || loads != 2 {
+		t.Errorf("expected a cached value, got %v after %v loads", value, loads)
+	}
+	c.Invalidate("public.users")
+	value, _ = c.Get(ctx, "user:1", []string{"users"}, load)
+	if value != 3 {
+		t.Errorf("expected a reload after invalidation, got %v", value)
+	}
+	value, _ = c.Get(ctx, "order:1", []string{"orders"}, load)
+	if value != 2 {
+		t.Errorf("entries of other tables should be kept, got %v", value)
+	}
+}
+
+func TestInvalidateDuringLoad(t *testing.T) {
+	c := New[string](time.Minute)
+	ctx := context.Background()
+	_, _ = c.Get(ctx, "k", []string{"t"}, func(ctx context.Context) (string, error) {
+		c.Invalidate("t")
+		return "stale", nil
+	})
+	value, _ := c.Get(ctx, "k", []string{"t"}, func(ctx context.Context) (string, error) {
+		return "fresh", nil
+	})
+	if value != "fresh" {
+		t.Errorf("a value loaded during an invalidation should not be cached, got %v", value)
+	}
+}
+
+func TestTriggerInvalidation(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("../testdb"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := InstallTrigger(ctx, db.Pool, "testtable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New[int64](0)
+	l := pg.NewListener(db.Pool)
+	connected := make(chan struct{}, 1)
+	l.OnConnect = func() { connected <- struct{}{} }
+	c.Register(l)
+	go func() { _ = l.Listen(ctx) }()
+	<-connected
+
+	count := func(ctx context.Context) (int64, error) {
+		return pg.Count(ctx, db.Pool, "SELECT * FROM testtable")
+	}
+	_, err = c.Get(ctx, "count", []string{"testtable"}, count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Pool.Exec(ctx, "INSERT INTO testtable (id, name) VALUES (2, 'name2')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		value, err := c.Get(ctx, "count", []string{"testtable"}, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value == 2 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Error("the entry was not invalidated by the trigger")
+}
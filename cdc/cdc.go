@@ -0,0 +1,79 @@
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	Insert Kind = "insert"
+	Update Kind = "update"
+	Delete Kind = "delete"
+)
+
+// LSN is a position in the write-ahead log.
+type LSN uint64
+
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+// Change is a row change decoded from the replication stream. Values are decoded into the Go types
+// pgx uses for the column types. Old is only set for updates and deletes, and holds either the
+// replica identity columns or, with REPLICA IDENTITY FULL, the whole previous row.
+type Change struct {
+	Kind       Kind
+	Schema     string
+	Table      string
+	Old        map[string]any
+	New        map[string]any
+	CommitTime time.Time
+	Xid        uint32
+}
+
+type Handler func(ctx context.Context, change Change) error
+
+// Setup creates the publication for tables, or for all tables when none are given, and the
+// logical replication slot using the pgoutput plugin. Either is left alone if it already exists.
+// The server must run with wal_level=logical.
+func Setup(ctx context.Context, q pg.Querier, publication string, slot string, tables ...string) error {
+	exists, err := pg.Exists(ctx, q, "SELECT 1 FROM pg_publication WHERE pubname = $1", publication)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		target := "ALL TABLES"
+		if len(tables) > 0 {
+			target = "TABLE " + strings.Join(pg.Map(tables, func(table string) string {
+				return pg.QuoteIdentifier(table)
+			}), ", ")
+		}
+		_, err = q.Exec(ctx, "CREATE PUBLICATION "+pgx.Identifier{publication}.Sanitize()+" FOR "+target)
+		if err != nil {
+			return err
+		}
+	}
+	exists, err = pg.Exists(ctx, q, "SELECT 1 FROM pg_replication_slots WHERE slot_name = $1", slot)
+	if err != nil || exists {
+		return err
+	}
+	_, err = q.Exec(ctx, "SELECT pg_create_logical_replication_slot($1, 'pgoutput')", slot)
+	return err
+}
+
+// Drop removes the slot and the publication. A slot that is not consumed makes the server retain
+// WAL indefinitely, so consumers that are retired must be dropped.
+func Drop(ctx context.Context, q pg.Querier, publication string, slot string) error {
+	_, err := q.Exec(ctx, "SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots WHERE slot_name = $1", slot)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, "DROP PUBLICATION IF EXISTS "+pgx.Identifier{publication}.Sanitize())
+	return err
+}
@@ -0,0 +1,204 @@
+package cdc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+var errShortMessage = errors.New("pgoutput message is truncated")
+
+// postgresEpoch is the zero point of the timestamps in the replication protocol.
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type relationColumn struct {
+	name    string
+	typeOID uint32
+}
+
+type relation struct {
+	schema  string
+	table   string
+	columns []relationColumn
+}
+
+// decoder turns pgoutput (protocol version 1) messages into changes. It keeps the relation
+// metadata that postgres sends once before the first change to each table.
+type decoder struct {
+	typeMap    *pgtype.Map
+	relations  map[uint32]relation
+	commitTime time.Time
+	xid        uint32
+}
+
+func newDecoder() *decoder {
+	return &decoder{
+		typeMap:   pgtype.NewMap(),
+		relations: make(map[uint32]relation),
+	}
+}
+
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil || len(r.data) < n {
+		r.err = errShortMessage
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) byte() byte     { return r.take(1)[0] }
+func (r *reader) uint16() uint16 { return binary.BigEndian.Uint16(r.take(2)) }
+func (r *reader) uint32() uint32 { return binary.BigEndian.Uint32(r.take(4)) }
+func (r *reader) uint64() uint64 { return binary.BigEndian.Uint64(r.take(8)) }
+
+func (r *reader) string() string {
+	for i, b := range r.data {
+		if b == 0 {
+			s := string(r.data[:i])
+			r.data = r.data[i+1:]
+			return s
+		}
+	}
+	r.err = errShortMessage
+	return ""
+}
+
+func (r *reader) time() time.Time {
+	return postgresEpoch.Add(time.Duration(int64(r.uint64())) * time.Microsecond)
+}
+
+// decode handles one message. It returns the change it carries, if any, and the end LSN of the
+// transaction when the message is a commit.
+func (d *decoder) decode(data []byte) (*Change, LSN, error) {
+	if len(data) == 0 {
+		return nil, 0, errShortMessage
+	}
+	r := &reader{data: data[1:]}
+	var change *Change
+	var commitLSN LSN
+	switch data[0] {
+	case 'B':
+		r.uint64()
+		d.commitTime = r.time()
+		d.xid = r.uint32()
+	case 'C':
+		r.byte()
+		r.uint64()
+		commitLSN = LSN(r.uint64())
+	case 'R':
+		id := r.uint32()
+		rel := relation{schema: r.string(), table: r.string()}
+		r.byte()
+		columns := int(r.uint16())
+		for i := 0; i < columns && r.err == nil; i++ {
+			r.byte()
+			column := relationColumn{name: r.string(), typeOID: r.uint32()}
+			r.uint32()
+			rel.columns = append(rel.columns, column)
+		}
+		d.relations[id] = rel
+	case 'I':
+		rel, err := d.relation(r.uint32())
+		if err != nil {
+			return nil, 0, err
+		}
+		r.byte()
+		change = d.change(Insert, rel)
+		change.New, err = d.tuple(r, rel)
+		if err != nil {
+			return nil, 0, err
+		}
+	case 'U':
+		rel, err := d.relation(r.uint32())
+		if err != nil {
+			return nil, 0, err
+		}
+		change = d.change(Update, rel)
+		kind := r.byte()
+		if kind == 'K' || kind == 'O' {
+			change.Old, err = d.tuple(r, rel)
+			if err != nil {
+				return nil, 0, err
+			}
+			r.byte()
+		}
+		change.New, err = d.tuple(r, rel)
+		if err != nil {
+			return nil, 0, err
+		}
+	case 'D':
+		rel, err := d.relation(r.uint32())
+		if err != nil {
+			return nil, 0, err
+		}
+		r.byte()
+		change = d.change(Delete, rel)
+		change.Old, err = d.tuple(r, rel)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	// Truncate, type, origin and logical decoding messages are not reported.
+	return change, commitLSN, r.err
+}
+
+func (d *decoder) relation(id uint32) (relation, error) {
+	rel, ok := d.relations[id]
+	if !ok {
+		return relation{}, fmt.Errorf("change for unknown relation %v", id)
+	}
+	return rel, nil
+}
+
+func (d *decoder) change(kind Kind, rel relation) *Change {
+	return &Change{
+		Kind:       kind,
+		Schema:     rel.schema,
+		Table:      rel.table,
+		CommitTime: d.commitTime,
+		Xid:        d.xid,
+	}
+}
+
+// tuple decodes the text representation of each column with the codec registered for its type.
+// Unchanged TOAST values are left out.
+func (d *decoder) tuple(r *reader, rel relation) (map[string]any, error) {
+	columns := int(r.uint16())
+	values := make(map[string]any, columns)
+	for i := 0; i < columns && r.err == nil; i++ {
+		kind := r.byte()
+		if i >= len(rel.columns) {
+			return nil, fmt.Errorf("tuple for %v.%v has more columns than its relation", rel.schema, rel.table)
+		}
+		column := rel.columns[i]
+		switch kind {
+		case 'n':
+			values[column.name] = nil
+		case 't':
+			data := r.take(int(r.uint32()))
+			value, err := d.value(column.typeOID, data)
+			if err != nil {
+				return nil, fmt.Errorf("column %v: %w", column.name, err)
+			}
+			values[column.name] = value
+		}
+	}
+	return values, r.err
+}
+
+func (d *decoder) value(typeOID uint32, data []byte) (any, error) {
+	t, ok := d.typeMap.TypeForOID(typeOID)
+	if !ok {
+		return string(data), nil
+	}
+	return t.Codec.DecodeValue(d.typeMap, typeOID, pgtype.TextFormatCode, data)
+}
@@ -0,0 +1,80 @@
+package cdc
+
+import (
+	"encoding/binary"
+	"github.com/jackc/pgx/v5/pgtype"
+	"testing"
+)
+
+type message []byte
+
+func (m message) byte(b byte) message { return append(m, b) }
+func (m message) uint16(v uint16) message {
+	return binary.BigEndian.AppendUint16(m, v)
+}
+func (m message) uint32(v uint32) message {
+	return binary.BigEndian.AppendUint32(m, v)
+}
+func (m message) uint64(v uint64) message {
+	return binary.BigEndian.AppendUint64(m, v)
+}
+func (m message) string(s string) message { return append(append(m, s...), 0) }
+func (m message) text(s string) message {
+	return append(m.byte('t').uint32(uint32(len(s))), s...)
+}
+
+func TestDecode(t *testing.T) {
+	d := newDecoder()
+	relationMessage := message{'R'}.uint32(42).string("public").string("users").byte('d').uint16(2).
+		byte(1).string("id").uint32(pgtype.Int8OID).uint32(0).
+		byte(0).string("name").uint32(pgtype.TextOID).uint32(0)
+	messages := []message{
+		message{'B'}.uint64(0x100).uint64(0).uint32(7),
+		relationMessage,
+		message{'I'}.uint32(42).byte('N').uint16(2).text("1").text("alice"),
+		message{'U'}.uint32(42).byte('K').uint16(2).text("1").byte('n').byte('N').uint16(2).text("1").text("bob"),
+		message{'D'}.uint32(42).byte('K').uint16(2).text("1").byte('n'),
+	}
+	var changes []*Change
+	for _, m := range messages {
+		change, commitLSN, err := d.decode(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if commitLSN != 0 {
+			t.Error("only commits carry an LSN")
+		}
+		if change != nil {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %v", len(changes))
+	}
+	if changes[0].Kind != Insert || changes[0].Table != "users" || changes[0].New["id"] != int64(1) || changes[0].New["name"] != "alice" {
+		t.Errorf("unexpected insert: %+v", changes[0])
+	}
+	if changes[1].Kind != Update || changes[1].Old["id"] != int64(1) || changes[1].New["name"] != "bob" || changes[1].Xid != 7 {
+		t.Errorf("unexpected update: %+v", changes[1])
+	}
+	if changes[2].Kind != Delete || changes[2].Old["id"] != int64(1) || changes[2].New != nil {
+		t.Errorf("unexpected delete: %+v", changes[2])
+	}
+
+	_, commitLSN, err := d.decode(message{'C'}.byte(0).uint64(0x100).uint64(0x1_0000_0200).uint64(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commitLSN.String() != "1/200" {
+		t.Errorf("unexpected commit LSN %v", commitLSN)
+	}
+
+	_, _, err = d.decode(message{'I'}.uint32(43).byte('N').uint16(0))
+	if err == nil {
+		t.Error("expected an error for an unknown relation")
+	}
+	_, _, err = d.decode(message{'I'}.uint32(42).byte('N').uint16(1).byte('t').uint32(10))
+	if err == nil {
+		t.Error("expected an error for a truncated message")
+	}
+}
@@ -0,0 +1,212 @@
+package cdc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	pg "github.com/msumera/pgutils"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const (
+	// StatusInterval is how often the consumer reports its position to the server.
+	StatusInterval = 10 * time.Second
+
+	objectInUse = "55006"
+)
+
+// ErrSlotInUse is returned by Stream when another consumer is attached to the slot.
+var ErrSlotInUse = errors.New("replication slot is in use by another consumer")
+
+// Stream consumes the slot created by Setup and calls handler for every change, in commit order.
+// The position is acknowledged to the server after all changes of a transaction were handled,
+// which is the checkpoint: after a restart or a handler error, streaming resumes with the first
+// transaction that was not fully handled, so delivery is at-least-once. Between transactions the
+// WAL end reported by the server is acknowledged too, so the slot advances while only unpublished
+// tables are written. Cancelling ctx stops streaming and returns nil.
+func Stream(ctx context.Context, c pg.Configuration, publication string, slot string, handler Handler) error {
+	conn, err := pgconn.Connect(ctx, pg.ConnectionString(c)+"&replication=database")
+	if err != nil {
+		return err
+	}
+	defer func(conn *pgconn.PgConn) {
+		_ = conn.Close(context.Background())
+	}(conn)
+	// The server picks up from the slot's confirmed position when started at 0/0.
+	start := "START_REPLICATION SLOT " + pgx.Identifier{slot}.Sanitize() + " LOGICAL 0/0 (proto_version '1', publication_names " +
+		quoteLiteral(publication) + ")"
+	conn.Frontend().Send(&pgproto3.Query{String: start})
+	err = conn.Frontend().Flush()
+	if err != nil {
+		return err
+	}
+	err = awaitCopyBoth(ctx, conn)
+	if err != nil {
+		return err
+	}
+	s := &stream{conn: conn, decoder: newDecoder(), handler: handler}
+	err = s.run(ctx)
+	// Report the final position so a restart does not redeliver handled transactions.
+	_ = s.sendStatus()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func awaitCopyBoth(ctx context.Context, conn *pgconn.PgConn) error {
+	for {
+		msg, err := conn.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.CopyBothResponse:
+			return nil
+		case *pgproto3.ErrorResponse:
+			pgErr := pgconn.ErrorResponseToPgError(msg)
+			if pgErr.Code == objectInUse {
+				return ErrSlotInUse
+			}
+			return pgErr
+		}
+	}
+}
+
+type stream struct {
+	conn    *pgconn.PgConn
+	decoder *decoder
+	handler Handler
+	// confirmed is the end of the last transaction whose changes were all handled, or the end of
+	// the WAL the server reported while no transaction was being received.
+	confirmed LSN
+	// inTransaction is set from a transaction's begin to its commit.
+	inTransaction bool
+	// pending holds the changes of the transaction being received.
+	pending []Change
+}
+
+func (s *stream) run(ctx context.Context) error {
+	nextStatus := time.Now().Add(StatusInterval)
+	for {
+		if time.Now().After(nextStatus) {
+			err := s.sendStatus()
+			if err != nil {
+				return err
+			}
+			nextStatus = time.Now().Add(StatusInterval)
+		}
+		receiveCtx, cancel := context.WithDeadline(ctx, nextStatus)
+		msg, err := s.conn.ReceiveMessage(receiveCtx)
+		cancel()
+		if pgconn.Timeout(err) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.CopyData:
+			replyRequested, err := s.handleCopyData(ctx, msg.Data)
+			if err != nil {
+				return err
+			}
+			if replyRequested {
+				nextStatus = time.Time{}
+			}
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(msg)
+		}
+	}
+}
+
+func (s *stream) handleCopyData(ctx context.Context, data []byte) (bool, error) {
+	if len(data) == 0 {
+		return false, errShortMessage
+	}
+	switch data[0] {
+	case 'k':
+		// Primary keepalive: WAL end, server time and whether a reply is requested.
+		if len(data) < 18 {
+			return false, errShortMessage
+		}
+		// The server skips transactions touching no published table, so between transactions
+		// everything up to its WAL end was delivered; confirming it lets the slot advance while
+		// only other tables are written.
+		s.advance(LSN(binary.BigEndian.Uint64(data[1:9])))
+		return data[17] == 1, nil
+	case 'w':
+		// XLogData: WAL start, WAL end and server time precede the pgoutput message.
+		if len(data) < 25 {
+			return false, errShortMessage
+		}
+		err := s.handleMessage(ctx, data[25:])
+		if err != nil {
+			return false, err
+		}
+		s.advance(LSN(binary.BigEndian.Uint64(data[1:9])) + LSN(len(data)-25))
+		return false, nil
+	}
+	return false, nil
+}
+
+func (s *stream) handleMessage(ctx context.Context, data []byte) error {
+	change, commitLSN, err := s.decoder.decode(data)
+	if err != nil {
+		return err
+	}
+	if data[0] == 'B' {
+		s.inTransaction = true
+	}
+	if change != nil {
+		s.pending = append(s.pending, *change)
+	}
+	if commitLSN == 0 {
+		return nil
+	}
+	for _, change := range s.pending {
+		err = s.handler(ctx, change)
+		if err != nil {
+			return fmt.Errorf("handling %v on %v.%v: %w", change.Kind, change.Schema, change.Table, err)
+		}
+	}
+	s.pending = s.pending[:0]
+	s.inTransaction = false
+	s.advance(commitLSN)
+	log.Debugf("Handled transaction up to %v", commitLSN)
+	return nil
+}
+
+// advance moves the confirmed position forward to lsn unless a transaction is being received.
+func (s *stream) advance(lsn LSN) {
+	if !s.inTransaction && lsn > s.confirmed {
+		s.confirmed = lsn
+	}
+}
+
+// sendStatus reports the confirmed position as written, flushed and applied, which lets the
+// server advance the slot and recycle WAL.
+func (s *stream) sendStatus() error {
+	data := make([]byte, 34)
+	data[0] = 'r'
+	binary.BigEndian.PutUint64(data[1:], uint64(s.confirmed))
+	binary.BigEndian.PutUint64(data[9:], uint64(s.confirmed))
+	binary.BigEndian.PutUint64(data[17:], uint64(s.confirmed))
+	binary.BigEndian.PutUint64(data[25:], uint64(time.Since(postgresEpoch).Microseconds()))
+	s.conn.Frontend().Send(&pgproto3.CopyData{Data: data})
+	err := s.conn.Frontend().Flush()
+	if err != nil {
+		return fmt.Errorf("sending standby status: %w", err)
+	}
+	return nil
+}
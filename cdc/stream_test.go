@@ -0,0 +1,135 @@
+package cdc
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func keepalive(walEnd uint64) []byte {
+	return message{'k'}.uint64(walEnd).uint64(0).byte(0)
+}
+
+func TestStreamConfirmsKeepalives(t *testing.T) {
+	s := &stream{decoder: newDecoder(), handler: func(ctx context.Context, change Change) error { return nil }}
+	ctx := context.Background()
+	_, err := s.handleCopyData(ctx, keepalive(0x100))
+	if err != nil || s.confirmed != 0x100 {
+		t.Errorf("expected the keepalive to be confirmed, got %v, %v", s.confirmed, err)
+	}
+	begin := message{'w'}.uint64(0x200).uint64(0x300).uint64(0)
+	begin = append(begin, message{'B'}.uint64(0x300).uint64(0).uint32(7)...)
+	_, err = s.handleCopyData(ctx, begin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.handleCopyData(ctx, keepalive(0x400))
+	if err != nil || s.confirmed != 0x100 {
+		t.Errorf("expected no confirmation inside a transaction, got %v, %v", s.confirmed, err)
+	}
+	other := message{'w'}.uint64(0x400).uint64(0x500).uint64(0)
+	other = append(other, message{'Y'}.uint32(1).string("public").string("t")...)
+	_, err = s.handleCopyData(ctx, other)
+	if err != nil || s.confirmed != 0x100 {
+		t.Errorf("expected no confirmation inside a transaction, got %v, %v", s.confirmed, err)
+	}
+	commit := message{'w'}.uint64(0x250).uint64(0x400).uint64(0)
+	commit = append(commit, message{'C'}.byte(0).uint64(0x250).uint64(0x300).uint64(0)...)
+	_, err = s.handleCopyData(ctx, commit)
+	if err != nil || s.confirmed != 0x300 {
+		t.Errorf("expected the end of the transaction to be confirmed, got %v, %v", s.confirmed, err)
+	}
+	_, err = s.handleCopyData(ctx, keepalive(0x100))
+	if err != nil || s.confirmed != 0x300 {
+		t.Errorf("expected the position not to move backwards, got %v, %v", s.confirmed, err)
+	}
+	_, err = s.handleCopyData(ctx, keepalive(0x600))
+	if err != nil || s.confirmed != 0x600 {
+		t.Errorf("expected the keepalive after the transaction to be confirmed, got %v, %v", s.confirmed, err)
+	}
+}
+
+func TestSetupAndStream(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithSettings(map[string]string{"wal_level": "logical"}))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE watched (id INT PRIMARY KEY, name TEXT); CREATE TABLE other (id INT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Setup(ctx, db.Pool, "changes", "changes_slot", "watched")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Setup leaves existing publications and slots alone.
+	err = Setup(ctx, db.Pool, "changes", "changes_slot", "watched")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan Change, 10)
+	streamCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- Stream(streamCtx, db.Configuration, "changes", "changes_slot", func(ctx context.Context, change Change) error {
+			changes <- change
+			return nil
+		})
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+		err := Drop(ctx, db.Pool, "changes", "changes_slot")
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	_, err = db.Pool.Exec(ctx, "INSERT INTO watched VALUES (1, 'a')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case change := <-changes:
+		if change.Kind != Insert || change.Table != "watched" || change.New["name"] != "a" {
+			t.Errorf("unexpected change %+v", change)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the change was not streamed")
+	}
+
+	// Only an unpublished table is written, yet the slot advances past it.
+	for i := 0; i < 10; i++ {
+		_, err = db.Pool.Exec(ctx, "INSERT INTO other VALUES ($1)", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var walEnd string
+	err = db.Pool.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&walEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(3 * StatusInterval)
+	for {
+		var advanced bool
+		err = db.Pool.QueryRow(ctx, "SELECT confirmed_flush_lsn >= $1::pg_lsn FROM pg_replication_slots WHERE slot_name = 'changes_slot'",
+			walEnd).Scan(&advanced)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if advanced {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the slot did not advance past the transactions of the unpublished table")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	select {
+	case change := <-changes:
+		t.Errorf("unexpected change %+v", change)
+	default:
+	}
+}
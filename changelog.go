@@ -0,0 +1,189 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangelogKey selects how migration versions are stored in the changelog.
+type ChangelogKey string
+
+const (
+	// ChangelogKeyText stores the dotted version string only, which sorts as text: 10 before 9.
+	ChangelogKeyText ChangelogKey = "text"
+	// ChangelogKeyIntArray adds a version column holding the parts of the version as BIGINT[],
+	// which sorts like the migration files.
+	ChangelogKeyIntArray ChangelogKey = "int_array"
+	// ChangelogKeyBigint adds a BIGINT version column. Versions must be single numbers then, e.g.
+	// timestamps like 20240131120000.
+	ChangelogKeyBigint ChangelogKey = "bigint"
+
+	// ChangelogVersionColumn is the generated sort key added by the numeric key strategies.
+	ChangelogVersionColumn = "version"
+)
+
+// ChangelogEntry is a row of the changelog.
+type ChangelogEntry struct {
+	Version   string
+	Name      string
+	Filename  string
+	Status    string
+	Timestamp time.Time
+}
+
+func (k ChangelogKey) versionType() (string, error) {
+	switch k {
+	case "", ChangelogKeyText:
+		return "", nil
+	case ChangelogKeyIntArray:
+		return "BIGINT[] GENERATED ALWAYS AS (string_to_array(id, '.')::BIGINT[]) STORED", nil
+	case ChangelogKeyBigint:
+		return "BIGINT GENERATED ALWAYS AS (id::BIGINT) STORED", nil
+	}
+	return "", fmt.Errorf("unknown changelog key %q", k)
+}
+
+// initChangelogVersion adds the version column of the configured key strategy, also to changelogs
+// created before the strategy was chosen.
+func (dbm *databaseMigrator) initChangelogVersion() error {
+	versionType, err := dbm.Configuration.ChangelogKey.versionType()
+	if err != nil || versionType == "" {
+		return err
+	}
+	index := QuoteIdentifier(dbm.Configuration.ChangelogTable + "_" + ChangelogVersionColumn)
+	_, err = dbm.exec(dbm.PgxPool, dbm.replaceEnv(`
+		ALTER TABLE {SCHEMA_TABLE} ADD COLUMN IF NOT EXISTS `+ChangelogVersionColumn+` `+versionType+`;
+		CREATE UNIQUE INDEX IF NOT EXISTS `+index+` ON {SCHEMA_TABLE} (`+ChangelogVersionColumn+`);
+	`))
+	return err
+}
+
+const (
+	// MigrationCompleted and MigrationFailed are the statuses of changelog entries.
+	MigrationCompleted = statusCompleted
+	MigrationFailed    = statusError
+)
+
+var ErrChangelogEntryNotFound = errors.New("changelog entry not found")
+
+// ChangelogFilter narrows Changelog; zero fields match everything.
+type ChangelogFilter struct {
+	Status string
+	// Since and Until bound the time the migration was recorded.
+	Since time.Time
+	Until time.Time
+	// Search matches a substring of the name or file name, case insensitively.
+	Search string
+}
+
+func (f ChangelogFilter) conditions() []Condition {
+	var conditions []Condition
+	if f.Status != "" {
+		conditions = append(conditions, Eq("status", f.Status))
+	}
+	if !f.Since.IsZero() {
+		conditions = append(conditions, Ge("timestamp", f.Since))
+	}
+	if !f.Until.IsZero() {
+		conditions = append(conditions, Lt("timestamp", f.Until))
+	}
+	if f.Search != "" {
+		pattern := "%" + escapeLike(f.Search) + "%"
+		conditions = append(conditions, Or(ILike("name", pattern), ILike("filename", pattern)))
+	}
+	return conditions
+}
+
+// changelogStatement renders statement, which names the changelog {SCHEMA_TABLE}, followed by a
+// WHERE clause of the conditions.
+func (c Configuration) changelogStatement(statement string, conditions ...Condition) (string, []any) {
+	args := &queryArgs{}
+	var sb strings.Builder
+	sb.WriteString(strings.ReplaceAll(statement, "{SCHEMA_TABLE}", c.schemaTable()))
+	renderWhere(&sb, args, conditions)
+	return sb.String(), args.values
+}
+
+func scanChangelogEntry(row pgx.CollectableRow) (ChangelogEntry, error) {
+	var e ChangelogEntry
+	err := row.Scan(&e.Version, &e.Name, &e.Filename, &e.Status, &e.Timestamp)
+	return e, err
+}
+
+// Changelog returns the recorded migrations matching filter in version order. Use it rather than
+// querying the changelog table, whose shape may change.
+func Changelog(ctx context.Context, q Querier, c Configuration, filter ChangelogFilter) ([]ChangelogEntry, error) {
+	orderBy := "id"
+	if c.ChangelogKey != "" && c.ChangelogKey != ChangelogKeyText {
+		orderBy = ChangelogVersionColumn
+	}
+	sql, args := c.changelogStatement("SELECT id, name, filename, status, timestamp FROM {SCHEMA_TABLE}", filter.conditions()...)
+	rows, err := q.Query(ctx, sql+" ORDER BY "+QuoteIdentifier(orderBy), args...)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := pgx.CollectRows(rows, scanChangelogEntry)
+	if err != nil {
+		return nil, err
+	}
+	if orderBy == "id" {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return compareMigrationIds(versionParts(entries[i].Version), versionParts(entries[j].Version)) < 0
+		})
+	}
+	return entries, nil
+}
+
+// GetChangelogEntry returns the entry of version, or ErrChangelogEntryNotFound.
+func GetChangelogEntry(ctx context.Context, q Querier, c Configuration, version string) (ChangelogEntry, error) {
+	sql, args := c.changelogStatement("SELECT id, name, filename, status, timestamp FROM {SCHEMA_TABLE}", Eq("id", version))
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return ChangelogEntry{}, err
+	}
+	entry, err := pgx.CollectExactlyOneRow(rows, scanChangelogEntry)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ChangelogEntry{}, fmt.Errorf("%w: %v", ErrChangelogEntryNotFound, version)
+	}
+	return entry, err
+}
+
+// MarkResolved records the failed migration version as completed, after its changes were applied
+// or made unnecessary by hand, so Migrate skips it.
+func MarkResolved(ctx context.Context, q Querier, c Configuration, version string) error {
+	sql, args := c.changelogStatement("UPDATE {SCHEMA_TABLE} SET status = '"+MigrationCompleted+"', timestamp = now()",
+		Eq("id", version), Eq("status", MigrationFailed))
+	tag, err := q.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: no failed migration %v", ErrChangelogEntryNotFound, version)
+	}
+	return nil
+}
+
+// DeleteFailed removes the entries of failed migrations, so Migrate runs them again. It returns
+// the number of entries removed.
+func DeleteFailed(ctx context.Context, q Querier, c Configuration) (int64, error) {
+	sql, args := c.changelogStatement("DELETE FROM {SCHEMA_TABLE}", Eq("status", MigrationFailed))
+	tag, err := q.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// versionParts splits a version for sorting; parts that are not numbers sort as 0.
+func versionParts(version string) []int {
+	return Map(strings.Split(version, "."), func(part string) int {
+		v, _ := strconv.Atoi(part)
+		return v
+	})
+}
@@ -0,0 +1,119 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChangelogKeyIntArray(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	c := db.Configuration
+	c.MigrationsDirectory = "testdb"
+	c.ChangelogKey = pg.ChangelogKeyIntArray
+	err := pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := pg.Changelog(ctx, db.Pool, c, pg.ChangelogFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := pg.Map(entries, func(e pg.ChangelogEntry) string { return e.Version })
+	if len(versions) != 3 || versions[0] != "0" || versions[1] != "0.1" || versions[2] != "1" {
+		t.Errorf("unexpected changelog order %v", versions)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id FROM changelog ORDER BY version DESC LIMIT 1", [][]any{{"1"}})
+}
+
+func TestChangelogAdministration(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	c := db.Configuration
+	_, err := db.Pool.Exec(ctx, "INSERT INTO changelog (id, name, filename, status, timestamp) VALUES ('2', 'broken', '2_broken.sql', 'ERROR', now()), ('3', 'other', '3_other.sql', 'ERROR', now())")
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := pg.Changelog(ctx, db.Pool, c, pg.ChangelogFilter{Status: pg.MigrationFailed})
+	if err != nil || len(failed) != 2 {
+		t.Fatalf("unexpected failed entries %+v, %v", failed, err)
+	}
+	found, err := pg.Changelog(ctx, db.Pool, c, pg.ChangelogFilter{Search: "ADDCOL"})
+	if err != nil || len(found) != 1 || found[0].Filename != "1_addcolumn.sql" {
+		t.Errorf("unexpected search result %+v, %v", found, err)
+	}
+	err = pg.MarkResolved(ctx, db.Pool, c, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := pg.GetChangelogEntry(ctx, db.Pool, c, "2")
+	if err != nil || entry.Status != pg.MigrationCompleted {
+		t.Errorf("unexpected entry %+v, %v", entry, err)
+	}
+	err = pg.MarkResolved(ctx, db.Pool, c, "1")
+	if !errors.Is(err, pg.ErrChangelogEntryNotFound) {
+		t.Errorf("completed migrations cannot be resolved, got %v", err)
+	}
+	deleted, err := pg.DeleteFailed(ctx, db.Pool, c)
+	if err != nil || deleted != 1 {
+		t.Errorf("unexpected deleted count %v, %v", deleted, err)
+	}
+	_, err = pg.GetChangelogEntry(ctx, db.Pool, c, "3")
+	if !errors.Is(err, pg.ErrChangelogEntryNotFound) {
+		t.Errorf("expected ErrChangelogEntryNotFound, got %v", err)
+	}
+}
+
+func TestFailedMigrationIsRecorded(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	directory := t.TempDir()
+	write := func(name string, script string) {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(script), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("0_create.sql", "CREATE TABLE a (id INT);")
+	write("1_broken.sql", "INSERT INTO missing VALUES (1);")
+	c := db.Configuration
+	c.MigrationsDirectory = directory
+	err := pg.Migrate(db.Pool, c)
+	var migrationError *pg.MigrationError
+	if !errors.As(err, &migrationError) || migrationError.Version != "1" {
+		t.Fatalf("expected the MigrationError of version 1, got %v", err)
+	}
+	failed, err := pg.Changelog(ctx, db.Pool, c, pg.ChangelogFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Version != "1" || failed[0].Status != pg.MigrationFailed {
+		t.Fatalf("expected only the failure to be recorded, got %+v", failed)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM pg_tables WHERE tablename = 'a'")
+
+	err = pg.MarkResolved(ctx, db.Pool, c, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id, status FROM changelog ORDER BY id", [][]any{{"0", "COMPLETED"}, {"1", "COMPLETED"}})
+
+	write("2_broken.sql", "SELECT 1 / 0;")
+	err = pg.Migrate(db.Pool, c)
+	if err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+	deleted, err := pg.DeleteFailed(ctx, db.Pool, c)
+	if err != nil || deleted != 1 {
+		t.Errorf("unexpected deleted count %v, %v", deleted, err)
+	}
+}
@@ -0,0 +1,41 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangelogKeyVersionType(t *testing.T) {
+	for key, expected := range map[ChangelogKey]string{
+		"":                   "",
+		ChangelogKeyText:     "",
+		ChangelogKeyIntArray: "BIGINT[] GENERATED ALWAYS AS (string_to_array(id, '.')::BIGINT[]) STORED",
+		ChangelogKeyBigint:   "BIGINT GENERATED ALWAYS AS (id::BIGINT) STORED",
+	} {
+		versionType, err := key.versionType()
+		if err != nil || versionType != expected {
+			t.Errorf("unexpected version type for %q: %v, %v", key, versionType, err)
+		}
+	}
+	_, err := ChangelogKey("uuid").versionType()
+	if err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+func TestVersionParts(t *testing.T) {
+	if parts := versionParts("10.2"); !reflect.DeepEqual(parts, []int{10, 2}) {
+		t.Errorf("unexpected parts %v", parts)
+	}
+}
+
+func TestChangelogFilter(t *testing.T) {
+	filter := ChangelogFilter{Status: MigrationFailed, Search: "50%_off"}
+	sql, args := Select("id").From("public.changelog").Where(filter.conditions()...).Build()
+	if sql != `SELECT "id" FROM "public"."changelog" WHERE ("status" = $1 AND ("name" ILIKE $2 OR "filename" ILIKE $3))` {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if !reflect.DeepEqual(args, []any{"ERROR", `%50\%\_off%`, `%50\%\_off%`}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
@@ -0,0 +1,81 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// ChunkError tells which chunk failed; the chunks before it were processed.
+type ChunkError struct {
+	Index int
+	// Offset is the position of the chunk's first item.
+	Offset int
+	Err    error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %v at item %v: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// InChunks calls fn with consecutive chunks of at most size items, stopping at the first error.
+// It panics if size is not positive.
+func InChunks[T any](items []T, size int, fn func(chunk []T) error) error {
+	for i, chunk := range Chunk(items, size) {
+		err := fn(chunk)
+		if err != nil {
+			return &ChunkError{Index: i, Offset: i * size, Err: err}
+		}
+	}
+	return nil
+}
+
+// InChunkTransactions is InChunks running each chunk in its own transaction, so large batches do
+// not hold locks and undo data for all items at once. A failing chunk is rolled back while the
+// chunks before it stay committed.
+func InChunkTransactions[T any](ctx context.Context, db TxBeginner, items []T, size int, fn func(tx pgx.Tx, chunk []T) error) error {
+	return InChunks(items, size, func(chunk []T) error {
+		start := time.Now()
+		committed := false
+		defer func() {
+			recordTx(start, committed)
+		}()
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer trackTx(tx)()
+		defer func(tx pgx.Tx, ctx context.Context) {
+			_ = tx.Rollback(ctx)
+		}(tx, context.Background())
+		err = fn(tx, chunk)
+		if err != nil {
+			return err
+		}
+		err = tx.Commit(ctx)
+		committed = err == nil
+		return err
+	})
+}
+
+// InsertRowsInChunks inserts rows like InsertRows, committing every size rows in a transaction of
+// their own. It returns the number of rows committed.
+func InsertRowsInChunks[T any](ctx context.Context, db TxBeginner, table string, rows []T, size int) (int64, error) {
+	var inserted, pending int64
+	err := InChunkTransactions(ctx, db, rows, size, func(tx pgx.Tx, chunk []T) error {
+		// Chunks only count once committed, which the previous one was when the next one starts.
+		inserted += pending
+		var err error
+		pending, err = InsertRows(ctx, tx, table, chunk)
+		return err
+	})
+	if err == nil {
+		inserted += pending
+	}
+	return inserted, err
+}
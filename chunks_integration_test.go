@@ -0,0 +1,48 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestInsertRowsInChunks(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	type row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	rows := []row{{10, "a"}, {11, "b"}, {12, "c"}, {1, "duplicate"}, {13, "d"}}
+	inserted, err := pg.InsertRowsInChunks(context.Background(), db.Pool, "testtable", rows, 2)
+	var chunkErr *pg.ChunkError
+	if !errors.As(err, &chunkErr) || chunkErr.Index != 1 {
+		t.Fatalf("expected the second chunk to fail, got %v", err)
+	}
+	if inserted != 2 {
+		t.Errorf("expected the first chunk to be committed, got %v rows", inserted)
+	}
+	pgtest.AssertRowCount(t, db.Pool, "testtable", 3)
+}
+
+func TestInsertRowsInChunksFailingCommit(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	// The deferred constraint is only checked by the commit.
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE items (id INT UNIQUE DEFERRABLE INITIALLY DEFERRED)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type item struct {
+		ID int `db:"id"`
+	}
+	inserted, err := pg.InsertRowsInChunks(ctx, db.Pool, "items", []item{{1}, {2}, {3}, {3}}, 2)
+	if err == nil {
+		t.Fatal("expected the commit of the second chunk to fail")
+	}
+	if inserted != 2 {
+		t.Errorf("expected only the committed chunk to count, got %v rows", inserted)
+	}
+	pgtest.AssertRowCount(t, db.Pool, "items", 2)
+}
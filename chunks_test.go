@@ -0,0 +1,30 @@
+package pg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInChunks(t *testing.T) {
+	var sizes []int
+	err := InChunks([]int{1, 2, 3, 4, 5}, 2, func(chunk []int) error {
+		sizes = append(sizes, len(chunk))
+		return nil
+	})
+	if err != nil || len(sizes) != 3 || sizes[2] != 1 {
+		t.Fatalf("unexpected chunks %v, %v", sizes, err)
+	}
+	failure := errors.New("failure")
+	calls := 0
+	err = InChunks([]int{1, 2, 3, 4, 5}, 2, func(chunk []int) error {
+		calls++
+		if chunk[0] == 3 {
+			return failure
+		}
+		return nil
+	})
+	var chunkErr *ChunkError
+	if !errors.Is(err, failure) || !errors.As(err, &chunkErr) || chunkErr.Index != 1 || chunkErr.Offset != 2 || calls != 2 {
+		t.Errorf("expected the second chunk to fail and stop, got %v after %v calls", err, calls)
+	}
+}
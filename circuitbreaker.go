@@ -0,0 +1,244 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCircuitThreshold = 5
+	DefaultCircuitCooldown  = 10 * time.Second
+)
+
+var ErrCircuitOpen = errors.New("database circuit open")
+
+// CircuitOpenError is returned without touching the database while the circuit is open. It
+// matches ErrCircuitOpen and unwraps to the failure that opened the circuit.
+type CircuitOpenError struct {
+	// RetryAt is when the next call probes the database.
+	RetryAt time.Time
+	Cause   error
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%v until %v: %v", ErrCircuitOpen, e.RetryAt.Format(time.RFC3339), e.Cause)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return e.Cause
+}
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	// CircuitHalfOpen is the state while a probe runs.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// CircuitBreaker wraps a pool and opens after Threshold consecutive connection failures, so calls
+// fail fast with a *CircuitOpenError during an outage instead of queueing on Acquire. After
+// Cooldown the next call runs Probe; the circuit closes when it succeeds and stays open for
+// another Cooldown otherwise. Errors reported by a reachable server, such as constraint
+// violations, do not count as failures. Errors met while iterating the rows of Query are not
+// seen by the breaker.
+type CircuitBreaker struct {
+	Pool      *pgxpool.Pool
+	Threshold int
+	Cooldown  time.Duration
+	// Probe checks whether the database is back, pinging it by default.
+	Probe func(ctx context.Context, pool *pgxpool.Pool) error
+	// OnStateChange is called, with the breaker locked, whenever the state changes.
+	OnStateChange func(state CircuitState)
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	cause    error
+	now      func() time.Time
+}
+
+func NewCircuitBreaker(pool *pgxpool.Pool) *CircuitBreaker {
+	return &CircuitBreaker{
+		Pool:      pool,
+		Threshold: DefaultCircuitThreshold,
+		Cooldown:  DefaultCircuitCooldown,
+		Probe: func(ctx context.Context, pool *pgxpool.Pool) error {
+			return pool.Ping(ctx)
+		},
+		now: time.Now,
+	}
+}
+
+// State returns the current state of the circuit.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.OnStateChange != nil {
+		b.OnStateChange(state)
+	}
+}
+
+// allow returns nil when a call may go to the database, probing it first when the cooldown has
+// passed.
+func (b *CircuitBreaker) allow(ctx context.Context) error {
+	b.mu.Lock()
+	if b.state == CircuitClosed {
+		b.mu.Unlock()
+		return nil
+	}
+	retryAt := b.openedAt.Add(b.Cooldown)
+	if b.state == CircuitHalfOpen || b.now().Before(retryAt) {
+		defer b.mu.Unlock()
+		return &CircuitOpenError{RetryAt: retryAt, Cause: b.cause}
+	}
+	b.setState(CircuitHalfOpen)
+	b.mu.Unlock()
+
+	err := b.Probe(ctx, b.Pool)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		if ctx.Err() != nil {
+			// The caller gave up, which says nothing about the database.
+			b.setState(CircuitOpen)
+			return ctx.Err()
+		}
+		b.open(err)
+		return &CircuitOpenError{RetryAt: b.openedAt.Add(b.Cooldown), Cause: err}
+	}
+	b.failures = 0
+	b.setState(CircuitClosed)
+	return nil
+}
+
+func (b *CircuitBreaker) open(cause error) {
+	b.openedAt = b.now()
+	b.cause = cause
+	b.setState(CircuitOpen)
+}
+
+// record counts err towards opening the circuit, or resets the count after a success.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !IsConnectionError(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitClosed && b.failures >= b.Threshold {
+		b.open(err)
+	}
+}
+
+// IsConnectionError reports whether err means the database could not be reached or is not
+// accepting work, rather than rejecting a statement. Exceeded deadlines count, as they are how
+// waiting on an unreachable database ends.
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Connection exceptions, insufficient resources and operator intervention like shutdowns.
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "53") || strings.HasPrefix(pgErr.Code, "57P")
+	}
+	var netErr net.Error
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &netErr) || errors.As(err, &connectErr) || errors.Is(err, context.DeadlineExceeded) ||
+		pgconn.SafeToRetry(err) || pgconn.Timeout(err)
+}
+
+func (b *CircuitBreaker) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	if err := b.allow(ctx); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := b.Pool.Exec(ctx, sql, arguments...)
+	b.record(err)
+	return tag, err
+}
+
+func (b *CircuitBreaker) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := b.allow(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := b.Pool.Query(ctx, sql, args...)
+	b.record(err)
+	return rows, err
+}
+
+func (b *CircuitBreaker) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := b.allow(ctx); err != nil {
+		return circuitRow{breaker: b, err: err}
+	}
+	return circuitRow{breaker: b, row: b.Pool.QueryRow(ctx, sql, args...)}
+}
+
+type circuitRow struct {
+	breaker *CircuitBreaker
+	row     pgx.Row
+	err     error
+}
+
+func (r circuitRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	err := r.row.Scan(dest...)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		r.breaker.record(err)
+	}
+	return err
+}
+
+func (b *CircuitBreaker) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := b.allow(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := b.Pool.Begin(ctx)
+	b.record(err)
+	return tx, err
+}
+
+func (b *CircuitBreaker) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	if err := b.allow(ctx); err != nil {
+		return nil, err
+	}
+	conn, err := b.Pool.Acquire(ctx)
+	b.record(err)
+	return conn, err
+}
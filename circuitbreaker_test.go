@@ -0,0 +1,94 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIsConnectionError(t *testing.T) {
+	for _, tt := range []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{&pgconn.PgError{Code: "08006"}, true},
+		{&pgconn.PgError{Code: "53300"}, true},
+		{&pgconn.PgError{Code: "57P01"}, true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{&pgconn.PgError{Code: "57014"}, false},
+		{&CircuitOpenError{Cause: context.DeadlineExceeded}, false},
+	} {
+		if actual := IsConnectionError(tt.err); actual != tt.expected {
+			t.Errorf("IsConnectionError(%v) = %v, expected %v", tt.err, actual, tt.expected)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	var probeErr error
+	probes := 0
+	var states []CircuitState
+	b := NewCircuitBreaker(nil)
+	b.Threshold = 2
+	b.now = func() time.Time { return now }
+	b.Probe = func(ctx context.Context, pool *pgxpool.Pool) error {
+		probes++
+		return probeErr
+	}
+	b.OnStateChange = func(state CircuitState) { states = append(states, state) }
+	ctx := context.Background()
+	outage := &pgconn.PgError{Code: "57P03"}
+
+	b.record(outage)
+	b.record(&pgconn.PgError{Code: "23505"})
+	b.record(outage)
+	if b.State() != CircuitClosed {
+		t.Fatal("expected statement errors to reset the failure count")
+	}
+	b.record(outage)
+	if b.State() != CircuitOpen {
+		t.Fatal("expected the circuit to open")
+	}
+
+	err := b.allow(ctx)
+	var openErr *CircuitOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &openErr) || !errors.Is(err, outage) {
+		t.Fatalf("expected a circuit open error caused by the outage, got %v", err)
+	}
+	if !openErr.RetryAt.Equal(now.Add(DefaultCircuitCooldown)) || probes != 0 {
+		t.Errorf("unexpected retry time %v or probes %v", openErr.RetryAt, probes)
+	}
+
+	now = now.Add(DefaultCircuitCooldown)
+	probeErr = &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	if err = b.allow(ctx); !errors.Is(err, ErrCircuitOpen) || probes != 1 {
+		t.Fatalf("expected the failed probe to keep the circuit open, got %v after %v probes", err, probes)
+	}
+	if err = b.allow(ctx); !errors.Is(err, ErrCircuitOpen) || probes != 1 {
+		t.Fatalf("expected no probe before the next cooldown, got %v after %v probes", err, probes)
+	}
+
+	now = now.Add(DefaultCircuitCooldown)
+	probeErr = nil
+	if err = b.allow(ctx); err != nil || b.State() != CircuitClosed {
+		t.Fatalf("expected the successful probe to close the circuit, got %v", err)
+	}
+	expected := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(states) != len(expected) {
+		t.Fatalf("expected transitions %v, got %v", expected, states)
+	}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Fatalf("expected transitions %v, got %v", expected, states)
+		}
+	}
+}
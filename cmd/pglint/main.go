@@ -0,0 +1,52 @@
+// Command pglint checks migration scripts for risky statements and exits with status 1 when an
+// error is found. The directory defaults to the one configured through DB_MIGRATIONS_DIRECTORY.
+//
+//	pglint -dir migrations -severity lock-timeout=error,drop-column-comment=off -json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	pg "github.com/msumera/pgutils"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+func main() {
+	dir := flag.String("dir", pg.CreateConfigurationFromEnv().MigrationsDirectory, "directory of the migration scripts")
+	severities := flag.String("severity", "", "comma separated rule=severity overrides, severity being off, warning or error")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	linter := pg.Linter{Severities: make(map[string]pg.LintSeverity)}
+	for _, override := range strings.Split(*severities, ",") {
+		if override == "" {
+			continue
+		}
+		rule, severity, ok := strings.Cut(override, "=")
+		if !ok {
+			log.Fatalf("Invalid severity override %q, expected rule=severity", override)
+		}
+		linter.Severities[rule] = pg.LintSeverity(severity)
+	}
+	report, err := linter.Lint(context.Background(), pg.DirectorySource(*dir))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		err = json.NewEncoder(os.Stdout).Encode(report)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		for _, issue := range report.Issues {
+			fmt.Println(issue)
+		}
+	}
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
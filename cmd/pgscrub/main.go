@@ -0,0 +1,61 @@
+// Command pgscrub masks personal data in the database configured through the DB_* environment
+// variables according to a rules file, see scrub.ParseRules. As a safeguard, the database name
+// must be repeated with -confirm.
+//
+//	pgscrub -rules scrub.yaml -confirm staging_copy
+//	pgscrub -rules scrub.yaml -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/scrub"
+	log "github.com/sirupsen/logrus"
+	"os"
+)
+
+func main() {
+	rulesFile := flag.String("rules", "scrub.yaml", "YAML file of masking rules")
+	dryRun := flag.Bool("dry-run", false, "print the statements instead of running them")
+	confirm := flag.String("confirm", "", "name of the database to scrub, which must match DB_NAME")
+	flag.Parse()
+
+	data, err := os.ReadFile(*rulesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rules, err := scrub.ParseRules(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *dryRun {
+		statements, err := rules.Statements()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, statement := range statements {
+			fmt.Printf("%v; -- %v\n", statement.SQL, statement.Args[1:])
+		}
+		return
+	}
+
+	c := pg.CreateConfigurationFromEnv()
+	c.MigrationsEnabled = false
+	if *confirm != c.Name {
+		log.Fatalf("Refusing to scrub %v without -confirm %v", c.Name, c.Name)
+	}
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+	results, err := scrub.Scrub(context.Background(), pool, rules)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, result := range results {
+		log.Infof("Scrubbed %v rows of %v", result.Rows, result.Table)
+	}
+}
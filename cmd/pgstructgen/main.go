@@ -0,0 +1,55 @@
+// Command pgstructgen writes a Go struct with db tags for every table of the database configured
+// through the DB_* environment variables.
+//
+//	pgstructgen -package models -schema public -out models/tables.go
+package main
+
+import (
+	"context"
+	"flag"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/gen"
+	"github.com/msumera/pgutils/introspect"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+func main() {
+	pkg := flag.String("package", "models", "package name of the generated file")
+	schemas := flag.String("schema", "", "comma separated schemas to read, all user schemas when empty")
+	tables := flag.String("tables", "", "comma separated tables to generate, all tables when empty")
+	out := flag.String("out", "", "output file, standard output when empty")
+	flag.Parse()
+
+	c := pg.CreateConfigurationFromEnv()
+	c.MigrationsEnabled = false
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+	d, err := introspect.Inspect(context.Background(), pool, split(*schemas)...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := gen.Generate(d, gen.Options{Package: *pkg, Tables: split(*tables)})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*out, src, 0o644)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
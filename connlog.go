@@ -0,0 +1,72 @@
+package pg
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sync/atomic"
+)
+
+// configureConnectionLogging hooks structured logging of connection attempts, failures, TLS
+// negotiation and pool size changes into config. The returned reference must be set to the pool
+// once it exists, so events can report its size.
+func configureConnectionLogging(config *pgxpool.Config, c Configuration) *atomic.Pointer[pgxpool.Pool] {
+	logger := c.Logger
+	if logger == nil {
+		logger = log.StandardLogger()
+	}
+	level, err := log.ParseLevel(c.ConnectionLogLevel)
+	if err != nil {
+		level = log.DebugLevel
+	}
+	poolRef := &atomic.Pointer[pgxpool.Pool]{}
+	poolFields := func(fields log.Fields) log.Fields {
+		if pool := poolRef.Load(); pool != nil {
+			stat := pool.Stat()
+			fields["pool_total"] = stat.TotalConns()
+			fields["pool_max"] = stat.MaxConns()
+		}
+		return fields
+	}
+
+	dial := config.ConnConfig.DialFunc
+	config.ConnConfig.DialFunc = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			logger.WithFields(poolFields(log.Fields{"address": addr, "error": err})).Warn("Database connection failed")
+		}
+		return conn, err
+	}
+
+	afterConnect := config.AfterConnect
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		fields := log.Fields{
+			"address": conn.Config().Host,
+			"pid":     conn.PgConn().PID(),
+			"tls":     false,
+		}
+		if tlsConn, ok := conn.PgConn().Conn().(*tls.Conn); ok {
+			state := tlsConn.ConnectionState()
+			fields["tls"] = true
+			fields["tls_version"] = tls.VersionName(state.Version)
+			fields["tls_cipher"] = tls.CipherSuiteName(state.CipherSuite)
+		}
+		logger.WithFields(poolFields(fields)).Log(level, "Database connection established")
+		if afterConnect != nil {
+			return afterConnect(ctx, conn)
+		}
+		return nil
+	}
+
+	beforeClose := config.BeforeClose
+	config.BeforeClose = func(conn *pgx.Conn) {
+		logger.WithFields(poolFields(log.Fields{"pid": conn.PgConn().PID()})).Log(level, "Database connection closed")
+		if beforeClose != nil {
+			beforeClose(conn)
+		}
+	}
+	return poolRef
+}
@@ -0,0 +1,62 @@
+package pg
+
+import (
+	"context"
+	"time"
+)
+
+const defaultReplayPoll = 10 * time.Millisecond
+
+// ConsistencyToken is a WAL position of the primary in the text form of pg_lsn, e.g. "0/16B3748".
+// A replica that has replayed past it sees every write committed before it was taken. The empty
+// token requires nothing.
+type ConsistencyToken string
+
+type consistencyTokenContextKey struct{}
+
+// WriteToken returns the primary's current WAL position. Take it after committing a write and
+// hand it to later reads, e.g. in a cookie or response header, for them to wait on a replica with
+// WaitForReplay.
+func WriteToken(ctx context.Context, q Querier) (ConsistencyToken, error) {
+	lsn, err := Scalar[string](ctx, q, "SELECT pg_current_wal_lsn()::text")
+	return ConsistencyToken(lsn), err
+}
+
+// ContextWithToken returns a context carrying token, so the code choosing a replica further down
+// the call chain can wait for it.
+func ContextWithToken(ctx context.Context, token ConsistencyToken) context.Context {
+	return context.WithValue(ctx, consistencyTokenContextKey{}, token)
+}
+
+func TokenFromContext(ctx context.Context) (ConsistencyToken, bool) {
+	token, ok := ctx.Value(consistencyTokenContextKey{}).(ConsistencyToken)
+	return token, ok && token != ""
+}
+
+// Replayed reports whether the server has replayed the WAL up to token. A primary has always
+// replayed it.
+func Replayed(ctx context.Context, q Querier, token ConsistencyToken) (bool, error) {
+	if token == "" {
+		return true, nil
+	}
+	return Scalar[bool](ctx, q, "SELECT NOT pg_is_in_recovery() OR COALESCE(pg_last_wal_replay_lsn() >= $1::pg_lsn, false)", string(token))
+}
+
+// WaitForReplay polls the server every poll, 10ms when zero, until it has replayed the WAL up to
+// token. Bound the wait with the deadline of ctx and fall back to the primary when it expires.
+func WaitForReplay(ctx context.Context, q Querier, token ConsistencyToken, poll time.Duration) error {
+	if poll <= 0 {
+		poll = defaultReplayPoll
+	}
+	for {
+		replayed, err := Replayed(ctx, q, token)
+		if err != nil || replayed {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
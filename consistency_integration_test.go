@@ -0,0 +1,31 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestWaitForReplay(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE notes (value TEXT); INSERT INTO notes VALUES ('a')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := pg.WriteToken(ctx, db.Pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" {
+		t.Fatal("expected a token")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	err = pg.WaitForReplay(ctx, db.Pool, token, 0)
+	if err != nil {
+		t.Errorf("expected a primary to have replayed its own writes, got %v", err)
+	}
+}
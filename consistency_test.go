@@ -0,0 +1,27 @@
+package pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTokenFromContext(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := TokenFromContext(ctx); ok {
+		t.Error("expected no token")
+	}
+	if _, ok := TokenFromContext(ContextWithToken(ctx, "")); ok {
+		t.Error("expected the empty token to be ignored")
+	}
+	token, ok := TokenFromContext(ContextWithToken(ctx, "0/16B3748"))
+	if !ok || token != "0/16B3748" {
+		t.Errorf("unexpected token %q", token)
+	}
+}
+
+func TestReplayedEmptyToken(t *testing.T) {
+	replayed, err := Replayed(context.Background(), nil, "")
+	if err != nil || !replayed {
+		t.Errorf("expected the empty token to be replayed, got %v, %v", replayed, err)
+	}
+}
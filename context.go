@@ -0,0 +1,26 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+)
+
+type txContextKey struct{}
+
+// ContextWithTx returns a context carrying tx, so code further down the call chain joins the transaction.
+func ContextWithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// QuerierFromContext returns the transaction carried by ctx, or fallback when there is none.
+func QuerierFromContext(ctx context.Context, fallback Querier) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return fallback
+}
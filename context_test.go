@@ -0,0 +1,26 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"testing"
+)
+
+type contextTestTx struct {
+	pgx.Tx
+}
+
+func TestQuerierFromContext(t *testing.T) {
+	var fallback Querier = contextTestTx{}
+	if _, ok := TxFromContext(context.Background()); ok {
+		t.Error("empty context should not carry a transaction")
+	}
+	tx := &contextTestTx{}
+	ctx := ContextWithTx(context.Background(), tx)
+	if QuerierFromContext(ctx, fallback) != tx {
+		t.Error("transaction from context should be preferred")
+	}
+	if QuerierFromContext(context.Background(), fallback) != fallback {
+		t.Error("fallback should be used without a transaction")
+	}
+}
@@ -0,0 +1,260 @@
+package pg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io"
+	"slices"
+	"strings"
+)
+
+type DataFormat string
+
+const (
+	// FormatCSV is comma separated values with a header row.
+	FormatCSV DataFormat = "csv"
+	// FormatJSONL is one JSON object per line, keyed by column name.
+	FormatJSONL DataFormat = "jsonl"
+	// FormatJSON is a JSON array of objects keyed by column name. Only EncodeRows supports it.
+	FormatJSON DataFormat = "json"
+)
+
+// jsonCopyOptions make COPY pass JSON documents through untouched: CSV with quote and delimiter
+// characters that JSON always escapes, so no value is ever quoted or split.
+const jsonCopyOptions = `(FORMAT csv, QUOTE e'\x01', DELIMITER e'\x02')`
+
+var ErrUnsupportedQuerier = errors.New("COPY needs a pool, connection or transaction")
+
+type ImportOptions struct {
+	// Columns lists the target columns. By default they are taken from the CSV header or the keys
+	// of the JSON objects. JSON objects fill the listed columns they have keys for.
+	Columns []string
+	// Mapping renames source columns or keys to table columns.
+	Mapping map[string]string
+	// NoHeader marks CSV input without a header row; Columns must be set then.
+	NoHeader bool
+}
+
+// withPgConn runs fn on the connection behind q, acquiring one when q is a pool.
+func withPgConn(ctx context.Context, q Querier, fn func(conn *pgconn.PgConn) error) error {
+	switch q := q.(type) {
+	case *pgxpool.Pool:
+		conn, err := q.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Release()
+		return fn(conn.Conn().PgConn())
+	case *pgx.Conn:
+		return fn(q.PgConn())
+	case interface{ Conn() *pgx.Conn }:
+		return fn(q.Conn().PgConn())
+	}
+	return ErrUnsupportedQuerier
+}
+
+// copySource turns a table name or a query into the source of COPY ... TO.
+func copySource(source string) string {
+	source = strings.TrimSpace(source)
+	if strings.ContainsAny(source, " \t\r\n") {
+		return "(" + source + ")"
+	}
+	return QuoteIdentifier(source)
+}
+
+// Export streams a table, or the result of a query, to w in format and returns the number of rows.
+func Export(ctx context.Context, q Querier, source string, format DataFormat, w io.Writer) (int64, error) {
+	var sql string
+	switch format {
+	case FormatCSV:
+		sql = "COPY " + copySource(source) + " TO STDOUT WITH (FORMAT csv, HEADER true)"
+	case FormatJSONL:
+		from := "SELECT * FROM " + QuoteIdentifier(strings.TrimSpace(source))
+		if strings.HasPrefix(copySource(source), "(") {
+			from = source
+		}
+		sql = "COPY (SELECT row_to_json(r) FROM (" + from + ") r) TO STDOUT WITH " + jsonCopyOptions
+	default:
+		return 0, fmt.Errorf("unsupported format %q", format)
+	}
+	var rows int64
+	err := withPgConn(ctx, q, func(conn *pgconn.PgConn) error {
+		tag, err := conn.CopyTo(ctx, w, sql)
+		rows = tag.RowsAffected()
+		return err
+	})
+	return rows, err
+}
+
+func (o ImportOptions) mapColumn(column string) string {
+	if mapped, ok := o.Mapping[column]; ok {
+		return mapped
+	}
+	return column
+}
+
+// Import streams r in format into table and returns the number of rows. Columns missing from the
+// input keep their defaults.
+func Import(ctx context.Context, q Querier, table string, format DataFormat, r io.Reader, opts ImportOptions) (int64, error) {
+	switch format {
+	case FormatCSV:
+		return importCSV(ctx, q, table, r, opts)
+	case FormatJSONL:
+		return importJSONL(ctx, q, table, r, opts)
+	}
+	return 0, fmt.Errorf("unsupported format %q", format)
+}
+
+func importCSV(ctx context.Context, q Querier, table string, r io.Reader, opts ImportOptions) (int64, error) {
+	reader := r
+	columns := opts.Columns
+	if !opts.NoHeader {
+		// The header is consumed here so the remaining stream can go to COPY unchanged. The CSV
+		// reader reads ahead, so what it read beyond the header is put back in front of r.
+		var read bytes.Buffer
+		headerReader := csv.NewReader(io.TeeReader(r, &read))
+		header, err := headerReader.Read()
+		if errors.Is(err, io.EOF) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("reading CSV header: %w", err)
+		}
+		reader = io.MultiReader(bytes.NewReader(read.Bytes()[headerReader.InputOffset():]), r)
+		if columns == nil {
+			columns = Map(header, opts.mapColumn)
+		}
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("CSV import without a header needs ImportOptions.Columns")
+	}
+	sql := "COPY " + QuoteIdentifier(table) + " (" + quoteIdentifiers(columns) + ") FROM STDIN WITH (FORMAT csv)"
+	var rows int64
+	err := withPgConn(ctx, q, func(conn *pgconn.PgConn) error {
+		tag, err := conn.CopyFrom(ctx, reader, sql)
+		rows = tag.RowsAffected()
+		return err
+	})
+	return rows, err
+}
+
+// importJSONL copies the documents into a temporary table and expands them into table's row type
+// with jsonb_populate_record, so values are converted by postgres like any other input. Documents
+// are inserted grouped by their keys, so the columns they have no key for keep their defaults. It
+// runs in a transaction of its own unless q is one.
+func importJSONL(ctx context.Context, q Querier, table string, r io.Reader, opts ImportOptions) (int64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeJSONDocuments(pw, scanner, opts))
+	}()
+	defer func(pr *io.PipeReader) {
+		_ = pr.Close()
+	}(pr)
+	var rows int64
+	err := withPgConn(ctx, q, func(conn *pgconn.PgConn) error {
+		own := conn.TxStatus() == 'I'
+		if own {
+			err := conn.Exec(ctx, "BEGIN").Close()
+			if err != nil {
+				return err
+			}
+			defer func(conn *pgconn.PgConn) {
+				if conn.TxStatus() != 'I' {
+					_ = conn.Exec(context.Background(), "ROLLBACK").Close()
+				}
+			}(conn)
+		}
+		temp := "pgutils_import"
+		err := conn.Exec(ctx, "CREATE TEMP TABLE "+temp+" (doc JSONB) ON COMMIT DROP").Close()
+		if err != nil {
+			return err
+		}
+		if !own {
+			// The surrounding transaction may import again before it commits.
+			defer func(conn *pgconn.PgConn) {
+				_ = conn.Exec(context.Background(), "DROP TABLE IF EXISTS "+temp).Close()
+			}(conn)
+		}
+		_, err = conn.CopyFrom(ctx, pr, "COPY "+temp+" (doc) FROM STDIN WITH "+jsonCopyOptions)
+		if err != nil {
+			return err
+		}
+		keys := "ARRAY(SELECT jsonb_object_keys(doc) ORDER BY 1)"
+		results, err := conn.Exec(ctx, "SELECT DISTINCT array_to_json("+keys+") FROM "+temp).ReadAll()
+		if err != nil {
+			return err
+		}
+		for _, row := range results[0].Rows {
+			var docKeys []string
+			err = json.Unmarshal(row[0], &docKeys)
+			if err != nil {
+				return err
+			}
+			columns := docKeys
+			if opts.Columns != nil {
+				columns = Filter(opts.Columns, func(c string) bool { return slices.Contains(docKeys, c) })
+			}
+			if len(columns) == 0 {
+				return fmt.Errorf("JSON documents with the keys %v have none of the columns %v", docKeys, opts.Columns)
+			}
+			//goland:noinspection SqlResolve
+			tag, err := conn.Exec(ctx, "INSERT INTO "+QuoteIdentifier(table)+" ("+quoteIdentifiers(columns)+") SELECT "+
+				strings.Join(Map(columns, func(c string) string { return "r." + QuoteIdentifier(c) }), ", ")+
+				" FROM "+temp+", jsonb_populate_record(NULL::"+QuoteIdentifier(table)+", doc) r"+
+				" WHERE "+keys+" = ARRAY["+strings.Join(Map(docKeys, quoteLiteral), ", ")+"]::text[]").ReadAll()
+			if err != nil {
+				return err
+			}
+			rows += tag[0].CommandTag.RowsAffected()
+		}
+		if own {
+			return conn.Exec(ctx, "COMMIT").Close()
+		}
+		return nil
+	})
+	return rows, err
+}
+
+// writeJSONDocuments writes one document per line, renaming keys according to opts.Mapping.
+func writeJSONDocuments(w io.Writer, scanner *bufio.Scanner, opts ImportOptions) error {
+	write := func(line []byte) error {
+		if len(opts.Mapping) > 0 {
+			var doc map[string]json.RawMessage
+			err := json.Unmarshal(line, &doc)
+			if err != nil {
+				return err
+			}
+			mapped := make(map[string]json.RawMessage, len(doc))
+			for key, value := range doc {
+				mapped[opts.mapColumn(key)] = value
+			}
+			line, err = json.Marshal(mapped)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := w.Write(append(line, '\n'))
+		return err
+	}
+	for scanner.Scan() {
+		line := []byte(strings.TrimSpace(scanner.Text()))
+		if len(line) == 0 {
+			continue
+		}
+		err := write(line)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
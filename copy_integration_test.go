@@ -0,0 +1,69 @@
+package pg_test
+
+import (
+	"bytes"
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	var csv bytes.Buffer
+	rows, err := pg.Export(ctx, db.Pool, "testtable", pg.FormatCSV, &csv)
+	if err != nil || rows != 1 {
+		t.Fatalf("unexpected export %v, %v", rows, err)
+	}
+	if !strings.HasPrefix(csv.String(), "id,name,description\n") {
+		t.Errorf("expected a header, got %q", csv.String())
+	}
+	var jsonl bytes.Buffer
+	_, err = pg.Export(ctx, db.Pool, "SELECT id, name FROM testtable", pg.FormatJSONL, &jsonl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsonl.String() != "{\"id\":1,\"name\":\"name1\"}\n" {
+		t.Errorf("unexpected JSON lines %q", jsonl.String())
+	}
+
+	rows, err = pg.Import(ctx, db.Pool, "testtable", pg.FormatCSV, strings.NewReader("key,name\n2,\"a, b\"\n"),
+		pg.ImportOptions{Mapping: map[string]string{"key": "id"}})
+	if err != nil || rows != 1 {
+		t.Fatalf("unexpected CSV import %v, %v", rows, err)
+	}
+	rows, err = pg.Import(ctx, db.Pool, "testtable", pg.FormatJSONL, strings.NewReader("{\"id\":3,\"name\":\"c\\\\d\"}\n{\"id\":4}\n"), pg.ImportOptions{})
+	if err != nil || rows != 2 {
+		t.Fatalf("unexpected JSON lines import %v, %v", rows, err)
+	}
+	name, err := pg.Scalar[string](ctx, db.Pool, "SELECT name FROM testtable WHERE id = 3")
+	if err != nil || name != `c\d` {
+		t.Errorf("unexpected imported name %q, %v", name, err)
+	}
+}
+
+func TestImportKeepsDefaults(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE items (id INT, name TEXT, note TEXT DEFAULT 'none')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second document has a key the first one lacks, and the first one keeps the default.
+	rows, err := pg.Import(ctx, db.Pool, "items", pg.FormatJSONL, strings.NewReader("{\"id\":1}\n{\"id\":2,\"note\":\"n\"}\n"), pg.ImportOptions{})
+	if err != nil || rows != 2 {
+		t.Fatalf("unexpected JSON lines import %v, %v", rows, err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id, note FROM items ORDER BY id", [][]any{{int32(1), "none"}, {int32(2), "n"}})
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM pg_tables WHERE tablename = 'pgutils_import'")
+
+	// A quoted header may span lines.
+	rows, err = pg.Import(ctx, db.Pool, "items", pg.FormatCSV, strings.NewReader("id,\"na\nme\"\n3,c\n"),
+		pg.ImportOptions{Mapping: map[string]string{"na\nme": "name"}})
+	if err != nil || rows != 1 {
+		t.Fatalf("unexpected CSV import %v, %v", rows, err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT name, note FROM items WHERE id = 3", [][]any{{"c", "none"}})
+}
@@ -0,0 +1,28 @@
+package pg
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestCopySource(t *testing.T) {
+	if copySource("public.users") != `"public"."users"` {
+		t.Errorf("unexpected table source %v", copySource("public.users"))
+	}
+	if copySource("SELECT id FROM users") != "(SELECT id FROM users)" {
+		t.Errorf("unexpected query source %v", copySource("SELECT id FROM users"))
+	}
+}
+
+func TestWriteJSONDocuments(t *testing.T) {
+	var sb strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader("{\"Name\":\"a\",\"id\":1}\n\n{\"Name\":\"b\"}\n"))
+	err := writeJSONDocuments(&sb, scanner, ImportOptions{Mapping: map[string]string{"Name": "name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != "{\"id\":1,\"name\":\"a\"}\n{\"name\":\"b\"}\n" {
+		t.Errorf("unexpected documents %q", sb.String())
+	}
+}
@@ -0,0 +1,165 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"io"
+	"strings"
+)
+
+const defaultCopyChunkSize = 10000
+
+// CopyOptions tunes CopyData.
+type CopyOptions struct {
+	// Columns are copied by name, so source and target may order them differently; by default all
+	// columns are copied by position.
+	Columns []string
+	// Key, when set for a table source, copies the table in chunks of ChunkSize rows ordered by
+	// Key, each a COPY of its own. Key must be unique, usually the primary key.
+	Key       string
+	ChunkSize int
+	// After resumes a chunked copy after the key of CopyProgress.LastKey; nil starts at the first
+	// row, so every key, the empty text included, can be resumed after.
+	After *string
+	// Truncate empties the target first.
+	Truncate bool
+	// OnProgress receives the totals after every chunk, or once for unchunked copies.
+	OnProgress func(CopyProgress)
+}
+
+// CopyProgress is how much CopyData copied. LastKey is the text form of the last key copied by
+// chunked copies, nil until a chunk was copied.
+type CopyProgress struct {
+	Rows    int64
+	Bytes   int64
+	Chunks  int
+	LastKey *string
+}
+
+// CopyData streams a table, or the result of a query, from one database or schema into the target
+// table of another with COPY, without buffering the data. from and to must be different
+// connections, e.g. two pools, or the same pool, which acquires one connection for each side.
+// Tenant moves pass schema qualified names, e.g. tenant_a.orders to tenant_b.orders.
+func CopyData(ctx context.Context, from Querier, source string, to Querier, target string, opts CopyOptions) (CopyProgress, error) {
+	progress := CopyProgress{LastKey: opts.After}
+	if opts.Truncate {
+		_, err := to.Exec(ctx, "TRUNCATE "+QuoteIdentifier(target))
+		if err != nil {
+			return progress, err
+		}
+	}
+	columns := "*"
+	into := QuoteIdentifier(target)
+	if len(opts.Columns) > 0 {
+		columns = quoteIdentifiers(opts.Columns)
+		into += " (" + columns + ")"
+	}
+	copyChunk := func(selectSQL string) error {
+		rows, bytes, err := copyStream(ctx, from, "COPY ("+selectSQL+") TO STDOUT", to, "COPY "+into+" FROM STDIN")
+		progress.Rows += rows
+		progress.Bytes += bytes
+		if err != nil {
+			return err
+		}
+		progress.Chunks++
+		return nil
+	}
+	if opts.Key == "" || strings.HasPrefix(copySource(source), "(") {
+		selectSQL := "SELECT " + columns + " FROM " + copySource(source)
+		if strings.HasPrefix(copySource(source), "(") {
+			selectSQL += " AS source"
+		}
+		err := copyChunk(selectSQL)
+		if err == nil && opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+		return progress, err
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultCopyChunkSize
+	}
+	table, key := QuoteIdentifier(source), pgx.Identifier{opts.Key}.Sanitize()
+	var keyType string
+	err := from.QueryRow(ctx, "SELECT format_type(atttypid, atttypmod) FROM pg_attribute WHERE attrelid = to_regclass($1) AND attname = $2 AND NOT attisdropped",
+		table, opts.Key).Scan(&keyType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return progress, fmt.Errorf("no key column %v in %v", opts.Key, source)
+	}
+	if err != nil {
+		return progress, err
+	}
+	//goland:noinspection SqlResolve
+	bound := fmt.Sprintf("SELECT max(%v)::text FROM (SELECT %v FROM %v WHERE $1::text IS NULL OR %v > $1::text::%v ORDER BY %v LIMIT %v) chunk",
+		key, key, table, key, keyType, key, chunkSize)
+	for {
+		var upper *string
+		after := progress.LastKey
+		err = from.QueryRow(ctx, bound, after).Scan(&upper)
+		if err != nil || upper == nil {
+			return progress, err
+		}
+		where := fmt.Sprintf("%v <= %v::%v", key, quoteLiteral(*upper), keyType)
+		if after != nil {
+			where = fmt.Sprintf("%v > %v::%v AND %v", key, quoteLiteral(*after), keyType, where)
+		}
+		err = copyChunk("SELECT " + columns + " FROM " + table + " WHERE " + where)
+		if err != nil {
+			return progress, err
+		}
+		progress.LastKey = upper
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	}
+}
+
+// copyStream pipes the output of copyTo on from into copyFrom on to and returns the rows and bytes
+// copied.
+func copyStream(ctx context.Context, from Querier, copyTo string, to Querier, copyFrom string) (int64, int64, error) {
+	reader, writer := io.Pipe()
+	counter := &countingReader{r: reader}
+	exported := make(chan error, 1)
+	go func() {
+		err := withPgConn(ctx, from, func(conn *pgconn.PgConn) error {
+			_, err := conn.CopyTo(ctx, writer, copyTo)
+			return err
+		})
+		_ = writer.CloseWithError(err)
+		exported <- err
+	}()
+	var rows int64
+	err := withPgConn(ctx, to, func(conn *pgconn.PgConn) error {
+		tag, err := conn.CopyFrom(ctx, counter, copyFrom)
+		rows = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		// Unblocks the export when the import stopped reading.
+		_ = reader.CloseWithError(err)
+	}
+	exportErr := <-exported
+	if err != nil {
+		return rows, counter.n, err
+	}
+	return rows, counter.n, exportErr
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
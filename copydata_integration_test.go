@@ -0,0 +1,90 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestCopyData(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE SCHEMA tenant_a;
+		CREATE SCHEMA tenant_b;
+		CREATE TABLE tenant_a.orders (id INT PRIMARY KEY, item TEXT);
+		CREATE TABLE tenant_b.orders (id INT PRIMARY KEY, item TEXT);
+		CREATE TABLE tenant_b.items (item TEXT, quantity INT);
+		INSERT INTO tenant_a.orders SELECT i, 'item ' || (i % 3) FROM generate_series(1, 25) i;
+		INSERT INTO tenant_b.orders VALUES (100, 'stale');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reports []pg.CopyProgress
+	progress, err := pg.CopyData(ctx, db.Pool, "tenant_a.orders", db.Pool, "tenant_b.orders", pg.CopyOptions{
+		Key:        "id",
+		ChunkSize:  10,
+		Truncate:   true,
+		OnProgress: func(p pg.CopyProgress) { reports = append(reports, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Rows != 25 || progress.Chunks != 3 || *progress.LastKey != "25" || progress.Bytes == 0 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+	if len(reports) != 3 || reports[0].Rows != 10 || *reports[0].LastKey != "10" {
+		t.Errorf("unexpected progress reports %+v", reports)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT count(*), max(id) FROM tenant_b.orders", [][]any{{int64(25), int32(25)}})
+
+	progress, err = pg.CopyData(ctx, db.Pool, "tenant_a.orders", db.Pool, "tenant_b.orders", pg.CopyOptions{Key: "id", After: progress.LastKey})
+	if err != nil || progress.Rows != 0 {
+		t.Errorf("expected a resumed copy to have nothing left, got %+v, %v", progress, err)
+	}
+
+	progress, err = pg.CopyData(ctx, db.Pool, "SELECT count(*) AS quantity, item FROM tenant_a.orders GROUP BY item", db.Pool, "tenant_b.items", pg.CopyOptions{
+		Columns: []string{"quantity", "item"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Rows != 3 || progress.Chunks != 1 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT sum(quantity) FROM tenant_b.items", [][]any{{int64(25)}})
+}
+
+func TestCopyDataResumesAfterEmptyKey(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE source (code TEXT PRIMARY KEY);
+		CREATE TABLE target (code TEXT PRIMARY KEY);
+		INSERT INTO source VALUES (''), ('a'), ('b');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress, err := pg.CopyData(ctx, db.Pool, "source", db.Pool, "target", pg.CopyOptions{Key: "code", ChunkSize: 1,
+		OnProgress: func(p pg.CopyProgress) {
+			if p.Chunks == 1 && *p.LastKey != "" {
+				t.Errorf("expected the empty key first, got %q", *p.LastKey)
+			}
+		}})
+	if err != nil || progress.Rows != 3 {
+		t.Fatalf("unexpected progress %+v, %v", progress, err)
+	}
+	_, err = db.Pool.Exec(ctx, "DELETE FROM target WHERE code <> ''")
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty := ""
+	progress, err = pg.CopyData(ctx, db.Pool, "source", db.Pool, "target", pg.CopyOptions{Key: "code", After: &empty})
+	if err != nil || progress.Rows != 2 {
+		t.Errorf("expected the copy to resume after the empty key, got %+v, %v", progress, err)
+	}
+	pgtest.AssertRowCount(t, db.Pool, "target", 3)
+}
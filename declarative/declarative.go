@@ -0,0 +1,213 @@
+// Package declarative keeps a schema in the state declared by idempotent SQL, as an alternative
+// to versioned migrations. The declared SQL is run in a scratch schema, which is compared with the
+// live one using the introspect model; the differences become the ALTERs to apply.
+package declarative
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/introspect"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const lockKey = "pgutils.declarative"
+
+var (
+	ErrDestructiveChanges = errors.New("plan drops objects")
+	ErrManualChanges      = errors.New("plan has changes that must be applied by hand")
+)
+
+// Schema applies declared state to a single schema. The declared SQL must create its objects
+// unqualified, e.g. CREATE TABLE posts (...), as it runs with search_path set to a scratch schema.
+type Schema struct {
+	Pool *pgxpool.Pool
+	Name string
+	// AllowDestructive lets Apply drop schemas, tables, columns and other objects not declared.
+	AllowDestructive bool
+}
+
+func New(pool *pgxpool.Pool, name string) *Schema {
+	return &Schema{
+		Pool: pool,
+		Name: name,
+	}
+}
+
+// ReadDir concatenates the .sql files of dir in name order into one declared state.
+func ReadDir(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	var b strings.Builder
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		b.Write(data)
+		b.WriteString("\n;\n")
+	}
+	return b.String(), nil
+}
+
+// Plan returns the changes that would bring the schema to the declared state, without applying
+// them; review its SQL as a dry run.
+func (s *Schema) Plan(ctx context.Context, declared string) (*introspect.SchemaDiff, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	return s.plan(ctx, tx, declared)
+}
+
+// Apply plans and applies the changes in one transaction, which also serializes concurrent
+// Apply calls. It refuses plans with changes it cannot generate, and plans dropping objects
+// unless AllowDestructive is set; nothing is changed then. The applied plan is returned.
+func (s *Schema) Apply(ctx context.Context, declared string) (*introspect.SchemaDiff, error) {
+	return pg.DoInTransaction(s.Pool, func(tx pgx.Tx) (*introspect.SchemaDiff, error) {
+		err := pg.AdvisoryXactLock(ctx, tx, lockKey)
+		if err != nil {
+			return nil, err
+		}
+		diff, err := s.plan(ctx, tx, declared)
+		if err != nil {
+			return nil, err
+		}
+		err = s.review(diff)
+		if err != nil {
+			return nil, err
+		}
+		if diff.Empty() {
+			return diff, nil
+		}
+		log.Infof("Applying declared state to schema %v:\n%v", s.Name, diff)
+		_, err = tx.Exec(ctx, diff.SQL())
+		if err != nil {
+			return nil, fmt.Errorf("apply declared state to schema %v: %w", s.Name, err)
+		}
+		return diff, nil
+	})
+}
+
+func (s *Schema) review(diff *introspect.SchemaDiff) error {
+	var manual, destructive []string
+	for _, c := range diff.Changes {
+		if len(c.Statements) == 0 {
+			manual = append(manual, c.String())
+		} else if c.Kind == introspect.Removed && !s.AllowDestructive {
+			destructive = append(destructive, c.String())
+		}
+	}
+	if len(manual) > 0 {
+		return fmt.Errorf("%w:\n%v", ErrManualChanges, strings.Join(manual, "\n"))
+	}
+	if len(destructive) > 0 {
+		return fmt.Errorf("%w:\n%v", ErrDestructiveChanges, strings.Join(destructive, "\n"))
+	}
+	return nil
+}
+
+// plan inspects the schema and the declared state in a savepoint of tx that is rolled back.
+func (s *Schema) plan(ctx context.Context, tx pgx.Tx, declared string) (*introspect.SchemaDiff, error) {
+	scratch, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(scratch, context.Background())
+
+	// Both are inspected with their schema on the search path, so definitions render alike.
+	_, err = scratch.Exec(ctx, "SELECT set_config('search_path', $1, true)", pg.QuoteIdentifier(s.Name))
+	if err != nil {
+		return nil, err
+	}
+	current, err := introspect.Inspect(ctx, scratch, s.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	name := "pgutils_declared_" + hex.EncodeToString(suffix)
+	_, err = scratch.Exec(ctx, "CREATE SCHEMA "+name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = scratch.Exec(ctx, "SELECT set_config('search_path', $1, true)", name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = scratch.Exec(ctx, declared)
+	if err != nil {
+		return nil, fmt.Errorf("run declared state: %w", err)
+	}
+	desired, err := introspect.Inspect(ctx, scratch, name)
+	if err != nil {
+		return nil, err
+	}
+	var quoted string
+	err = scratch.QueryRow(ctx, "SELECT quote_ident($1)", s.Name).Scan(&quoted)
+	if err != nil {
+		return nil, err
+	}
+	renameSchema(desired, name, s.Name, quoted)
+	return introspect.Compare(current, desired), nil
+}
+
+// renameSchema moves the model of schema from to schema to, including the definitions
+// qualifying objects with from, which quoted replaces as rendered by the server.
+func renameSchema(d *introspect.Database, from string, to string, quoted string) {
+	replace := func(s string) string {
+		return strings.ReplaceAll(s, from+".", quoted+".")
+	}
+	for i := range d.Schemas {
+		schema := &d.Schemas[i]
+		schema.Name = to
+		for j := range schema.Tables {
+			table := &schema.Tables[j]
+			table.Schema = to
+			for k := range table.Columns {
+				if table.Columns[k].Default != nil {
+					def := replace(*table.Columns[k].Default)
+					table.Columns[k].Default = &def
+				}
+			}
+			for k := range table.Indexes {
+				table.Indexes[k].Definition = replace(table.Indexes[k].Definition)
+			}
+			for k := range table.Constraints {
+				constraint := &table.Constraints[k]
+				constraint.Definition = replace(constraint.Definition)
+				if strings.HasPrefix(constraint.References, from+".") {
+					constraint.References = to + strings.TrimPrefix(constraint.References, from)
+				}
+			}
+		}
+		for j := range schema.Enums {
+			schema.Enums[j].Schema = to
+		}
+		for j := range schema.Functions {
+			schema.Functions[j].Schema = to
+		}
+		for j := range schema.MaterializedViews {
+			schema.MaterializedViews[j].Schema = to
+			schema.MaterializedViews[j].Definition = replace(schema.MaterializedViews[j].Definition)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package declarative
+
+import (
+	"context"
+	"errors"
+	"github.com/msumera/pgutils/introspect"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"2_posts.sql": "CREATE TABLE posts (id INT)",
+		"1_users.sql": "CREATE TABLE users (id INT);",
+		"notes.txt":   "ignored",
+	} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	declared, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if declared != "CREATE TABLE users (id INT);\n;\nCREATE TABLE posts (id INT)\n;\n" {
+		t.Errorf("unexpected declared state %q", declared)
+	}
+}
+
+func TestRenameSchema(t *testing.T) {
+	def := "nextval('scratch.posts_id_seq'::regclass)"
+	d := &introspect.Database{Schemas: []introspect.Schema{{
+		Name: "scratch",
+		Tables: []introspect.Table{{
+			Schema:      "scratch",
+			Name:        "posts",
+			Columns:     []introspect.Column{{Name: "id", Default: &def}},
+			Indexes:     []introspect.Index{{Definition: "CREATE INDEX posts_id ON scratch.posts USING btree (id)"}},
+			Constraints: []introspect.Constraint{{References: "scratch.users"}},
+		}},
+	}}}
+	renameSchema(d, "scratch", "My App", `"My App"`)
+	table := d.Table("My App", "posts")
+	if table == nil || *table.Columns[0].Default != `nextval('"My App".posts_id_seq'::regclass)` ||
+		table.Indexes[0].Definition != `CREATE INDEX posts_id ON "My App".posts USING btree (id)` ||
+		table.Constraints[0].References != "My App.users" {
+		t.Errorf("unexpected model %+v", table)
+	}
+}
+
+func TestApply(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("../testdb"))
+	ctx := context.Background()
+	s := New(db.Pool, "app")
+	v1 := `
+		CREATE TYPE mood AS ENUM ('happy', 'sad');
+		CREATE TABLE users (id INT PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE posts (
+			id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			author INT NOT NULL REFERENCES users (id),
+			mood mood
+		);
+	`
+	diff, err := s.Apply(ctx, v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(diff.String(), "+ schema app\n") {
+		t.Errorf("unexpected plan\n%v", diff)
+	}
+	diff, err = s.Plan(ctx, v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected the declared state to be applied, got\n%v", diff)
+	}
+
+	v2 := strings.Replace(v1, "mood mood", "mood mood,\n\t\t\ttitle TEXT NOT NULL DEFAULT ''", 1) + "CREATE INDEX posts_title ON posts (title);"
+	diff, err = s.Plan(ctx, v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.String() != "+ column app.posts.title\n+ index app.posts.posts_title\n" {
+		t.Errorf("unexpected plan\n%v", diff)
+	}
+	_, err = db.Pool.Exec(ctx, "INSERT INTO app.users VALUES (1, 'user1'); INSERT INTO app.posts (author) VALUES (1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Apply(ctx, v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT title FROM app.posts", [][]any{{""}})
+
+	v3 := strings.Replace(v2, ", name TEXT NOT NULL", "", 1)
+	_, err = s.Apply(ctx, v3)
+	if !errors.Is(err, ErrDestructiveChanges) || !strings.Contains(err.Error(), "- column app.users.name") {
+		t.Fatalf("expected the drop to be refused, got %v", err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT name FROM app.users", [][]any{{"user1"}})
+	s.AllowDestructive = true
+	_, err = s.Apply(ctx, v3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT 1 FROM information_schema.columns WHERE table_schema = 'app' AND column_name = 'name'")
+
+	_, err = s.Apply(ctx, v3+"CREATE FUNCTION add(a INT, b INT) RETURNS INT AS 'SELECT a + b' LANGUAGE sql;")
+	if !errors.Is(err, ErrManualChanges) {
+		t.Errorf("expected the function to be refused, got %v", err)
+	}
+}
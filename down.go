@@ -0,0 +1,94 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MigrateDown reverts the completed migrations in reverse order using their down scripts, which sit
+// next to the migrations: 1_addcolumn.down.sql for 1_addcolumn.sql, 000001_init.down.sql for
+// 000001_init.up.sql with MigrationNamingGolangMigrate, and the undo script U1__init.sql for
+// V1__init.sql with MigrationNamingFlyway, whose repeatable scripts are left as they are. Reverted
+// migrations are removed from the changelog, so the next Migrate applies them again.
+func MigrateDown(pool *pgxpool.Pool, c Configuration) error {
+	return createDatabaseMigrator(pool, c).MigrateDown()
+}
+
+func (dbm *databaseMigrator) MigrateDown() error {
+	policy, err := dbm.Configuration.resolvePolicy(context.Background())
+	if err != nil {
+		return err
+	}
+	dbm.policy = policy
+	err = dbm.initChangelogTable()
+	if err != nil {
+		return err
+	}
+	migrations, err := dbm.getMigrations()
+	if err != nil {
+		return err
+	}
+	slices.Reverse(migrations)
+	tx, err := dbm.PgxPool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	_, err = dbm.exec(tx, dbm.replaceEnv("LOCK TABLE {SCHEMA_TABLE} IN ACCESS EXCLUSIVE MODE"))
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if migration.Repeatable {
+			continue
+		}
+		err = dbm.revertMigration(migration, tx)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(context.Background())
+}
+
+func (dbm *databaseMigrator) revertMigration(migration migration, tx pgx.Tx) error {
+	id := strings.Join(Map(migration.Id, strconv.Itoa), ".")
+	status, err := dbm.getMigrationStatus(id, tx)
+	if err != nil {
+		return err
+	}
+	// Failed migrations are recorded, but their transaction was rolled back, so there is nothing to
+	// revert; their rows stay for MarkResolved and DeleteFailed.
+	if status != statusCompleted {
+		return nil
+	}
+	downFilename := migration.Down
+	log.Printf("Reverting migration %v", migration.Filename)
+	bytes, err := dbm.Configuration.source().Read(context.Background(), downFilename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("migration %v has no down migration %v", migration.Filename, downFilename)
+	}
+	if err != nil {
+		return err
+	}
+	err = dbm.checkDestructive(downFilename, id, string(bytes))
+	if err != nil {
+		return err
+	}
+	_, err = dbm.exec(tx, string(bytes))
+	if err != nil {
+		return newMigrationError(downFilename, id, string(bytes), err)
+	}
+	//goland:noinspection SqlResolve
+	_, err = dbm.exec(tx, dbm.replaceEnv("DELETE FROM {SCHEMA_TABLE} WHERE id = $1"), id)
+	return err
+}
@@ -0,0 +1,42 @@
+package pg_test
+
+import (
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateDownSkipsFailedMigrations(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	directory := t.TempDir()
+	write := func(name string, script string) {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(script), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("0_create.sql", "CREATE TABLE a (id INT);")
+	write("0_create.down.sql", "DROP TABLE a;")
+	c := db.Configuration
+	c.MigrationsDirectory = directory
+	err := pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("1_broken.sql", "INSERT INTO missing VALUES (1);")
+	write("1_broken.down.sql", "CREATE TABLE reverted (id INT);")
+	err = pg.Migrate(db.Pool, c)
+	if err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+
+	err = pg.MigrateDown(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM pg_tables WHERE tablename = 'reverted'")
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM pg_tables WHERE tablename = 'a'")
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id, status FROM changelog", [][]any{{"1", "ERROR"}})
+}
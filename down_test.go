@@ -0,0 +1,18 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetMigrationsSkipsDownMigrations(t *testing.T) {
+	dbm := createDatabaseMigrator(nil, Configuration{MigrationsDirectory: "testdb"})
+	migrations, err := dbm.getMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filenames := Map(migrations, func(m migration) string { return m.Filename })
+	if !reflect.DeepEqual(filenames, []string{"0_init.sql", "0_1_init_data.sql", "1_addcolumn.sql"}) {
+		t.Errorf("unexpected migrations: %v", filenames)
+	}
+}
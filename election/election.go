@@ -0,0 +1,128 @@
+package election
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	DefaultHeartbeatInterval = 5 * time.Second
+	DefaultRetryInterval     = 5 * time.Second
+)
+
+type options struct {
+	heartbeatInterval time.Duration
+	retryInterval     time.Duration
+}
+
+type Option func(*options)
+
+// WithHeartbeatInterval sets how often the leader checks that the connection holding the lock is alive.
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.heartbeatInterval = interval
+	}
+}
+
+// WithRetryInterval sets how often a follower tries to take over leadership.
+func WithRetryInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.retryInterval = interval
+	}
+}
+
+// Run campaigns for leadership of key until ctx is cancelled. Leadership is a session-level
+// advisory lock held on a dedicated connection: if the leader process dies or its connection
+// drops, postgres releases the lock and another candidate takes over on its next retry.
+//
+// onElected is started in its own goroutine with a context that is cancelled when leadership is
+// lost, and onResigned is called right after that cancellation. Either callback may be nil.
+// Cancelling ctx resigns gracefully and returns nil.
+func Run(ctx context.Context, pool *pgxpool.Pool, key string, onElected func(ctx context.Context), onResigned func(), opts ...Option) error {
+	o := options{
+		heartbeatInterval: DefaultHeartbeatInterval,
+		retryInterval:     DefaultRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	lockKey := pg.AdvisoryLockKey(key)
+	for {
+		elected, err := campaign(ctx, pool, key, lockKey, o, onElected, onResigned)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Warnf("Error in leader election for %v: %v", key, err)
+		}
+		if elected {
+			// Lost leadership while ctx is alive; try again right away.
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(o.retryInterval):
+		}
+	}
+}
+
+// campaign tries to take the lock once and, if it succeeds, leads until the connection fails or
+// ctx is done. It reports whether this candidate was elected.
+func campaign(ctx context.Context, pool *pgxpool.Pool, key string, lockKey int64, o options, onElected func(ctx context.Context), onResigned func()) (bool, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	var acquired bool
+	err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Release()
+		return false, err
+	}
+	log.Infof("Elected leader for %v", key)
+	leaderCtx, cancel := context.WithCancel(ctx)
+	if onElected != nil {
+		go onElected(leaderCtx)
+	}
+	err = heartbeat(ctx, conn, o.heartbeatInterval)
+	cancel()
+	log.Infof("Resigned leadership for %v", key)
+	if onResigned != nil {
+		onResigned()
+	}
+	if err != nil {
+		// The lock state of a broken session is unknown, so it must not go back to the pool.
+		_ = conn.Conn().Close(context.Background())
+		conn.Release()
+		return true, err
+	}
+	_, err = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+	if err != nil {
+		_ = conn.Conn().Close(context.Background())
+	}
+	conn.Release()
+	return true, nil
+}
+
+// heartbeat pings the lock connection until a ping fails or ctx is done, which returns nil.
+func heartbeat(ctx context.Context, conn *pgxpool.Conn, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		err := conn.Ping(pingCtx)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+	}
+}
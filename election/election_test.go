@@ -0,0 +1,46 @@
+package election
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	leader := ""
+	elected := make(chan string, 2)
+	run := func(name string) {
+		_ = Run(ctx, db.Pool, "election-test", func(ctx context.Context) {
+			mu.Lock()
+			leader = name
+			mu.Unlock()
+			elected <- name
+		}, nil, WithHeartbeatInterval(50*time.Millisecond), WithRetryInterval(50*time.Millisecond))
+	}
+	go run("a")
+	first := <-elected
+	go run("b")
+
+	_, err := db.Pool.Exec(ctx, "SELECT pg_terminate_backend(pid) FROM pg_locks WHERE locktype = 'advisory' AND granted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case second := <-elected:
+		mu.Lock()
+		defer mu.Unlock()
+		if second != leader {
+			t.Errorf("expected %v to be the current leader, got %v", second, leader)
+		}
+		t.Logf("leadership moved from %v to %v", first, second)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no candidate took over after the leader's connection dropped")
+	}
+}
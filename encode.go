@@ -0,0 +1,159 @@
+package pg
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Column describes a column of a result set.
+type Column struct {
+	Name string `json:"name"`
+	// Type is the name of the postgres type, or its OID when pgx does not know it.
+	Type string `json:"type"`
+	OID  uint32 `json:"oid"`
+}
+
+// RowsColumns returns the columns of rows, e.g. to send them ahead of rows encoded by EncodeRows.
+func RowsColumns(rows pgx.Rows) []Column {
+	typeMap := pgtype.NewMap()
+	if conn := rows.Conn(); conn != nil {
+		typeMap = conn.TypeMap()
+	}
+	return Map(rows.FieldDescriptions(), func(field pgconn.FieldDescription) Column {
+		column := Column{Name: field.Name, Type: strconv.FormatUint(uint64(field.DataTypeOID), 10), OID: field.DataTypeOID}
+		if t, ok := typeMap.TypeForOID(field.DataTypeOID); ok {
+			column.Type = t.Name
+		}
+		return column
+	})
+}
+
+// EncodeRows streams rows to w in format and returns the number of rows. CSV starts with a header
+// of the column names; JSON objects are keyed by them. rows is closed.
+func EncodeRows(rows pgx.Rows, format DataFormat, w io.Writer) (int64, error) {
+	defer rows.Close()
+	columns := RowsColumns(rows)
+	switch format {
+	case FormatCSV:
+		return encodeCSV(rows, columns, w)
+	case FormatJSON, FormatJSONL:
+		return encodeJSON(rows, columns, format == FormatJSON, w)
+	}
+	return 0, fmt.Errorf("unsupported format %q", format)
+}
+
+func encodeCSV(rows pgx.Rows, columns []Column, w io.Writer) (int64, error) {
+	writer := csv.NewWriter(w)
+	err := writer.Write(Map(columns, func(c Column) string { return c.Name }))
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	record := make([]string, len(columns))
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return n, err
+		}
+		for i, value := range values {
+			record[i], err = csvValue(value)
+			if err != nil {
+				return n, err
+			}
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return n, err
+	}
+	return n, rows.Err()
+}
+
+func encodeJSON(rows pgx.Rows, columns []Column, array bool, w io.Writer) (int64, error) {
+	separator, end := "\n", "\n"
+	if array {
+		if _, err := io.WriteString(w, "["); err != nil {
+			return 0, err
+		}
+		separator, end = ",", "]\n"
+	}
+	var n int64
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return n, err
+		}
+		object := make(map[string]any, len(values))
+		for i, value := range values {
+			object[columns[i].Name] = jsonValue(value)
+		}
+		line, err := json.Marshal(object)
+		if err != nil {
+			return n, err
+		}
+		if array && n > 0 {
+			line = append([]byte(separator), line...)
+		} else if !array {
+			line = append(line, separator...)
+		}
+		if _, err = w.Write(line); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	if array {
+		_, err := io.WriteString(w, end)
+		return n, err
+	}
+	return n, nil
+}
+
+// jsonValue converts the values pgx decodes to types encoding/json renders as postgres would.
+func jsonValue(value any) any {
+	switch v := value.(type) {
+	case [16]byte:
+		return formatUUID(v)
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	}
+	return value
+}
+
+func csvValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case [16]byte, []byte:
+		return jsonValue(v).(string), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	// Numbers, booleans, arrays and documents look the same in JSON.
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+func formatUUID(u [16]byte) string {
+	h := hex.EncodeToString(u[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
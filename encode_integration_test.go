@@ -0,0 +1,53 @@
+package pg_test
+
+import (
+	"bytes"
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestEncodeRows(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	sql := "SELECT id, name, description, '6ba7b810-9dad-11d1-80b4-00c04fd430c8'::uuid AS key FROM testtable"
+	for _, tt := range []struct {
+		format   pg.DataFormat
+		expected string
+	}{
+		{pg.FormatCSV, "id,name,description,key\n1,name1,,6ba7b810-9dad-11d1-80b4-00c04fd430c8\n"},
+		{pg.FormatJSON, `[{"description":null,"id":1,"key":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","name":"name1"}]` + "\n"},
+		{pg.FormatJSONL, `{"description":null,"id":1,"key":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","name":"name1"}` + "\n"},
+	} {
+		rows, err := db.Pool.Query(ctx, sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		n, err := pg.EncodeRows(rows, tt.format, &buf)
+		if err != nil || n != 1 || buf.String() != tt.expected {
+			t.Errorf("%v: unexpected output %q, %v, %v", tt.format, buf.String(), n, err)
+		}
+	}
+
+	rows, err := db.Pool.Query(ctx, sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := pg.RowsColumns(rows)
+	rows.Close()
+	if len(columns) != 4 || columns[0].Type != "int4" || columns[3].Type != "uuid" {
+		t.Errorf("unexpected columns %+v", columns)
+	}
+
+	rows, err = db.Pool.Query(ctx, "SELECT 1 WHERE false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	_, err = pg.EncodeRows(rows, pg.FormatJSON, &buf)
+	if err != nil || buf.String() != "[]\n" {
+		t.Errorf("expected an empty array, got %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,32 @@
+package pg
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestCSVValue(t *testing.T) {
+	uuid := [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}
+	for _, tt := range []struct {
+		value    any
+		expected string
+	}{
+		{nil, ""},
+		{"text", "text"},
+		{int32(42), "42"},
+		{1.5, "1.5"},
+		{true, "true"},
+		{time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC), "2024-01-31T12:00:00Z"},
+		{uuid, "12345678-9abc-def0-1234-56789abcdef0"},
+		{[]byte{0xde, 0xad}, `\xdead`},
+		{map[string]any{"a": 1}, `{"a":1}`},
+		{[]any{"a", nil}, `["a",null]`},
+		{netip.MustParsePrefix("10.0.0.0/8"), "10.0.0.0/8"},
+	} {
+		actual, err := csvValue(tt.value)
+		if err != nil || actual != tt.expected {
+			t.Errorf("csvValue(%v) = %q, %v, expected %q", tt.value, actual, err, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Plan is the JSON output of EXPLAIN. The timings are only set by ExplainAnalyze.
+type Plan struct {
+	Root          PlanNode `json:"Plan"`
+	PlanningTime  float64  `json:"Planning Time"`
+	ExecutionTime float64  `json:"Execution Time"`
+}
+
+// PlanNode is a node of a query plan. Costs are in planner units, times in milliseconds; the
+// Actual fields are only set by ExplainAnalyze.
+type PlanNode struct {
+	NodeType     string  `json:"Node Type"`
+	RelationName string  `json:"Relation Name"`
+	Schema       string  `json:"Schema"`
+	Alias        string  `json:"Alias"`
+	IndexName    string  `json:"Index Name"`
+	JoinType     string  `json:"Join Type"`
+	StartupCost  float64 `json:"Startup Cost"`
+	TotalCost    float64 `json:"Total Cost"`
+	PlanRows     float64 `json:"Plan Rows"`
+	PlanWidth    int     `json:"Plan Width"`
+	Filter       string  `json:"Filter"`
+	IndexCond    string  `json:"Index Cond"`
+
+	ActualStartupTime float64 `json:"Actual Startup Time"`
+	ActualTotalTime   float64 `json:"Actual Total Time"`
+	ActualRows        float64 `json:"Actual Rows"`
+	ActualLoops       float64 `json:"Actual Loops"`
+
+	Plans []PlanNode `json:"Plans"`
+}
+
+// Walk calls fn for the node and all its descendants, depth first.
+func (n *PlanNode) Walk(fn func(node *PlanNode)) {
+	fn(n)
+	for i := range n.Plans {
+		n.Plans[i].Walk(fn)
+	}
+}
+
+// SeqScans returns the relations read with a sequential scan, a common sign of a missing index.
+func (p *Plan) SeqScans() []string {
+	var relations []string
+	p.Root.Walk(func(node *PlanNode) {
+		if node.NodeType == "Seq Scan" {
+			relations = append(relations, node.RelationName)
+		}
+	})
+	return relations
+}
+
+// Explain returns the plan postgres would use for sql without running it.
+func Explain(ctx context.Context, q Querier, sql string, args ...any) (*Plan, error) {
+	return explain(ctx, q, "EXPLAIN (FORMAT JSON, VERBOSE) ", sql, args)
+}
+
+// ExplainAnalyze runs sql and returns its plan with actual row counts and timings. The statement
+// takes effect, so run data modifying statements in a transaction that is rolled back.
+func ExplainAnalyze(ctx context.Context, q Querier, sql string, args ...any) (*Plan, error) {
+	return explain(ctx, q, "EXPLAIN (FORMAT JSON, VERBOSE, ANALYZE, BUFFERS) ", sql, args)
+}
+
+func explain(ctx context.Context, q Querier, prefix string, sql string, args []any) (*Plan, error) {
+	var output []byte
+	err := q.QueryRow(ctx, prefix+sql, args...).Scan(&output)
+	if err != nil {
+		return nil, err
+	}
+	var plans []Plan
+	err = json.Unmarshal(output, &plans)
+	if err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, errors.New("EXPLAIN returned no plan")
+	}
+	return &plans[0], nil
+}
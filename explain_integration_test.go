@@ -0,0 +1,106 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplain(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	plan, err := pg.Explain(ctx, db.Pool, "SELECT * FROM testtable WHERE name = $1", "name1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Root.NodeType != "Seq Scan" || plan.Root.TotalCost == 0 || plan.Root.ActualLoops != 0 {
+		t.Errorf("unexpected plan %+v", plan.Root)
+	}
+	plan, err = pg.ExplainAnalyze(ctx, db.Pool, "SELECT * FROM testtable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Root.ActualRows != 1 || plan.ExecutionTime == 0 {
+		t.Errorf("expected actual rows and timings, got %+v", plan)
+	}
+}
+
+func TestSlowQueryPlans(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	slow := make(chan pg.SlowQuery, 1)
+	c := db.Configuration
+	c.MigrationsEnabled = false
+	c.SlowQueryThreshold = 50 * time.Millisecond
+	c.SlowQueryPlans = true
+	c.OnSlowQuery = func(q pg.SlowQuery) { slow <- q }
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	_, err = pool.Exec(context.Background(), "SELECT pg_sleep(0.1), count(*) FROM testtable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case q := <-slow:
+		if q.Plan == nil {
+			t.Errorf("expected a captured plan, got %v", q.PlanErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the slow query was not reported")
+	}
+}
+
+func TestSlowQueryAnalyze(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	slow := make(chan pg.SlowQuery, 3)
+	c := db.Configuration
+	c.MigrationsEnabled = false
+	c.SlowQueryThreshold = 50 * time.Millisecond
+	c.SlowQueryPlans = true
+	c.SlowQueryAnalyze = true
+	c.SlowQueryAutoExplain = true
+	c.OnSlowQuery = func(q pg.SlowQuery) { slow <- q }
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+	_, err = pool.Exec(ctx, "SELECT pg_sleep(0.1), count(*) FROM testtable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pool.Exec(ctx, "UPDATE testtable SET name = name || '!' WHERE pg_sleep(0.1) IS NOT NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Pool.Exec(ctx, "CREATE SEQUENCE s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pool.Exec(ctx, "SELECT pg_sleep(0.1), nextval('s')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		select {
+		case q := <-slow:
+			if q.Plan == nil {
+				t.Fatalf("expected a captured plan, got %v", q.PlanErr)
+			}
+			// The update and nextval would change data, so their plans are not analyzed.
+			if analyzed := q.Plan.ExecutionTime > 0; analyzed != strings.HasPrefix(q.SQL, "SELECT pg_sleep(0.1), count") {
+				t.Errorf("unexpected plan of %v: %+v", q.SQL, q.Plan)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the slow query was not reported")
+		}
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT name FROM testtable WHERE id = 1", [][]any{{"name1!"}})
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT last_value FROM s", [][]any{{int64(1)}})
+}
@@ -0,0 +1,82 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPlanSeqScans(t *testing.T) {
+	output := `[{"Plan": {"Node Type": "Hash Join", "Total Cost": 42.5, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "orders", "Plan Rows": 1000},
+		{"Node Type": "Hash", "Plans": [{"Node Type": "Index Scan", "Relation Name": "users", "Index Name": "users_pkey"}]}
+	]}, "Planning Time": 0.1}]`
+	var plans []Plan
+	err := json.Unmarshal([]byte(output), &plans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plans[0].Root.TotalCost != 42.5 || plans[0].Root.Plans[1].Plans[0].IndexName != "users_pkey" {
+		t.Errorf("unexpected plan %+v", plans[0])
+	}
+	scans := plans[0].SeqScans()
+	if len(scans) != 1 || scans[0] != "orders" {
+		t.Errorf("unexpected sequential scans %v", scans)
+	}
+}
+
+func TestSlowQueryTracer(t *testing.T) {
+	var reported []SlowQuery
+	tracer := &slowQueryTracer{
+		threshold: 10 * time.Millisecond,
+		report:    func(slow SlowQuery) { reported = append(reported, slow) },
+		poolRef:   &atomic.Pointer[pgxpool.Pool]{},
+	}
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT pg_sleep(1)"})
+	time.Sleep(15 * time.Millisecond)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	if len(reported) != 1 || reported[0].SQL != "SELECT pg_sleep(1)" || reported[0].Duration < 10*time.Millisecond {
+		t.Errorf("unexpected reports %+v", reported)
+	}
+}
+
+func TestAnalyzeCandidate(t *testing.T) {
+	functions, ok := analyzeCandidate("SELECT count(*), nextval ('s') FROM t WHERE name = 'setval(x)'")
+	if !ok || len(functions) != 2 || functions[0] != "count" || functions[1] != "nextval" {
+		t.Errorf("unexpected candidate %v, %v", functions, ok)
+	}
+	for _, sql := range []string{
+		"UPDATE t SET a = 1",
+		"WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d",
+		"SELECT * FROM t FOR UPDATE",
+		"SELECT * FROM t FOR NO KEY UPDATE SKIP LOCKED",
+		"SELECT * INTO copy FROM t",
+		"SELECT 1; DELETE FROM t",
+	} {
+		if _, ok := analyzeCandidate(sql); ok {
+			t.Errorf("expected %v not to be analyzed", sql)
+		}
+	}
+}
+
+func TestSlowQuerySampling(t *testing.T) {
+	value := 0.0
+	tracer := &slowQueryTracer{sampleRate: 0.25, random: func() float64 { return value }}
+	if !tracer.sampled() {
+		t.Error("expected a draw below the rate to be sampled")
+	}
+	value = 0.5
+	if tracer.sampled() {
+		t.Error("expected a draw above the rate not to be sampled")
+	}
+	tracer.sampleRate = 0
+	if !tracer.sampled() {
+		t.Error("expected every plan to be captured without a rate")
+	}
+}
@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrExtensionUnavailable = errors.New("extension is not available on the server")
+	ErrExtensionPrivileges  = errors.New("insufficient privileges to create extension")
+)
+
+// insufficientPrivilege is the SQLSTATE of permission errors.
+const insufficientPrivilege = "42501"
+
+// EnsureExtensions creates the named extensions unless they are installed already. Extensions the
+// server does not ship fail with ErrExtensionUnavailable, and those the role may not create with
+// ErrExtensionPrivileges; all problems are reported together.
+func EnsureExtensions(ctx context.Context, q Querier, names ...string) error {
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, ensureExtension(ctx, q, name))
+	}
+	return errors.Join(errs...)
+}
+
+func ensureExtension(ctx context.Context, q Querier, name string) error {
+	var installed, available bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS (SELECT FROM pg_extension WHERE extname = $1),
+			EXISTS (SELECT FROM pg_available_extensions WHERE name = $1)
+	`, name).Scan(&installed, &available)
+	if err != nil {
+		return err
+	}
+	if installed {
+		return nil
+	}
+	if !available {
+		return fmt.Errorf("%w: %v", ErrExtensionUnavailable, name)
+	}
+	_, err = q.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS "+QuoteIdentifier(name))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == insufficientPrivilege {
+		return fmt.Errorf("%w %v, have a superuser run CREATE EXTENSION %v: %w", ErrExtensionPrivileges, name, QuoteIdentifier(name), err)
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestEnsureExtensions(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	err := pg.EnsureExtensions(ctx, db.Pool, "pg_trgm", "pgutils_missing")
+	if !errors.Is(err, pg.ErrExtensionUnavailable) {
+		t.Errorf("expected ErrExtensionUnavailable, got %v", err)
+	}
+	err = pg.RunPreflightChecks(ctx, db.Pool, pg.RequireExtension("pg_trgm"))
+	if err != nil {
+		t.Errorf("the available extension should have been created, got %v", err)
+	}
+
+	_, err = db.Pool.Exec(ctx, "CREATE ROLE pgutils_app LOGIN PASSWORD 'app'; GRANT CREATE ON SCHEMA public TO pgutils_app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := db.Configuration
+	c.Username, c.Password = "pgutils_app", "app"
+	c.MigrationsDirectory = "testdb"
+	c.Extensions = []string{"pg_stat_statements"}
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	err = pg.Migrate(pool, c)
+	if !errors.Is(err, pg.ErrExtensionPrivileges) {
+		t.Errorf("expected ErrExtensionPrivileges, got %v", err)
+	}
+}
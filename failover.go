@@ -0,0 +1,176 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	DefaultFailoverInterval       = 5 * time.Second
+	DefaultFailoverErrorThreshold = 3
+)
+
+// FailoverEventKind is what a FailoverMonitor noticed.
+type FailoverEventKind string
+
+const (
+	// FailoverPromoted is a standby that left recovery and became a primary.
+	FailoverPromoted FailoverEventKind = "promoted"
+	// FailoverDemoted is a primary that came back as a standby.
+	FailoverDemoted FailoverEventKind = "demoted"
+	// FailoverMoved is a different server answering, e.g. after the address the pool connects to
+	// was pointed at a new primary, or a restart of the same one.
+	FailoverMoved FailoverEventKind = "moved"
+	// FailoverUnreachable is ErrorThreshold checks failing in a row.
+	FailoverUnreachable FailoverEventKind = "unreachable"
+	// FailoverReachable is the first successful check after FailoverUnreachable.
+	FailoverReachable FailoverEventKind = "reachable"
+)
+
+// ServerState is the server a pool's connections reach.
+type ServerState struct {
+	Address string
+	Port    int
+	// Started is when the postmaster started, telling a restarted server apart.
+	Started time.Time
+	Standby bool
+}
+
+func (s ServerState) String() string {
+	role := "primary"
+	if s.Standby {
+		role = "standby"
+	}
+	return fmt.Sprintf("%v %v:%v started %v", role, s.Address, s.Port, s.Started.Format(time.RFC3339))
+}
+
+// FailoverEvent is a change of the server behind a pool. Previous is the zero value before the
+// first successful check, Current after failed checks; Err is the last error of FailoverUnreachable.
+type FailoverEvent struct {
+	Kind     FailoverEventKind
+	Previous ServerState
+	Current  ServerState
+	Err      error
+	At       time.Time
+}
+
+// FailoverMonitor checks the server behind a pool every Interval and reports promotions, servers
+// being replaced and the server becoming unreachable, so applications can flush caches or re-run
+// sanity checks when the primary moves.
+type FailoverMonitor struct {
+	pool           *pgxpool.Pool
+	Interval       time.Duration
+	ErrorThreshold int
+	// ResetPool closes the connections of the pool when the server moved or changed role, so none
+	// keep talking to the old server.
+	ResetPool bool
+	// OnEvent receives every event; they are logged as warnings when it is nil.
+	OnEvent func(FailoverEvent)
+
+	known       bool
+	last        ServerState
+	failures    int
+	unreachable bool
+}
+
+func NewFailoverMonitor(pool *pgxpool.Pool) *FailoverMonitor {
+	return &FailoverMonitor{
+		pool:           pool,
+		Interval:       DefaultFailoverInterval,
+		ErrorThreshold: DefaultFailoverErrorThreshold,
+	}
+}
+
+// Run checks every Interval until ctx is cancelled, which returns nil. The first check records
+// the server without reporting it.
+func (m *FailoverMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		_, err := m.Check(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Debugf("Error checking the server for failover: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check queries the server and reports what changed since the last check. It must not be called
+// concurrently.
+func (m *FailoverMonitor) Check(ctx context.Context) ([]FailoverEvent, error) {
+	state, err := CurrentServerState(ctx, m.pool)
+	if ctx.Err() != nil {
+		return nil, err
+	}
+	events := m.observe(state, err, time.Now())
+	for _, event := range events {
+		if m.ResetPool && event.Kind != FailoverUnreachable && event.Kind != FailoverReachable {
+			m.pool.Reset()
+		}
+		m.report(event)
+	}
+	return events, err
+}
+
+// CurrentServerState returns the server q is connected to.
+func CurrentServerState(ctx context.Context, q Querier) (ServerState, error) {
+	var s ServerState
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(host(inet_server_addr()), ''), COALESCE(inet_server_port(), 0),
+			pg_postmaster_start_time(), pg_is_in_recovery()
+	`).Scan(&s.Address, &s.Port, &s.Started, &s.Standby)
+	return s, err
+}
+
+// observe records the result of a check and returns the events it causes.
+func (m *FailoverMonitor) observe(state ServerState, err error, at time.Time) []FailoverEvent {
+	var events []FailoverEvent
+	if err != nil {
+		m.failures++
+		if m.failures >= max(m.ErrorThreshold, 1) && !m.unreachable {
+			m.unreachable = true
+			events = append(events, FailoverEvent{Kind: FailoverUnreachable, Previous: m.last, Err: err, At: at})
+		}
+		return events
+	}
+	m.failures = 0
+	if m.unreachable {
+		m.unreachable = false
+		events = append(events, FailoverEvent{Kind: FailoverReachable, Previous: m.last, Current: state, At: at})
+	}
+	if m.known {
+		switch {
+		case m.last.Address != state.Address || m.last.Port != state.Port || !m.last.Started.Equal(state.Started):
+			events = append(events, FailoverEvent{Kind: FailoverMoved, Previous: m.last, Current: state, At: at})
+		case m.last.Standby && !state.Standby:
+			events = append(events, FailoverEvent{Kind: FailoverPromoted, Previous: m.last, Current: state, At: at})
+		case !m.last.Standby && state.Standby:
+			events = append(events, FailoverEvent{Kind: FailoverDemoted, Previous: m.last, Current: state, At: at})
+		}
+	}
+	m.known, m.last = true, state
+	return events
+}
+
+func (m *FailoverMonitor) report(e FailoverEvent) {
+	if m.OnEvent != nil {
+		m.OnEvent(e)
+		return
+	}
+	entry := log.WithFields(log.Fields{
+		"kind":     e.Kind,
+		"previous": e.Previous.String(),
+	})
+	if e.Kind == FailoverUnreachable {
+		entry.WithError(e.Err).Warn("Database server unreachable")
+		return
+	}
+	entry.WithField("current", e.Current.String()).Warn("Database server changed")
+}
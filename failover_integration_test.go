@@ -0,0 +1,30 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestFailoverMonitorCheck(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	state, err := pg.CurrentServerState(ctx, db.Pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Standby || state.Started.IsZero() {
+		t.Errorf("unexpected server state %v", state)
+	}
+	m := pg.NewFailoverMonitor(db.Pool)
+	for range 2 {
+		events, err := m.Check(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 0 {
+			t.Errorf("expected no events, got %v", events)
+		}
+	}
+}
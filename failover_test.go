@@ -0,0 +1,46 @@
+package pg
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFailoverMonitorObserve(t *testing.T) {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	standby := ServerState{Address: "10.0.0.2", Port: 5432, Started: started, Standby: true}
+	promoted := standby
+	promoted.Standby = false
+	moved := ServerState{Address: "10.0.0.3", Port: 5432, Started: started}
+	lost := errors.New("connection refused")
+
+	m := &FailoverMonitor{ErrorThreshold: 2}
+	steps := []struct {
+		state ServerState
+		err   error
+		kinds []FailoverEventKind
+	}{
+		{standby, nil, nil},
+		{standby, nil, nil},
+		{promoted, nil, []FailoverEventKind{FailoverPromoted}},
+		{ServerState{}, lost, nil},
+		{ServerState{}, lost, []FailoverEventKind{FailoverUnreachable}},
+		{ServerState{}, lost, nil},
+		{moved, nil, []FailoverEventKind{FailoverReachable, FailoverMoved}},
+		{ServerState{}, lost, nil},
+		{moved, nil, nil},
+		{standby, nil, []FailoverEventKind{FailoverMoved}},
+	}
+	for i, step := range steps {
+		events := m.observe(step.state, step.err, time.Now())
+		kinds := Map(events, func(e FailoverEvent) FailoverEventKind { return e.Kind })
+		if len(kinds) != len(step.kinds) {
+			t.Fatalf("step %v: expected %v, got %v", i, step.kinds, kinds)
+		}
+		for j := range kinds {
+			if kinds[j] != step.kinds[j] {
+				t.Errorf("step %v: expected %v, got %v", i, step.kinds, kinds)
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package pg
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Operators understood by FilterSchema. OpContains matches a substring case insensitively,
+// OpIn takes a list and OpIsNull a boolean.
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpLt       = "lt"
+	OpLe       = "le"
+	OpGt       = "gt"
+	OpGe       = "ge"
+	OpLike     = "like"
+	OpILike    = "ilike"
+	OpContains = "contains"
+	OpIn       = "in"
+	OpIsNull   = "isnull"
+)
+
+// FieldFilter is a single condition requested by a client, e.g. decoded from JSON.
+type FieldFilter struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value any    `json:"value"`
+}
+
+// ListParams are the filters, sorting and page of a list request. Sort fields prefixed with - sort
+// descending.
+type ListParams struct {
+	Filters []FieldFilter `json:"filters"`
+	Sort    []string      `json:"sort"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+}
+
+// FilterField exposes a column to clients under a field name.
+type FilterField struct {
+	Column string
+	// Operators restricts the operators allowed on the field; nil allows all.
+	Operators []string
+	Sortable  bool
+}
+
+// FilterSchema is the allow-list of fields clients may filter and sort by. Nothing else reaches
+// the statement, and values are always bound as parameters.
+type FilterSchema struct {
+	Fields      map[string]FilterField
+	DefaultSort []string
+	// DefaultLimit applies when no limit is requested; MaxLimit caps requested limits. Zero leaves
+	// them unlimited.
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// Apply adds the conditions, sorting and page of params to b, or fails with ErrInvalidFilter when
+// params use fields or operators the schema does not allow.
+func (s FilterSchema) Apply(b *SelectBuilder, params ListParams) error {
+	for _, filter := range params.Filters {
+		condition, err := s.condition(filter)
+		if err != nil {
+			return err
+		}
+		b.Where(condition)
+	}
+	sort := params.Sort
+	if len(sort) == 0 {
+		sort = s.DefaultSort
+	}
+	for _, name := range sort {
+		field, ok := s.Fields[strings.TrimPrefix(name, "-")]
+		if !ok || !field.Sortable {
+			return fmt.Errorf("%w: cannot sort by %q", ErrInvalidFilter, name)
+		}
+		if strings.HasPrefix(name, "-") {
+			b.OrderBy("-" + field.Column)
+		} else {
+			b.OrderBy(field.Column)
+		}
+	}
+	if params.Limit < 0 || params.Offset < 0 {
+		return fmt.Errorf("%w: negative limit or offset", ErrInvalidFilter)
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = s.DefaultLimit
+	}
+	if s.MaxLimit > 0 && (limit == 0 || limit > s.MaxLimit) {
+		limit = s.MaxLimit
+	}
+	b.Limit(limit).Offset(params.Offset)
+	return nil
+}
+
+func (s FilterSchema) condition(filter FieldFilter) (Condition, error) {
+	field, ok := s.Fields[filter.Field]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, filter.Field)
+	}
+	op := filter.Op
+	if op == "" {
+		op = OpEq
+	}
+	if field.Operators != nil && !slices.Contains(field.Operators, op) {
+		return nil, fmt.Errorf("%w: operator %q is not allowed on %q", ErrInvalidFilter, op, filter.Field)
+	}
+	column := field.Column
+	switch op {
+	case OpEq:
+		return Eq(column, filter.Value), nil
+	case OpNe:
+		return Ne(column, filter.Value), nil
+	case OpLt:
+		return Lt(column, filter.Value), nil
+	case OpLe:
+		return Le(column, filter.Value), nil
+	case OpGt:
+		return Gt(column, filter.Value), nil
+	case OpGe:
+		return Ge(column, filter.Value), nil
+	case OpLike:
+		return Like(column, filter.Value), nil
+	case OpILike:
+		return ILike(column, filter.Value), nil
+	case OpContains:
+		value, ok := filter.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q needs a string on %q", ErrInvalidFilter, op, filter.Field)
+		}
+		return ILike(column, "%"+escapeLike(value)+"%"), nil
+	case OpIn:
+		if values, ok := filter.Value.(string); ok {
+			return In(column, strings.Split(values, ",")), nil
+		}
+		if _, ok := filter.Value.([]any); !ok {
+			return nil, fmt.Errorf("%w: %q needs a list on %q", ErrInvalidFilter, op, filter.Field)
+		}
+		return In(column, filter.Value), nil
+	case OpIsNull:
+		isNull, err := filterBool(filter.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q on %q: %v", ErrInvalidFilter, op, filter.Field, err)
+		}
+		if isNull {
+			return IsNull(column), nil
+		}
+		return IsNotNull(column), nil
+	}
+	return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, op)
+}
+
+func filterBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("expected a boolean, got %v", value)
+}
+
+// escapeLike escapes the wildcards of LIKE patterns.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// ParseListParams reads list parameters from a query string: sort=-name,id, limit=20, offset=40,
+// and filters as field=value or field=op:value, e.g. name=contains:smith or id=in:1,2,3. Values
+// are passed on as strings, which postgres converts to the column's type.
+func ParseListParams(values url.Values) (ListParams, error) {
+	var params ListParams
+	var err error
+	for key, list := range values {
+		for _, value := range list {
+			switch key {
+			case "sort":
+				params.Sort = append(params.Sort, strings.Split(value, ",")...)
+			case "limit":
+				params.Limit, err = strconv.Atoi(value)
+			case "offset":
+				params.Offset, err = strconv.Atoi(value)
+			default:
+				filter := FieldFilter{Field: key, Op: OpEq, Value: value}
+				if op, operand, ok := strings.Cut(value, ":"); ok && isFilterOperator(op) {
+					filter.Op, filter.Value = op, operand
+				}
+				params.Filters = append(params.Filters, filter)
+			}
+			if err != nil {
+				return params, fmt.Errorf("%w: %v: %v", ErrInvalidFilter, key, err)
+			}
+		}
+	}
+	slices.SortStableFunc(params.Filters, func(a, b FieldFilter) int { return strings.Compare(a.Field, b.Field) })
+	return params, nil
+}
+
+func isFilterOperator(op string) bool {
+	switch op {
+	case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe, OpLike, OpILike, OpContains, OpIn, OpIsNull:
+		return true
+	}
+	return false
+}
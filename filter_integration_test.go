@@ -0,0 +1,42 @@
+package pg_test
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"net/url"
+	"testing"
+)
+
+func TestFilterSchema(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "INSERT INTO testtable (id, name) VALUES (2, 'Name 2'), (3, 'other')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := pg.FilterSchema{Fields: map[string]pg.FilterField{
+		"id":   {Column: "id", Sortable: true},
+		"name": {Column: "name"},
+	}}
+	values, _ := url.ParseQuery("id=in:1,2,3&name=contains:NAME&sort=-id")
+	params, err := pg.ParseListParams(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := pg.Select("id").From("testtable")
+	err = schema.Apply(b, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql, args := b.Build()
+	rows, err := db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil || len(ids) != 2 || ids[0] != 2 || ids[1] != 1 {
+		t.Errorf("unexpected ids %v, %v", ids, err)
+	}
+}
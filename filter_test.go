@@ -0,0 +1,94 @@
+package pg
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+var testFilterSchema = FilterSchema{
+	Fields: map[string]FilterField{
+		"id":   {Column: "id", Sortable: true},
+		"name": {Column: "full_name", Operators: []string{OpEq, OpContains}, Sortable: true},
+		"note": {Column: "description", Operators: []string{OpIsNull}},
+	},
+	DefaultSort:  []string{"id"},
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
+func TestFilterSchemaApply(t *testing.T) {
+	b := Select().From("users")
+	err := testFilterSchema.Apply(b, ListParams{
+		Filters: []FieldFilter{
+			{Field: "name", Op: OpContains, Value: "50%_off"},
+			{Field: "id", Op: OpIn, Value: []any{1.0, 2.0}},
+			{Field: "note", Op: OpIsNull, Value: "false"},
+		},
+		Sort:  []string{"-name"},
+		Limit: 500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql, args := b.Build()
+	expected := `SELECT * FROM "users" WHERE ("full_name" ILIKE $1 AND "id" = ANY($2) AND "description" IS NOT NULL) ORDER BY "full_name" DESC LIMIT $3`
+	if sql != expected {
+		t.Errorf("expected %v, got %v", expected, sql)
+	}
+	if !reflect.DeepEqual(args, []any{`%50\%\_off%`, []any{1.0, 2.0}, 100}) {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	b = Select().From("users")
+	if err = testFilterSchema.Apply(b, ListParams{Offset: 40}); err != nil {
+		t.Fatal(err)
+	}
+	sql, args = b.Build()
+	if sql != `SELECT * FROM "users" ORDER BY "id" LIMIT $1 OFFSET $2` || !reflect.DeepEqual(args, []any{20, 40}) {
+		t.Errorf("expected the defaults, got %v %v", sql, args)
+	}
+}
+
+func TestFilterSchemaRejects(t *testing.T) {
+	for _, params := range []ListParams{
+		{Filters: []FieldFilter{{Field: "password", Value: "x"}}},
+		{Filters: []FieldFilter{{Field: "name", Op: OpGt, Value: "x"}}},
+		{Filters: []FieldFilter{{Field: "id", Op: "regex", Value: "x"}}},
+		{Filters: []FieldFilter{{Field: "id", Op: OpIn, Value: 1.0}}},
+		{Sort: []string{"note"}},
+		{Sort: []string{"id; DROP TABLE users"}},
+		{Limit: -1},
+	} {
+		err := testFilterSchema.Apply(Select().From("users"), params)
+		if !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("expected %+v to be rejected, got %v", params, err)
+		}
+	}
+}
+
+func TestParseListParams(t *testing.T) {
+	values, _ := url.ParseQuery("name=contains:smith&id=in:1,2&note=a:b&sort=-name,id&limit=10&offset=20")
+	params, err := ParseListParams(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ListParams{
+		Filters: []FieldFilter{
+			{Field: "id", Op: OpIn, Value: "1,2"},
+			{Field: "name", Op: OpContains, Value: "smith"},
+			{Field: "note", Op: OpEq, Value: "a:b"},
+		},
+		Sort:   []string{"-name", "id"},
+		Limit:  10,
+		Offset: 20,
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %+v, got %+v", expected, params)
+	}
+	_, err = ParseListParams(url.Values{"limit": {"ten"}})
+	if !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("expected an invalid limit, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package gen
+
+import (
+	"fmt"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/introspect"
+	"reflect"
+	"sort"
+)
+
+// Mismatch describes a difference between a struct and the table it is mapped to.
+type Mismatch struct {
+	Column string
+	Reason string
+}
+
+func (m Mismatch) String() string {
+	return m.Column + ": " + m.Reason
+}
+
+// Compare checks a struct against table and reports columns missing from the struct, fields
+// without a column, and nullable columns mapped to fields that cannot hold NULL.
+func Compare(table *introspect.Table, model any) ([]Mismatch, error) {
+	fields, err := pg.StructColumns(reflect.TypeOf(model))
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []Mismatch
+	columns := make(map[string]bool)
+	for _, c := range table.Columns {
+		columns[c.Name] = true
+		field, ok := fields[c.Name]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{Column: c.Name, Reason: "no struct field"})
+			continue
+		}
+		if !c.NotNull && !canHoldNull(field.Type) {
+			mismatches = append(mismatches, Mismatch{Column: c.Name, Reason: fmt.Sprintf("column is nullable but field %v is %v", field.Name, field.Type)})
+		}
+	}
+	for column, field := range fields {
+		if !columns[column] {
+			mismatches = append(mismatches, Mismatch{Column: column, Reason: fmt.Sprintf("field %v has no column in %v.%v", field.Name, table.Schema, table.Name)})
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].Column < mismatches[j].Column
+	})
+	return mismatches, nil
+}
+
+// canHoldNull reports whether pgx can scan NULL into t: pointers, slices, maps, interfaces and the
+// pgtype style structs with a Valid field.
+func canHoldNull(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	case reflect.Struct:
+		valid, ok := t.FieldByName("Valid")
+		return ok && valid.Type.Kind() == reflect.Bool
+	}
+	return false
+}
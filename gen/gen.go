@@ -0,0 +1,155 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/msumera/pgutils/introspect"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+type Options struct {
+	Package string
+	// Tables limits generation to the given tables, schema qualified or not. All tables are
+	// generated when it is empty.
+	Tables []string
+}
+
+// goType is the Go type a column is scanned into and the import it needs.
+type goType struct {
+	name     string
+	imp      string
+	nullable bool
+}
+
+var goTypes = map[string]goType{
+	"smallint":                    {name: "int16"},
+	"integer":                     {name: "int32"},
+	"bigint":                      {name: "int64"},
+	"real":                        {name: "float32"},
+	"double precision":            {name: "float64"},
+	"numeric":                     {name: "pgtype.Numeric", imp: "github.com/jackc/pgx/v5/pgtype", nullable: true},
+	"boolean":                     {name: "bool"},
+	"text":                        {name: "string"},
+	"character varying":           {name: "string"},
+	"character":                   {name: "string"},
+	"citext":                      {name: "string"},
+	"uuid":                        {name: "pgtype.UUID", imp: "github.com/jackc/pgx/v5/pgtype", nullable: true},
+	"bytea":                       {name: "[]byte", nullable: true},
+	"json":                        {name: "json.RawMessage", imp: "encoding/json", nullable: true},
+	"jsonb":                       {name: "json.RawMessage", imp: "encoding/json", nullable: true},
+	"date":                        {name: "time.Time", imp: "time"},
+	"timestamp without time zone": {name: "time.Time", imp: "time"},
+	"timestamp with time zone":    {name: "time.Time", imp: "time"},
+	"time without time zone":      {name: "pgtype.Time", imp: "github.com/jackc/pgx/v5/pgtype", nullable: true},
+	"interval":                    {name: "pgtype.Interval", imp: "github.com/jackc/pgx/v5/pgtype", nullable: true},
+	"inet":                        {name: "netip.Prefix", imp: "net/netip"},
+	"cidr":                        {name: "netip.Prefix", imp: "net/netip"},
+}
+
+// columnType maps a column to its Go type. Unknown types, including enums, are scanned as
+// strings; nullable columns become pointers unless the type can represent NULL itself.
+func columnType(c introspect.Column) goType {
+	sqlType := c.Type
+	array := strings.HasSuffix(sqlType, "[]")
+	sqlType = strings.TrimSuffix(sqlType, "[]")
+	if i := strings.Index(sqlType, "("); i >= 0 {
+		// Drop the modifier, e.g. character varying(20) or timestamp(3) with time zone.
+		sqlType = strings.TrimSpace(sqlType[:i] + sqlType[strings.Index(sqlType, ")")+1:])
+	}
+	t, ok := goTypes[sqlType]
+	if !ok {
+		t = goType{name: "string"}
+	}
+	if array {
+		return goType{name: "[]" + t.name, imp: t.imp, nullable: true}
+	}
+	if !c.NotNull && !t.nullable {
+		t.name = "*" + t.name
+	}
+	return t
+}
+
+var initialisms = map[string]string{"id": "ID", "url": "URL", "uuid": "UUID", "ip": "IP", "json": "JSON", "api": "API", "http": "HTTP"}
+
+// GoName turns a snake_case identifier into an exported Go name, e.g. user_id into UserID.
+func GoName(s string) string {
+	var sb strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '.' || r == ' ' || r == '-' }) {
+		if initialism, ok := initialisms[strings.ToLower(part)]; ok {
+			sb.WriteString(initialism)
+			continue
+		}
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	name := sb.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "T" + name
+	}
+	return name
+}
+
+func selected(t introspect.Table, tables []string) bool {
+	if len(tables) == 0 {
+		return true
+	}
+	for _, table := range tables {
+		if table == t.Name || table == t.Schema+"."+t.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate renders a formatted Go file with a struct per table of d. Structs of tables outside the
+// public schema are prefixed with the schema name to keep them apart.
+func Generate(d *introspect.Database, opts Options) ([]byte, error) {
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "models"
+	}
+	var body bytes.Buffer
+	imports := make(map[string]bool)
+	for _, s := range d.Schemas {
+		for _, t := range s.Tables {
+			if !selected(t, opts.Tables) {
+				continue
+			}
+			name := GoName(t.Name)
+			if t.Schema != "public" {
+				name = GoName(t.Schema) + name
+			}
+			body.WriteString("\n")
+			if t.Comment != "" {
+				fmt.Fprintf(&body, "// %v %v\n", name, strings.ReplaceAll(t.Comment, "\n", "\n// "))
+			}
+			fmt.Fprintf(&body, "type %v struct {\n", name)
+			for _, c := range t.Columns {
+				goType := columnType(c)
+				if goType.imp != "" {
+					imports[goType.imp] = true
+				}
+				fmt.Fprintf(&body, "\t%v %v `db:%q`\n", GoName(c.Name), goType.name, c.Name)
+			}
+			body.WriteString("}\n")
+		}
+	}
+	var src bytes.Buffer
+	src.WriteString("// Code generated by pgstructgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %v\n", pkg)
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		src.WriteString("\nimport (\n")
+		for _, path := range paths {
+			fmt.Fprintf(&src, "\t%q\n", path)
+		}
+		src.WriteString(")\n")
+	}
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
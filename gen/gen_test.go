@@ -0,0 +1,71 @@
+package gen
+
+import (
+	"github.com/msumera/pgutils/introspect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func stringPointer(s string) *string {
+	return &s
+}
+
+var users = introspect.Table{
+	Schema:  "public",
+	Name:    "user_accounts",
+	Comment: "holds registered users.",
+	Columns: []introspect.Column{
+		{Name: "id", Type: "bigint", NotNull: true, Identity: true},
+		{Name: "email", Type: "character varying(320)", NotNull: true},
+		{Name: "avatar_url", Type: "text"},
+		{Name: "tags", Type: "text[]", NotNull: true},
+		{Name: "settings", Type: "jsonb", NotNull: true, Default: stringPointer("'{}'::jsonb")},
+		{Name: "created_at", Type: "timestamp(3) with time zone", NotNull: true},
+	},
+}
+
+func TestGenerate(t *testing.T) {
+	d := &introspect.Database{Schemas: []introspect.Schema{{Name: "public", Tables: []introspect.Table{users}}}}
+	src, err := Generate(d, Options{Package: "models"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "// Code generated by pgstructgen. DO NOT EDIT.\n\n" +
+		"package models\n\n" +
+		"import (\n\t\"encoding/json\"\n\t\"time\"\n)\n\n" +
+		"// UserAccounts holds registered users.\n" +
+		"type UserAccounts struct {\n" +
+		"\tID        int64           `db:\"id\"`\n" +
+		"\tEmail     string          `db:\"email\"`\n" +
+		"\tAvatarURL *string         `db:\"avatar_url\"`\n" +
+		"\tTags      []string        `db:\"tags\"`\n" +
+		"\tSettings  json.RawMessage `db:\"settings\"`\n" +
+		"\tCreatedAt time.Time       `db:\"created_at\"`\n" +
+		"}\n"
+	if string(src) != expected {
+		t.Errorf("unexpected source:\n%v", string(src))
+	}
+}
+
+func TestCompare(t *testing.T) {
+	type account struct {
+		ID        int64
+		Email     string
+		AvatarURL string `db:"avatar_url"`
+		Settings  []byte
+		CreatedAt time.Time
+		Nickname  string
+	}
+	mismatches, err := Compare(&users, account{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reported []string
+	for _, m := range mismatches {
+		reported = append(reported, m.Column)
+	}
+	if strings.Join(reported, ",") != "avatar_url,nickname,tags" {
+		t.Errorf("unexpected mismatches %v", mismatches)
+	}
+}
@@ -0,0 +1,191 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+// Role is an application role created by CreateRole or a grants file.
+type Role struct {
+	Name  string `yaml:"name"`
+	Login bool   `yaml:"login"`
+	// Password is set on login roles by CreateRole. Grants files cannot carry it.
+	Password string   `yaml:"-"`
+	MemberOf []string `yaml:"member_of"`
+}
+
+// Grant gives Role privileges on the tables and sequences of Schema, including USAGE on the
+// schema itself.
+type Grant struct {
+	Role   string `yaml:"role"`
+	Schema string `yaml:"schema"`
+	// Tables limits the grant to these tables of Schema. When empty, all tables are covered, and
+	// applying the grant again after new tables were created extends it to them.
+	Tables     []string `yaml:"tables"`
+	Privileges []string `yaml:"privileges"`
+	// SequencePrivileges are granted on all sequences of Schema, e.g. USAGE for serial columns.
+	SequencePrivileges []string `yaml:"sequence_privileges"`
+	// Default also grants the privileges on tables and sequences the current role creates later.
+	Default bool `yaml:"default"`
+}
+
+// GrantsFile declares roles and their grants, see ParseGrantsFile.
+type GrantsFile struct {
+	Roles  []Role  `yaml:"roles"`
+	Grants []Grant `yaml:"grants"`
+}
+
+var privileges = map[string]bool{
+	"ALL": true, "SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true, "TRUNCATE": true,
+	"REFERENCES": true, "TRIGGER": true, "USAGE": true,
+}
+
+func privilegeList(list []string) (string, error) {
+	normalized := Map(list, func(p string) string { return strings.ToUpper(strings.TrimSpace(p)) })
+	for _, privilege := range normalized {
+		if !privileges[privilege] {
+			return "", fmt.Errorf("unknown privilege %q", privilege)
+		}
+	}
+	return strings.Join(normalized, ", "), nil
+}
+
+// CreateRole creates role unless it exists, and brings an existing one in line with it: login,
+// password and memberships are set again, memberships not listed are kept.
+func CreateRole(ctx context.Context, q Querier, role Role) error {
+	var exists bool
+	err := q.QueryRow(ctx, "SELECT EXISTS (SELECT FROM pg_roles WHERE rolname = $1)", role.Name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	name := QuoteIdentifier(role.Name)
+	login := "NOLOGIN"
+	if role.Login {
+		login = "LOGIN"
+	}
+	statement := "ALTER ROLE " + name + " " + login
+	if !exists {
+		statement = "CREATE ROLE " + name + " " + login
+	}
+	_, err = q.Exec(ctx, statement)
+	if err != nil {
+		return err
+	}
+	if role.Password != "" {
+		// format() quotes the password server side, as utility statements take no parameters.
+		var alter string
+		err = q.QueryRow(ctx, "SELECT format('ALTER ROLE %I PASSWORD %L', $1::text, $2::text)", role.Name, role.Password).Scan(&alter)
+		if err != nil {
+			return err
+		}
+		_, err = q.Exec(ctx, alter)
+		if err != nil {
+			return err
+		}
+	}
+	for _, group := range role.MemberOf {
+		_, err = q.Exec(ctx, "GRANT "+QuoteIdentifier(group)+" TO "+name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GrantStatements renders g as GRANT statements.
+func GrantStatements(g Grant) ([]string, error) {
+	if g.Role == "" || g.Schema == "" {
+		return nil, fmt.Errorf("grant needs a role and a schema")
+	}
+	role := QuoteIdentifier(g.Role)
+	schema := QuoteIdentifier(g.Schema)
+	statements := []string{"GRANT USAGE ON SCHEMA " + schema + " TO " + role}
+	if len(g.Privileges) > 0 {
+		list, err := privilegeList(g.Privileges)
+		if err != nil {
+			return nil, err
+		}
+		target := "ALL TABLES IN SCHEMA " + schema
+		if len(g.Tables) > 0 {
+			target = "TABLE " + strings.Join(Map(g.Tables, func(table string) string {
+				return schema + "." + QuoteIdentifier(table)
+			}), ", ")
+		}
+		statements = append(statements, "GRANT "+list+" ON "+target+" TO "+role)
+		if g.Default && len(g.Tables) == 0 {
+			statements = append(statements, "ALTER DEFAULT PRIVILEGES IN SCHEMA "+schema+" GRANT "+list+" ON TABLES TO "+role)
+		}
+	}
+	if len(g.SequencePrivileges) > 0 {
+		list, err := privilegeList(g.SequencePrivileges)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, "GRANT "+list+" ON ALL SEQUENCES IN SCHEMA "+schema+" TO "+role)
+		if g.Default {
+			statements = append(statements, "ALTER DEFAULT PRIVILEGES IN SCHEMA "+schema+" GRANT "+list+" ON SEQUENCES TO "+role)
+		}
+	}
+	return statements, nil
+}
+
+// ApplyGrants executes the statements of grants.
+func ApplyGrants(ctx context.Context, q Querier, grants ...Grant) error {
+	for _, g := range grants {
+		statements, err := GrantStatements(g)
+		if err != nil {
+			return err
+		}
+		for _, statement := range statements {
+			_, err = q.Exec(ctx, statement)
+			if err != nil {
+				return fmt.Errorf("%v: %w", statement, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseGrantsFile reads a YAML grants file:
+//
+//	roles:
+//	  - name: app_readonly
+//	grants:
+//	  - role: app_readonly
+//	    schema: public
+//	    privileges: [SELECT]
+func ParseGrantsFile(data []byte) (GrantsFile, error) {
+	var f GrantsFile
+	err := yaml.Unmarshal(data, &f)
+	return f, err
+}
+
+// ApplyGrantsFile creates the roles of f and applies its grants. It is idempotent.
+func ApplyGrantsFile(ctx context.Context, q Querier, f GrantsFile) error {
+	for _, role := range f.Roles {
+		err := CreateRole(ctx, q, role)
+		if err != nil {
+			return err
+		}
+	}
+	return ApplyGrants(ctx, q, f.Grants...)
+}
+
+// applyGrantsFile applies c.GrantsFile from the migrations source after the migrations, so tables
+// created by them are covered. Its statements are audited like the migrations.
+func (dbm *databaseMigrator) applyGrantsFile(ctx context.Context, q Querier) error {
+	if dbm.Configuration.GrantsFile == "" {
+		return nil
+	}
+	data, err := dbm.Configuration.source().Read(ctx, dbm.Configuration.GrantsFile)
+	if err != nil {
+		return err
+	}
+	f, err := ParseGrantsFile(data)
+	if err != nil {
+		return fmt.Errorf("%v: %w", dbm.Configuration.GrantsFile, err)
+	}
+	return ApplyGrantsFile(ctx, dbm.audited(q), f)
+}
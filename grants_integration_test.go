@@ -0,0 +1,44 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGrantsFile(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	directory := t.TempDir()
+	files := map[string]string{
+		"0_init.sql":  "CREATE TABLE items (id SERIAL PRIMARY KEY, name TEXT);",
+		"grants.yaml": "roles:\n  - name: pgutils_reader\ngrants:\n  - role: pgutils_reader\n    schema: public\n    privileges: [SELECT]\n",
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := db.Configuration
+	c.MigrationsDirectory = directory
+	c.GrantsFile = "grants.yaml"
+	err := pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT has_table_privilege('pgutils_reader', 'items', 'SELECT'), has_table_privilege('pgutils_reader', 'items', 'INSERT')", [][]any{{true, false}})
+
+	err = pg.CreateRole(ctx, db.Pool, pg.Role{Name: "pgutils_app", Login: true, Password: "it's secret", MemberOf: []string{"pgutils_reader"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.CreateRole(ctx, db.Pool, pg.Role{Name: "pgutils_app", Login: true})
+	if err != nil {
+		t.Errorf("creating an existing role should converge, got %v", err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT rolcanlogin, pg_has_role('pgutils_app', 'pgutils_reader', 'MEMBER') FROM pg_roles WHERE rolname = 'pgutils_app'", [][]any{{true, true}})
+}
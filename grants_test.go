@@ -0,0 +1,61 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrantStatements(t *testing.T) {
+	statements, err := GrantStatements(Grant{
+		Role:               "app",
+		Schema:             "public",
+		Privileges:         []string{"select", "insert"},
+		SequencePrivileges: []string{"usage"},
+		Default:            true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`GRANT USAGE ON SCHEMA "public" TO "app"`,
+		`GRANT SELECT, INSERT ON ALL TABLES IN SCHEMA "public" TO "app"`,
+		`ALTER DEFAULT PRIVILEGES IN SCHEMA "public" GRANT SELECT, INSERT ON TABLES TO "app"`,
+		`GRANT USAGE ON ALL SEQUENCES IN SCHEMA "public" TO "app"`,
+		`ALTER DEFAULT PRIVILEGES IN SCHEMA "public" GRANT USAGE ON SEQUENCES TO "app"`,
+	}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("unexpected statements %#v", statements)
+	}
+	statements, err = GrantStatements(Grant{Role: "app", Schema: "public", Tables: []string{"users"}, Privileges: []string{"SELECT"}})
+	if err != nil || statements[1] != `GRANT SELECT ON TABLE "public"."users" TO "app"` {
+		t.Errorf("unexpected statements %#v, %v", statements, err)
+	}
+	_, err = GrantStatements(Grant{Role: "app", Schema: "public", Privileges: []string{"SELECT; DROP TABLE users"}})
+	if err == nil {
+		t.Error("expected an error for an unknown privilege")
+	}
+}
+
+func TestParseGrantsFile(t *testing.T) {
+	f, err := ParseGrantsFile([]byte(`
+roles:
+  - name: app
+    login: true
+    member_of: [readers]
+grants:
+  - role: app
+    schema: public
+    privileges: [SELECT]
+    default: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := GrantsFile{
+		Roles:  []Role{{Name: "app", Login: true, MemberOf: []string{"readers"}}},
+		Grants: []Grant{{Role: "app", Schema: "public", Privileges: []string{"SELECT"}, Default: true}},
+	}
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("unexpected file %+v", f)
+	}
+}
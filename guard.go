@@ -0,0 +1,80 @@
+package pg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// allowDestructiveDirective acknowledges the destructive statements of a script when
+// Configuration.DestructiveGuard is set, on a line of its own.
+const allowDestructiveDirective = "-- pg:allow-destructive"
+
+var ErrDestructiveMigration = errors.New("destructive statement")
+
+var (
+	dropTable = regexp.MustCompile(`^DROP TABLE\b`)
+	truncate  = regexp.MustCompile(`^TRUNCATE\b`)
+	deleteAll = regexp.MustCompile(`^DELETE FROM\b`)
+	where     = regexp.MustCompile(`\bWHERE\b`)
+)
+
+// destructiveStatement returns the first statement of script that drops a table, truncates one
+// or deletes without WHERE, unless the script acknowledges them with the allow-destructive
+// directive.
+func destructiveStatement(script string) (scriptStatement, bool) {
+	for _, line := range strings.Split(script, "\n") {
+		if strings.TrimSpace(line) == allowDestructiveDirective {
+			return scriptStatement{}, false
+		}
+	}
+	return firstDestructiveStatement(script)
+}
+
+// firstDestructiveStatement is destructiveStatement ignoring the directive.
+func firstDestructiveStatement(script string) (scriptStatement, bool) {
+	for _, statement := range splitScript(script) {
+		switch {
+		case dropTable.MatchString(statement.normalized),
+			truncate.MatchString(statement.normalized),
+			deleteAll.MatchString(statement.normalized) && !where.MatchString(statement.normalized):
+			return statement, true
+		}
+	}
+	return scriptStatement{}, false
+}
+
+// checkDestructive fails with a *MigrationError wrapping ErrDestructiveMigration when the guard
+// is enabled and the script has an unacknowledged destructive statement, or any destructive
+// statement when the policy forbids them.
+func (dbm *databaseMigrator) checkDestructive(filename string, version string, script string) error {
+	forbidden := dbm.policy != nil && dbm.policy.Destructive == DestructiveForbid
+	if forbidden {
+		statement, ok := firstDestructiveStatement(script)
+		if !ok {
+			return nil
+		}
+		return &MigrationError{
+			Filename:  filename,
+			Version:   version,
+			Statement: statement.SQL,
+			Err: fmt.Errorf("%w at line %v, forbidden by the policy of environment %v", ErrDestructiveMigration,
+				statement.Line, dbm.Configuration.Environment),
+		}
+	}
+	if !dbm.Configuration.DestructiveGuard && (dbm.policy == nil || dbm.policy.Destructive != DestructiveGuard) {
+		return nil
+	}
+	statement, ok := destructiveStatement(script)
+	if !ok {
+		return nil
+	}
+	return &MigrationError{
+		Filename:  filename,
+		Version:   version,
+		Statement: statement.SQL,
+		Err: fmt.Errorf("%w at line %v, acknowledge it with a %q line", ErrDestructiveMigration,
+			statement.Line, allowDestructiveDirective),
+	}
+}
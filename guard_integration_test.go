@@ -0,0 +1,43 @@
+package pg_test
+
+import (
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDestructiveGuard(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	directory := t.TempDir()
+	for name, script := range map[string]string{
+		"0_create.sql": "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+		"1_drop.sql":   "INSERT INTO b VALUES (1);\nDROP TABLE a;\n",
+	} {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(script), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := db.Configuration
+	c.MigrationsDirectory = directory
+	c.DestructiveGuard = true
+	err := pg.Migrate(db.Pool, c)
+	var migrationError *pg.MigrationError
+	if !errors.Is(err, pg.ErrDestructiveMigration) || !errors.As(err, &migrationError) || migrationError.Statement != "DROP TABLE a" {
+		t.Fatalf("expected the drop to be rejected, got %v", err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT 1 FROM pg_class WHERE relname IN ('a', 'b')")
+
+	err = os.WriteFile(filepath.Join(directory, "1_drop.sql"), []byte("-- pg:allow-destructive\nDROP TABLE a;\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT relname::text FROM pg_class WHERE relname IN ('a', 'b')", [][]any{{"b"}})
+}
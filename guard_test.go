@@ -0,0 +1,20 @@
+package pg
+
+import "testing"
+
+func TestDestructiveStatement(t *testing.T) {
+	for script, expected := range map[string]string{
+		"CREATE TABLE a (id INT);\ndrop table a;":                           "drop table a",
+		"TRUNCATE a CASCADE":                                                "TRUNCATE a CASCADE",
+		"DELETE FROM a WHERE id = 1;\nDELETE FROM b":                        "DELETE FROM b",
+		"DELETE FROM a WHERE id = 1":                                        "",
+		"INSERT INTO a VALUES ('DROP TABLE a')":                             "",
+		"CREATE FUNCTION f() RETURNS void AS $$ TRUNCATE a $$ LANGUAGE sql": "",
+		"-- pg:allow-destructive\nDROP TABLE a":                             "",
+	} {
+		statement, ok := destructiveStatement(script)
+		if ok != (expected != "") || statement.SQL != expected {
+			t.Errorf("expected %q in %q, got %q", expected, script, statement.SQL)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// ErrIdempotencyTableNotSet is returned by Idempotency when Configuration.IdempotencyTable is empty.
+var ErrIdempotencyTableNotSet = errors.New("idempotency table not configured")
+
+// Idempotency records processed keys in the table configured by Configuration.IdempotencyTable,
+// which the migrator creates in the changelog schema. The migrator only creates it when the name
+// is set, so there is no default.
+type Idempotency struct {
+	Configuration Configuration
+}
+
+func NewIdempotency(c Configuration) *Idempotency {
+	return &Idempotency{Configuration: c}
+}
+
+func (i *Idempotency) schemaTable() (string, error) {
+	if i.Configuration.IdempotencyTable == "" {
+		return "", ErrIdempotencyTableNotSet
+	}
+	return QuoteIdentifier(i.Configuration.ChangelogSchema) + "." + QuoteIdentifier(i.Configuration.IdempotencyTable), nil
+}
+
+// Once runs fn at most once per key. The key is claimed in the same transaction as fn's effects,
+// so a failed fn releases it for a retry, and concurrent callers with the same key block until
+// the first one finishes. It reports whether fn was run.
+func (i *Idempotency) Once(ctx context.Context, q TxBeginner, key string, fn func(ctx context.Context, tx pgx.Tx) error) (bool, error) {
+	table, err := i.schemaTable()
+	if err != nil {
+		return false, err
+	}
+	tx, err := q.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	//goland:noinspection SqlResolve
+	tag, err := tx.Exec(ctx, "INSERT INTO "+table+" (key, timestamp) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING", key, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	err = fn(ContextWithTx(ctx, tx), tx)
+	if err != nil {
+		return false, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Purge removes keys recorded more than olderThan ago, allowing them to be processed again.
+func (i *Idempotency) Purge(ctx context.Context, q Querier, olderThan time.Duration) (int64, error) {
+	table, err := i.schemaTable()
+	if err != nil {
+		return 0, err
+	}
+	//goland:noinspection SqlResolve
+	tag, err := q.Exec(ctx, "DELETE FROM "+table+" WHERE timestamp < $1", time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (dbm *databaseMigrator) initIdempotencyTable() error {
+	if dbm.Configuration.IdempotencyTable == "" {
+		return nil
+	}
+	script := `
+		CREATE SCHEMA IF NOT EXISTS {SCHEMA};
+		CREATE TABLE IF NOT EXISTS {SCHEMA}.{IDEMPOTENCY_TABLE}
+		(
+			key TEXT PRIMARY KEY NOT NULL,
+			timestamp TIMESTAMPTZ NOT NULL
+		);
+	`
+	_, err := dbm.exec(dbm.PgxPool, dbm.replaceEnv(script))
+	return err
+}
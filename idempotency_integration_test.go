@@ -0,0 +1,64 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyOnce(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	c := db.Configuration
+	c.MigrationsDirectory = "testdb"
+	c.IdempotencyTable = "idempotency"
+	err := pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idempotency := pg.NewIdempotency(c)
+	insert := func(id int) func(ctx context.Context, tx pgx.Tx) error {
+		return func(ctx context.Context, tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, "INSERT INTO testtable (id, name) VALUES ($1, 'once')", id)
+			return err
+		}
+	}
+
+	failed := errors.New("failed")
+	ran, err := idempotency.Once(ctx, db.Pool, "a", func(ctx context.Context, tx pgx.Tx) error {
+		err := insert(2)(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return failed
+	})
+	if ran || !errors.Is(err, failed) {
+		t.Errorf("expected the failure, got %v, %v", ran, err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM testtable WHERE id = 2")
+
+	for i, expected := range []bool{true, false} {
+		ran, err = idempotency.Once(ctx, db.Pool, "a", insert(2+i))
+		if err != nil || ran != expected {
+			t.Errorf("run %v: expected %v, got %v, %v", i, expected, ran, err)
+		}
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id FROM testtable WHERE name = 'once'", [][]any{{int32(2)}})
+
+	purged, err := idempotency.Purge(ctx, db.Pool, time.Hour)
+	if err != nil || purged != 0 {
+		t.Errorf("expected the recent key to be kept, got %v, %v", purged, err)
+	}
+	purged, err = idempotency.Purge(ctx, db.Pool, -time.Hour)
+	if err != nil || purged != 1 {
+		t.Errorf("expected the key to be purged, got %v, %v", purged, err)
+	}
+	ran, err = idempotency.Once(ctx, db.Pool, "a", insert(4))
+	if err != nil || !ran {
+		t.Errorf("expected the purged key to run again, got %v, %v", ran, err)
+	}
+}
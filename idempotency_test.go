@@ -0,0 +1,24 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyWithoutTable(t *testing.T) {
+	idempotency := NewIdempotency(Configuration{ChangelogSchema: "public"})
+	ran, err := idempotency.Once(context.Background(), nil, "key", func(ctx context.Context, tx pgx.Tx) error {
+		t.Error("fn must not run")
+		return nil
+	})
+	if ran || !errors.Is(err, ErrIdempotencyTableNotSet) {
+		t.Errorf("expected ErrIdempotencyTableNotSet, got %v, %v", ran, err)
+	}
+	_, err = idempotency.Purge(context.Background(), nil, time.Hour)
+	if !errors.Is(err, ErrIdempotencyTableNotSet) {
+		t.Errorf("expected ErrIdempotencyTableNotSet, got %v", err)
+	}
+}
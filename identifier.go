@@ -0,0 +1,38 @@
+package pg
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"strings"
+)
+
+// maxIdentifierLength is postgres' NAMEDATALEN - 1; longer identifiers are silently truncated.
+const maxIdentifierLength = 63
+
+var ErrInvalidIdentifier = errors.New("invalid identifier")
+
+// QuoteIdentifier quotes a possibly schema qualified name for use in SQL, e.g. app.users becomes
+// "app"."users". Quotes inside the parts are escaped, so the result is safe to concatenate into
+// statements.
+func QuoteIdentifier(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
+
+func quoteIdentifiers(names []string) string {
+	return strings.Join(Map(names, QuoteIdentifier), ", ")
+}
+
+// SanitizeIdentifier validates a single, unqualified identifier taken from user input or
+// configuration and returns it quoted. Dots are part of the name rather than separators.
+func SanitizeIdentifier(name string) (string, error) {
+	switch {
+	case name == "":
+		return "", fmt.Errorf("%w: empty name", ErrInvalidIdentifier)
+	case strings.ContainsRune(name, 0):
+		return "", fmt.Errorf("%w %q: contains a NUL byte", ErrInvalidIdentifier, name)
+	case len(name) > maxIdentifierLength:
+		return "", fmt.Errorf("%w %q: longer than %v bytes", ErrInvalidIdentifier, name, maxIdentifierLength)
+	}
+	return pgx.Identifier{name}.Sanitize(), nil
+}
@@ -0,0 +1,30 @@
+package pg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	if quoted := QuoteIdentifier(`app.user"s`); quoted != `"app"."user""s"` {
+		t.Errorf("unexpected quoted name %v", quoted)
+	}
+	c := Configuration{ChangelogSchema: "my schema", ChangelogTable: "changelog"}
+	if c.schemaTable() != `"my schema"."changelog"` {
+		t.Errorf("unexpected changelog table %v", c.schemaTable())
+	}
+}
+
+func TestSanitizeIdentifier(t *testing.T) {
+	quoted, err := SanitizeIdentifier("a.b")
+	if err != nil || quoted != `"a.b"` {
+		t.Errorf("unexpected result %v, %v", quoted, err)
+	}
+	for _, name := range []string{"", "a\x00b", strings.Repeat("x", 64)} {
+		_, err = SanitizeIdentifier(name)
+		if !errors.Is(err, ErrInvalidIdentifier) {
+			t.Errorf("expected ErrInvalidIdentifier for %q, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+)
+
+// UnusedIndex is an index that is rarely scanned. Size is in bytes.
+type UnusedIndex struct {
+	Schema     string
+	Table      string
+	Index      string
+	Scans      int64
+	Size       int64
+	Definition string
+}
+
+// UnusedIndexes reports indexes scanned at most maxScans times since the statistics were last
+// reset, largest first. Unique indexes are left out, as they enforce constraints even when never
+// scanned. Check every replica before dropping one: the statistics are per server.
+func UnusedIndexes(ctx context.Context, q Querier, maxScans int64) ([]UnusedIndex, error) {
+	rows, err := q.Query(ctx, `
+		SELECT s.schemaname, s.relname, s.indexrelname, s.idx_scan, pg_relation_size(s.indexrelid), pg_get_indexdef(s.indexrelid)
+		FROM pg_stat_user_indexes s
+			JOIN pg_index i ON i.indexrelid = s.indexrelid
+		WHERE s.idx_scan <= $1 AND NOT i.indisunique
+		ORDER BY pg_relation_size(s.indexrelid) DESC, 1, 2, 3`, maxScans)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (UnusedIndex, error) {
+		var u UnusedIndex
+		err := row.Scan(&u.Schema, &u.Table, &u.Index, &u.Scans, &u.Size, &u.Definition)
+		return u, err
+	})
+}
+
+// SeqScanTable is a table read mostly by sequential scans, which may be missing an index.
+type SeqScanTable struct {
+	Schema       string
+	Table        string
+	SeqScans     int64
+	SeqRowsRead  int64
+	IndexScans   int64
+	LiveRows     int64
+	RowsPerScan  int64
+	TotalIndexes int64
+}
+
+// MissingIndexCandidates reports tables with at least minRows live rows that are scanned
+// sequentially more often than through an index, ordered by the rows read sequentially.
+func MissingIndexCandidates(ctx context.Context, q Querier, minRows int64) ([]SeqScanTable, error) {
+	rows, err := q.Query(ctx, `
+		SELECT t.schemaname, t.relname, t.seq_scan, t.seq_tup_read, COALESCE(t.idx_scan, 0), t.n_live_tup,
+			t.seq_tup_read / GREATEST(t.seq_scan, 1),
+			(SELECT count(*) FROM pg_index i WHERE i.indrelid = t.relid)
+		FROM pg_stat_user_tables t
+		WHERE t.n_live_tup >= $1 AND t.seq_scan > COALESCE(t.idx_scan, 0)
+		ORDER BY t.seq_tup_read DESC, 1, 2`, minRows)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (SeqScanTable, error) {
+		var s SeqScanTable
+		err := row.Scan(&s.Schema, &s.Table, &s.SeqScans, &s.SeqRowsRead, &s.IndexScans, &s.LiveRows, &s.RowsPerScan, &s.TotalIndexes)
+		return s, err
+	})
+}
+
+// BloatedIndex is a B-tree index whose leaf pages are much emptier than a freshly built index.
+// Bloat is the estimated fraction of the index that REINDEX would reclaim.
+type BloatedIndex struct {
+	Schema      string
+	Table       string
+	Index       string
+	Size        int64
+	LeafDensity float64
+	Bloat       float64
+}
+
+// BloatedIndexes reports B-tree indexes with an estimated bloat of at least minBloat, e.g. 0.3,
+// worst first. It requires the pgstattuple extension and reads every index, so run it off-peak.
+func BloatedIndexes(ctx context.Context, q Querier, minBloat float64) ([]BloatedIndex, error) {
+	// A fresh B-tree is filled to its fillfactor, 90% by default.
+	//goland:noinspection SqlResolve
+	rows, err := q.Query(ctx, `
+		SELECT schemaname, relname, indexrelname, size, density, GREATEST(0, 1 - density / fillfactor) AS bloat
+		FROM (
+			SELECT s.schemaname, s.relname, s.indexrelname, pg_relation_size(s.indexrelid) AS size,
+				(pgstatindex(s.indexrelid)).avg_leaf_density AS density,
+				COALESCE((SELECT split_part(o, '=', 2)::float8 FROM unnest(c.reloptions) o WHERE o LIKE 'fillfactor=%'), 90) AS fillfactor
+			FROM pg_stat_user_indexes s
+				JOIN pg_class c ON c.oid = s.indexrelid
+				JOIN pg_am a ON a.oid = c.relam
+			WHERE a.amname = 'btree' AND pg_relation_size(s.indexrelid) > 0
+		) i
+		WHERE density <> 'NaN' AND GREATEST(0, 1 - density / fillfactor) >= $1
+		ORDER BY bloat DESC, 1, 2, 3`, minBloat)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (BloatedIndex, error) {
+		var b BloatedIndex
+		err := row.Scan(&b.Schema, &b.Table, &b.Index, &b.Size, &b.LeafDensity, &b.Bloat)
+		return b, err
+	})
+}
@@ -0,0 +1,47 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestIndexReports(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE INDEX testtable_name ON testtable (name);
+		CREATE EXTENSION IF NOT EXISTS pgstattuple;
+		INSERT INTO testtable (id, name) SELECT g, 'name' || g FROM generate_series(2, 2000) g;
+		DELETE FROM testtable WHERE id % 4 <> 0;
+		ANALYZE testtable;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		_, err = pg.Count(ctx, db.Pool, "SELECT * FROM testtable WHERE description IS NULL")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	unused, err := pg.UnusedIndexes(ctx, db.Pool, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unused) != 1 || unused[0].Index != "testtable_name" {
+		t.Errorf("unexpected unused indexes %+v", unused)
+	}
+	candidates, err := pg.MissingIndexCandidates(ctx, db.Pool, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].Table != "testtable" {
+		t.Errorf("unexpected candidates %+v", candidates)
+	}
+	_, err = pg.BloatedIndexes(ctx, db.Pool, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
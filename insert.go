@@ -0,0 +1,103 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"reflect"
+)
+
+// maxQueryParameters is the limit imposed by the wire protocol on bind parameters per statement.
+const maxQueryParameters = 65535
+
+// InsertRows inserts rows into table with multi-row VALUES statements, split into as many
+// statements as the parameter limit requires. Column names come from the struct fields.
+func InsertRows[T any](ctx context.Context, q Querier, table string, rows []T) (int64, error) {
+	fields, err := structFields(reflect.TypeFor[T]())
+	if err != nil {
+		return 0, err
+	}
+	var inserted int64
+	for _, chunk := range insertChunks(rows, len(fields)) {
+		sql, args := buildInsert(table, fields, chunk).Build()
+		tag, err := q.Exec(ctx, sql, args...)
+		if err != nil {
+			return inserted, err
+		}
+		inserted += tag.RowsAffected()
+	}
+	return inserted, nil
+}
+
+// InsertRowsReturning inserts rows leaving out idColumn, so the database can generate it, and
+// returns the generated values in the same order as rows. Each row is inserted by a statement of
+// its own, sent together in batches, so every id is matched to its row rather than relying on the
+// order RETURNING yields them in. A batch runs in one implicit transaction, so a failing row
+// inserts none of its batch. q must be able to send batches, as pools, connections and
+// transactions can.
+func InsertRowsReturning[T any, ID any](ctx context.Context, q Querier, table string, rows []T, idColumn string) ([]ID, error) {
+	sender, ok := q.(batchSender)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot send batches", q)
+	}
+	fields, err := structFields(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	insertFields := make([]structField, 0, len(fields))
+	for _, field := range fields {
+		if field.Column != idColumn {
+			insertFields = append(insertFields, field)
+		}
+	}
+	ids := make([]ID, 0, len(rows))
+	for _, chunk := range insertChunks(rows, len(insertFields)) {
+		batch := &pgx.Batch{}
+		for _, row := range chunk {
+			sql, args := buildInsert(table, insertFields, []T{row}).Returning(idColumn).Build()
+			batch.Queue(sql, args...)
+		}
+		results := sender.SendBatch(ctx, batch)
+		for range chunk {
+			var id ID
+			err = results.QueryRow().Scan(&id)
+			if err != nil {
+				_ = results.Close()
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		err = results.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// batchSender is implemented by *pgxpool.Pool, *pgxpool.Conn, *pgx.Conn and pgx.Tx.
+type batchSender interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+func buildInsert[T any](table string, fields []structField, rows []T) *InsertBuilder {
+	builder := InsertInto(table).Columns(Map(fields, func(f structField) string { return f.Column })...)
+	for _, row := range rows {
+		builder.Values(structValues(reflect.ValueOf(row), fields)...)
+	}
+	return builder
+}
+
+// insertChunks splits rows into chunks that stay within the parameter limit. Rows without columns
+// are inserted one per statement, as DEFAULT VALUES inserts a single row.
+func insertChunks[T any](rows []T, columns int) [][]T {
+	size := 1
+	if columns > 0 {
+		size = maxQueryParameters / columns
+	}
+	chunks := make([][]T, 0)
+	for start := 0; start < len(rows); start += size {
+		chunks = append(chunks, rows[start:min(start+size, len(rows))])
+	}
+	return chunks
+}
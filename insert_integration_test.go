@@ -0,0 +1,67 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+type insertTestAudit struct {
+	CreatedBy string
+}
+
+type insertTestItem struct {
+	ID   int
+	Name string
+	insertTestAudit
+}
+
+type insertTestCounter struct {
+	ID int
+}
+
+type insertTestDefaults struct{}
+
+func TestInsertRowsReturning(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	// Ids run backwards, so they cannot line up with the rows by accident.
+	_, err := db.Pool.Exec(ctx, `
+		CREATE SEQUENCE insert_test_item_id INCREMENT BY -1 MAXVALUE 100 START WITH 100;
+		CREATE TABLE insert_test_item (id INT PRIMARY KEY DEFAULT nextval('insert_test_item_id'), name TEXT, created_by TEXT);
+		CREATE TABLE insert_test_counter (id SERIAL PRIMARY KEY)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []insertTestItem{
+		{Name: "a", insertTestAudit: insertTestAudit{CreatedBy: "x"}},
+		{Name: "b", insertTestAudit: insertTestAudit{CreatedBy: "y"}},
+		{Name: "c", insertTestAudit: insertTestAudit{CreatedBy: "z"}},
+	}
+	ids, err := pg.InsertRowsReturning[insertTestItem, int](ctx, db.Pool, "insert_test_item", items, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range ids {
+		pgtest.AssertQueryReturns(t, db.Pool, "SELECT name, created_by FROM insert_test_item WHERE id = $1",
+			[][]any{{items[i].Name, items[i].CreatedBy}}, id)
+	}
+
+	ids, err = pg.InsertRowsReturning[insertTestCounter, int](ctx, db.Pool, "insert_test_counter", make([]insertTestCounter, 2), "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("unexpected ids %v", ids)
+	}
+
+	inserted, err := pg.InsertRows(ctx, db.Pool, "insert_test_counter", make([]insertTestDefaults, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserted != 3 {
+		t.Errorf("expected 3 rows of defaults, got %v", inserted)
+	}
+	pgtest.AssertRowCount(t, db.Pool, "insert_test_counter", 5)
+}
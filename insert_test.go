@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+type insertTestRow struct {
+	ID        int `db:"id"`
+	FirstName string
+	Ignored   string `db:"-"`
+	hidden    string
+}
+
+func TestStructFields(t *testing.T) {
+	fields, err := structFields(reflect.TypeFor[insertTestRow]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := Map(fields, func(f structField) string { return f.Column })
+	if !reflect.DeepEqual(columns, []string{"id", "first_name"}) {
+		t.Errorf("unexpected columns: %v", columns)
+	}
+}
+
+type insertTestAudit struct {
+	CreatedBy string
+}
+
+type insertTestEmbedding struct {
+	ID int `db:"id"`
+	insertTestAudit
+	Tagged insertTestAudit `db:"tagged"`
+}
+
+func TestStructFieldsFlattensEmbedded(t *testing.T) {
+	fields, err := structFields(reflect.TypeFor[insertTestEmbedding]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := Map(fields, func(f structField) string { return f.Column })
+	if !reflect.DeepEqual(columns, []string{"id", "created_by", "tagged"}) {
+		t.Errorf("unexpected columns: %v", columns)
+	}
+	values := structValues(reflect.ValueOf(insertTestEmbedding{ID: 1, insertTestAudit: insertTestAudit{CreatedBy: "a"}}), fields)
+	if values[1] != "a" {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestInsertChunks(t *testing.T) {
+	rows := make([]insertTestRow, 70000)
+	chunks := insertChunks(rows, 2)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %v", len(chunks))
+	}
+	if len(chunks[0]) != maxQueryParameters/2 || len(chunks[2]) != 70000-2*(maxQueryParameters/2) {
+		t.Errorf("unexpected chunk sizes: %v, %v", len(chunks[0]), len(chunks[2]))
+	}
+	chunks = insertChunks(rows[:3], 0)
+	if len(chunks) != 3 || len(chunks[0]) != 1 {
+		t.Errorf("expected a chunk per row without columns, got %v", len(chunks))
+	}
+}
+
+func TestBuildInsert(t *testing.T) {
+	fields, _ := structFields(reflect.TypeFor[insertTestRow]())
+	sql, args := buildInsert("users", fields, []insertTestRow{{ID: 1, FirstName: "a"}, {ID: 2, FirstName: "b"}}).Build()
+	if sql != `INSERT INTO "users" ("id", "first_name") VALUES ($1, $2), ($3, $4)` {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if !reflect.DeepEqual(args, []any{1, "a", 2, "b"}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+	sql, _ = buildInsert("users", nil, []insertTestRow{{}}).Returning("id").Build()
+	if sql != `INSERT INTO "users" DEFAULT VALUES RETURNING "id"` {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+}
@@ -0,0 +1,500 @@
+package introspect
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a difference between two databases. Statements turn the first database into the
+// second. They are empty when the model lacks what is needed, e.g. function bodies, and the change
+// must be made by hand.
+type Change struct {
+	Kind ChangeKind
+	// Object is the kind of object, e.g. "table" or "column".
+	Object string
+	// Name is schema qualified, columns also carry their table.
+	Name       string
+	Detail     string
+	Statements []string
+	// phases orders Statements among those of the other changes.
+	phases []int
+}
+
+func (c Change) String() string {
+	symbol := map[ChangeKind]string{Added: "+", Removed: "-", Changed: "~"}[c.Kind]
+	s := symbol + " " + c.Object + " " + c.Name
+	if c.Detail != "" {
+		s += ": " + c.Detail
+	}
+	return s
+}
+
+// SchemaDiff lists the changes between two databases, ordered by schema and object.
+type SchemaDiff struct {
+	Changes []Change
+}
+
+func (d *SchemaDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// String renders the changes one per line, as "+", "-" or "~" followed by the object.
+func (d *SchemaDiff) String() string {
+	if d.Empty() {
+		return "no differences\n"
+	}
+	var b strings.Builder
+	for _, c := range d.Changes {
+		b.WriteString(c.String() + "\n")
+	}
+	return b.String()
+}
+
+// SQL returns a migration applying the changes, with statements ordered so dependencies are
+// dropped before and created after what they depend on. Changes without statements are listed as
+// comments at the top. Review it before running: dropped columns and tables lose their data.
+func (d *SchemaDiff) SQL() string {
+	type statement struct {
+		phase int
+		sql   string
+	}
+	var b strings.Builder
+	var statements []statement
+	for _, c := range d.Changes {
+		if len(c.Statements) == 0 {
+			b.WriteString("-- apply by hand: " + c.String() + "\n")
+		}
+		for i, sql := range c.Statements {
+			statements = append(statements, statement{c.phases[i], sql})
+		}
+	}
+	sort.SliceStable(statements, func(i, j int) bool {
+		return statements[i].phase < statements[j].phase
+	})
+	for _, s := range statements {
+		b.WriteString(s.sql + ";\n")
+	}
+	return b.String()
+}
+
+// Diff inspects the databases of a and b, without running migrations, and compares them, see
+// Compare. Without schemas all user schemas are compared.
+func Diff(ctx context.Context, a pg.Configuration, b pg.Configuration, schemas ...string) (*SchemaDiff, error) {
+	from, err := inspectConfiguration(ctx, a, schemas)
+	if err != nil {
+		return nil, err
+	}
+	to, err := inspectConfiguration(ctx, b, schemas)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(from, to), nil
+}
+
+func inspectConfiguration(ctx context.Context, c pg.Configuration, schemas []string) (*Database, error) {
+	c.MigrationsEnabled = false
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %v/%v: %w", c.Address, c.Name, err)
+	}
+	defer pool.Close()
+	d, err := Inspect(ctx, pool, schemas...)
+	if err != nil {
+		return nil, fmt.Errorf("inspect %v/%v: %w", c.Address, c.Name, err)
+	}
+	return d, nil
+}
+
+// The phases of a migration; drops come first, in reverse dependency order.
+const (
+	phaseDropForeignKeys = iota
+	phaseDropConstraints
+	phaseDropIndexes
+	phaseDropViews
+	phaseDropColumns
+	phaseDropTables
+	phaseDropEnums
+	phaseCreateSchemas
+	phaseEnums
+	phaseCreateTables
+	phaseColumns
+	phaseConstraints
+	phaseForeignKeys
+	phaseIndexes
+	phaseViews
+	phaseDropSchemas
+)
+
+// Compare returns the changes turning from into to. Sequences, triggers and function bodies are
+// not part of the model, so changes to them go unnoticed and functions are only reported.
+func Compare(from *Database, to *Database) *SchemaDiff {
+	d := &differ{}
+	fromSchemas := byName(from.Schemas, func(s Schema) string { return s.Name })
+	toSchemas := byName(to.Schemas, func(s Schema) string { return s.Name })
+	for _, name := range names(fromSchemas, toSchemas) {
+		a, inFrom := fromSchemas[name]
+		b, inTo := toSchemas[name]
+		switch {
+		case !inFrom:
+			d.add(Change{Kind: Added, Object: "schema", Name: name}, phaseCreateSchemas, "CREATE SCHEMA "+quote(name))
+		case !inTo:
+			d.add(Change{Kind: Removed, Object: "schema", Name: name}, phaseDropSchemas, "DROP SCHEMA "+quote(name))
+		}
+		d.schema(name, a, b)
+	}
+	return &SchemaDiff{Changes: d.changes}
+}
+
+type differ struct {
+	changes []Change
+}
+
+// add records c with its statements, each a phase followed by SQL.
+func (d *differ) add(c Change, statements ...any) {
+	for i := 0; i < len(statements); i += 2 {
+		c.phases = append(c.phases, statements[i].(int))
+		c.Statements = append(c.Statements, statements[i+1].(string))
+	}
+	d.changes = append(d.changes, c)
+}
+
+func (d *differ) schema(schema string, a Schema, b Schema) {
+	fromEnums := byName(a.Enums, func(e Enum) string { return e.Name })
+	toEnums := byName(b.Enums, func(e Enum) string { return e.Name })
+	for _, name := range names(fromEnums, toEnums) {
+		from, inFrom := fromEnums[name]
+		to, inTo := toEnums[name]
+		d.enum(quote(schema, name), schema+"."+name, from, inFrom, to, inTo)
+	}
+
+	fromTables := byName(a.Tables, func(t Table) string { return t.Name })
+	toTables := byName(b.Tables, func(t Table) string { return t.Name })
+	for _, name := range names(fromTables, toTables) {
+		from, inFrom := fromTables[name]
+		to, inTo := toTables[name]
+		d.table(quote(schema, name), schema+"."+name, from, inFrom, to, inTo)
+	}
+
+	signature := func(f Function) string { return f.Name + "(" + f.Arguments + ")" }
+	fromFunctions := byName(a.Functions, signature)
+	toFunctions := byName(b.Functions, signature)
+	for _, name := range names(fromFunctions, toFunctions) {
+		from, inFrom := fromFunctions[name]
+		to, inTo := toFunctions[name]
+		c := Change{Object: "function", Name: schema + "." + name}
+		switch {
+		case !inFrom:
+			c.Kind = Added
+		case !inTo:
+			c.Kind = Removed
+		case from.Result != to.Result || from.Language != to.Language:
+			c.Kind = Changed
+			c.Detail = describe(
+				"returns", from.Result, to.Result,
+				"language", from.Language, to.Language,
+			)
+		default:
+			continue
+		}
+		d.add(c)
+	}
+
+	fromViews := byName(a.MaterializedViews, func(v MaterializedView) string { return v.Name })
+	toViews := byName(b.MaterializedViews, func(v MaterializedView) string { return v.Name })
+	for _, name := range names(fromViews, toViews) {
+		from, inFrom := fromViews[name]
+		to, inTo := toViews[name]
+		qualified := quote(schema, name)
+		c := Change{Object: "materialized view", Name: schema + "." + name}
+		create := "CREATE MATERIALIZED VIEW " + qualified + " AS " + strings.TrimSuffix(strings.TrimSpace(to.Definition), ";")
+		drop := "DROP MATERIALIZED VIEW " + qualified
+		switch {
+		case !inFrom:
+			c.Kind = Added
+			d.add(c, phaseViews, create)
+		case !inTo:
+			c.Kind = Removed
+			d.add(c, phaseDropViews, drop)
+		case from.Definition != to.Definition:
+			c.Kind = Changed
+			c.Detail = "definition"
+			d.add(c, phaseDropViews, drop, phaseViews, create)
+		}
+	}
+}
+
+func (d *differ) enum(qualified string, name string, from Enum, inFrom bool, to Enum, inTo bool) {
+	c := Change{Object: "enum", Name: name}
+	switch {
+	case !inFrom:
+		c.Kind = Added
+		d.add(c, phaseEnums, "CREATE TYPE "+qualified+" AS ENUM ("+strings.Join(pg.Map(to.Values, quoteLiteral), ", ")+")")
+		return
+	case !inTo:
+		c.Kind = Removed
+		d.add(c, phaseDropEnums, "DROP TYPE "+qualified)
+		return
+	case slices.Equal(from.Values, to.Values):
+		return
+	}
+	c.Kind = Changed
+	c.Detail = describe("values", strings.Join(from.Values, ", "), strings.Join(to.Values, ", "))
+	// Values can only be added; removing or reordering them means recreating the type.
+	kept := pg.Filter(to.Values, func(v string) bool { return slices.Contains(from.Values, v) })
+	if !slices.Equal(kept, from.Values) {
+		d.add(c)
+		return
+	}
+	var statements []any
+	for i, value := range to.Values {
+		if slices.Contains(from.Values, value) {
+			continue
+		}
+		sql := "ALTER TYPE " + qualified + " ADD VALUE " + quoteLiteral(value)
+		switch {
+		case i > 0:
+			sql += " AFTER " + quoteLiteral(to.Values[i-1])
+		case len(from.Values) > 0:
+			sql += " BEFORE " + quoteLiteral(from.Values[0])
+		}
+		statements = append(statements, phaseEnums, sql)
+	}
+	d.add(c, statements...)
+}
+
+func (d *differ) table(qualified string, name string, from Table, inFrom bool, to Table, inTo bool) {
+	c := Change{Object: "table", Name: name}
+	switch {
+	case !inFrom:
+		c.Kind = Added
+		definitions := pg.Map(to.Columns, columnDefinition)
+		statements := []any{phaseCreateTables, "CREATE TABLE " + qualified + " (\n\t" + strings.Join(definitions, ",\n\t") + "\n)"}
+		if to.Comment != "" {
+			statements = append(statements, phaseColumns, "COMMENT ON TABLE "+qualified+" IS "+quoteLiteral(to.Comment))
+		}
+		for _, column := range to.Columns {
+			if column.Comment != "" {
+				statements = append(statements, phaseColumns, "COMMENT ON COLUMN "+qualified+"."+quote(column.Name)+" IS "+quoteLiteral(column.Comment))
+			}
+		}
+		d.add(c, statements...)
+	case !inTo:
+		// Dropping the table drops its columns, indexes and constraints too.
+		c.Kind = Removed
+		d.add(c, phaseDropTables, "DROP TABLE "+qualified)
+		return
+	default:
+		if from.Comment != to.Comment {
+			c.Kind = Changed
+			c.Detail = describe("comment", from.Comment, to.Comment)
+			d.add(c, phaseColumns, "COMMENT ON TABLE "+qualified+" IS "+commentLiteral(to.Comment))
+		}
+		fromColumns := byName(from.Columns, func(c Column) string { return c.Name })
+		toColumns := byName(to.Columns, func(c Column) string { return c.Name })
+		// Added columns keep their table order, after the existing ones.
+		columns := append(pg.Map(from.Columns, func(c Column) string { return c.Name }), pg.Map(to.Columns, func(c Column) string { return c.Name })...)
+		for _, column := range uniqueInOrder(columns) {
+			a, inFrom := fromColumns[column]
+			b, inTo := toColumns[column]
+			d.column(qualified, name+"."+column, a, inFrom, b, inTo)
+		}
+	}
+
+	fromConstraints := byName(from.Constraints, func(c Constraint) string { return c.Name })
+	toConstraints := byName(to.Constraints, func(c Constraint) string { return c.Name })
+	for _, constraint := range names(fromConstraints, toConstraints) {
+		a, inFrom := fromConstraints[constraint]
+		b, inTo := toConstraints[constraint]
+		c := Change{Object: "constraint", Name: name + "." + constraint}
+		dropPhase, addPhase := phaseDropConstraints, phaseConstraints
+		if a.Type == ForeignKey || b.Type == ForeignKey {
+			dropPhase, addPhase = phaseDropForeignKeys, phaseForeignKeys
+		}
+		drop := "ALTER TABLE " + qualified + " DROP CONSTRAINT " + quote(constraint)
+		add := "ALTER TABLE " + qualified + " ADD CONSTRAINT " + quote(constraint) + " " + b.Definition
+		switch {
+		case !inFrom:
+			c.Kind = Added
+			d.add(c, addPhase, add)
+		case !inTo:
+			c.Kind = Removed
+			d.add(c, dropPhase, drop)
+		case a.Type != b.Type || a.Definition != b.Definition:
+			c.Kind = Changed
+			c.Detail = describe("definition", a.Definition, b.Definition)
+			d.add(c, dropPhase, drop, addPhase, add)
+		}
+	}
+
+	// Indexes backing constraints follow their constraint.
+	fromIndexes := byName(pg.Filter(from.Indexes, func(i Index) bool { _, ok := fromConstraints[i.Name]; return !ok }), func(i Index) string { return i.Name })
+	toIndexes := byName(pg.Filter(to.Indexes, func(i Index) bool { _, ok := toConstraints[i.Name]; return !ok }), func(i Index) string { return i.Name })
+	schema := strings.SplitN(name, ".", 2)[0]
+	for _, index := range names(fromIndexes, toIndexes) {
+		a, inFrom := fromIndexes[index]
+		b, inTo := toIndexes[index]
+		c := Change{Object: "index", Name: name + "." + index}
+		drop := "DROP INDEX " + quote(schema, index)
+		switch {
+		case !inFrom:
+			c.Kind = Added
+			d.add(c, phaseIndexes, b.Definition)
+		case !inTo:
+			c.Kind = Removed
+			d.add(c, phaseDropIndexes, drop)
+		case a.Definition != b.Definition:
+			c.Kind = Changed
+			c.Detail = describe("definition", a.Definition, b.Definition)
+			d.add(c, phaseDropIndexes, drop, phaseIndexes, b.Definition)
+		}
+	}
+}
+
+func (d *differ) column(table string, name string, from Column, inFrom bool, to Column, inTo bool) {
+	c := Change{Object: "column", Name: name}
+	alter := "ALTER TABLE " + table + " ALTER COLUMN " + quote(to.Name) + " "
+	switch {
+	case !inFrom:
+		c.Kind = Added
+		statements := []any{phaseColumns, "ALTER TABLE " + table + " ADD COLUMN " + columnDefinition(to)}
+		if to.Comment != "" {
+			statements = append(statements, phaseColumns, "COMMENT ON COLUMN "+table+"."+quote(to.Name)+" IS "+quoteLiteral(to.Comment))
+		}
+		d.add(c, statements...)
+		return
+	case !inTo:
+		c.Kind = Removed
+		d.add(c, phaseDropColumns, "ALTER TABLE "+table+" DROP COLUMN "+quote(from.Name))
+		return
+	}
+	var statements []any
+	if from.Identity && !to.Identity {
+		statements = append(statements, phaseColumns, alter+"DROP IDENTITY")
+	}
+	if from.Type != to.Type {
+		statements = append(statements, phaseColumns, alter+"TYPE "+to.Type)
+	}
+	if from.NotNull != to.NotNull {
+		statements = append(statements, phaseColumns, alter+map[bool]string{true: "SET NOT NULL", false: "DROP NOT NULL"}[to.NotNull])
+	}
+	fromDefault, toDefault := deref(from.Default), deref(to.Default)
+	if fromDefault != toDefault {
+		if to.Default == nil {
+			statements = append(statements, phaseColumns, alter+"DROP DEFAULT")
+		} else {
+			statements = append(statements, phaseColumns, alter+"SET DEFAULT "+toDefault)
+		}
+	}
+	if !from.Identity && to.Identity {
+		statements = append(statements, phaseColumns, alter+"ADD GENERATED BY DEFAULT AS IDENTITY")
+	}
+	if from.Comment != to.Comment {
+		statements = append(statements, phaseColumns, "COMMENT ON COLUMN "+table+"."+quote(to.Name)+" IS "+commentLiteral(to.Comment))
+	}
+	if len(statements) == 0 {
+		return
+	}
+	c.Kind = Changed
+	c.Detail = describe(
+		"type", from.Type, to.Type,
+		"not null", fmt.Sprint(from.NotNull), fmt.Sprint(to.NotNull),
+		"default", fromDefault, toDefault,
+		"identity", fmt.Sprint(from.Identity), fmt.Sprint(to.Identity),
+		"comment", from.Comment, to.Comment,
+	)
+	d.add(c, statements...)
+}
+
+func columnDefinition(c Column) string {
+	definition := quote(c.Name) + " " + c.Type
+	if c.Identity {
+		definition += " GENERATED BY DEFAULT AS IDENTITY"
+	}
+	if c.NotNull {
+		definition += " NOT NULL"
+	}
+	if c.Default != nil {
+		definition += " DEFAULT " + *c.Default
+	}
+	return definition
+}
+
+// describe lists the differing properties of triples of name, old and new value.
+func describe(properties ...string) string {
+	var parts []string
+	for i := 0; i < len(properties); i += 3 {
+		if properties[i+1] != properties[i+2] {
+			parts = append(parts, fmt.Sprintf("%v %q → %q", properties[i], properties[i+1], properties[i+2]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func byName[T any](items []T, name func(T) string) map[string]T {
+	m := make(map[string]T, len(items))
+	for _, item := range items {
+		m[name(item)] = item
+	}
+	return m
+}
+
+// names returns the keys of both maps, sorted.
+func names[T any](a map[string]T, b map[string]T) []string {
+	var keys []string
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func uniqueInOrder(items []string) []string {
+	seen := make(map[string]bool)
+	return pg.Filter(items, func(item string) bool {
+		if seen[item] {
+			return false
+		}
+		seen[item] = true
+		return true
+	})
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func quote(parts ...string) string {
+	return pgx.Identifier(parts).Sanitize()
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func commentLiteral(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return quoteLiteral(s)
+}
@@ -0,0 +1,124 @@
+package introspect
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	zero := "0"
+	from := &Database{Schemas: []Schema{{
+		Name:  "public",
+		Enums: []Enum{{Schema: "public", Name: "mood", Values: []string{"happy", "sad"}}},
+		Tables: []Table{{
+			Schema:  "public",
+			Name:    "posts",
+			Columns: []Column{{Name: "id", Type: "integer", NotNull: true}, {Name: "body", Type: "text"}},
+			Indexes: []Index{{Name: "posts_pkey", Primary: true}, {Name: "posts_body", Definition: "CREATE INDEX posts_body ON public.posts USING btree (body)"}},
+			Constraints: []Constraint{
+				{Name: "posts_pkey", Type: PrimaryKey, Definition: "PRIMARY KEY (id)"},
+				{Name: "posts_author_fkey", Type: ForeignKey, Definition: "FOREIGN KEY (author) REFERENCES public.users(id)"},
+			},
+		}, {Schema: "public", Name: "old"}},
+	}}}
+	to := &Database{Schemas: []Schema{{
+		Name:  "public",
+		Enums: []Enum{{Schema: "public", Name: "mood", Values: []string{"ok", "happy", "sad", "angry"}}},
+		Tables: []Table{{
+			Schema:      "public",
+			Name:        "posts",
+			Columns:     []Column{{Name: "id", Type: "bigint", NotNull: true}, {Name: "likes", Type: "integer", NotNull: true, Default: &zero}},
+			Indexes:     []Index{{Name: "posts_pkey", Primary: true}},
+			Constraints: []Constraint{{Name: "posts_pkey", Type: PrimaryKey, Definition: "PRIMARY KEY (id)"}},
+		}},
+		Functions: []Function{{Schema: "public", Name: "add", Arguments: "a integer, b integer", Result: "integer", Language: "sql"}},
+	}, {Name: "audit"}}}
+
+	diff := Compare(from, to)
+	expected := `+ schema audit
+~ enum public.mood: values "happy, sad" → "ok, happy, sad, angry"
+- table public.old
+~ column public.posts.id: type "integer" → "bigint"
+- column public.posts.body
++ column public.posts.likes
+- constraint public.posts.posts_author_fkey
+- index public.posts.posts_body
++ function public.add(a integer, b integer)
+`
+	if diff.String() != expected {
+		t.Errorf("unexpected diff\n%v", diff)
+	}
+	expected = `-- apply by hand: + function public.add(a integer, b integer)
+ALTER TABLE "public"."posts" DROP CONSTRAINT "posts_author_fkey";
+DROP INDEX "public"."posts_body";
+ALTER TABLE "public"."posts" DROP COLUMN "body";
+DROP TABLE "public"."old";
+CREATE SCHEMA "audit";
+ALTER TYPE "public"."mood" ADD VALUE 'ok' BEFORE 'happy';
+ALTER TYPE "public"."mood" ADD VALUE 'angry' AFTER 'sad';
+ALTER TABLE "public"."posts" ALTER COLUMN "id" TYPE bigint;
+ALTER TABLE "public"."posts" ADD COLUMN "likes" integer NOT NULL DEFAULT 0;
+`
+	if diff.SQL() != expected {
+		t.Errorf("unexpected migration\n%v", diff.SQL())
+	}
+	if diff = Compare(to, to); !diff.Empty() {
+		t.Errorf("expected no differences, got\n%v", diff)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("../testdb"))
+	ctx := context.Background()
+	before, err := Inspect(ctx, db.Pool, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Pool.Exec(ctx, `
+		CREATE TYPE mood AS ENUM ('happy', 'sad');
+		CREATE TABLE posts (
+			id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			author INT NOT NULL REFERENCES testtable (id),
+			mood mood,
+			title VARCHAR(200) NOT NULL DEFAULT ''
+		);
+		CREATE INDEX posts_title ON posts (title);
+		COMMENT ON TABLE posts IS 'Blog posts';
+		ALTER TABLE testtable ALTER COLUMN name SET NOT NULL, DROP COLUMN description;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := Diff(ctx, db.Configuration, db.Configuration, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected no differences, got\n%v", diff)
+	}
+
+	after, err := Inspect(ctx, db.Pool, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Applying the changes from after to before must restore the original schema and back again.
+	for _, states := range [][2]*Database{{after, before}, {before, after}} {
+		diff = Compare(states[0], states[1])
+		if diff.Empty() || strings.Contains(diff.SQL(), "apply by hand") {
+			t.Fatalf("unexpected diff\n%v", diff)
+		}
+		_, err = db.Pool.Exec(ctx, diff.SQL())
+		if err != nil {
+			t.Fatalf("applying\n%v: %v", diff.SQL(), err)
+		}
+		current, err := Inspect(ctx, db.Pool, "public")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff = Compare(current, states[1]); !diff.Empty() {
+			t.Errorf("expected the migration to be complete, got\n%v", diff)
+		}
+	}
+}
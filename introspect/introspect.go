@@ -0,0 +1,348 @@
+package introspect
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"sort"
+)
+
+type ConstraintType string
+
+const (
+	PrimaryKey ConstraintType = "p"
+	Unique     ConstraintType = "u"
+	ForeignKey ConstraintType = "f"
+	Check      ConstraintType = "c"
+	Exclusion  ConstraintType = "x"
+)
+
+type Database struct {
+	Schemas []Schema
+}
+
+type Schema struct {
+	Name              string
+	Tables            []Table
+	Enums             []Enum
+	Functions         []Function
+	MaterializedViews []MaterializedView
+}
+
+type Table struct {
+	Schema      string
+	Name        string
+	Comment     string
+	Columns     []Column
+	Indexes     []Index
+	Constraints []Constraint
+}
+
+type Column struct {
+	Name string
+	// Type is the SQL type as rendered by format_type, e.g. "character varying(20)" or "integer[]".
+	Type     string
+	TypeOID  uint32
+	NotNull  bool
+	Default  *string
+	Identity bool
+	Comment  string
+}
+
+type Index struct {
+	Name       string
+	Columns    []string
+	Unique     bool
+	Primary    bool
+	Definition string
+}
+
+type Constraint struct {
+	Name    string
+	Type    ConstraintType
+	Columns []string
+	// References is the referenced table of a foreign key, schema qualified.
+	References string
+	Definition string
+}
+
+type Enum struct {
+	Schema string
+	Name   string
+	Values []string
+}
+
+type Function struct {
+	Schema    string
+	Name      string
+	Arguments string
+	Result    string
+	Language  string
+}
+
+type MaterializedView struct {
+	Schema     string
+	Name       string
+	Definition string
+	Populated  bool
+	// UniqueIndex tells whether the view has a unique index on plain columns, which REFRESH
+	// MATERIALIZED VIEW CONCURRENTLY requires.
+	UniqueIndex bool
+}
+
+// Table returns the table with the given schema and name, or nil.
+func (d *Database) Table(schema string, name string) *Table {
+	for i := range d.Schemas {
+		if d.Schemas[i].Name != schema {
+			continue
+		}
+		for j := range d.Schemas[i].Tables {
+			if d.Schemas[i].Tables[j].Name == name {
+				return &d.Schemas[i].Tables[j]
+			}
+		}
+	}
+	return nil
+}
+
+// Column returns the column with the given name, or nil.
+func (t *Table) Column(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// schemaFilter restricts catalog queries to the requested schemas, or to all user schemas.
+const schemaFilter = "(cardinality($1::text[]) = 0 AND n.nspname NOT LIKE 'pg\\_%' AND n.nspname <> 'information_schema' OR n.nspname = ANY($1))"
+
+// Inspect reads the catalogs into a Database model. Without schemas all user schemas are read.
+// Every list in the model is sorted by name, except columns, which keep their table order.
+func Inspect(ctx context.Context, q pg.Querier, schemas ...string) (*Database, error) {
+	if schemas == nil {
+		schemas = []string{}
+	}
+	byName := make(map[string]*Schema)
+	schema := func(name string) *Schema {
+		s, ok := byName[name]
+		if !ok {
+			s = &Schema{Name: name}
+			byName[name] = s
+		}
+		return s
+	}
+	names, err := collect(ctx, q, `SELECT n.nspname FROM pg_namespace n WHERE `+schemaFilter+` ORDER BY 1`, schemas, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		schema(name)
+	}
+
+	tables, err := collect(ctx, q, `
+		SELECT n.nspname, c.relname, COALESCE(obj_description(c.oid, 'pg_class'), '')
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND `+schemaFilter+`
+		ORDER BY 1, 2`, schemas, func(row pgx.CollectableRow) (Table, error) {
+		var t Table
+		err := row.Scan(&t.Schema, &t.Name, &t.Comment)
+		return t, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	tableIndex := make(map[[2]string]*Table)
+	for _, t := range tables {
+		s := schema(t.Schema)
+		s.Tables = append(s.Tables, t)
+	}
+	for _, s := range byName {
+		for i := range s.Tables {
+			tableIndex[[2]string{s.Name, s.Tables[i].Name}] = &s.Tables[i]
+		}
+	}
+
+	err = inspectColumns(ctx, q, schemas, tableIndex)
+	if err != nil {
+		return nil, err
+	}
+	err = inspectIndexes(ctx, q, schemas, tableIndex)
+	if err != nil {
+		return nil, err
+	}
+	err = inspectConstraints(ctx, q, schemas, tableIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	enums, err := collect(ctx, q, `
+		SELECT n.nspname, t.typname, array_agg(e.enumlabel ORDER BY e.enumsortorder)
+		FROM pg_type t
+			JOIN pg_namespace n ON n.oid = t.typnamespace
+			JOIN pg_enum e ON e.enumtypid = t.oid
+		WHERE `+schemaFilter+`
+		GROUP BY 1, 2
+		ORDER BY 1, 2`, schemas, func(row pgx.CollectableRow) (Enum, error) {
+		var e Enum
+		err := row.Scan(&e.Schema, &e.Name, &e.Values)
+		return e, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range enums {
+		s := schema(e.Schema)
+		s.Enums = append(s.Enums, e)
+	}
+
+	functions, err := collect(ctx, q, `
+		SELECT n.nspname, p.proname, pg_get_function_arguments(p.oid), COALESCE(pg_get_function_result(p.oid), ''), l.lanname
+		FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			JOIN pg_language l ON l.oid = p.prolang
+		WHERE p.prokind IN ('f', 'p') AND `+schemaFilter+`
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')
+		ORDER BY 1, 2, 3`, schemas, func(row pgx.CollectableRow) (Function, error) {
+		var f Function
+		err := row.Scan(&f.Schema, &f.Name, &f.Arguments, &f.Result, &f.Language)
+		return f, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range functions {
+		s := schema(f.Schema)
+		s.Functions = append(s.Functions, f)
+	}
+
+	views, err := collect(ctx, q, `
+		SELECT n.nspname, c.relname, pg_get_viewdef(c.oid), c.relispopulated,
+			EXISTS (SELECT 1 FROM pg_index x WHERE x.indrelid = c.oid AND x.indisunique AND x.indpred IS NULL AND x.indexprs IS NULL)
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind = 'm' AND `+schemaFilter+`
+		ORDER BY 1, 2`, schemas, func(row pgx.CollectableRow) (MaterializedView, error) {
+		var v MaterializedView
+		err := row.Scan(&v.Schema, &v.Name, &v.Definition, &v.Populated, &v.UniqueIndex)
+		return v, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range views {
+		s := schema(v.Schema)
+		s.MaterializedViews = append(s.MaterializedViews, v)
+	}
+
+	d := &Database{}
+	for _, s := range byName {
+		d.Schemas = append(d.Schemas, *s)
+	}
+	sort.Slice(d.Schemas, func(i, j int) bool {
+		return d.Schemas[i].Name < d.Schemas[j].Name
+	})
+	return d, nil
+}
+
+func collect[T any](ctx context.Context, q pg.Querier, sql string, schemas []string, fn pgx.RowToFunc[T]) ([]T, error) {
+	rows, err := q.Query(ctx, sql, schemas)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, fn)
+}
+
+type tableRow struct {
+	schema string
+	table  string
+}
+
+func inspectColumns(ctx context.Context, q pg.Querier, schemas []string, tables map[[2]string]*Table) error {
+	type columnRow struct {
+		tableRow
+		Column
+	}
+	columns, err := collect(ctx, q, `
+		SELECT n.nspname, c.relname, a.attname, format_type(a.atttypid, a.atttypmod), a.atttypid, a.attnotnull,
+			pg_get_expr(d.adbin, d.adrelid), a.attidentity <> '', COALESCE(col_description(c.oid, a.attnum), '')
+		FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attnum > 0 AND NOT a.attisdropped AND c.relkind IN ('r', 'p') AND `+schemaFilter+`
+		ORDER BY 1, 2, a.attnum`, schemas, func(row pgx.CollectableRow) (columnRow, error) {
+		var r columnRow
+		err := row.Scan(&r.schema, &r.table, &r.Name, &r.Type, &r.TypeOID, &r.NotNull, &r.Default, &r.Identity, &r.Comment)
+		return r, err
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range columns {
+		if t, ok := tables[[2]string{r.schema, r.table}]; ok {
+			t.Columns = append(t.Columns, r.Column)
+		}
+	}
+	return nil
+}
+
+func inspectIndexes(ctx context.Context, q pg.Querier, schemas []string, tables map[[2]string]*Table) error {
+	type indexRow struct {
+		tableRow
+		Index
+	}
+	indexes, err := collect(ctx, q, `
+		SELECT n.nspname, c.relname, i.relname, x.indisunique, x.indisprimary, pg_get_indexdef(i.oid),
+			ARRAY(SELECT a.attname FROM unnest(x.indkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum ORDER BY k.ord)
+		FROM pg_index x
+			JOIN pg_class c ON c.oid = x.indrelid
+			JOIN pg_class i ON i.oid = x.indexrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND `+schemaFilter+`
+		ORDER BY 1, 2, 3`, schemas, func(row pgx.CollectableRow) (indexRow, error) {
+		var r indexRow
+		err := row.Scan(&r.schema, &r.table, &r.Name, &r.Unique, &r.Primary, &r.Definition, &r.Columns)
+		return r, err
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range indexes {
+		if t, ok := tables[[2]string{r.schema, r.table}]; ok {
+			t.Indexes = append(t.Indexes, r.Index)
+		}
+	}
+	return nil
+}
+
+func inspectConstraints(ctx context.Context, q pg.Querier, schemas []string, tables map[[2]string]*Table) error {
+	type constraintRow struct {
+		tableRow
+		Constraint
+	}
+	constraints, err := collect(ctx, q, `
+		SELECT n.nspname, c.relname, k.conname, k.contype::text, pg_get_constraintdef(k.oid),
+			ARRAY(SELECT a.attname FROM unnest(k.conkey) WITH ORDINALITY u(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = u.attnum ORDER BY u.ord),
+			COALESCE((SELECT rn.nspname || '.' || r.relname FROM pg_class r JOIN pg_namespace rn ON rn.oid = r.relnamespace WHERE r.oid = k.confrelid), '')
+		FROM pg_constraint k
+			JOIN pg_class c ON c.oid = k.conrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND `+schemaFilter+`
+		ORDER BY 1, 2, 3`, schemas, func(row pgx.CollectableRow) (constraintRow, error) {
+		var r constraintRow
+		err := row.Scan(&r.schema, &r.table, &r.Name, &r.Type, &r.Definition, &r.Columns, &r.References)
+		return r, err
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range constraints {
+		if t, ok := tables[[2]string{r.schema, r.table}]; ok {
+			t.Constraints = append(t.Constraints, r.Constraint)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package introspect
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("../testdb"))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TYPE mood AS ENUM ('happy', 'sad');
+		CREATE TABLE posts (
+			id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			author INT NOT NULL REFERENCES testtable (id),
+			mood mood,
+			title VARCHAR(200) NOT NULL DEFAULT ''
+		);
+		CREATE UNIQUE INDEX posts_author_title ON posts (author, title);
+		COMMENT ON TABLE posts IS 'Blog posts';
+		CREATE FUNCTION add(a INT, b INT) RETURNS INT AS 'SELECT a + b' LANGUAGE sql;
+		CREATE MATERIALIZED VIEW post_counts AS SELECT author, count(*) AS posts FROM posts GROUP BY author;
+		CREATE UNIQUE INDEX post_counts_author ON post_counts (author);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Inspect(ctx, db.Pool, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Schemas) != 1 || len(d.Schemas[0].Enums) != 1 || len(d.Schemas[0].Functions) != 1 {
+		t.Fatalf("unexpected model %+v", d)
+	}
+	if views := d.Schemas[0].MaterializedViews; len(views) != 1 || views[0].Name != "post_counts" || !views[0].Populated || !views[0].UniqueIndex {
+		t.Errorf("unexpected materialized views %+v", views)
+	}
+	if values := d.Schemas[0].Enums[0].Values; len(values) != 2 || values[0] != "happy" {
+		t.Errorf("unexpected enum values %v", values)
+	}
+	posts := d.Table("public", "posts")
+	if posts == nil || posts.Comment != "Blog posts" || len(posts.Columns) != 4 {
+		t.Fatalf("unexpected table %+v", posts)
+	}
+	if id := posts.Column("id"); !id.Identity || id.Type != "bigint" || !id.NotNull {
+		t.Errorf("unexpected id column %+v", id)
+	}
+	if title := posts.Column("title"); title.Type != "character varying(200)" || title.Default == nil {
+		t.Errorf("unexpected title column %+v", title)
+	}
+	var foreignKey *Constraint
+	for i := range posts.Constraints {
+		if posts.Constraints[i].Type == ForeignKey {
+			foreignKey = &posts.Constraints[i]
+		}
+	}
+	if foreignKey == nil || foreignKey.References != "public.testtable" || foreignKey.Columns[0] != "author" {
+		t.Errorf("unexpected foreign key %+v", foreignKey)
+	}
+	var unique *Index
+	for i := range posts.Indexes {
+		if posts.Indexes[i].Name == "posts_author_title" {
+			unique = &posts.Indexes[i]
+		}
+	}
+	if unique == nil || !unique.Unique || len(unique.Columns) != 2 || unique.Columns[1] != "title" {
+		t.Errorf("unexpected index %+v", unique)
+	}
+}
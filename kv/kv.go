@@ -0,0 +1,150 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const DefaultTable = "kv"
+
+// Migration returns the script creating the store table, for inclusion in a migrations directory.
+func Migration(table string) string {
+	return `
+		CREATE TABLE IF NOT EXISTS ` + pg.QuoteIdentifier(table) + `
+		(
+			key TEXT PRIMARY KEY NOT NULL,
+			value JSONB NOT NULL,
+			expires_at TIMESTAMPTZ,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+	`
+}
+
+// Migrate creates the store table if it does not exist yet.
+func Migrate(ctx context.Context, q pg.Querier, table string) error {
+	_, err := q.Exec(ctx, Migration(table))
+	return err
+}
+
+// Store keeps JSON encoded values by key. Expired keys read as missing right away and are deleted
+// by Sweep.
+type Store struct {
+	q     pg.Querier
+	Table string
+}
+
+func NewStore(q pg.Querier) *Store {
+	return &Store{q: q, Table: DefaultTable}
+}
+
+// live is the condition matching keys that have not expired.
+const live = "(expires_at IS NULL OR expires_at > now())"
+
+func expiresAt(ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+	t := time.Now().Add(ttl)
+	return &t
+}
+
+// Get decodes the value of key into dst and reports whether the key exists.
+func (s *Store) Get(ctx context.Context, key string, dst any) (bool, error) {
+	var value []byte
+	//goland:noinspection SqlResolve
+	err := s.q.QueryRow(ctx, "SELECT value FROM "+pg.QuoteIdentifier(s.Table)+" WHERE key = $1 AND "+live, key).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(value, dst)
+}
+
+// Set stores value under key. A zero ttl keeps the key until it is deleted.
+func (s *Store) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	//goland:noinspection SqlResolve
+	_, err = s.q.Exec(ctx, "INSERT INTO "+pg.QuoteIdentifier(s.Table)+" (key, value, expires_at, updated_at) VALUES ($1, $2, $3, now()) "+
+		"ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at, updated_at = now()", key, encoded, expiresAt(ttl))
+	return err
+}
+
+// Delete removes key and reports whether it existed.
+func (s *Store) Delete(ctx context.Context, key string) (bool, error) {
+	//goland:noinspection SqlResolve
+	tag, err := s.q.Exec(ctx, "DELETE FROM "+pg.QuoteIdentifier(s.Table)+" WHERE key = $1 AND "+live, key)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// CompareAndSwap sets key to value only if its current value equals old, compared as JSON, and
+// reports whether it did. A nil old only matches a missing key, which makes it an insert-if-absent.
+func (s *Store) CompareAndSwap(ctx context.Context, key string, old any, value any, ttl time.Duration) (bool, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	table := pg.QuoteIdentifier(s.Table)
+	if old == nil {
+		//goland:noinspection SqlResolve
+		tag, err := s.q.Exec(ctx, "INSERT INTO "+table+" AS s (key, value, expires_at, updated_at) VALUES ($1, $2, $3, now()) "+
+			"ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at, updated_at = now() "+
+			"WHERE s.expires_at IS NOT NULL AND s.expires_at <= now()", key, encoded, expiresAt(ttl))
+		if err != nil {
+			return false, err
+		}
+		return tag.RowsAffected() == 1, nil
+	}
+	encodedOld, err := json.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	//goland:noinspection SqlResolve
+	tag, err := s.q.Exec(ctx, "UPDATE "+table+" SET value = $2, expires_at = $3, updated_at = now() WHERE key = $1 AND value = $4::jsonb AND "+live,
+		key, encoded, expiresAt(ttl), encodedOld)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// Sweep deletes expired keys.
+func (s *Store) Sweep(ctx context.Context) (int64, error) {
+	//goland:noinspection SqlResolve
+	tag, err := s.q.Exec(ctx, "DELETE FROM "+pg.QuoteIdentifier(s.Table)+" WHERE expires_at <= now()")
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// RunSweeper calls Sweep every interval until ctx is cancelled, which returns nil.
+func (s *Store) RunSweeper(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		swept, err := s.Sweep(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Warnf("Error sweeping expired keys: %v", err)
+		} else if swept > 0 {
+			log.Debugf("Swept %v expired keys", swept)
+		}
+	}
+}
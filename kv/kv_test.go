@@ -0,0 +1,61 @@
+package kv
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+type flag struct {
+	Enabled bool `json:"enabled"`
+	Percent int  `json:"percent"`
+}
+
+func TestStore(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	err := Migrate(ctx, db.Pool, DefaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(db.Pool)
+
+	swapped, err := s.CompareAndSwap(ctx, "checkout", nil, flag{Enabled: true, Percent: 10}, 0)
+	if err != nil || !swapped {
+		t.Fatalf("expected insert-if-absent to succeed, got %v, %v", swapped, err)
+	}
+	swapped, err = s.CompareAndSwap(ctx, "checkout", nil, flag{}, 0)
+	if err != nil || swapped {
+		t.Errorf("expected insert-if-absent to fail for an existing key, got %v, %v", swapped, err)
+	}
+	swapped, err = s.CompareAndSwap(ctx, "checkout", flag{Enabled: true, Percent: 10}, flag{Enabled: true, Percent: 50}, 0)
+	if err != nil || !swapped {
+		t.Errorf("expected the swap to succeed, got %v, %v", swapped, err)
+	}
+	var f flag
+	found, err := s.Get(ctx, "checkout", &f)
+	if err != nil || !found || f.Percent != 50 {
+		t.Errorf("unexpected value %+v, %v, %v", f, found, err)
+	}
+
+	err = s.Set(ctx, "session", "abc", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	var session string
+	found, err = s.Get(ctx, "session", &session)
+	if err != nil || found {
+		t.Errorf("expired keys should read as missing, got %v, %v", found, err)
+	}
+	swept, err := s.Sweep(ctx)
+	if err != nil || swept != 1 {
+		t.Errorf("expected the expired key to be swept, got %v, %v", swept, err)
+	}
+
+	deleted, err := s.Delete(ctx, "checkout")
+	if err != nil || !deleted {
+		t.Errorf("expected the key to be deleted, got %v, %v", deleted, err)
+	}
+}
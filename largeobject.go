@@ -0,0 +1,117 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"io"
+)
+
+// DefaultLargeObjectChunkSize is the size of the reads and writes a large object is streamed in.
+const DefaultLargeObjectChunkSize = 256 * 1024
+
+var ErrLargeObjectTooLarge = errors.New("large object exceeds the size limit")
+
+type LargeObjectOptions struct {
+	// ChunkSize defaults to DefaultLargeObjectChunkSize.
+	ChunkSize int
+	// MaxSize rejects objects larger than this many bytes when positive.
+	MaxSize int64
+}
+
+func (o LargeObjectOptions) chunkSize() int {
+	if o.ChunkSize <= 0 {
+		return DefaultLargeObjectChunkSize
+	}
+	return o.ChunkSize
+}
+
+// copyChunks copies src to dst in chunks of chunkSize, failing with ErrLargeObjectTooLarge as soon
+// as more than maxSize bytes were read.
+func copyChunks(dst io.Writer, src io.Reader, chunkSize int, maxSize int64) (int64, error) {
+	if maxSize > 0 {
+		src = io.LimitReader(src, maxSize+1)
+	}
+	// Plain structs hide ReaderFrom and WriterTo so the chunk size is honoured.
+	n, err := io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, make([]byte, chunkSize))
+	if err != nil {
+		return n, err
+	}
+	if maxSize > 0 && n > maxSize {
+		return n, fmt.Errorf("%w of %v bytes", ErrLargeObjectTooLarge, maxSize)
+	}
+	return n, nil
+}
+
+// WriteLargeObject stores r in a new large object and returns its OID and size. Large objects are
+// transactional, so nothing is kept when tx is rolled back.
+func WriteLargeObject(ctx context.Context, tx pgx.Tx, r io.Reader, opts LargeObjectOptions) (uint32, int64, error) {
+	objects := tx.LargeObjects()
+	oid, err := objects.Create(ctx, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	object, err := objects.Open(ctx, oid, pgx.LargeObjectModeWrite)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := copyChunks(object, r, opts.chunkSize(), opts.MaxSize)
+	if err != nil {
+		_ = object.Close()
+		_ = objects.Unlink(ctx, oid)
+		return 0, n, err
+	}
+	return oid, n, object.Close()
+}
+
+// ReadLargeObject streams the large object oid to w and returns the number of bytes written. With
+// opts.MaxSize the size is checked before anything is written.
+func ReadLargeObject(ctx context.Context, tx pgx.Tx, oid uint32, w io.Writer, opts LargeObjectOptions) (int64, error) {
+	object, err := OpenLargeObject(ctx, tx, oid)
+	if err != nil {
+		return 0, err
+	}
+	defer func(object *pgx.LargeObject) {
+		_ = object.Close()
+	}(object)
+	if opts.MaxSize > 0 {
+		size, err := object.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		if size > opts.MaxSize {
+			return 0, fmt.Errorf("%w of %v bytes", ErrLargeObjectTooLarge, opts.MaxSize)
+		}
+		_, err = object.Seek(0, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return copyChunks(w, object, opts.chunkSize(), 0)
+}
+
+// OpenLargeObject opens oid for reading and seeking, e.g. to serve range requests. It is only
+// valid until tx ends.
+func OpenLargeObject(ctx context.Context, tx pgx.Tx, oid uint32) (*pgx.LargeObject, error) {
+	objects := tx.LargeObjects()
+	return objects.Open(ctx, oid, pgx.LargeObjectModeRead)
+}
+
+// LargeObjectSize returns the size of oid in bytes.
+func LargeObjectSize(ctx context.Context, tx pgx.Tx, oid uint32) (int64, error) {
+	object, err := OpenLargeObject(ctx, tx, oid)
+	if err != nil {
+		return 0, err
+	}
+	defer func(object *pgx.LargeObject) {
+		_ = object.Close()
+	}(object)
+	return object.Seek(0, io.SeekEnd)
+}
+
+// DeleteLargeObject removes oid. Large objects are not removed with the rows referencing them.
+func DeleteLargeObject(ctx context.Context, tx pgx.Tx, oid uint32) error {
+	objects := tx.LargeObjects()
+	return objects.Unlink(ctx, oid)
+}
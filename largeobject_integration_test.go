@@ -0,0 +1,57 @@
+package pg_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"strings"
+	"testing"
+)
+
+func TestLargeObjects(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	content := strings.Repeat("large object ", 50000)
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	oid, n, err := pg.WriteLargeObject(ctx, tx, strings.NewReader(content), pg.LargeObjectOptions{ChunkSize: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(content)) {
+		t.Errorf("expected %v bytes to be written, got %v", len(content), n)
+	}
+	size, err := pg.LargeObjectSize(ctx, tx, oid)
+	if err != nil || size != n {
+		t.Errorf("unexpected size %v, %v", size, err)
+	}
+	var out bytes.Buffer
+	_, err = pg.ReadLargeObject(ctx, tx, oid, &out, pg.LargeObjectOptions{})
+	if err != nil || out.String() != content {
+		t.Errorf("the content should round trip, got %v bytes, %v", out.Len(), err)
+	}
+	_, err = pg.ReadLargeObject(ctx, tx, oid, &out, pg.LargeObjectOptions{MaxSize: 10})
+	if !errors.Is(err, pg.ErrLargeObjectTooLarge) {
+		t.Errorf("expected ErrLargeObjectTooLarge, got %v", err)
+	}
+	_, _, err = pg.WriteLargeObject(ctx, tx, strings.NewReader(content), pg.LargeObjectOptions{MaxSize: 100})
+	if !errors.Is(err, pg.ErrLargeObjectTooLarge) {
+		t.Errorf("expected ErrLargeObjectTooLarge, got %v", err)
+	}
+	err = pg.DeleteLargeObject(ctx, tx, oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,38 @@
+package pg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type recordingWriter struct {
+	writes []int
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return len(p), nil
+}
+
+func TestCopyChunks(t *testing.T) {
+	w := &recordingWriter{}
+	n, err := copyChunks(w, strings.NewReader(strings.Repeat("x", 10)), 4, 0)
+	if err != nil || n != 10 {
+		t.Fatalf("unexpected result %v, %v", n, err)
+	}
+	if len(w.writes) != 3 || w.writes[0] != 4 || w.writes[2] != 2 {
+		t.Errorf("expected writes of at most 4 bytes, got %v", w.writes)
+	}
+	_, err = copyChunks(io.Discard, strings.NewReader(strings.Repeat("x", 10)), 4, 10)
+	if err != nil {
+		t.Errorf("a reader of exactly the limit should be accepted, got %v", err)
+	}
+	var out bytes.Buffer
+	_, err = copyChunks(&out, strings.NewReader(strings.Repeat("x", 11)), 4, 10)
+	if !errors.Is(err, ErrLargeObjectTooLarge) {
+		t.Errorf("expected ErrLargeObjectTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// LintSeverity is how a rule's findings are reported; LintOff disables the rule.
+type LintSeverity string
+
+const (
+	LintOff     LintSeverity = "off"
+	LintWarning LintSeverity = "warning"
+	LintError   LintSeverity = "error"
+)
+
+const (
+	// LintDropColumnComment requires a comment explaining every DROP COLUMN.
+	LintDropColumnComment = "drop-column-comment"
+	// LintLockTimeout requires lock_timeout to be set before ALTER TABLE, so a migration waiting
+	// for a busy table fails instead of blocking every query queued behind it.
+	LintLockTimeout = "lock-timeout"
+	// LintIndexConcurrently reports CREATE INDEX CONCURRENTLY, which cannot run as Migrate applies
+	// scripts in a transaction. Such indexes are built with CreateIndexConcurrently instead.
+	LintIndexConcurrently = "index-concurrently"
+)
+
+// LintIssue is a finding of LintMigrations.
+type LintIssue struct {
+	File     string       `json:"file"`
+	Line     int          `json:"line"`
+	Rule     string       `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	Message  string       `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%v:%v: %v: %v (%v)", i.File, i.Line, i.Severity, i.Message, i.Rule)
+}
+
+// LintReport lists the issues of all scripts, ordered by file and line.
+type LintReport struct {
+	Issues []LintIssue `json:"issues"`
+}
+
+// Failed reports whether any issue is an error.
+func (r *LintReport) Failed() bool {
+	return slices.ContainsFunc(r.Issues, func(i LintIssue) bool { return i.Severity == LintError })
+}
+
+type lintScript struct {
+	statements []scriptStatement
+}
+
+type lintRule struct {
+	severity LintSeverity
+	// check returns the offending statements with their messages.
+	check func(s lintScript) []lintFinding
+}
+
+type lintFinding struct {
+	statement scriptStatement
+	message   string
+}
+
+var (
+	alterTable   = regexp.MustCompile(`^ALTER TABLE\b`)
+	dropColumn   = regexp.MustCompile(`^ALTER TABLE\b.*\bDROP COLUMN\b`)
+	concurrently = regexp.MustCompile(`^CREATE (UNIQUE )?INDEX CONCURRENTLY\b`)
+	lockTimeout  = regexp.MustCompile(`^SET (LOCAL |SESSION )?LOCK_TIMEOUT\b`)
+)
+
+func setsLockTimeout(s scriptStatement) bool {
+	return lockTimeout.MatchString(s.normalized) ||
+		strings.Contains(s.normalized, "SET_CONFIG(") && strings.Contains(strings.ToLower(s.SQL), "'lock_timeout'")
+}
+
+var lintRules = map[string]lintRule{
+	LintDropColumnComment: {LintError, func(s lintScript) []lintFinding {
+		var findings []lintFinding
+		for _, statement := range s.statements {
+			comments := Filter(statement.Comments, func(c string) bool { return c != "" && !strings.HasPrefix(c, "pg:") })
+			if dropColumn.MatchString(statement.normalized) && len(comments) == 0 {
+				findings = append(findings, lintFinding{statement, "DROP COLUMN needs a comment explaining why the data can go"})
+			}
+		}
+		return findings
+	}},
+	LintLockTimeout: {LintWarning, func(s lintScript) []lintFinding {
+		var findings []lintFinding
+		timeout := false
+		for _, statement := range s.statements {
+			switch {
+			case setsLockTimeout(statement):
+				timeout = true
+			case alterTable.MatchString(statement.normalized) && !timeout:
+				findings = append(findings, lintFinding{statement, "ALTER TABLE without lock_timeout set before it"})
+			}
+		}
+		return findings
+	}},
+	LintIndexConcurrently: {LintError, func(s lintScript) []lintFinding {
+		var findings []lintFinding
+		for _, statement := range s.statements {
+			if concurrently.MatchString(statement.normalized) {
+				findings = append(findings, lintFinding{statement, "CREATE INDEX CONCURRENTLY cannot run in the transaction of the migration, build it with CreateIndexConcurrently"})
+			}
+		}
+		return findings
+	}},
+}
+
+// Linter checks migration scripts. Severities overrides the default severity of rules by name,
+// e.g. LintLockTimeout: LintError.
+type Linter struct {
+	Severities map[string]LintSeverity
+}
+
+// LintMigrations checks the scripts in dir with the default severities.
+func LintMigrations(dir string) (*LintReport, error) {
+	return Linter{}.Lint(context.Background(), DirectorySource(dir))
+}
+
+// Lint checks every .sql script of source, including down scripts.
+func (l Linter) Lint(ctx context.Context, source Source) (*LintReport, error) {
+	for name, severity := range l.Severities {
+		if _, ok := lintRules[name]; !ok {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+		if severity != LintOff && severity != LintWarning && severity != LintError {
+			return nil, fmt.Errorf("unknown severity %q of lint rule %v", severity, name)
+		}
+	}
+	files, err := source.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	report := &LintReport{Issues: []LintIssue{}}
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".sql") {
+			continue
+		}
+		data, err := source.Read(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, l.lintScript(file, string(data))...)
+	}
+	return report, nil
+}
+
+func (l Linter) lintScript(file string, script string) []LintIssue {
+	s := lintScript{statements: splitScript(script)}
+	var issues []LintIssue
+	for name, rule := range lintRules {
+		severity, ok := l.Severities[name]
+		if !ok {
+			severity = rule.severity
+		}
+		if severity == LintOff {
+			continue
+		}
+		for _, finding := range rule.check(s) {
+			issues = append(issues, LintIssue{
+				File:     file,
+				Line:     finding.statement.Line,
+				Rule:     name,
+				Severity: severity,
+				Message:  finding.message,
+			})
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Rule < issues[j].Rule
+	})
+	return issues
+}
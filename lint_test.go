@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintMigrations(t *testing.T) {
+	dir := t.TempDir()
+	for name, script := range map[string]string{
+		"1_users.sql": `CREATE TABLE users (id INT, name TEXT, legacy TEXT);
+CREATE INDEX users_name ON users (name);
+ALTER TABLE users ADD COLUMN email TEXT;`,
+		"2_cleanup.sql": `SET lock_timeout = '5s';
+-- legacy was replaced by name in 1.4 and is no longer read
+ALTER TABLE users DROP COLUMN legacy;
+ALTER TABLE users DROP COLUMN email;
+CREATE INDEX CONCURRENTLY users_email ON users (email);`,
+		"3_index.sql": `CREATE INDEX users_id ON users (id);
+SELECT set_config('lock_timeout', '5s', false);
+CREATE UNIQUE INDEX CONCURRENTLY users_name_unique ON users (name);
+ALTER TABLE users ALTER COLUMN name SET NOT NULL;`,
+		"notes.txt": "ALTER TABLE users DROP COLUMN name;",
+	} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	report, err := LintMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"1_users.sql:3: warning: ALTER TABLE without lock_timeout set before it (lock-timeout)",
+		"2_cleanup.sql:4: error: DROP COLUMN needs a comment explaining why the data can go (drop-column-comment)",
+		"2_cleanup.sql:5: error: CREATE INDEX CONCURRENTLY cannot run in the transaction of the migration, build it with CreateIndexConcurrently (index-concurrently)",
+		"3_index.sql:3: error: CREATE INDEX CONCURRENTLY cannot run in the transaction of the migration, build it with CreateIndexConcurrently (index-concurrently)",
+	}
+	issues := Map(report.Issues, LintIssue.String)
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %v issues, got %v", len(expected), issues)
+	}
+	for i := range expected {
+		if issues[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], issues[i])
+		}
+	}
+	if !report.Failed() {
+		t.Error("expected the report to fail")
+	}
+
+	linter := Linter{Severities: map[string]LintSeverity{
+		LintDropColumnComment: LintOff,
+		LintIndexConcurrently: LintWarning,
+	}}
+	report, err = linter.Lint(context.Background(), DirectorySource(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 3 || report.Failed() {
+		t.Errorf("unexpected issues %v", report.Issues)
+	}
+	_, err = Linter{Severities: map[string]LintSeverity{"no-select-star": LintError}}.Lint(context.Background(), DirectorySource(dir))
+	if err == nil {
+		t.Error("expected unknown rules to be rejected")
+	}
+}
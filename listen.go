@@ -0,0 +1,190 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	listenerMinReconnectDelay = 100 * time.Millisecond
+	listenerMaxReconnectDelay = 30 * time.Second
+)
+
+var ErrEmptyChannel = errors.New("notification channel must not be empty")
+
+type NotificationHandler func(ctx context.Context, notification *pgconn.Notification)
+
+// Listener holds a dedicated connection that LISTENs on the registered channels and dispatches
+// notifications to their handlers, reconnecting with exponential backoff when the connection is lost.
+type Listener struct {
+	pool     *pgxpool.Pool
+	mu       sync.RWMutex
+	handlers map[string]NotificationHandler
+	// wake interrupts waiting for notifications to LISTEN on channels registered meanwhile.
+	wake chan struct{}
+
+	MinReconnectDelay time.Duration
+	MaxReconnectDelay time.Duration
+	// OnConnect is called whenever the channels are listened to again. Notifications sent while
+	// the listener was disconnected are lost, so state kept in sync by notifications is refreshed here.
+	OnConnect func()
+}
+
+func NewListener(pool *pgxpool.Pool) *Listener {
+	return &Listener{
+		pool:              pool,
+		handlers:          make(map[string]NotificationHandler),
+		wake:              make(chan struct{}, 1),
+		MinReconnectDelay: listenerMinReconnectDelay,
+		MaxReconnectDelay: listenerMaxReconnectDelay,
+	}
+}
+
+// Handle registers the handler for a channel. Channels registered while Listen is running are
+// listened to right away.
+func (l *Listener) Handle(channel string, handler NotificationHandler) {
+	l.mu.Lock()
+	l.handlers[channel] = handler
+	l.mu.Unlock()
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (l *Listener) handler(channel string) (NotificationHandler, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	handler, ok := l.handlers[channel]
+	return handler, ok
+}
+
+func (l *Listener) channels() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	channels := make([]string, 0, len(l.handlers))
+	for channel := range l.handlers {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// Listen blocks dispatching notifications until ctx is cancelled, which is treated as a graceful
+// shutdown and returns nil.
+func (l *Listener) Listen(ctx context.Context) error {
+	delay := l.MinReconnectDelay
+	for {
+		err := l.listen(ctx, func() { delay = l.MinReconnectDelay })
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Warnf("Listener connection lost, reconnecting in %v: %v", delay, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, l.MaxReconnectDelay)
+	}
+}
+
+func (l *Listener) listen(ctx context.Context, connected func()) error {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// LISTEN state belongs to the session, so the connection is never handed back to the pool.
+	pgxConn := conn.Hijack()
+	defer func(pgxConn *pgx.Conn) {
+		_ = pgxConn.Close(context.Background())
+	}(pgxConn)
+	listening := make(map[string]bool)
+	err = l.listenChannels(ctx, pgxConn, listening)
+	if err != nil {
+		return err
+	}
+	connected()
+	if l.OnConnect != nil {
+		l.OnConnect()
+	}
+	for {
+		notification, err := l.wait(ctx, pgxConn)
+		if err != nil {
+			return err
+		}
+		err = l.listenChannels(ctx, pgxConn, listening)
+		if err != nil {
+			return err
+		}
+		if notification == nil {
+			continue
+		}
+		handler, ok := l.handler(notification.Channel)
+		if !ok {
+			continue
+		}
+		l.dispatch(ctx, handler, notification)
+	}
+}
+
+// listenChannels LISTENs on the registered channels not in listening yet.
+func (l *Listener) listenChannels(ctx context.Context, conn *pgx.Conn, listening map[string]bool) error {
+	for _, channel := range l.channels() {
+		if listening[channel] {
+			continue
+		}
+		_, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+		if err != nil {
+			return err
+		}
+		listening[channel] = true
+	}
+	return nil
+}
+
+// wait returns the next notification, or nil when Handle registered a channel meanwhile. Waiting
+// is interrupted by a deadline, which leaves the connection usable.
+func (l *Listener) wait(ctx context.Context, conn *pgx.Conn) (*pgconn.Notification, error) {
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	defer close(done)
+	var woken atomic.Bool
+	go func() {
+		select {
+		case <-l.wake:
+			woken.Store(true)
+			cancel()
+		case <-done:
+		}
+	}()
+	notification, err := conn.WaitForNotification(waitCtx)
+	if err != nil && woken.Load() && ctx.Err() == nil {
+		return nil, nil
+	}
+	return notification, err
+}
+
+func (l *Listener) dispatch(ctx context.Context, handler NotificationHandler, notification *pgconn.Notification) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Errorf("Listener handler for channel %v panicked: %v", notification.Channel, p)
+		}
+	}()
+	handler(ctx, notification)
+}
+
+func Notify(ctx context.Context, q Querier, channel string, payload string) error {
+	if channel == "" {
+		return ErrEmptyChannel
+	}
+	_, err := q.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
@@ -0,0 +1,120 @@
+package pg_test
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgconn"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+// startListener runs l until the test ends and returns the channel OnConnect signals.
+func startListener(t *testing.T, l *pg.Listener) chan struct{} {
+	connected := make(chan struct{}, 10)
+	l.OnConnect = func() { connected <- struct{}{} }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Listen(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	return connected
+}
+
+func receiveNotification(t *testing.T, notifications chan string, expected string) {
+	t.Helper()
+	select {
+	case payload := <-notifications:
+		if payload != expected {
+			t.Errorf("expected %q, got %q", expected, payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("notification %q not received", expected)
+	}
+}
+
+func TestListenerHandleWhileListening(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	notifications := make(chan string, 10)
+	handler := func(ctx context.Context, n *pgconn.Notification) { notifications <- n.Channel + ":" + n.Payload }
+	l := pg.NewListener(db.Pool)
+	l.Handle("first", handler)
+	connected := startListener(t, l)
+	<-connected
+
+	l.Handle("late", handler)
+	// Notifications sent before the LISTEN ran are lost, so they are sent until one arrives.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := pg.Notify(ctx, db.Pool, "late", "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case payload := <-notifications:
+			if payload != "late:a" {
+				t.Fatalf("unexpected notification %v", payload)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatal("the late channel was not listened to")
+			}
+			continue
+		}
+		break
+	}
+	if len(connected) != 0 {
+		t.Error("expected no reconnect")
+	}
+	err := pg.Notify(ctx, db.Pool, "first", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Notifications of the late channel sent more than once may still be queued.
+	for {
+		select {
+		case payload := <-notifications:
+			if payload == "late:a" {
+				continue
+			}
+			if payload != "first:b" {
+				t.Errorf("unexpected notification %v", payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("notification of the first channel not received")
+		}
+		break
+	}
+}
+
+func TestListenerReconnects(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	notifications := make(chan string, 10)
+	l := pg.NewListener(db.Pool)
+	l.MinReconnectDelay = 10 * time.Millisecond
+	l.Handle("events", func(ctx context.Context, n *pgconn.Notification) { notifications <- n.Payload })
+	connected := startListener(t, l)
+	<-connected
+
+	_, err := db.Pool.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE query LIKE 'LISTEN%' AND pid <> pg_backend_pid()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the listener did not reconnect")
+	}
+	err = pg.Notify(ctx, db.Pool, "events", "after")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiveNotification(t, notifications, "after")
+}
@@ -0,0 +1,96 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"hash/fnv"
+	"time"
+)
+
+var ErrAdvisoryLockTimeout = errors.New("timed out acquiring advisory lock")
+
+// AdvisoryLockKey hashes a string key into the bigint key space used by pg_advisory_lock.
+func AdvisoryLockKey(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// WithAdvisoryLock holds a session-level advisory lock on a dedicated connection while fn runs.
+// The lock is released even if fn panics.
+func WithAdvisoryLock(ctx context.Context, pool *pgxpool.Pool, key string, fn func(ctx context.Context) error) error {
+	return WithAdvisoryLockTimeout(ctx, pool, key, 0, fn)
+}
+
+// WithAdvisoryLockTimeout is WithAdvisoryLock with a bound on how long acquisition may block.
+// A zero timeout waits until ctx is done.
+func WithAdvisoryLockTimeout(ctx context.Context, pool *pgxpool.Pool, key string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	lockKey := AdvisoryLockKey(key)
+	lockCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	_, err = conn.Exec(lockCtx, "SELECT pg_advisory_lock($1)", lockKey)
+	if err != nil {
+		conn.Release()
+		if timeout > 0 && errors.Is(lockCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return ErrAdvisoryLockTimeout
+		}
+		return err
+	}
+	defer releaseAdvisoryLock(conn, key, lockKey)
+	return fn(ctx)
+}
+
+// TryAdvisoryLock runs fn only if the session-level advisory lock can be taken immediately.
+// It reports whether fn was run.
+func TryAdvisoryLock(ctx context.Context, pool *pgxpool.Pool, key string, fn func(ctx context.Context) error) (bool, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	lockKey := AdvisoryLockKey(key)
+	var acquired bool
+	err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Release()
+		return false, err
+	}
+	defer releaseAdvisoryLock(conn, key, lockKey)
+	return true, fn(ctx)
+}
+
+func releaseAdvisoryLock(conn *pgxpool.Conn, key string, lockKey int64) {
+	_, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+	if err != nil {
+		// The lock is tied to the session, so a connection we could not unlock must not go back to the pool.
+		log.Warnf("Error releasing advisory lock %v: %v", key, err)
+		_ = conn.Conn().Close(context.Background())
+	}
+	conn.Release()
+}
+
+// AdvisoryXactLock takes a transaction-level advisory lock that is released on commit or rollback.
+func AdvisoryXactLock(ctx context.Context, tx pgx.Tx, key string) error {
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", AdvisoryLockKey(key))
+	return err
+}
+
+// TryAdvisoryXactLock is the non-blocking variant of AdvisoryXactLock.
+func TryAdvisoryXactLock(ctx context.Context, tx pgx.Tx, key string) (bool, error) {
+	var acquired bool
+	err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", AdvisoryLockKey(key)).Scan(&acquired)
+	if err != nil {
+		return false, err
+	}
+	return acquired, nil
+}
@@ -0,0 +1,93 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+// advisoryLocks returns the number of advisory locks held on the server.
+func advisoryLocks(t *testing.T, q pg.Querier) int64 {
+	t.Helper()
+	count, err := pg.Count(context.Background(), q, "SELECT FROM pg_locks WHERE locktype = 'advisory'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	err := pg.WithAdvisoryLock(ctx, db.Pool, "jobs", func(ctx context.Context) error {
+		if advisoryLocks(t, db.Pool) != 1 {
+			t.Error("expected the lock to be held")
+		}
+		ran, err := pg.TryAdvisoryLock(ctx, db.Pool, "jobs", func(ctx context.Context) error {
+			t.Error("the held lock must not be taken again")
+			return nil
+		})
+		if err != nil || ran {
+			t.Errorf("expected the lock to be busy, got %v, %v", ran, err)
+		}
+		err = pg.WithAdvisoryLockTimeout(ctx, db.Pool, "jobs", 100*time.Millisecond, func(ctx context.Context) error {
+			t.Error("the held lock must not be taken again")
+			return nil
+		})
+		if !errors.Is(err, pg.ErrAdvisoryLockTimeout) {
+			t.Errorf("expected ErrAdvisoryLockTimeout, got %v", err)
+		}
+		ran, err = pg.TryAdvisoryLock(ctx, db.Pool, "other", func(ctx context.Context) error { return nil })
+		if err != nil || !ran {
+			t.Errorf("expected another key to be free, got %v, %v", ran, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advisoryLocks(t, db.Pool) != 0 {
+		t.Error("expected the locks to be released")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to be rethrown")
+			}
+		}()
+		_ = pg.WithAdvisoryLock(ctx, db.Pool, "jobs", func(ctx context.Context) error { panic("fn panicked") })
+	}()
+	ran, err := pg.TryAdvisoryLock(ctx, db.Pool, "jobs", func(ctx context.Context) error { return nil })
+	if err != nil || !ran {
+		t.Errorf("expected the lock to be released after a panic, got %v, %v", ran, err)
+	}
+}
+
+func TestAdvisoryXactLock(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	err := pg.DoInTransactionNoResult(db.Pool, func(tx pgx.Tx) error {
+		err := pg.AdvisoryXactLock(ctx, tx, "jobs")
+		if err != nil {
+			return err
+		}
+		return pg.DoInTransactionNoResult(db.Pool, func(other pgx.Tx) error {
+			acquired, err := pg.TryAdvisoryXactLock(ctx, other, "jobs")
+			if err != nil || acquired {
+				t.Errorf("expected the lock to be held by the first transaction, got %v, %v", acquired, err)
+			}
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advisoryLocks(t, db.Pool) != 0 {
+		t.Error("expected the lock to end with the transaction")
+	}
+}
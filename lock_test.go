@@ -0,0 +1,12 @@
+package pg
+
+import "testing"
+
+func TestAdvisoryLockKey(t *testing.T) {
+	if AdvisoryLockKey("migrations") != AdvisoryLockKey("migrations") {
+		t.Error("key hashing should be deterministic")
+	}
+	if AdvisoryLockKey("migrations") == AdvisoryLockKey("jobs") {
+		t.Error("different keys should hash differently")
+	}
+}
@@ -0,0 +1,110 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"sort"
+	"time"
+)
+
+// SessionActivity is a backend from pg_stat_activity.
+type SessionActivity struct {
+	PID           int32
+	User          string
+	Application   string
+	State         string
+	Query         string
+	WaitEventType string
+	WaitEvent     string
+	// Duration is how long the current query, or the transaction if idle, has been running.
+	Duration time.Duration
+}
+
+// LockWait is an edge of the lock graph: Blocked waits for a lock held or requested earlier by Blocking.
+type LockWait struct {
+	Blocked  int32
+	Blocking int32
+	LockType string
+	Mode     string
+	Relation string
+	// WaitDuration is how long Blocked has been waiting for the lock.
+	WaitDuration time.Duration
+}
+
+// LockGraph describes which sessions block which.
+type LockGraph struct {
+	Sessions map[int32]SessionActivity
+	Waits    []LockWait
+}
+
+// Roots returns the sessions that block others without waiting themselves, which are usually the
+// ones to look at or terminate.
+func (g LockGraph) Roots() []int32 {
+	blocked := make(map[int32]bool)
+	for _, wait := range g.Waits {
+		blocked[wait.Blocked] = true
+	}
+	seen := make(map[int32]bool)
+	roots := make([]int32, 0)
+	for _, wait := range g.Waits {
+		if !blocked[wait.Blocking] && !seen[wait.Blocking] {
+			seen[wait.Blocking] = true
+			roots = append(roots, wait.Blocking)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+	return roots
+}
+
+// BlockingQueries returns the current lock waits between sessions together with the activity of
+// every session involved. It requires PostgreSQL 14 or later.
+func BlockingQueries(ctx context.Context, q Querier) (LockGraph, error) {
+	graph := LockGraph{Sessions: make(map[int32]SessionActivity)}
+	//goland:noinspection SqlResolve
+	rows, err := q.Query(ctx, `
+		SELECT a.pid, b.pid, COALESCE(l.locktype, ''), COALESCE(l.mode, ''),
+			COALESCE(l.relation::regclass::text, ''),
+			COALESCE(now() - l.waitstart, now() - a.query_start, '0')
+		FROM pg_stat_activity a
+			CROSS JOIN LATERAL unnest(pg_blocking_pids(a.pid)) AS b(pid)
+			LEFT JOIN pg_locks l ON l.pid = a.pid AND NOT l.granted
+		ORDER BY a.pid, b.pid
+	`)
+	if err != nil {
+		return graph, err
+	}
+	graph.Waits, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (LockWait, error) {
+		var w LockWait
+		err := row.Scan(&w.Blocked, &w.Blocking, &w.LockType, &w.Mode, &w.Relation, &w.WaitDuration)
+		return w, err
+	})
+	if err != nil || len(graph.Waits) == 0 {
+		return graph, err
+	}
+	pids := make([]int32, 0, len(graph.Waits)*2)
+	for _, wait := range graph.Waits {
+		pids = append(pids, wait.Blocked, wait.Blocking)
+	}
+	rows, err = q.Query(ctx, `
+		SELECT pid, COALESCE(usename, ''), COALESCE(application_name, ''), COALESCE(state, ''), COALESCE(query, ''),
+			COALESCE(wait_event_type, ''), COALESCE(wait_event, ''),
+			COALESCE(now() - CASE WHEN state LIKE 'idle%' THEN xact_start ELSE query_start END, '0')
+		FROM pg_stat_activity
+		WHERE pid = ANY($1)
+	`, pids)
+	if err != nil {
+		return graph, err
+	}
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SessionActivity, error) {
+		var s SessionActivity
+		err := row.Scan(&s.PID, &s.User, &s.Application, &s.State, &s.Query, &s.WaitEventType, &s.WaitEvent, &s.Duration)
+		return s, err
+	})
+	if err != nil {
+		return graph, err
+	}
+	for _, session := range sessions {
+		graph.Sessions[session.PID] = session
+	}
+	return graph, nil
+}
@@ -0,0 +1,18 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLockGraphRoots(t *testing.T) {
+	graph := LockGraph{Waits: []LockWait{
+		{Blocked: 30, Blocking: 20},
+		{Blocked: 20, Blocking: 10},
+		{Blocked: 40, Blocking: 10},
+		{Blocked: 50, Blocking: 5},
+	}}
+	if roots := graph.Roots(); !reflect.DeepEqual(roots, []int32{5, 10}) {
+		t.Errorf("unexpected roots: %v", roots)
+	}
+}
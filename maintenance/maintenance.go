@@ -0,0 +1,149 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type Task string
+
+const (
+	Vacuum        Task = "VACUUM"
+	Analyze       Task = "ANALYZE"
+	VacuumAnalyze Task = "VACUUM ANALYZE"
+	// Reindex rebuilds the table's indexes with REINDEX TABLE CONCURRENTLY, which does not block
+	// writes (PG12+).
+	Reindex Task = "REINDEX"
+
+	DefaultProgressInterval = time.Second
+)
+
+// progressQueries read the progress of a running task from the pg_stat_progress_* views.
+var progressQueries = map[Task]string{
+	Vacuum:        "SELECT phase, heap_blks_scanned, heap_blks_total FROM pg_stat_progress_vacuum WHERE pid = $1",
+	VacuumAnalyze: "SELECT phase, heap_blks_scanned, heap_blks_total FROM pg_stat_progress_vacuum WHERE pid = $1",
+	Analyze:       "SELECT phase, sample_blks_scanned, sample_blks_total FROM pg_stat_progress_analyze WHERE pid = $1",
+	Reindex:       "SELECT phase, blocks_done, blocks_total FROM pg_stat_progress_create_index WHERE pid = $1",
+}
+
+// Progress is a snapshot of a running task; Done and Total count blocks.
+type Progress struct {
+	Table string
+	Task  Task
+	Phase string
+	Done  int64
+	Total int64
+}
+
+type Plan struct {
+	Tables []string
+	Tasks  []Task
+	// OnProgress is called with the progress of the running task every ProgressInterval. It may be nil.
+	OnProgress       func(Progress)
+	ProgressInterval time.Duration
+}
+
+func statement(task Task, table string) (string, error) {
+	quoted := pg.QuoteIdentifier(table)
+	switch task {
+	case Vacuum, Analyze, VacuumAnalyze:
+		return string(task) + " " + quoted, nil
+	case Reindex:
+		return "REINDEX TABLE CONCURRENTLY " + quoted, nil
+	}
+	return "", fmt.Errorf("unknown maintenance task %q", task)
+}
+
+// Run executes every task of plan on every table in turn. A failed task is logged and the
+// remaining ones still run; all errors are returned together.
+func Run(ctx context.Context, pool *pgxpool.Pool, plan Plan) error {
+	var errs []error
+	for _, table := range plan.Tables {
+		for _, task := range plan.Tasks {
+			err := runTask(ctx, pool, plan, task, table)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				log.Warnf("Error running %v on %v: %v", task, table, err)
+				errs = append(errs, fmt.Errorf("%v %v: %w", task, table, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runTask(ctx context.Context, pool *pgxpool.Pool, plan Plan, task Task, table string) error {
+	sql, err := statement(task, table)
+	if err != nil {
+		return err
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	if plan.OnProgress != nil {
+		reportCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go reportProgress(reportCtx, pool, plan, task, table, conn.Conn().PgConn().PID())
+	}
+	start := time.Now()
+	log.Infof("Running %v", sql)
+	_, err = conn.Exec(ctx, sql)
+	if err != nil {
+		return err
+	}
+	log.Infof("Finished %v in %v", sql, time.Since(start))
+	return nil
+}
+
+// reportProgress polls the progress view for the backend running the task until ctx is done.
+func reportProgress(ctx context.Context, pool *pgxpool.Pool, plan Plan, task Task, table string, pid uint32) {
+	interval := plan.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		p := Progress{Table: table, Task: task}
+		err := pool.QueryRow(ctx, progressQueries[task], int32(pid)).Scan(&p.Phase, &p.Done, &p.Total)
+		if err == nil {
+			plan.OnProgress(p)
+		}
+	}
+}
+
+// RunScheduled runs plan every interval until ctx is cancelled, which returns nil. Each run takes
+// an advisory lock first, so only one instance of a replicated service maintains the tables.
+func RunScheduled(ctx context.Context, pool *pgxpool.Pool, interval time.Duration, plan Plan) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, err := pg.TryAdvisoryLock(ctx, pool, "pgutils.maintenance", func(ctx context.Context) error {
+			return Run(ctx, pool, plan)
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Warnf("Scheduled maintenance failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package maintenance
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestStatement(t *testing.T) {
+	tests := map[Task]string{
+		Vacuum:        `VACUUM "public"."users"`,
+		VacuumAnalyze: `VACUUM ANALYZE "public"."users"`,
+		Reindex:       `REINDEX TABLE CONCURRENTLY "public"."users"`,
+	}
+	for task, expected := range tests {
+		sql, err := statement(task, "public.users")
+		if err != nil || sql != expected {
+			t.Errorf("%v: unexpected statement %v, %v", task, sql, err)
+		}
+	}
+	_, err := statement("CLUSTER", "users")
+	if err == nil {
+		t.Error("expected an error for an unknown task")
+	}
+}
+
+func TestRun(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("../testdb"))
+	err := Run(context.Background(), db.Pool, Plan{
+		Tables: []string{"testtable"},
+		Tasks:  []Task{VacuumAnalyze, Reindex},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
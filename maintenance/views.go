@@ -0,0 +1,244 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/introspect"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	DefaultRefreshTable        = "matview_refreshes"
+	DefaultRefreshTickInterval = 10 * time.Second
+)
+
+// View is a materialized view to refresh. Interval enables refreshing it from ViewRefresher.Run.
+type View struct {
+	Name string
+	// Concurrently refreshes without blocking readers; the view needs a unique index on plain
+	// columns. The first refresh of an unpopulated view is never concurrent.
+	Concurrently bool
+	Interval     time.Duration
+}
+
+// RefreshResult is the outcome of refreshing a view.
+type RefreshResult struct {
+	View         string
+	Concurrently bool
+	RefreshedAt  time.Time
+	Duration     time.Duration
+	Err          error
+}
+
+// RefreshStatus is the last recorded refresh of a view.
+type RefreshStatus struct {
+	View        string
+	RefreshedAt time.Time
+	Duration    time.Duration
+	LastError   string
+}
+
+// RefreshMigration returns the script creating the table recording refreshes, for inclusion in a
+// migrations directory.
+func RefreshMigration(table string) string {
+	return `
+		CREATE TABLE IF NOT EXISTS ` + pg.QuoteIdentifier(table) + `
+		(
+			view_name TEXT PRIMARY KEY NOT NULL,
+			refreshed_at TIMESTAMPTZ NOT NULL,
+			duration_ms BIGINT NOT NULL,
+			last_error TEXT NOT NULL DEFAULT ''
+		);
+	`
+}
+
+// MigrateRefreshes creates the table recording refreshes if it does not exist yet.
+func MigrateRefreshes(ctx context.Context, q pg.Querier, table string) error {
+	_, err := q.Exec(ctx, RefreshMigration(table))
+	return err
+}
+
+// DiscoverViews returns the materialized views of schemas, or of all user schemas, refreshed every
+// interval and concurrently where their indexes allow it.
+func DiscoverViews(ctx context.Context, q pg.Querier, interval time.Duration, schemas ...string) ([]View, error) {
+	d, err := introspect.Inspect(ctx, q, schemas...)
+	if err != nil {
+		return nil, err
+	}
+	var views []View
+	for _, s := range d.Schemas {
+		for _, v := range s.MaterializedViews {
+			views = append(views, View{Name: v.Schema + "." + v.Name, Concurrently: v.UniqueIndex, Interval: interval})
+		}
+	}
+	return views, nil
+}
+
+// ViewRefresher refreshes materialized views on demand or on their intervals, recording every
+// refresh in Table. Scheduled refreshes take an advisory lock per view, so only one instance of a
+// replicated service refreshes a view.
+type ViewRefresher struct {
+	pool         *pgxpool.Pool
+	Views        []View
+	Table        string
+	TickInterval time.Duration
+	// OnRefresh receives every refresh, e.g. to export durations as metrics. It may be nil.
+	OnRefresh func(RefreshResult)
+}
+
+func NewViewRefresher(pool *pgxpool.Pool, views ...View) *ViewRefresher {
+	return &ViewRefresher{
+		pool:         pool,
+		Views:        views,
+		Table:        DefaultRefreshTable,
+		TickInterval: DefaultRefreshTickInterval,
+	}
+}
+
+func (r *ViewRefresher) view(name string) View {
+	for _, v := range r.Views {
+		if v.Name == name {
+			return v
+		}
+	}
+	return View{Name: name}
+}
+
+// Refresh refreshes the view now. Views that were not declared are refreshed without
+// CONCURRENTLY.
+func (r *ViewRefresher) Refresh(ctx context.Context, name string) error {
+	return r.refresh(ctx, r.view(name)).Err
+}
+
+// RefreshAll refreshes every declared view in turn; all errors are returned together.
+func (r *ViewRefresher) RefreshAll(ctx context.Context) error {
+	var errs []error
+	for _, v := range r.Views {
+		result := r.refresh(ctx, v)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", v.Name, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ViewRefresher) refresh(ctx context.Context, v View) RefreshResult {
+	result := RefreshResult{View: v.Name, RefreshedAt: time.Now()}
+	if v.Concurrently {
+		var populated bool
+		err := r.pool.QueryRow(ctx, "SELECT relispopulated FROM pg_class WHERE oid = $1::regclass", v.Name).Scan(&populated)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Concurrently = populated
+	}
+	sql := "REFRESH MATERIALIZED VIEW "
+	if result.Concurrently {
+		sql += "CONCURRENTLY "
+	}
+	sql += pg.QuoteIdentifier(v.Name)
+	_, result.Err = r.pool.Exec(ctx, sql)
+	result.Duration = time.Since(result.RefreshedAt)
+	if result.Err != nil {
+		log.Warnf("Error refreshing %v: %v", v.Name, result.Err)
+	} else {
+		log.Infof("Refreshed %v in %v", v.Name, result.Duration)
+	}
+	lastError := ""
+	if result.Err != nil {
+		lastError = result.Err.Error()
+	}
+	//goland:noinspection SqlResolve
+	_, err := r.pool.Exec(ctx, "INSERT INTO "+pg.QuoteIdentifier(r.Table)+" (view_name, refreshed_at, duration_ms, last_error) "+
+		"VALUES ($1, $2, $3, $4) ON CONFLICT (view_name) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at, "+
+		"duration_ms = EXCLUDED.duration_ms, last_error = EXCLUDED.last_error",
+		v.Name, result.RefreshedAt, result.Duration.Milliseconds(), lastError)
+	if err != nil {
+		log.Warnf("Error recording the refresh of %v: %v", v.Name, err)
+	}
+	if r.OnRefresh != nil {
+		r.OnRefresh(result)
+	}
+	return result
+}
+
+// Status returns the last recorded refresh of every view, ordered by name.
+func (r *ViewRefresher) Status(ctx context.Context) ([]RefreshStatus, error) {
+	//goland:noinspection SqlResolve
+	rows, err := r.pool.Query(ctx, "SELECT view_name, refreshed_at, duration_ms, last_error FROM "+pg.QuoteIdentifier(r.Table)+" ORDER BY view_name")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (RefreshStatus, error) {
+		var s RefreshStatus
+		var durationMs int64
+		err := row.Scan(&s.View, &s.RefreshedAt, &durationMs, &s.LastError)
+		s.Duration = time.Duration(durationMs) * time.Millisecond
+		return s, err
+	})
+}
+
+// Run refreshes the views whose interval elapsed every TickInterval until ctx is cancelled, which
+// returns nil.
+func (r *ViewRefresher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.TickInterval)
+	defer ticker.Stop()
+	for {
+		err := r.Tick(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Warnf("Error refreshing materialized views: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick refreshes the views that are due, skipping those another instance is refreshing.
+func (r *ViewRefresher) Tick(ctx context.Context) error {
+	var errs []error
+	for _, v := range r.Views {
+		if v.Interval <= 0 {
+			continue
+		}
+		_, err := pg.TryAdvisoryLock(ctx, r.pool, "pgutils.matview."+v.Name, func(ctx context.Context) error {
+			// Checked under the lock, as another instance may have refreshed the view just now.
+			due, err := r.due(ctx, v)
+			if err != nil || !due {
+				return err
+			}
+			return r.refresh(ctx, v).Err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", v.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ViewRefresher) due(ctx context.Context, v View) (bool, error) {
+	var refreshedAt time.Time
+	//goland:noinspection SqlResolve
+	err := r.pool.QueryRow(ctx, "SELECT refreshed_at FROM "+pg.QuoteIdentifier(r.Table)+" WHERE view_name = $1", v.Name).Scan(&refreshedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return time.Since(refreshedAt) >= v.Interval, nil
+}
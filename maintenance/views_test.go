@@ -0,0 +1,67 @@
+package maintenance
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestViewRefresher(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("../testdb"))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE MATERIALIZED VIEW names AS SELECT id, name FROM testtable WITH NO DATA;
+		CREATE UNIQUE INDEX names_id ON names (id);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = MigrateRefreshes(ctx, db.Pool, DefaultRefreshTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	views, err := DiscoverViews(ctx, db.Pool, time.Hour, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != 1 || views[0].Name != "public.names" || !views[0].Concurrently {
+		t.Fatalf("unexpected views %+v", views)
+	}
+	r := NewViewRefresher(db.Pool, views...)
+	var results []RefreshResult
+	r.OnRefresh = func(result RefreshResult) { results = append(results, result) }
+
+	err = r.Tick(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertRowCount(t, db.Pool, "names", 1)
+	err = r.Tick(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Concurrently {
+		t.Fatalf("expected a single refresh that could not be concurrent, got %+v", results)
+	}
+
+	_, err = db.Pool.Exec(ctx, "INSERT INTO testtable (id, name) VALUES (2, 'name2')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Refresh(ctx, "public.names")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertRowCount(t, db.Pool, "names", 2)
+	if len(results) != 2 || !results[1].Concurrently {
+		t.Errorf("expected a concurrent refresh, got %+v", results)
+	}
+	status, err := r.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || status[0].View != "public.names" || status[0].LastError != "" || status[0].RefreshedAt.IsZero() {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
@@ -0,0 +1,76 @@
+package pg
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"sync"
+	"time"
+)
+
+const meterName = "github.com/msumera/pgutils"
+
+type txInstruments struct {
+	commits   metric.Int64Counter
+	rollbacks metric.Int64Counter
+	retries   metric.Int64Counter
+	duration  metric.Float64Histogram
+}
+
+var (
+	meterProviderMu sync.Mutex
+	meterProvider   metric.MeterProvider
+	instruments     *txInstruments
+)
+
+// SetMeterProvider sets the provider receiving the transaction helper metrics. The global
+// provider is used until it is called.
+func SetMeterProvider(mp metric.MeterProvider) {
+	meterProviderMu.Lock()
+	defer meterProviderMu.Unlock()
+	meterProvider = mp
+	instruments = nil
+}
+
+func txMetrics() *txInstruments {
+	meterProviderMu.Lock()
+	defer meterProviderMu.Unlock()
+	if instruments != nil {
+		return instruments
+	}
+	mp := meterProvider
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(meterName)
+	// Instrument creation only fails for invalid names; the returned instruments are usable no-ops then.
+	commits, _ := meter.Int64Counter("pgutils.tx.commits", metric.WithDescription("Transactions committed by the transaction helpers"))
+	rollbacks, _ := meter.Int64Counter("pgutils.tx.rollbacks", metric.WithDescription("Transactions rolled back by the transaction helpers"))
+	retries, _ := meter.Int64Counter("pgutils.tx.retries", metric.WithDescription("Transactions retried after a serialization failure or deadlock"))
+	duration, _ := meter.Float64Histogram("pgutils.tx.duration", metric.WithUnit("s"), metric.WithDescription("Duration of transactions run by the transaction helpers"))
+	instruments = &txInstruments{
+		commits:   commits,
+		rollbacks: rollbacks,
+		retries:   retries,
+		duration:  duration,
+	}
+	return instruments
+}
+
+// recordTx records the outcome of a transaction started at start.
+func recordTx(start time.Time, committed bool) {
+	m := txMetrics()
+	outcome := "rollback"
+	if committed {
+		outcome = "commit"
+		m.commits.Add(context.Background(), 1)
+	} else {
+		m.rollbacks.Add(context.Background(), 1)
+	}
+	m.duration.Record(context.Background(), time.Since(start).Seconds(), metric.WithAttributes(attribute.String("outcome", outcome)))
+}
+
+func recordTxRetry() {
+	txMetrics().retries.Add(context.Background(), 1)
+}
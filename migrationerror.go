@@ -0,0 +1,68 @@
+package pg
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+)
+
+// MigrationError is returned when a migration script fails. Err is the underlying error, usually a
+// *pgconn.PgError. The position fields are set when postgres reports where in the script the error
+// occurred.
+type MigrationError struct {
+	Filename string
+	Version  string
+	SQLState string
+	// Line and Column are 1-based positions in the script.
+	Line   int
+	Column int
+	// Statement is the statement of the script the error occurred in.
+	Statement string
+	Err       error
+}
+
+func (e *MigrationError) Error() string {
+	message := fmt.Sprintf("migration %v: %v", e.Filename, e.Err)
+	if e.Line > 0 {
+		message += fmt.Sprintf(" at line %v, column %v", e.Line, e.Column)
+	}
+	return message
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+func newMigrationError(filename string, version string, script string, err error) *MigrationError {
+	migrationError := &MigrationError{Filename: filename, Version: version, Err: err}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return migrationError
+	}
+	migrationError.SQLState = pgErr.Code
+	if pgErr.Position > 0 {
+		migrationError.Line, migrationError.Column, migrationError.Statement = locate(script, int(pgErr.Position))
+	}
+	return migrationError
+}
+
+// locate resolves a 1-based character position reported by postgres to the line and column in
+// script and the statement around it. Statements are split on semicolons, which is good enough to
+// point at the failing one in a report.
+func locate(script string, position int) (int, int, string) {
+	runes := []rune(script)
+	if position > len(runes) {
+		return 0, 0, ""
+	}
+	before := string(runes[:position-1])
+	lineStart := strings.LastIndex(before, "\n") + 1
+	line := strings.Count(before, "\n") + 1
+	column := len([]rune(before[lineStart:])) + 1
+	start := strings.LastIndex(before, ";") + 1
+	end := strings.Index(script[len(before):], ";")
+	if end < 0 {
+		end = len(script) - len(before)
+	}
+	return line, column, strings.TrimSpace(script[start : len(before)+end])
+}
@@ -0,0 +1,32 @@
+package pg_test
+
+import (
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrationError(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	directory := t.TempDir()
+	err := os.WriteFile(filepath.Join(directory, "0_broken.sql"), []byte("CREATE TABLE a (id INT);\nINSERT INTO missing VALUES (1);\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := db.Configuration
+	c.MigrationsDirectory = directory
+	err = pg.Migrate(db.Pool, c)
+	var migrationError *pg.MigrationError
+	if !errors.As(err, &migrationError) {
+		t.Fatalf("expected a MigrationError, got %v", err)
+	}
+	if migrationError.Filename != "0_broken.sql" || migrationError.Version != "0" || migrationError.SQLState != "42P01" {
+		t.Errorf("unexpected error %+v", migrationError)
+	}
+	if migrationError.Line != 2 || migrationError.Statement != "INSERT INTO missing VALUES (1)" {
+		t.Errorf("unexpected location %+v", migrationError)
+	}
+}
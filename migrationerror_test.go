@@ -0,0 +1,30 @@
+package pg
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"testing"
+)
+
+func TestNewMigrationError(t *testing.T) {
+	script := "CREATE TABLE a (id INT);\nINSERT INTO missing\n  VALUES (1);\nSELECT 1;"
+	pgErr := &pgconn.PgError{Severity: "ERROR", Code: "42P01", Message: `relation "missing" does not exist`, Position: 38}
+	err := newMigrationError("1_insert.sql", "1", script, pgErr)
+	if err.SQLState != "42P01" || err.Line != 2 || err.Column != 13 {
+		t.Errorf("unexpected position %+v", err)
+	}
+	if err.Statement != "INSERT INTO missing\n  VALUES (1)" {
+		t.Errorf("unexpected statement %q", err.Statement)
+	}
+	if !errors.Is(err, pgErr) {
+		t.Error("the error should wrap the postgres error")
+	}
+	expected := `migration 1_insert.sql: ERROR: relation "missing" does not exist (SQLSTATE 42P01) at line 2, column 13`
+	if err.Error() != expected {
+		t.Errorf("unexpected message %v", err.Error())
+	}
+	err = newMigrationError("1_insert.sql", "1", script, errors.New("connection lost"))
+	if err.Line != 0 || err.Statement != "" {
+		t.Errorf("errors without a position should not be located, got %+v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package pg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MigrationNaming is the file naming convention of the migration scripts.
+type MigrationNaming string
+
+const (
+	// MigrationNamingAuto detects the convention the scripts of the source follow. Sources mixing
+	// conventions are rejected, as the scripts of the others would silently be skipped.
+	MigrationNamingAuto MigrationNaming = "auto"
+	// MigrationNamingDefault names scripts by their dotted version in parts, e.g. 1_2_addcolumn.sql
+	// for version 1.2, with down scripts ending in .down.sql.
+	MigrationNamingDefault MigrationNaming = "default"
+	// MigrationNamingGolangMigrate is the convention of golang-migrate, e.g.
+	// 000001_create_users.up.sql and 000001_create_users.down.sql.
+	MigrationNamingGolangMigrate MigrationNaming = "golang-migrate"
+	// MigrationNamingFlyway is the convention of Flyway: V1_2__add_column.sql for version 1.2, with
+	// "." or "_" between the parts, its undo script U1_2__add_column.sql, and repeatable scripts
+	// like R__views.sql. Repeatable scripts run after the versioned ones whenever their content
+	// changed. Trailing zero parts are dropped, so V1 and V1.0 are the same version, as in Flyway.
+	MigrationNamingFlyway MigrationNaming = "flyway"
+)
+
+const upMigrationSuffix = ".up.sql"
+
+var (
+	golangMigrateFilename = regexp.MustCompile(`^(\d+)_(.*)\.up\.sql$`)
+	flywayFilename        = regexp.MustCompile(`^V(\d+(?:[._]\d+)*)__(.+)\.sql$`)
+	flywayUndo            = regexp.MustCompile(`^U\d+(?:[._]\d+)*__.+\.sql$`)
+	flywayRepeatable      = regexp.MustCompile(`^R__(.+)\.sql$`)
+	defaultFilename       = regexp.MustCompile(`^\d+_.*\.sql$`)
+)
+
+// resolve picks the convention for auto detection from the names of the source.
+func (n MigrationNaming) resolve(filenames []string) (MigrationNaming, error) {
+	switch n {
+	case "", MigrationNamingAuto:
+		var detected []MigrationNaming
+		examples := make(map[MigrationNaming]string)
+		for _, filename := range filenames {
+			var naming MigrationNaming
+			switch {
+			case strings.HasSuffix(filename, downMigrationSuffix):
+				// Down scripts are named alike by the default convention and golang-migrate.
+				continue
+			case strings.HasSuffix(filename, upMigrationSuffix):
+				naming = MigrationNamingGolangMigrate
+			case flywayFilename.MatchString(filename) || flywayUndo.MatchString(filename) || flywayRepeatable.MatchString(filename):
+				naming = MigrationNamingFlyway
+			case defaultFilename.MatchString(filename):
+				naming = MigrationNamingDefault
+			default:
+				continue
+			}
+			if _, ok := examples[naming]; !ok {
+				detected = append(detected, naming)
+				examples[naming] = filename
+			}
+		}
+		if len(detected) > 1 {
+			return "", fmt.Errorf("the migrations mix naming conventions, %v follows %v and %v follows %v; set MigrationNaming",
+				examples[detected[0]], detected[0], examples[detected[1]], detected[1])
+		}
+		if len(detected) == 1 {
+			return detected[0], nil
+		}
+		return MigrationNamingDefault, nil
+	case MigrationNamingDefault, MigrationNamingGolangMigrate, MigrationNamingFlyway:
+		return n, nil
+	}
+	return "", fmt.Errorf("unknown migration naming %q", n)
+}
+
+// parse returns the migration of an up script, or false for down scripts and files that are no
+// migrations.
+func (n MigrationNaming) parse(filename string) (migration, bool) {
+	switch n {
+	case MigrationNamingFlyway:
+		if match := flywayRepeatable.FindStringSubmatch(filename); match != nil {
+			return migration{Name: strings.ReplaceAll(match[1], "_", " "), Filename: filename, Repeatable: true}, true
+		}
+		match := flywayFilename.FindStringSubmatch(filename)
+		if match == nil {
+			return migration{}, false
+		}
+		var ids []int
+		for _, part := range strings.FieldsFunc(match[1], func(r rune) bool { return r == '.' || r == '_' }) {
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return migration{}, false
+			}
+			ids = append(ids, id)
+		}
+		for len(ids) > 1 && ids[len(ids)-1] == 0 {
+			ids = ids[:len(ids)-1]
+		}
+		return migration{
+			Id:       ids,
+			Name:     strings.ReplaceAll(match[2], "_", " "),
+			Filename: filename,
+			Down:     "U" + strings.TrimPrefix(filename, "V"),
+		}, true
+	case MigrationNamingGolangMigrate:
+		match := golangMigrateFilename.FindStringSubmatch(filename)
+		if match == nil {
+			return migration{}, false
+		}
+		id, err := strconv.Atoi(match[1])
+		if err != nil {
+			return migration{}, false
+		}
+		return migration{
+			Id:       []int{id},
+			Name:     strings.ReplaceAll(match[2], "_", " "),
+			Filename: filename,
+			Down:     strings.TrimSuffix(filename, upMigrationSuffix) + downMigrationSuffix,
+		}, true
+	default:
+		if !strings.HasSuffix(filename, ".sql") || strings.HasSuffix(filename, downMigrationSuffix) {
+			return migration{}, false
+		}
+		parts := strings.Split(filename, "_")
+		ids := make([]int, 0)
+		for _, part := range parts {
+			v, err := strconv.Atoi(part)
+			if err != nil {
+				break
+			}
+			ids = append(ids, v)
+		}
+		return migration{
+			Id:       ids,
+			Name:     strings.TrimSuffix(strings.Join(parts[len(ids):], " "), ".sql"),
+			Filename: filename,
+			Down:     strings.TrimSuffix(filename, ".sql") + downMigrationSuffix,
+		}, true
+	}
+}
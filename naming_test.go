@@ -0,0 +1,105 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGolangMigrateNaming(t *testing.T) {
+	source := FSSource{FS: fstest.MapFS{
+		"000002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"000002_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP email;")},
+		"000001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+		"000001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"000010_seed.up.sql":           {Data: []byte("INSERT INTO users VALUES (1);")},
+		"README.md":                    {Data: []byte("migrations")},
+	}}
+	for _, naming := range []MigrationNaming{"", MigrationNamingAuto, MigrationNamingGolangMigrate} {
+		dbm := createDatabaseMigrator(nil, Configuration{MigrationsSource: source, MigrationNaming: naming})
+		migrations, err := dbm.getMigrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []migration{
+			{Id: []int{1}, Name: "create users", Filename: "000001_create_users.up.sql", Down: "000001_create_users.down.sql"},
+			{Id: []int{2}, Name: "add email", Filename: "000002_add_email.up.sql", Down: "000002_add_email.down.sql"},
+			{Id: []int{10}, Name: "seed", Filename: "000010_seed.up.sql", Down: "000010_seed.down.sql"},
+		}
+		if !reflect.DeepEqual(migrations, expected) {
+			t.Errorf("naming %q: unexpected migrations %v", naming, migrations)
+		}
+	}
+}
+
+func TestDefaultNaming(t *testing.T) {
+	dbm := createDatabaseMigrator(nil, Configuration{MigrationsDirectory: "testdb", MigrationNaming: MigrationNamingAuto})
+	migrations, err := dbm.getMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 || migrations[0].Filename != "0_init.sql" || migrations[0].Down != "0_init.down.sql" {
+		t.Errorf("unexpected migrations %v", migrations)
+	}
+	_, err = MigrationNaming("unknown").resolve(nil)
+	if err == nil {
+		t.Error("expected an unknown naming to fail")
+	}
+}
+
+func TestFlywayNaming(t *testing.T) {
+	source := FSSource{FS: fstest.MapFS{
+		"V1__create_users.sql":    {Data: []byte("CREATE TABLE users (id INT);")},
+		"U1__create_users.sql":    {Data: []byte("DROP TABLE users;")},
+		"V1_2__add_email.sql":     {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"V1.10__add_name.sql":     {Data: []byte("ALTER TABLE users ADD name TEXT;")},
+		"R__views.sql":            {Data: []byte("CREATE OR REPLACE VIEW v AS SELECT 1;")},
+		"R__functions.sql":        {Data: []byte("SELECT 1;")},
+		"V2__notes.txt":           {Data: []byte("not a migration")},
+		"Vx__not_a_migration.sql": {Data: []byte("SELECT 1;")},
+	}}
+	for _, naming := range []MigrationNaming{MigrationNamingAuto, MigrationNamingFlyway} {
+		dbm := createDatabaseMigrator(nil, Configuration{MigrationsSource: source, MigrationNaming: naming})
+		migrations, err := dbm.getMigrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []migration{
+			{Id: []int{1}, Name: "create users", Filename: "V1__create_users.sql", Down: "U1__create_users.sql"},
+			{Id: []int{1, 2}, Name: "add email", Filename: "V1_2__add_email.sql", Down: "U1_2__add_email.sql"},
+			{Id: []int{1, 10}, Name: "add name", Filename: "V1.10__add_name.sql", Down: "U1.10__add_name.sql"},
+			{Name: "functions", Filename: "R__functions.sql", Repeatable: true},
+			{Name: "views", Filename: "R__views.sql", Repeatable: true},
+		}
+		if !reflect.DeepEqual(migrations, expected) {
+			t.Errorf("naming %q: unexpected migrations %v", naming, migrations)
+		}
+	}
+}
+
+func TestMixedNaming(t *testing.T) {
+	for _, files := range []fstest.MapFS{
+		{"0_init.sql": {}, "V1__add_users.sql": {}},
+		{"000001_init.up.sql": {}, "R__views.sql": {}},
+		{"1_init.sql": {}, "000002_add_users.up.sql": {}},
+	} {
+		dbm := createDatabaseMigrator(nil, Configuration{MigrationsSource: FSSource{FS: files}})
+		_, err := dbm.getMigrations()
+		if err == nil {
+			t.Errorf("expected %v to be rejected", files)
+		}
+	}
+}
+
+func TestFlywayVersionsNormalized(t *testing.T) {
+	source := FSSource{FS: fstest.MapFS{"V1__init.sql": {}, "V1.0__again.sql": {}}}
+	dbm := createDatabaseMigrator(nil, Configuration{MigrationsSource: source})
+	_, err := dbm.getMigrations()
+	if err == nil {
+		t.Error("expected V1 and V1.0 to be the same version")
+	}
+	migration, ok := MigrationNamingFlyway.parse("V2_0_1_0__fix.sql")
+	if !ok || !reflect.DeepEqual(migration.Id, []int{2, 0, 1}) {
+		t.Errorf("unexpected migration %v", migration)
+	}
+}
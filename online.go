@@ -0,0 +1,209 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// The helpers below change large, busy tables without long locks. They run their statements one
+// by one, so pass a pool or connection rather than a transaction, except where noted.
+
+const (
+	lockNotAvailable = "55P03"
+	queryCanceled    = "57014"
+
+	defaultIndexAttempts = 3
+	indexRetryDelay      = time.Second
+
+	defaultBackfillBatchSize = 1000
+)
+
+// ConcurrentIndex is an index built by CreateIndexConcurrently.
+type ConcurrentIndex struct {
+	// Name is unqualified; the index lives in the schema of Table.
+	Name  string
+	Table string
+	// Using follows the table in CREATE INDEX, e.g. "(email)" or "USING gin (tags) WHERE deleted_at IS NULL".
+	Using  string
+	Unique bool
+	// Attempts bounds the builds, 3 when zero.
+	Attempts int
+}
+
+// CreateIndexConcurrently builds the index with CREATE INDEX CONCURRENTLY, which does not block
+// writes. A failed build leaves an invalid index behind; it is dropped, and builds that failed on
+// a lock timeout, deadlock or statement timeout are retried with a growing delay. An existing
+// valid index with the name is left as is.
+func CreateIndexConcurrently(ctx context.Context, q Querier, index ConcurrentIndex) error {
+	attempts := index.Attempts
+	if attempts <= 0 {
+		attempts = defaultIndexAttempts
+	}
+	schema, _, qualified := strings.Cut(index.Table, ".")
+	name := pgx.Identifier{index.Name}.Sanitize()
+	if qualified {
+		name = pgx.Identifier{schema, index.Name}.Sanitize()
+	}
+	create := "CREATE INDEX CONCURRENTLY IF NOT EXISTS "
+	if index.Unique {
+		create = "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS "
+	}
+	create += pgx.Identifier{index.Name}.Sanitize() + " ON " + QuoteIdentifier(index.Table) + " " + index.Using
+	delay := indexRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := dropInvalidIndex(ctx, q, name)
+		if err != nil {
+			return err
+		}
+		_, err = q.Exec(ctx, create)
+		if err == nil {
+			return nil
+		}
+		if attempt >= attempts || !isTransientLockError(err) {
+			return errors.Join(err, dropInvalidIndex(context.Background(), q, name))
+		}
+		log.Warnf("Retrying index %v after attempt %v: %v", index.Name, attempt, err)
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), dropInvalidIndex(context.Background(), q, name))
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// dropInvalidIndex drops the quoted index when an interrupted concurrent build left it invalid.
+func dropInvalidIndex(ctx context.Context, q Querier, name string) error {
+	var invalid bool
+	err := q.QueryRow(ctx, "SELECT EXISTS (SELECT FROM pg_index WHERE indexrelid = to_regclass($1) AND NOT indisvalid)", name).Scan(&invalid)
+	if err != nil || !invalid {
+		return err
+	}
+	_, err = q.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+name)
+	return err
+}
+
+func isTransientLockError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == lockNotAvailable || pgErr.Code == deadlockDetected || pgErr.Code == queryCanceled
+}
+
+// AddNotNull makes column NOT NULL without holding an exclusive lock while the table is scanned:
+// a NOT VALID check constraint is added and validated, which allows writes, and then lets SET NOT
+// NULL skip its own scan. The check constraint is dropped afterwards. It is safe to run again
+// after an interruption.
+func AddNotNull(ctx context.Context, q Querier, table string, column string) error {
+	_, name, qualified := strings.Cut(table, ".")
+	if !qualified {
+		name = table
+	}
+	constraint := pgx.Identifier{name + "_" + column + "_not_null"}.Sanitize()
+	quotedTable, quotedColumn := QuoteIdentifier(table), pgx.Identifier{column}.Sanitize()
+	var exists bool
+	err := q.QueryRow(ctx, "SELECT EXISTS (SELECT FROM pg_constraint WHERE conrelid = to_regclass($1) AND conname = $2)",
+		quotedTable, name+"_"+column+"_not_null").Scan(&exists)
+	if err != nil {
+		return err
+	}
+	statements := []string{
+		"ALTER TABLE " + quotedTable + " VALIDATE CONSTRAINT " + constraint,
+		"ALTER TABLE " + quotedTable + " ALTER COLUMN " + quotedColumn + " SET NOT NULL",
+		"ALTER TABLE " + quotedTable + " DROP CONSTRAINT " + constraint,
+	}
+	if !exists {
+		add := "ALTER TABLE " + quotedTable + " ADD CONSTRAINT " + constraint + " CHECK (" + quotedColumn + " IS NOT NULL) NOT VALID"
+		statements = append([]string{add}, statements...)
+	}
+	for _, statement := range statements {
+		_, err = q.Exec(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchBackfill is an update run by BackfillInBatches.
+type BatchBackfill struct {
+	Table string
+	// Set is the SET clause, e.g. "email_lower = lower(email)".
+	Set string
+	// Where selects the rows still to update and must stop matching updated rows, e.g.
+	// "email_lower IS NULL", or the backfill never ends.
+	Where string
+	Args  []any
+	// BatchSize is the number of rows per statement, 1000 when zero.
+	BatchSize int
+	// Pause is slept between batches, leaving room for other writes and for replicas to catch up.
+	Pause time.Duration
+}
+
+// BackfillInBatches runs the update in short statements of BatchSize rows until Where matches no
+// more rows, so no statement holds row locks for long. It returns the number of rows updated, also
+// when it fails midway.
+func BackfillInBatches(ctx context.Context, q Querier, b BatchBackfill) (int64, error) {
+	batchSize := b.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBackfillBatchSize
+	}
+	table := QuoteIdentifier(b.Table)
+	sql := fmt.Sprintf("UPDATE %v SET %v WHERE ctid = ANY(ARRAY(SELECT ctid FROM %v WHERE %v LIMIT %v))",
+		table, b.Set, table, b.Where, batchSize)
+	var updated int64
+	for {
+		tag, err := q.Exec(ctx, sql, b.Args...)
+		if err != nil {
+			return updated, err
+		}
+		updated += tag.RowsAffected()
+		if tag.RowsAffected() == 0 {
+			return updated, nil
+		}
+		select {
+		case <-ctx.Done():
+			return updated, ctx.Err()
+		case <-time.After(b.Pause):
+		}
+	}
+}
+
+// RenameTableWithView renames table to newName and leaves an updatable view under the old name,
+// so code using either name keeps working while it is deployed. Both happen in one transaction.
+// Drop the view with DropViewShim once nothing uses the old name.
+func RenameTableWithView(ctx context.Context, db TxBeginner, table string, newName string) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	renamed := pgx.Identifier{newName}.Sanitize()
+	if schema, _, qualified := strings.Cut(table, "."); qualified {
+		renamed = pgx.Identifier{schema, newName}.Sanitize()
+	}
+	_, err = tx.Exec(ctx, "ALTER TABLE "+QuoteIdentifier(table)+" RENAME TO "+pgx.Identifier{newName}.Sanitize())
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "CREATE VIEW "+QuoteIdentifier(table)+" AS SELECT * FROM "+renamed)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DropViewShim drops the view RenameTableWithView left under the old name of a table.
+func DropViewShim(ctx context.Context, q Querier, table string) error {
+	_, err := q.Exec(ctx, "DROP VIEW IF EXISTS "+QuoteIdentifier(table))
+	return err
+}
@@ -0,0 +1,78 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestOnlineMigrationHelpers(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE users (id INT PRIMARY KEY, email TEXT, email_lower TEXT);
+		INSERT INTO users SELECT i, 'User' || i || '@Example.com' FROM generate_series(1, 250) i;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := pg.BackfillInBatches(ctx, db.Pool, pg.BatchBackfill{
+		Table:     "users",
+		Set:       "email_lower = lower(email)",
+		Where:     "email_lower IS NULL AND id > $1",
+		Args:      []any{0},
+		BatchSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != 250 {
+		t.Errorf("expected 250 updated rows, got %v", updated)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT 1 FROM users WHERE email_lower IS DISTINCT FROM lower(email)")
+
+	err = pg.AddNotNull(ctx, db.Pool, "public.users", "email_lower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT is_nullable::text FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'email_lower'", [][]any{{"NO"}})
+	pgtest.AssertNoRows(t, db.Pool, "SELECT 1 FROM pg_constraint WHERE conname = 'users_email_lower_not_null' AND contype = 'c'")
+
+	// A duplicate fails the unique build, which must not leave an invalid index behind.
+	_, err = db.Pool.Exec(ctx, "UPDATE users SET email_lower = 'dup' WHERE id IN (1, 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := pg.ConcurrentIndex{Name: "users_email_lower", Table: "public.users", Using: "(email_lower)", Unique: true}
+	err = pg.CreateIndexConcurrently(ctx, db.Pool, index)
+	if err == nil {
+		t.Fatal("expected the duplicate to fail the build")
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT 1 FROM pg_class WHERE relname = 'users_email_lower'")
+	_, err = db.Pool.Exec(ctx, "UPDATE users SET email_lower = lower(email)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.CreateIndexConcurrently(ctx, db.Pool, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT indisvalid FROM pg_index WHERE indexrelid = 'users_email_lower'::regclass", [][]any{{true}})
+
+	err = pg.RenameTableWithView(ctx, db.Pool, "users", "accounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Pool.Exec(ctx, "INSERT INTO users (id, email, email_lower) VALUES (251, 'new@example.com', 'new@example.com')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertRowCount(t, db.Pool, "accounts", 251)
+	err = pg.DropViewShim(ctx, db.Pool, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT 1 FROM pg_class WHERE relname = 'users'")
+}
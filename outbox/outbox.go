@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	pg "github.com/msumera/pgutils"
+	"time"
+)
+
+const DefaultTable = "outbox"
+
+// Event is a message written to the outbox in the same transaction as the state change it
+// describes, and handed to a Publisher by the Relay once that transaction has committed.
+type Event struct {
+	ID        int64
+	Topic     string
+	Key       string
+	Payload   json.RawMessage
+	Headers   map[string]string
+	CreatedAt time.Time
+}
+
+// Migration returns the script creating the outbox table, for inclusion in a migrations directory.
+func Migration(table string) string {
+	return `
+		CREATE TABLE IF NOT EXISTS ` + pg.QuoteIdentifier(table) + `
+		(
+			id BIGSERIAL PRIMARY KEY NOT NULL,
+			topic TEXT NOT NULL,
+			key TEXT NOT NULL DEFAULT '',
+			payload JSONB NOT NULL,
+			headers JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+	`
+}
+
+// Migrate creates the outbox table if it does not exist yet.
+func Migrate(ctx context.Context, q pg.Querier, table string) error {
+	_, err := q.Exec(ctx, Migration(table))
+	return err
+}
+
+// Enqueue writes event to the default outbox table. Pass the transaction that carries the
+// business change, so the event is published if and only if that change commits.
+func Enqueue(ctx context.Context, tx pg.Querier, event Event) error {
+	return EnqueueTo(ctx, tx, DefaultTable, event)
+}
+
+func EnqueueTo(ctx context.Context, tx pg.Querier, table string, event Event) error {
+	headers := event.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	//goland:noinspection SqlResolve
+	_, err := tx.Exec(ctx, "INSERT INTO "+pg.QuoteIdentifier(table)+" (topic, key, payload, headers) VALUES ($1, $2, $3, $4)", event.Topic, event.Key, event.Payload, headers)
+	return err
+}
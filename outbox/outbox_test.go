@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/msumera/pgutils/pgtest"
+	"strings"
+	"testing"
+)
+
+func TestMigrationQuotesTable(t *testing.T) {
+	script := Migration("events.outbox")
+	if !strings.Contains(script, `CREATE TABLE IF NOT EXISTS "events"."outbox"`) {
+		t.Errorf("unexpected migration: %v", script)
+	}
+}
+
+func TestRelay(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	err := Migrate(ctx, db.Pool, DefaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"1", "2", "3"} {
+		err = Enqueue(ctx, db.Pool, Event{Topic: "orders", Key: key, Payload: json.RawMessage(`{"key":` + key + `}`)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	failing := NewRelay(db.Pool, PublisherFunc(func(ctx context.Context, events []Event) error {
+		return errors.New("broker down")
+	}))
+	_, err = failing.RelayBatch(ctx)
+	if err == nil {
+		t.Fatal("expected the publisher error")
+	}
+
+	var published []Event
+	relay := NewRelay(db.Pool, PublisherFunc(func(ctx context.Context, events []Event) error {
+		published = append(published, events...)
+		return nil
+	}))
+	relay.BatchSize = 2
+	n, err := relay.RelayBatch(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("expected a full batch, got %v, %v", n, err)
+	}
+	n, err = relay.RelayBatch(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected the remaining event, got %v, %v", n, err)
+	}
+	if len(published) != 3 || published[0].Key != "1" || published[2].Key != "3" {
+		t.Errorf("events should be published in order after a failed attempt, got %v", published)
+	}
+}
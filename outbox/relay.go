@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"time"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+
+	meterName = "github.com/msumera/pgutils/outbox"
+)
+
+// Publisher delivers a batch of events, e.g. to a message broker. When it returns an error the
+// whole batch is delivered again later, so consumers must tolerate duplicates.
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+}
+
+type PublisherFunc func(ctx context.Context, events []Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, events []Event) error {
+	return f(ctx, events)
+}
+
+// Relay polls the outbox table and hands events to the Publisher in insertion order. Rows are
+// locked with SKIP LOCKED, so several relays can run side by side, and deleted only after the
+// Publisher succeeded, which makes delivery at-least-once.
+type Relay struct {
+	pool      *pgxpool.Pool
+	publisher Publisher
+
+	Table        string
+	BatchSize    int
+	PollInterval time.Duration
+	// MeterProvider receives the relay metrics. The global provider is used when it is nil.
+	MeterProvider metric.MeterProvider
+}
+
+func NewRelay(pool *pgxpool.Pool, publisher Publisher) *Relay {
+	return &Relay{
+		pool:         pool,
+		publisher:    publisher,
+		Table:        DefaultTable,
+		BatchSize:    DefaultBatchSize,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+type relayInstruments struct {
+	published metric.Int64Counter
+	failures  metric.Int64Counter
+	duration  metric.Float64Histogram
+}
+
+func (r *Relay) instruments() relayInstruments {
+	mp := r.MeterProvider
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(meterName)
+	published, _ := meter.Int64Counter("pgutils.outbox.published", metric.WithDescription("Outbox events delivered to the publisher"))
+	failures, _ := meter.Int64Counter("pgutils.outbox.failures", metric.WithDescription("Outbox batches that failed to be delivered"))
+	duration, _ := meter.Float64Histogram("pgutils.outbox.batch.duration", metric.WithUnit("s"), metric.WithDescription("Duration of relaying a batch of outbox events"))
+	return relayInstruments{
+		published: published,
+		failures:  failures,
+		duration:  duration,
+	}
+}
+
+// Run relays batches until ctx is cancelled, which is treated as a graceful shutdown and returns
+// nil. Full batches are followed immediately by the next one; otherwise the relay waits for
+// PollInterval.
+func (r *Relay) Run(ctx context.Context) error {
+	m := r.instruments()
+	for {
+		n, err := r.relay(ctx, m)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Warnf("Error relaying outbox events: %v", err)
+		}
+		if err == nil && n == r.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.PollInterval):
+		}
+	}
+}
+
+// RelayBatch delivers at most one batch and reports how many events were published.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	return r.relay(ctx, r.instruments())
+}
+
+func (r *Relay) relay(ctx context.Context, m relayInstruments) (int, error) {
+	start := time.Now()
+	n, err := r.relayBatch(ctx)
+	if err != nil {
+		m.failures.Add(ctx, 1)
+		return 0, err
+	}
+	if n > 0 {
+		m.published.Add(ctx, int64(n))
+		m.duration.Record(ctx, time.Since(start).Seconds())
+	}
+	return n, nil
+}
+
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	//goland:noinspection SqlResolve
+	rows, err := tx.Query(ctx, "SELECT id, topic, key, payload, headers, created_at FROM "+pg.QuoteIdentifier(r.Table)+" ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED", r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Event, error) {
+		var event Event
+		err := row.Scan(&event.ID, &event.Topic, &event.Key, &event.Payload, &event.Headers, &event.CreatedAt)
+		return event, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+	err = r.publisher.Publish(ctx, events)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	//goland:noinspection SqlResolve
+	_, err = tx.Exec(ctx, "DELETE FROM "+pg.QuoteIdentifier(r.Table)+" WHERE id = ANY($1)", ids)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
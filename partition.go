@@ -0,0 +1,196 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"sync"
+)
+
+// PartitionedPool shares a pool between labels, e.g. tenants or batch jobs, so a single label
+// cannot hold all connections. Each label may use at most its limit of connections; when the pool
+// is exhausted, freed connections go to the waiting label with the fewest connections in use
+// relative to its weight.
+type PartitionedPool struct {
+	Pool *pgxpool.Pool
+	// DefaultLimit caps labels without their own limit; 0 leaves them uncapped.
+	DefaultLimit int
+	scheduler    *partitionScheduler
+}
+
+// PartitionStats tells how many connections a label uses and how many acquires are waiting.
+type PartitionStats struct {
+	Active  int
+	Waiting int
+}
+
+func NewPartitionedPool(pool *pgxpool.Pool) *PartitionedPool {
+	p := &PartitionedPool{Pool: pool}
+	p.scheduler = newPartitionScheduler(int(pool.Config().MaxConns), func() int { return p.DefaultLimit })
+	return p
+}
+
+// SetPartition configures label. Weight defaults to 1; a limit of 0 falls back to DefaultLimit.
+func (p *PartitionedPool) SetPartition(label string, weight int, limit int) {
+	p.scheduler.configure(label, weight, limit)
+}
+
+// PartitionedConn is a connection acquired for a label. Release it instead of the embedded
+// connection, so the label's slot is freed too.
+type PartitionedConn struct {
+	*pgxpool.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *PartitionedConn) Release() {
+	c.once.Do(func() {
+		c.Conn.Release()
+		c.release()
+	})
+}
+
+// Acquire waits for a slot of label and then a connection of the pool.
+func (p *PartitionedPool) Acquire(ctx context.Context, label string) (*PartitionedConn, error) {
+	err := p.scheduler.acquire(ctx, label)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := p.Pool.Acquire(ctx)
+	if err != nil {
+		p.scheduler.release(label)
+		return nil, err
+	}
+	return &PartitionedConn{Conn: conn, release: func() { p.scheduler.release(label) }}, nil
+}
+
+// Stats returns the state of the labels that used the pool.
+func (p *PartitionedPool) Stats() map[string]PartitionStats {
+	return p.scheduler.stats()
+}
+
+type partitionWaiter struct {
+	ready chan struct{}
+	seq   uint64
+}
+
+type partition struct {
+	weight  int
+	limit   int
+	active  int
+	waiters []*partitionWaiter
+}
+
+// partitionScheduler hands out capacity slots to labels.
+type partitionScheduler struct {
+	capacity     int
+	defaultLimit func() int
+	mu           sync.Mutex
+	partitions   map[string]*partition
+	active       int
+	seq          uint64
+}
+
+func newPartitionScheduler(capacity int, defaultLimit func() int) *partitionScheduler {
+	return &partitionScheduler{capacity: capacity, defaultLimit: defaultLimit, partitions: make(map[string]*partition)}
+}
+
+func (s *partitionScheduler) partition(label string) *partition {
+	p, ok := s.partitions[label]
+	if !ok {
+		p = &partition{weight: 1}
+		s.partitions[label] = p
+	}
+	return p
+}
+
+func (s *partitionScheduler) configure(label string, weight int, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.partition(label)
+	p.weight = max(weight, 1)
+	p.limit = limit
+	s.dispatch()
+}
+
+func (s *partitionScheduler) limit(p *partition) int {
+	if p.limit > 0 {
+		return p.limit
+	}
+	return s.defaultLimit()
+}
+
+// dispatch grants slots while there is capacity, preferring the label with the lowest share of
+// active connections per weight and, between equal shares, the longest waiting acquire.
+func (s *partitionScheduler) dispatch() {
+	for s.active < s.capacity {
+		var next *partition
+		for _, p := range s.partitions {
+			if len(p.waiters) == 0 || (s.limit(p) > 0 && p.active >= s.limit(p)) {
+				continue
+			}
+			if next == nil {
+				next = p
+				continue
+			}
+			share, nextShare := p.active*next.weight, next.active*p.weight
+			if share < nextShare || (share == nextShare && p.waiters[0].seq < next.waiters[0].seq) {
+				next = p
+			}
+		}
+		if next == nil {
+			return
+		}
+		waiter := next.waiters[0]
+		next.waiters = next.waiters[1:]
+		next.active++
+		s.active++
+		close(waiter.ready)
+	}
+}
+
+func (s *partitionScheduler) acquire(ctx context.Context, label string) error {
+	s.mu.Lock()
+	p := s.partition(label)
+	s.seq++
+	waiter := &partitionWaiter{ready: make(chan struct{}), seq: s.seq}
+	p.waiters = append(p.waiters, waiter)
+	s.dispatch()
+	s.mu.Unlock()
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, w := range p.waiters {
+		if w == waiter {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			return ctx.Err()
+		}
+	}
+	// The slot was granted while the context ended; hand it to the next waiter.
+	p.active--
+	s.active--
+	s.dispatch()
+	return ctx.Err()
+}
+
+func (s *partitionScheduler) release(label string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.partition(label)
+	p.active--
+	s.active--
+	s.dispatch()
+}
+
+func (s *partitionScheduler) stats() map[string]PartitionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(map[string]PartitionStats, len(s.partitions))
+	for label, p := range s.partitions {
+		stats[label] = PartitionStats{Active: p.active, Waiting: len(p.waiters)}
+	}
+	return stats
+}
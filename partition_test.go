@@ -0,0 +1,95 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func acquireAsync(s *partitionScheduler, label string, granted chan<- string) {
+	go func() {
+		if s.acquire(context.Background(), label) == nil {
+			granted <- label
+		}
+	}()
+}
+
+func waitForWaiters(t *testing.T, s *partitionScheduler, label string, n int) {
+	deadline := time.Now().Add(time.Second)
+	for s.stats()[label].Waiting != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %v waiting for %v, got %+v", n, label, s.stats()[label])
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPartitionLimit(t *testing.T) {
+	s := newPartitionScheduler(10, func() int { return 0 })
+	s.configure("batch", 1, 2)
+	ctx := context.Background()
+	for range 2 {
+		if err := s.acquire(ctx, "batch"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err := s.acquire(timeout, "batch")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the third acquire to wait, got %v", err)
+	}
+	if err = s.acquire(ctx, "web"); err != nil {
+		t.Fatalf("expected other labels to be unaffected, got %v", err)
+	}
+	if stats := s.stats()["batch"]; stats.Active != 2 || stats.Waiting != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestPartitionDefaultLimit(t *testing.T) {
+	s := newPartitionScheduler(10, func() int { return 1 })
+	if err := s.acquire(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	granted := make(chan string, 1)
+	acquireAsync(s, "a", granted)
+	waitForWaiters(t, s, "a", 1)
+	s.release("a")
+	if label := <-granted; label != "a" {
+		t.Errorf("unexpected grant %v", label)
+	}
+}
+
+func TestPartitionWeightedFairness(t *testing.T) {
+	s := newPartitionScheduler(4, func() int { return 0 })
+	s.configure("heavy", 3, 0)
+	ctx := context.Background()
+	for range 4 {
+		if err := s.acquire(ctx, "noisy"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	granted := make(chan string, 10)
+	for i := range 3 {
+		acquireAsync(s, "noisy", granted)
+		waitForWaiters(t, s, "noisy", i+1)
+	}
+	for i := range 3 {
+		acquireAsync(s, "heavy", granted)
+		waitForWaiters(t, s, "heavy", i+1)
+	}
+	var order []string
+	for range 4 {
+		s.release("noisy")
+		order = append(order, <-granted)
+	}
+	// heavy holds nothing and has three times the weight, so it gets the first three slots.
+	expected := []string{"heavy", "heavy", "heavy", "noisy"}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected grants %v, got %v", expected, order)
+		}
+	}
+}
@@ -1,13 +1,15 @@
 package pg
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	log "github.com/sirupsen/logrus"
-	"io"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"io/fs"
 	"os"
 	"sort"
@@ -33,18 +35,52 @@ const (
 
 	EnvMigrationsEnabled = "DB_MIGRATIONS_ENABLED"
 
+	// EnvEnvironment selects the migration policy, e.g. "production", see Configuration.Environment.
+	EnvEnvironment = "DB_ENVIRONMENT"
+	EnvPolicyFile  = "DB_MIGRATION_POLICY_FILE"
+
 	EnvChangelogSchema        = "DB_CHANGELOG_SCHEMA"
 	EnvChangelogSchemaDefault = "public"
 
+	// EnvChangelogTable may hold placeholders of environment variables, e.g. "{SERVICE}_changelog",
+	// see ExpandTemplate.
 	EnvChangelogTable        = "DB_CHANGELOG_TABLE"
 	EnvChangelogTableDefault = "changelog"
 
+	EnvChangelogKey = "DB_CHANGELOG_KEY"
+
+	// EnvMigrationNaming selects Configuration.MigrationNaming, e.g. "golang-migrate".
+	EnvMigrationNaming            = "DB_MIGRATION_NAMING"
 	EnvMigrationsDirectory        = "DB_MIGRATIONS_DIRECTORY"
 	EnvMigrationsDirectoryDefault = "db"
 
+	EnvIdempotencyTable = "DB_IDEMPOTENCY_TABLE"
+
+	EnvConnectionLogLevel        = "DB_CONNECTION_LOG_LEVEL"
+	EnvConnectionLogLevelDefault = "debug"
+
+	EnvMinServerVersion = "DB_MIN_SERVER_VERSION"
+
+	// EnvExtensions is a comma separated list of extensions to ensure before migrating.
+	EnvExtensions = "DB_EXTENSIONS"
+
+	EnvSlowQueryThreshold = "DB_SLOW_QUERY_THRESHOLD"
+	EnvSlowQueryPlans     = "DB_SLOW_QUERY_PLANS"
+	// EnvSlowQueryPlanSampleRate is the fraction of slow queries whose plan is captured, e.g. 0.1.
+	EnvSlowQueryPlanSampleRate = "DB_SLOW_QUERY_PLAN_SAMPLE_RATE"
+
+	// EnvDestructiveGuard enables Configuration.DestructiveGuard, e.g. in production.
+	EnvDestructiveGuard = "DB_DESTRUCTIVE_GUARD"
+
 	statusCompleted migrationStatus = "COMPLETED"
 	statusError     migrationStatus = "ERROR"
 	statusNew       migrationStatus = "NEW"
+	// statusSkipped is reported for migrations that were already applied; it is never stored.
+	statusSkipped migrationStatus = "SKIPPED"
+	// statusHeld is reported for migrations waiting for approval; it is never stored.
+	statusHeld migrationStatus = "HELD"
+
+	downMigrationSuffix = ".down.sql"
 )
 
 type Configuration struct {
@@ -53,10 +89,88 @@ type Configuration struct {
 	Password string
 	Name     string
 
-	MigrationsEnabled   bool
+	MigrationsEnabled bool
+	// ChangelogSchema, ChangelogTable and IdempotencyTable are quoted, so they are case sensitive.
+	// CreateConfigurationFromEnv folds them to lower case.
 	ChangelogSchema     string
 	ChangelogTable      string
 	MigrationsDirectory string
+	IdempotencyTable    string
+	// ChangelogKey selects how versions are stored, ChangelogKeyText by default.
+	ChangelogKey ChangelogKey
+	// MigrationNaming is the file naming convention of the scripts, detected when empty.
+	MigrationNaming MigrationNaming
+	// MigrationsSource provides the scripts instead of MigrationsDirectory when set.
+	MigrationsSource Source
+	// MinServerVersion, e.g. "15", makes Migrate fail before applying anything on older servers.
+	// Scripts can require a version themselves with a "-- pg:min-version 15" line; they fail the
+	// same way unless SkipUnsupportedMigrations is set, which leaves them pending instead.
+	MinServerVersion          string
+	SkipUnsupportedMigrations bool
+	// DestructiveGuard rejects scripts that drop or truncate tables or delete without WHERE,
+	// unless they carry a "-- pg:allow-destructive" line. Nothing of the run is applied then.
+	DestructiveGuard bool
+	// GrantsFile names a YAML file of the migrations source applied with ApplyGrantsFile after
+	// every migration run, e.g. "grants.yaml".
+	GrantsFile string
+	// Extensions are created with EnsureExtensions before migrating.
+	Extensions []string
+	// Environment selects the MigrationPolicy, from PolicyFile, a YAML file of the migrations
+	// source, or the built-in ones for "production" and "development". Policy sets it directly.
+	Environment string
+	PolicyFile  string
+	Policy      *MigrationPolicy
+	// Approver, when set, is consulted before each migration is applied. A migration it does not
+	// approve is held with all after it: they are logged and counted as held, not applied, and
+	// Migrate succeeds with the approved ones.
+	Approver MigrationApprover
+	// PreflightChecks run before any migration is applied; Migrate fails with a *PreflightError
+	// listing every check that did not pass.
+	PreflightChecks []PreflightCheck
+
+	// Logger receives connection lifecycle events at ConnectionLogLevel. The standard logrus
+	// logger is used when it is nil.
+	Logger             log.FieldLogger
+	ConnectionLogLevel string
+
+	// AuditSink records every statement the migrator executes when set.
+	AuditSink AuditSink
+	// TracerProvider receives the migration spans. The global provider is used when it is nil.
+	TracerProvider trace.TracerProvider
+
+	// SlowQueryThreshold enables reporting statements that take at least this long. With
+	// SlowQueryPlans their plans are captured with EXPLAIN. OnSlowQuery receives the reports; they
+	// are logged through Logger when it is nil.
+	SlowQueryThreshold time.Duration
+	SlowQueryPlans     bool
+	OnSlowQuery        func(SlowQuery)
+	// SlowQueryPlanSampleRate is the fraction of slow queries whose plan is captured, bounding the
+	// overhead; 0 captures all of them.
+	SlowQueryPlanSampleRate float64
+	// SlowQueryAnalyze captures plans with EXPLAIN ANALYZE, running the statement again in a read
+	// only transaction that is rolled back. Only plain SELECTs without locking clauses or volatile
+	// functions such as nextval or pg_advisory_lock are run again, others fall back to EXPLAIN.
+	SlowQueryAnalyze bool
+	// SlowQueryAutoExplain loads auto_explain on every connection, so the server logs the plans
+	// of statements exceeding SlowQueryThreshold, sampled at SlowQueryPlanSampleRate. Connections
+	// work without it where the module cannot be loaded.
+	SlowQueryAutoExplain bool
+
+	// OnStartup receives the summary of ConnectWithConfig, also when migrating failed. The summary
+	// is logged through Logger when it is nil.
+	OnStartup func(StartupSummary)
+
+	// StatementCacheStats tracks the pgx statement cache of every connection, reported as
+	// pgutils.stmtcache metrics and by GetStatementCacheStats.
+	StatementCacheStats bool
+
+	// AfterConnect runs on every new connection, e.g. to register custom types such as
+	// vector.RegisterTypes.
+	AfterConnect func(ctx context.Context, conn *pgx.Conn) error
+
+	// envErr is why CreateConfigurationFromEnv could not read the environment, returned by
+	// ConnectWithConfig and Migrate.
+	envErr error
 }
 
 func CreateConfigurationFromEnv() Configuration {
@@ -82,32 +196,87 @@ func CreateConfigurationFromEnv() Configuration {
 		migrationsEnabled = false
 	}
 
-	changelogSchema := os.Getenv(EnvChangelogSchema)
+	// The changelog names are quoted in statements, so they are folded to lower case as postgres
+	// folded them when they were not quoted yet, and mixed case settings find the same tables.
+	changelogSchema, schemaErr := ExpandTemplate(os.Getenv(EnvChangelogSchema))
+	changelogSchema = strings.ToLower(changelogSchema)
 	if changelogSchema == "" {
 		changelogSchema = EnvChangelogSchemaDefault
 	}
-	changelogTable := os.Getenv(EnvChangelogTable)
+	changelogTable, tableErr := ExpandTemplate(os.Getenv(EnvChangelogTable))
+	changelogTable = strings.ToLower(changelogTable)
 	if changelogTable == "" {
 		changelogTable = EnvChangelogTableDefault
 	}
+	changelogKey := ChangelogKey(os.Getenv(EnvChangelogKey))
+	if changelogKey == "" {
+		changelogKey = ChangelogKeyText
+	}
 	migrationsDirectory := os.Getenv(EnvMigrationsDirectory)
 	if migrationsDirectory == "" {
 		migrationsDirectory = EnvMigrationsDirectoryDefault
 	}
+	migrationNaming := MigrationNaming(os.Getenv(EnvMigrationNaming))
+	if migrationNaming == "" {
+		migrationNaming = MigrationNamingAuto
+	}
+	idempotencyTable, idempotencyErr := ExpandTemplate(os.Getenv(EnvIdempotencyTable))
+	idempotencyTable = strings.ToLower(idempotencyTable)
+	connectionLogLevel := os.Getenv(EnvConnectionLogLevel)
+	if connectionLogLevel == "" {
+		connectionLogLevel = EnvConnectionLogLevelDefault
+	}
+	minServerVersion := os.Getenv(EnvMinServerVersion)
+	var extensions []string
+	if value := os.Getenv(EnvExtensions); value != "" {
+		extensions = Filter(Map(strings.Split(value, ","), strings.TrimSpace), func(name string) bool { return name != "" })
+	}
+	slowQueryThreshold, err := time.ParseDuration(os.Getenv(EnvSlowQueryThreshold))
+	if err != nil {
+		slowQueryThreshold = 0
+	}
+	slowQueryPlans, err := strconv.ParseBool(os.Getenv(EnvSlowQueryPlans))
+	if err != nil {
+		slowQueryPlans = false
+	}
+	slowQueryPlanSampleRate, err := strconv.ParseFloat(os.Getenv(EnvSlowQueryPlanSampleRate), 64)
+	if err != nil {
+		slowQueryPlanSampleRate = 0
+	}
+	environment := os.Getenv(EnvEnvironment)
+	policyFile := os.Getenv(EnvPolicyFile)
+	destructiveGuard, err := strconv.ParseBool(os.Getenv(EnvDestructiveGuard))
+	if err != nil {
+		destructiveGuard = false
+	}
 	return Configuration{
-		Address:             address,
-		Username:            username,
-		Password:            password,
-		Name:                name,
-		MigrationsEnabled:   migrationsEnabled,
-		ChangelogSchema:     changelogSchema,
-		ChangelogTable:      changelogTable,
-		MigrationsDirectory: migrationsDirectory,
+		Address:                 address,
+		Username:                username,
+		Password:                password,
+		Name:                    name,
+		MigrationsEnabled:       migrationsEnabled,
+		ChangelogSchema:         changelogSchema,
+		ChangelogTable:          changelogTable,
+		ChangelogKey:            changelogKey,
+		MigrationsDirectory:     migrationsDirectory,
+		MigrationNaming:         migrationNaming,
+		IdempotencyTable:        idempotencyTable,
+		MinServerVersion:        minServerVersion,
+		Extensions:              extensions,
+		ConnectionLogLevel:      connectionLogLevel,
+		SlowQueryThreshold:      slowQueryThreshold,
+		SlowQueryPlans:          slowQueryPlans,
+		SlowQueryPlanSampleRate: slowQueryPlanSampleRate,
+		DestructiveGuard:        destructiveGuard,
+		Environment:             environment,
+		PolicyFile:              policyFile,
+		envErr:                  errors.Join(schemaErr, tableErr, idempotencyErr),
 	}
 }
 
+// schemaTable returns the quoted changelog table.
 func (c Configuration) schemaTable() string {
-	return c.ChangelogSchema + "." + c.ChangelogTable
+	return QuoteIdentifier(c.ChangelogSchema) + "." + QuoteIdentifier(c.ChangelogTable)
 }
 
 func Connect() (*pgxpool.Pool, error) {
@@ -115,29 +284,59 @@ func Connect() (*pgxpool.Pool, error) {
 	return ConnectWithConfig(c)
 }
 
+func ConnectionString(c Configuration) string {
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", c.Username, c.Password, c.Address, c.Name)
+}
+
 func ConnectWithConfig(c Configuration) (*pgxpool.Pool, error) {
-	url := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", c.Username, c.Password, c.Address, c.Name)
-	config, err := pgxpool.ParseConfig(url)
+	if c.envErr != nil {
+		return nil, c.envErr
+	}
+	config, err := pgxpool.ParseConfig(ConnectionString(c))
 	if err != nil {
 		return nil, err
 	}
+	start := time.Now()
+	config.AfterConnect = c.AfterConnect
+	poolRef := configureConnectionLogging(config, c)
+	addQueryTracer(config, QueryBudgetTracer{})
+	configureSlowQueryLog(config, c, poolRef)
+	configureStatementCacheStats(config, c)
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, err
 	}
+	poolRef.Store(pool)
+	var migrations MigrationCounts
 	if c.MigrationsEnabled {
 		dm := createDatabaseMigrator(pool, c)
 		err = dm.Migrate()
-		if err != nil {
-			return nil, err
-		}
+		migrations = dm.counts
+	}
+	reportStartup(pool, c, migrations, start)
+	if err != nil {
+		return nil, err
 	}
 	return pool, nil
 }
 
+// Migrate applies the pending migrations regardless of c.MigrationsEnabled.
+func Migrate(pool *pgxpool.Pool, c Configuration) error {
+	return createDatabaseMigrator(pool, c).Migrate()
+}
+
 type databaseMigrator struct {
 	PgxPool       *pgxpool.Pool
 	Configuration Configuration
+	counts        MigrationCounts
+	serverVersion int
+	// holding is set once a migration was not approved.
+	holding bool
+	// failed is the migration whose script failed in this run.
+	failed *failedMigration
+	policy *MigrationPolicy
+	// latest is the version of the latest applied migration when the run started.
+	latest []int
 }
 
 func createDatabaseMigrator(pgxPool *pgxpool.Pool, config Configuration) *databaseMigrator {
@@ -151,10 +350,39 @@ type migration struct {
 	Id       []int
 	Name     string
 	Filename string
+	// Down is the file name of the down script, which may not exist.
+	Down string
+	// Repeatable migrations have no version and run again whenever their script changed.
+	Repeatable bool
 }
 
 func (dbm *databaseMigrator) Migrate() error {
-	err := dbm.initChangelogTable()
+	ctx, span := dbm.tracer().Start(context.Background(), "pgutils.migrate", trace.WithAttributes(
+		attribute.String("db.migrations.directory", dbm.Configuration.MigrationsDirectory),
+		attribute.String("db.migrations.changelog", dbm.Configuration.ChangelogSchema+"."+dbm.Configuration.ChangelogTable),
+	))
+	err := dbm.migrate(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func (dbm *databaseMigrator) migrate(ctx context.Context) error {
+	if dbm.Configuration.envErr != nil {
+		return dbm.Configuration.envErr
+	}
+	err := dbm.initPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	err = dbm.initChangelogTable()
+	if err != nil {
+		return err
+	}
+	err = dbm.autoBaseline()
+	if err != nil {
+		return err
+	}
+	err = dbm.initIdempotencyTable()
 	if err != nil {
 		return err
 	}
@@ -162,84 +390,159 @@ func (dbm *databaseMigrator) Migrate() error {
 	if err != nil {
 		return err
 	}
+	dbm.serverVersion, err = dbm.queryServerVersion()
+	if err != nil {
+		return err
+	}
+	if dbm.Configuration.MinServerVersion != "" {
+		required, err := parseServerVersion(dbm.Configuration.MinServerVersion)
+		if err != nil {
+			return err
+		}
+		err = checkServerVersion(dbm.serverVersion, required, "the configuration")
+		if err != nil {
+			return err
+		}
+	}
+	err = EnsureExtensions(ctx, dbm.audited(dbm.PgxPool), dbm.Configuration.Extensions...)
+	if err != nil {
+		return err
+	}
+	err = RunPreflightChecks(ctx, dbm.PgxPool, dbm.Configuration.PreflightChecks...)
+	if err != nil {
+		return err
+	}
 	tx, err := dbm.PgxPool.Begin(context.Background())
 	if err != nil {
 		return err
 	}
+	// Rolling back also on errors releases the changelog lock for the next run.
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	// Migrations only count as applied once the run commits.
+	committed := false
 	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback(context.Background())
-			panic(p)
+		if !committed {
+			dbm.counts.Applied = 0
 		}
 	}()
-	_, err = tx.Exec(context.Background(), dbm.replaceEnv("LOCK TABLE {SCHEMA_TABLE} IN ACCESS EXCLUSIVE MODE"))
+	_, err = dbm.exec(tx, dbm.replaceEnv("LOCK TABLE {SCHEMA_TABLE} IN ACCESS EXCLUSIVE MODE"))
+	if err != nil {
+		return err
+	}
+	dbm.latest, err = dbm.latestApplied(tx)
 	if err != nil {
 		return err
 	}
 	for _, migration := range migrations {
-		err = dbm.applyMigration(migration, tx)
+		err = dbm.applyMigrationTraced(ctx, migration, tx)
 		if err != nil {
+			_ = tx.Rollback(context.Background())
+			dbm.recordFailure()
 			return err
 		}
 	}
+	err = dbm.applyGrantsFile(ctx, tx)
+	if err != nil {
+		return err
+	}
 	err = tx.Commit(context.Background())
 	if err != nil {
 		return err
 	}
+	committed = true
 	return nil
 }
 
-func Map[T, R any](list []T, fn func(T) R) []R {
-	result := make([]R, 0, len(list))
-	for _, t := range list {
-		result = append(result, fn(t))
+func (dbm *databaseMigrator) applyMigration(migration migration, tx pgx.Tx) (migrationStatus, error) {
+	if migration.Repeatable {
+		return dbm.applyRepeatableMigration(migration, tx)
 	}
-	return result
-}
-
-func (dbm *databaseMigrator) applyMigration(migration migration, tx pgx.Tx) error {
 	log.Printf("Applying migration %v", migration.Filename)
 	id := strings.Join(Map(migration.Id, strconv.Itoa), ".")
 	status, err := dbm.getMigrationStatus(id, tx)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if status == statusCompleted {
 		log.Printf("Migration %v already applied", migration.Filename)
-		return nil
+		return statusSkipped, nil
 	}
-	scriptFile, err := os.Open(dbm.Configuration.MigrationsDirectory + string(os.PathSeparator) + migration.Filename)
+	err = dbm.checkOrder(migration)
 	if err != nil {
-		log.Printf("Error opening migration file %v: %v", migration.Filename, err)
-		return err
+		return "", err
 	}
-	defer func(scriptFile *os.File) {
-		_ = scriptFile.Close()
-	}(scriptFile)
-	bytes, err := io.ReadAll(scriptFile)
+	bytes, err := dbm.Configuration.source().Read(context.Background(), migration.Filename)
 	if err != nil {
 		log.Printf("Error reading migration file %v: %v", migration.Filename, err)
-		return err
+		return "", err
 	}
 	script := string(bytes)
-	_, migrationError := tx.Exec(context.Background(), script)
-	if migrationError != nil {
-		status = statusError
-	} else {
-		status = statusCompleted
+	approved, err := dbm.approve(context.Background(), tx, PendingMigration{
+		Version:  id,
+		Name:     migration.Name,
+		Filename: migration.Filename,
+		Script:   script,
+		Checksum: scriptChecksum(bytes),
+	})
+	if err != nil || !approved {
+		return statusHeld, err
 	}
-	log.Printf("Migration status: %v", status)
-	err = dbm.updateMigrationStatus(id, migration, status, tx)
+	required, err := requiredServerVersion(script)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("migration %v: %w", migration.Filename, err)
+	}
+	err = checkServerVersion(dbm.serverVersion, required, "migration "+migration.Filename)
+	if err != nil && dbm.Configuration.SkipUnsupportedMigrations {
+		log.Warnf("Skipping migration %v: %v", migration.Filename, err)
+		return statusSkipped, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	err = dbm.checkDestructive(migration.Filename, id, script)
+	if err != nil {
+		return "", err
+	}
+	_, err = dbm.exec(tx, script)
+	if err != nil {
+		// The failed statement aborted the transaction, so the failure is recorded by
+		// recordFailure once it is rolled back.
+		log.Printf("Migration status: %v", statusError)
+		dbm.failed = &failedMigration{id: id, migration: migration}
+		return statusError, newMigrationError(migration.Filename, id, script, err)
+	}
+	log.Printf("Migration status: %v", statusCompleted)
+	err = dbm.updateMigrationStatus(id, migration, statusCompleted, tx)
+	if err != nil {
+		return "", err
+	}
+	return statusCompleted, nil
+}
+
+type failedMigration struct {
+	id        string
+	migration migration
+}
+
+// recordFailure stores the ERROR status of the failed migration in a transaction of its own, as
+// the run rolled back, so it shows in the changelog for MarkResolved and DeleteFailed. Nothing
+// else of the run is kept.
+func (dbm *databaseMigrator) recordFailure() {
+	if dbm.failed == nil {
+		return
+	}
+	err := dbm.updateMigrationStatus(dbm.failed.id, dbm.failed.migration, statusError, dbm.PgxPool)
+	if err != nil {
+		log.Warnf("Error recording the failure of migration %v: %v", dbm.failed.migration.Filename, err)
 	}
-	return migrationError
 }
 
 func (dbm *databaseMigrator) getMigrationStatus(id string, tx pgx.Tx) (migrationStatus, error) {
 	//goland:noinspection SqlResolve
 	query := dbm.replaceEnv("SELECT status FROM {SCHEMA_TABLE} WHERE id = $1 FOR UPDATE")
-	row := tx.QueryRow(context.Background(), query, id)
+	row := dbm.queryRow(tx, query, id)
 	var migrationStatus migrationStatus
 	err := row.Scan(&migrationStatus)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -251,10 +554,10 @@ func (dbm *databaseMigrator) getMigrationStatus(id string, tx pgx.Tx) (migration
 	return migrationStatus, nil
 }
 
-func (dbm *databaseMigrator) updateMigrationStatus(id string, migration migration, status migrationStatus, tx pgx.Tx) error {
+func (dbm *databaseMigrator) updateMigrationStatus(id string, migration migration, status migrationStatus, q Querier) error {
 	//goland:noinspection SqlResolve
 	insert := dbm.replaceEnv("INSERT INTO {SCHEMA_TABLE} (id, name, filename, status, timestamp) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO UPDATE SET status = $4, timestamp = $5")
-	_, err := tx.Exec(context.Background(), insert, id, migration.Name, migration.Filename, status, time.Now())
+	_, err := dbm.exec(q, insert, id, migration.Name, migration.Filename, status, time.Now())
 	if err != nil {
 		log.Printf("Error inserting migration info %v: %v", migration.Filename, err)
 		return err
@@ -263,65 +566,50 @@ func (dbm *databaseMigrator) updateMigrationStatus(id string, migration migratio
 }
 
 func (dbm *databaseMigrator) getMigrations() ([]migration, error) {
-	migrationsDir := dbm.Configuration.MigrationsDirectory
-	entries, err := os.ReadDir(migrationsDir)
+	filenames, err := dbm.Configuration.source().List(context.Background())
 	if errors.Is(err, fs.ErrNotExist) {
-		log.Warnf("Directory %v does not exist", dbm.Configuration.MigrationsDirectory)
+		log.Warnf("Migrations not found: %v", err)
 		return make([]migration, 0), nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	naming, err := dbm.Configuration.MigrationNaming.resolve(filenames)
+	if err != nil {
+		return nil, err
+	}
 	migrations := make([]migration, 0)
-	for i := range entries {
-		entry := entries[i]
-		if !entry.IsDir() {
-			if strings.HasSuffix(entry.Name(), ".sql") {
-				parts := strings.Split(entry.Name(), "_")
-				ids := make([]int, 0)
-				for _, part := range parts {
-					v, err := strconv.Atoi(part)
-					if err == nil {
-						ids = append(ids, v)
-					} else {
-						break
-					}
-				}
-				names := make([]string, 0)
-				for i := 0; i < len(parts)-len(ids); i++ {
-					names = append(names, parts[i+len(ids)])
-				}
-				name := strings.TrimSuffix(strings.Join(names, " "), ".sql")
-				migration := migration{
-					Id:       ids,
-					Name:     name,
-					Filename: entry.Name(),
-				}
-				migrations = append(migrations, migration)
-			}
+	for _, filename := range filenames {
+		if migration, ok := naming.parse(filename); ok {
+			migrations = append(migrations, migration)
 		}
 	}
 	sort.Slice(migrations, func(i, j int) bool {
-		m1 := migrations[i].Id
-		m2 := migrations[j].Id
-		for i := 0; i < min(len(m1), len(m2)); i++ {
-			i1 := m1[i]
-			i2 := m2[i]
-			if i1 < i2 {
-				return true
-			}
-			if i1 > i2 {
-				return false
-			}
+		if migrations[i].Repeatable || migrations[j].Repeatable {
+			return !migrations[i].Repeatable || migrations[j].Repeatable && migrations[i].Name < migrations[j].Name
 		}
-		if len(m1) < len(m2) {
-			return true
-		}
-		return false
+		return compareMigrationIds(migrations[i].Id, migrations[j].Id) < 0
 	})
+	for i := 1; i < len(migrations); i++ {
+		previous, current := migrations[i-1], migrations[i]
+		if len(current.Id) > 0 && !current.Repeatable && compareMigrationIds(previous.Id, current.Id) == 0 {
+			return nil, fmt.Errorf("migrations %v and %v have the same version", previous.Filename, current.Filename)
+		}
+	}
 	return migrations, nil
 }
 
+// compareMigrationIds orders versions part by part, a version sorting before its extensions, e.g.
+// 1 < 1.2 < 2.
+func compareMigrationIds(m1 []int, m2 []int) int {
+	for i := 0; i < min(len(m1), len(m2)); i++ {
+		if m1[i] != m2[i] {
+			return cmp.Compare(m1[i], m2[i])
+		}
+	}
+	return cmp.Compare(len(m1), len(m2))
+}
+
 func (dbm *databaseMigrator) initChangelogTable() error {
 	exists, err := dbm.tableExists(dbm.Configuration.ChangelogSchema, dbm.Configuration.ChangelogTable)
 	if err != nil {
@@ -333,13 +621,13 @@ func (dbm *databaseMigrator) initChangelogTable() error {
 			return err
 		}
 	}
-	return nil
+	return dbm.initChangelogVersion()
 }
 
 func (dbm *databaseMigrator) tableExists(schema string, table string) (bool, error) {
 	//goland:noinspection SqlResolve
 	querySql := "SELECT EXISTS (SELECT FROM pg_tables WHERE schemaname = $1 AND tablename = $2)"
-	row := dbm.PgxPool.QueryRow(context.Background(), querySql, schema, table)
+	row := dbm.queryRow(dbm.PgxPool, querySql, schema, table)
 	var exists bool
 	err := row.Scan(&exists)
 	if err != nil {
@@ -370,7 +658,7 @@ func (dbm *databaseMigrator) createChangelogTable() error {
 			timestamp TIMESTAMPTZ NOT NULL
 		);
 	`
-	_, err = tx.Exec(context.Background(), dbm.replaceEnv(script))
+	_, err = dbm.exec(tx, dbm.replaceEnv(script))
 	if err != nil {
 		return err
 	}
@@ -383,15 +671,22 @@ func (dbm *databaseMigrator) createChangelogTable() error {
 
 func (dbm *databaseMigrator) replaceEnv(s string) string {
 	s = strings.ReplaceAll(s, "{SCHEMA_TABLE}", dbm.Configuration.schemaTable())
-	s = strings.ReplaceAll(s, "{SCHEMA}", dbm.Configuration.ChangelogSchema)
+	s = strings.ReplaceAll(s, "{SCHEMA}", QuoteIdentifier(dbm.Configuration.ChangelogSchema))
+	s = strings.ReplaceAll(s, "{IDEMPOTENCY_TABLE}", QuoteIdentifier(dbm.Configuration.IdempotencyTable))
 	return s
 }
 
 func DoInTransaction[R any](pool *pgxpool.Pool, fn func(tx pgx.Tx) (*R, error)) (*R, error) {
+	start := time.Now()
+	committed := false
+	defer func() {
+		recordTx(start, committed)
+	}()
 	tx, err := pool.Begin(context.Background())
 	if err != nil {
 		return nil, err
 	}
+	defer trackTx(tx)()
 	defer func(tx pgx.Tx, ctx context.Context) {
 		_ = tx.Rollback(ctx)
 	}(tx, context.Background())
@@ -403,24 +698,13 @@ func DoInTransaction[R any](pool *pgxpool.Pool, fn func(tx pgx.Tx) (*R, error))
 	if err != nil {
 		return nil, err
 	}
+	committed = true
 	return result, nil
 }
 
 func DoInTransactionNoResult(pool *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
-	tx, err := pool.Begin(context.Background())
-	if err != nil {
-		return err
-	}
-	defer func(tx pgx.Tx, ctx context.Context) {
-		_ = tx.Rollback(ctx)
-	}(tx, context.Background())
-	err = fn(tx)
-	if err != nil {
-		return err
-	}
-	err = tx.Commit(context.Background())
-	if err != nil {
-		return err
-	}
-	return nil
+	_, err := DoInTransaction(pool, func(tx pgx.Tx) (*struct{}, error) {
+		return nil, fn(tx)
+	})
+	return err
 }
@@ -1,53 +1,24 @@
-package pg
+package pg_test
 
 import (
 	"context"
-	"github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/wait"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
 	"testing"
-	"time"
 )
 
 func TestConnect(t *testing.T) {
-	containerRequest := testcontainers.ContainerRequest{
-		Image:        "postgres:17",
-		ExposedPorts: []string{"5432/tcp"},
-		Env: map[string]string{
-			"POSTGRES_USER":     "test_user",
-			"POSTGRES_PASSWORD": "test_password",
-			"POSTGRES_DB":       "test_db",
-		},
-		WaitingFor: wait.ForListeningPort("5432/tcp").WithPollInterval(time.Second),
-	}
-	postgres, err := testcontainers.GenericContainer(context.Background(), testcontainers.GenericContainerRequest{
-		ContainerRequest: containerRequest,
-		Started:          true,
-	})
-	if err != nil {
-		t.Error(err)
-	}
-	defer func(postgres testcontainers.Container, ctx context.Context) {
-		_ = postgres.Terminate(ctx)
-	}(postgres, context.Background())
-
-	port, err := postgres.MappedPort(context.Background(), "5432")
-	if err != nil {
-		t.Error(err)
-	}
-	t.Setenv(EnvDatabaseAddress, "localhost:"+port.Port())
-	t.Setenv(EnvDatabaseUsername, "test_user")
-	t.Setenv(EnvDatabasePassword, "test_password")
-	t.Setenv(EnvDatabaseName, "test_db")
-	t.Setenv(EnvMigrationsDirectory, "testdb")
-	t.Setenv(EnvMigrationsEnabled, "true")
-	pool, err := Connect()
+	pgtest.StartPostgres(t, pgtest.WithEnv())
+	t.Setenv(pg.EnvMigrationsDirectory, "testdb")
+	t.Setenv(pg.EnvMigrationsEnabled, "true")
+	pool, err := pg.Connect()
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
 	rows, err := pool.Query(context.Background(), "SELECT id, name, description FROM testtable")
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 	data := make([]map[string]interface{}, 0)
 	for rows.Next() {
@@ -65,7 +36,7 @@ func TestConnect(t *testing.T) {
 		})
 	}
 	if len(data) != 1 {
-		t.Error("data len is not 1")
+		t.Fatal("data len is not 1")
 	}
 	if data[0]["id"] != 1 {
 		t.Error("id should be 1")
@@ -77,3 +48,17 @@ func TestConnect(t *testing.T) {
 		t.Error("description should be name1")
 	}
 }
+
+func TestMixedCaseChangelogFromEnv(t *testing.T) {
+	pgtest.StartPostgres(t, pgtest.WithEnv())
+	t.Setenv(pg.EnvMigrationsDirectory, "testdb")
+	t.Setenv(pg.EnvMigrationsEnabled, "true")
+	t.Setenv(pg.EnvChangelogTable, "Service_Changelog")
+	pool, err := pg.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	// Created unquoted, as earlier versions did, the table is found under its folded name.
+	pgtest.AssertQueryReturns(t, pool, "SELECT count(*) FROM service_changelog", [][]any{{int64(3)}})
+}
@@ -0,0 +1,94 @@
+package pgtest
+
+import (
+	"context"
+	"fmt"
+	pg "github.com/msumera/pgutils"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// AssertRowCount fails the test unless table holds exactly expected rows.
+func AssertRowCount(t testing.TB, q pg.Querier, table string, expected int64) {
+	t.Helper()
+	sql, args := pg.Select().From(table).Build()
+	count, err := pg.Count(context.Background(), q, sql, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != expected {
+		t.Errorf("expected %v rows in %v, got %v", expected, table, count)
+	}
+}
+
+// AssertNoRows fails the test if the query returns any row.
+func AssertNoRows(t testing.TB, q pg.Querier, sql string, args ...any) {
+	t.Helper()
+	actual := queryRows(t, q, sql, args...)
+	if len(actual) > 0 {
+		t.Errorf("expected no rows from %v, got:\n%v", sql, formatRows(actual))
+	}
+}
+
+// AssertQueryReturns fails the test unless the query returns exactly the expected rows, in order.
+// Values are compared after converting them to the type of the expected value, so untyped
+// constants like 1 match int4 as well as int8 columns.
+func AssertQueryReturns(t testing.TB, q pg.Querier, sql string, expected [][]any, args ...any) {
+	t.Helper()
+	actual := queryRows(t, q, sql, args...)
+	if rowsEqual(expected, actual) {
+		return
+	}
+	t.Errorf("unexpected rows from %v:\n%v", sql, lineDiff(formatRows(expected), formatRows(actual)))
+}
+
+func queryRows(t testing.TB, q pg.Querier, sql string, args ...any) [][]any {
+	t.Helper()
+	values, err := collectRows(q, sql, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func rowsEqual(expected [][]any, actual [][]any) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if len(expected[i]) != len(actual[i]) {
+			return false
+		}
+		for j := range expected[i] {
+			if !valuesEqual(expected[i][j], actual[i][j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func valuesEqual(expected any, actual any) bool {
+	if expected == nil || actual == nil {
+		return expected == nil && actual == nil
+	}
+	e := reflect.ValueOf(expected)
+	a := reflect.ValueOf(actual)
+	if a.Type() != e.Type() && a.Type().ConvertibleTo(e.Type()) && a.Kind() != reflect.String && e.Kind() != reflect.String {
+		a = a.Convert(e.Type())
+	}
+	return reflect.DeepEqual(e.Interface(), a.Interface())
+}
+
+func formatRows(rows [][]any) string {
+	lines := pg.Map(rows, func(row []any) string {
+		return strings.Join(pg.Map(row, func(value any) string {
+			if value == nil {
+				return "NULL"
+			}
+			return fmt.Sprintf("%#v", value)
+		}), " | ")
+	})
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,25 @@
+package pgtest
+
+import "testing"
+
+func TestRowsEqual(t *testing.T) {
+	if !rowsEqual([][]any{{1, "a", nil}}, [][]any{{int32(1), "a", nil}}) {
+		t.Error("numeric values should be compared by value")
+	}
+	if rowsEqual([][]any{{1}}, [][]any{{int64(2)}}) {
+		t.Error("different values should not be equal")
+	}
+	if rowsEqual([][]any{{"1"}}, [][]any{{int32(1)}}) {
+		t.Error("strings should not be converted from numbers")
+	}
+	if rowsEqual([][]any{{1}}, [][]any{{1}, {2}}) {
+		t.Error("different row counts should not be equal")
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	db := StartPostgres(t, WithMigrations("../testdb"))
+	AssertRowCount(t, db.Pool, "testtable", 1)
+	AssertQueryReturns(t, db.Pool, "SELECT id, name FROM testtable WHERE id = $1", [][]any{{1, "name1"}}, 1)
+	AssertNoRows(t, db.Pool, "SELECT id FROM testtable WHERE id = $1", 2)
+}
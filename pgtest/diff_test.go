@@ -0,0 +1,10 @@
+package pgtest
+
+import "testing"
+
+func TestLineDiff(t *testing.T) {
+	diff := lineDiff("a\nb\nc", "a\nc\nd")
+	if diff != "  a\n- b\n  c\n+ d\n" {
+		t.Errorf("unexpected diff:\n%v", diff)
+	}
+}
@@ -0,0 +1,78 @@
+package pgtest
+
+import (
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+type Backend int
+
+const (
+	// BackendContainer runs postgres in a Docker container through testcontainers.
+	BackendContainer Backend = iota
+	// BackendEmbedded runs a postgres distribution downloaded and cached on the host, for CI
+	// environments without Docker.
+	BackendEmbedded
+)
+
+var embeddedVersions = map[string]embeddedpostgres.PostgresVersion{
+	"15": embeddedpostgres.V15,
+	"14": embeddedpostgres.V14,
+	"13": embeddedpostgres.V13,
+	"12": embeddedpostgres.V12,
+	"11": embeddedpostgres.V11,
+	"10": embeddedpostgres.V10,
+}
+
+func WithBackend(backend Backend) Option {
+	return func(o *options) {
+		o.backend = backend
+	}
+}
+
+func startEmbedded(t testing.TB, o options) string {
+	t.Helper()
+	port, err := freePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtimePath := t.TempDir()
+	config := embeddedpostgres.DefaultConfig().
+		Username(o.username).
+		Password(o.password).
+		Database(o.database).
+		Port(port).
+		RuntimePath(runtimePath).
+		DataPath(filepath.Join(runtimePath, "data")).
+		StartParameters(o.settings)
+	if o.versionSet {
+		version, ok := embeddedVersions[o.version]
+		if !ok {
+			version = embeddedpostgres.PostgresVersion(o.version)
+		}
+		config = config.Version(version)
+	}
+	postgres := embeddedpostgres.NewDatabase(config)
+	err = postgres.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = postgres.Stop()
+	})
+	return "localhost:" + strconv.Itoa(int(port))
+}
+
+func freePort() (uint32, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer func(listener net.Listener) {
+		_ = listener.Close()
+	}(listener)
+	return uint32(listener.Addr().(*net.TCPAddr).Port), nil
+}
@@ -0,0 +1,44 @@
+package pgtest
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"testing"
+	"time"
+)
+
+const eventuallyPollInterval = 50 * time.Millisecond
+
+// Eventually polls the query until predicate holds for its rows, failing the test if it does not
+// within timeout. Query errors are retried as well and the last one is reported on timeout.
+func Eventually(t testing.TB, q pg.Querier, sql string, predicate func(rows [][]any) bool, timeout time.Duration, args ...any) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	var rows [][]any
+	var err error
+	for {
+		rows, err = collectRows(q, sql, args...)
+		if err == nil && predicate(rows) {
+			return
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(eventuallyPollInterval)
+	}
+	if err != nil {
+		t.Fatalf("condition on %v not met within %v, last error: %v", sql, timeout, err)
+	}
+	t.Fatalf("condition on %v not met within %v, last rows:\n%v", sql, timeout, formatRows(rows))
+}
+
+func collectRows(q pg.Querier, sql string, args ...any) ([][]any, error) {
+	rows, err := q.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) ([]any, error) {
+		return row.Values()
+	})
+}
@@ -0,0 +1,18 @@
+package pgtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventually(t *testing.T) {
+	fq := NewFakeQuerier()
+	fq.On("SELECT status").Returns([]string{"status"}, []any{"pending"}).Times(2)
+	fq.On("SELECT status").Returns([]string{"status"}, []any{"done"})
+	Eventually(t, fq, "SELECT status FROM jobs", func(rows [][]any) bool {
+		return len(rows) == 1 && rows[0][0] == "done"
+	}, time.Second)
+	if len(fq.Calls()) != 3 {
+		t.Errorf("expected 3 polls, got %v", len(fq.Calls()))
+	}
+}
@@ -0,0 +1,240 @@
+package pgtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	pg "github.com/msumera/pgutils"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var _ pg.Querier = (*FakeQuerier)(nil)
+
+// FakeCall records a statement executed against a FakeQuerier.
+type FakeCall struct {
+	SQL  string
+	Args []any
+}
+
+// FakeResult scripts the outcome of statements whose SQL contains a fragment.
+type FakeResult struct {
+	fragment string
+	columns  []string
+	rows     [][]any
+	tag      string
+	err      error
+	times    int
+	used     int
+}
+
+// Returns scripts the rows returned by Query and QueryRow.
+func (r *FakeResult) Returns(columns []string, rows ...[]any) *FakeResult {
+	r.columns = columns
+	r.rows = rows
+	return r
+}
+
+// ReturnsTag scripts the command tag returned by Exec, e.g. "UPDATE 2".
+func (r *FakeResult) ReturnsTag(tag string) *FakeResult {
+	r.tag = tag
+	return r
+}
+
+// ReturnsError makes the matching statements fail with err.
+func (r *FakeResult) ReturnsError(err error) *FakeResult {
+	r.err = err
+	return r
+}
+
+// Times limits the result to the next n matching statements. By default it matches any number.
+func (r *FakeResult) Times(n int) *FakeResult {
+	r.times = n
+	return r
+}
+
+// FakeQuerier implements pg.Querier without a database, returning scripted results and
+// recording every call. Statements without a scripted result fail.
+type FakeQuerier struct {
+	mu      sync.Mutex
+	results []*FakeResult
+	calls   []FakeCall
+}
+
+func NewFakeQuerier() *FakeQuerier {
+	return &FakeQuerier{}
+}
+
+// On scripts the result for statements whose whitespace-normalized SQL contains fragment.
+// Results are matched in the order they were scripted.
+func (f *FakeQuerier) On(fragment string) *FakeResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := &FakeResult{fragment: normalizeSQL(fragment)}
+	f.results = append(f.results, result)
+	return result
+}
+
+// Calls returns the statements executed so far.
+func (f *FakeQuerier) Calls() []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeCall(nil), f.calls...)
+}
+
+func (f *FakeQuerier) match(sql string, args []any) (*FakeResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, FakeCall{SQL: sql, Args: args})
+	normalized := normalizeSQL(sql)
+	for _, result := range f.results {
+		if result.times > 0 && result.used >= result.times {
+			continue
+		}
+		if strings.Contains(normalized, result.fragment) {
+			result.used++
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("pgtest: unexpected statement: %v", sql)
+}
+
+func (f *FakeQuerier) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	result, err := f.match(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if result.err != nil {
+		return pgconn.CommandTag{}, result.err
+	}
+	return pgconn.NewCommandTag(result.tag), nil
+}
+
+func (f *FakeQuerier) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	result, err := f.match(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	return &fakeRows{columns: result.columns, rows: result.rows, tag: result.tag, index: -1}, nil
+}
+
+func (f *FakeQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	rows, err := f.Query(ctx, sql, args...)
+	return &fakeRow{rows: rows, err: err}
+}
+
+func normalizeSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]any
+	tag     string
+	index   int
+	err     error
+	closed  bool
+}
+
+func (r *fakeRows) Close() {
+	r.closed = true
+}
+
+func (r *fakeRows) Err() error {
+	return r.err
+}
+
+func (r *fakeRows) CommandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag(r.tag)
+}
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription {
+	return pg.Map(r.columns, func(column string) pgconn.FieldDescription {
+		return pgconn.FieldDescription{Name: column}
+	})
+}
+
+func (r *fakeRows) Next() bool {
+	if r.closed || r.err != nil {
+		return false
+	}
+	r.index++
+	if r.index >= len(r.rows) {
+		r.closed = true
+		return false
+	}
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	row := r.rows[r.index]
+	if len(dest) != len(row) {
+		r.err = fmt.Errorf("pgtest: scanning %v values into %v destinations", len(row), len(dest))
+		return r.err
+	}
+	for i, value := range row {
+		err := assignFake(dest[i], value)
+		if err != nil {
+			r.err = fmt.Errorf("pgtest: column %v: %w", i, err)
+			return r.err
+		}
+	}
+	return nil
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return r.rows[r.index], nil
+}
+
+func (r *fakeRows) RawValues() [][]byte {
+	return nil
+}
+
+func (r *fakeRows) Conn() *pgx.Conn {
+	return nil
+}
+
+type fakeRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r *fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		return pgx.ErrNoRows
+	}
+	return r.rows.Scan(dest...)
+}
+
+func assignFake(dest any, value any) error {
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return fmt.Errorf("destination %T is not a non-nil pointer", dest)
+	}
+	target = target.Elem()
+	if value == nil {
+		target.SetZero()
+		return nil
+	}
+	source := reflect.ValueOf(value)
+	if target.Kind() == reflect.Pointer && source.Type().ConvertibleTo(target.Type().Elem()) {
+		pointer := reflect.New(target.Type().Elem())
+		pointer.Elem().Set(source.Convert(target.Type().Elem()))
+		target.Set(pointer)
+		return nil
+	}
+	if !source.Type().ConvertibleTo(target.Type()) {
+		return fmt.Errorf("cannot assign %T to %v", value, target.Type())
+	}
+	target.Set(source.Convert(target.Type()))
+	return nil
+}
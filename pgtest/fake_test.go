@@ -0,0 +1,57 @@
+package pgtest
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"testing"
+)
+
+func TestFakeQuerier(t *testing.T) {
+	fq := NewFakeQuerier()
+	fq.On("SELECT name FROM users").Returns([]string{"name"}, []any{"alice"}, []any{"bob"})
+	fq.On("SELECT count(*)").Returns([]string{"count"}, []any{2})
+	fq.On("UPDATE users").ReturnsTag("UPDATE 1").Times(1)
+	fq.On("UPDATE users").ReturnsError(errors.New("boom"))
+
+	names, err := pg.ScanColumn[string](context.Background(), fq, "SELECT name FROM users ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[1] != "bob" {
+		t.Errorf("unexpected names: %v", names)
+	}
+	count, err := pg.Count(context.Background(), fq, "SELECT * FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("unexpected count: %v", count)
+	}
+	tag, err := fq.Exec(context.Background(), "UPDATE users SET name = $1", "carol")
+	if err != nil || tag.RowsAffected() != 1 {
+		t.Errorf("unexpected exec result: %v, %v", tag, err)
+	}
+	_, err = fq.Exec(context.Background(), "UPDATE users SET name = $1", "dave")
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("expected injected error, got %v", err)
+	}
+	_, err = fq.Exec(context.Background(), "DELETE FROM users")
+	if err == nil {
+		t.Error("unscripted statement should fail")
+	}
+	calls := fq.Calls()
+	if len(calls) != 5 || calls[2].Args[0] != "carol" {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+}
+
+func TestFakeQuerierNoRows(t *testing.T) {
+	fq := NewFakeQuerier()
+	fq.On("SELECT id").Returns([]string{"id"})
+	_, err := pg.Scalar[int](context.Background(), fq, "SELECT id FROM users")
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected ErrNoRows, got %v", err)
+	}
+}
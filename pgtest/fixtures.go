@@ -0,0 +1,285 @@
+package pgtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Fixtures holds the inserted rows, including generated columns, by table and label.
+type Fixtures map[string]map[string]map[string]any
+
+// Get returns a column of an inserted fixture row, e.g. f.Get("users", "alice", "id").
+func (f Fixtures) Get(table string, label string, column string) any {
+	return f[table][label][column]
+}
+
+type fixtureFile struct {
+	table string
+	rows  map[string]map[string]any
+	// dependsOn lists tables referenced through foreign keys.
+	dependsOn []string
+}
+
+// LoadFixtures inserts the fixtures found in dir and returns the inserted rows.
+//
+// Each .yml, .yaml or .json file holds the rows of the table it is named after, keyed by a label:
+//
+//	alice:
+//	  name: Alice
+//
+// A string value of the form "@table.label.column" refers to a column of another fixture row,
+// e.g. user_id: "@users.alice.id"; tables are inserted in an order that satisfies the references.
+// Foreign keys between the tables are honoured as well. Use "@@" for a literal leading @.
+// Sequences of the loaded tables are then moved past the inserted values. Finally, .sql files are
+// executed in name order.
+func LoadFixtures(t testing.TB, q pg.Querier, dir string) Fixtures {
+	t.Helper()
+	fixtures, err := loadFixtures(context.Background(), q, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixtures
+}
+
+func loadFixtures(ctx context.Context, q pg.Querier, dir string) (Fixtures, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]fixtureFile, 0)
+	scripts := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		ext := filepath.Ext(entry.Name())
+		switch ext {
+		case ".sql":
+			scripts = append(scripts, path)
+		case ".yml", ".yaml", ".json":
+			file, err := readFixtureFile(path, ext)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+	}
+	fixtures, err := insertFixtureFiles(ctx, q, files)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(scripts)
+	for _, script := range scripts {
+		bytes, err := os.ReadFile(script)
+		if err != nil {
+			return nil, err
+		}
+		_, err = q.Exec(ctx, string(bytes))
+		if err != nil {
+			return nil, fmt.Errorf("fixture %v: %w", script, err)
+		}
+	}
+	return fixtures, nil
+}
+
+// insertFixtureFiles inserts files in dependency order and moves the sequences of their tables
+// past the inserted values.
+func insertFixtureFiles(ctx context.Context, q pg.Querier, files []fixtureFile) (Fixtures, error) {
+	err := addForeignKeyDependencies(ctx, q, files)
+	if err != nil {
+		return nil, err
+	}
+	files, err = sortFixtureFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	fixtures := make(Fixtures)
+	for _, file := range files {
+		err = insertFixtureFile(ctx, q, file, fixtures)
+		if err != nil {
+			return nil, err
+		}
+		_, err = pg.FixTableSequences(ctx, q, file.table)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fixtures, nil
+}
+
+// addForeignKeyDependencies records which of the other files each file references through
+// foreign keys. Table names are compared by the relation they resolve to, so "users" and
+// "public.users" are the same table.
+func addForeignKeyDependencies(ctx context.Context, q pg.Querier, files []fixtureFile) error {
+	tables := pg.Map(files, func(f fixtureFile) string { return f.table })
+	//goland:noinspection SqlResolve
+	rows, err := q.Query(ctx, `
+		SELECT t.name, r.name
+		FROM unnest($1::text[]) AS t(name)
+			JOIN pg_constraint k ON k.conrelid = to_regclass(t.name) AND k.contype = 'f'
+			JOIN unnest($1::text[]) AS r(name) ON k.confrelid = to_regclass(r.name)
+		WHERE t.name <> r.name
+	`, tables)
+	if err != nil {
+		return err
+	}
+	dependencies := make(map[string][]string)
+	var table, referenced string
+	_, err = pgx.ForEachRow(rows, []any{&table, &referenced}, func() error {
+		dependencies[table] = append(dependencies[table], referenced)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := range files {
+		files[i].dependsOn = dependencies[files[i].table]
+	}
+	return nil
+}
+
+func readFixtureFile(path string, ext string) (fixtureFile, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return fixtureFile{}, err
+	}
+	rows := make(map[string]map[string]any)
+	if ext == ".json" {
+		err = json.Unmarshal(bytes, &rows)
+	} else {
+		err = yaml.Unmarshal(bytes, &rows)
+	}
+	if err != nil {
+		return fixtureFile{}, fmt.Errorf("fixture %v: %w", path, err)
+	}
+	return fixtureFile{
+		table: strings.TrimSuffix(filepath.Base(path), ext),
+		rows:  rows,
+	}, nil
+}
+
+// fixtureReference parses "@table.label.column". Tables may be schema qualified.
+func fixtureReference(value any) (table string, label string, column string, ok bool) {
+	s, isString := value.(string)
+	if !isString || !strings.HasPrefix(s, "@") || strings.HasPrefix(s, "@@") {
+		return "", "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(s, "@"), ".")
+	if len(parts) < 3 {
+		return "", "", "", false
+	}
+	n := len(parts)
+	return strings.Join(parts[:n-2], "."), parts[n-2], parts[n-1], true
+}
+
+// sortFixtureFiles orders files so that referenced tables are inserted first.
+func sortFixtureFiles(files []fixtureFile) ([]fixtureFile, error) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].table < files[j].table
+	})
+	sorted := make([]fixtureFile, 0, len(files))
+	done := make(map[string]bool)
+	for len(sorted) < len(files) {
+		progress := false
+		for _, file := range files {
+			if done[file.table] || !fixtureDependenciesDone(file, done) {
+				continue
+			}
+			sorted = append(sorted, file)
+			done[file.table] = true
+			progress = true
+		}
+		if !progress {
+			pending := make([]string, 0)
+			for _, file := range files {
+				if !done[file.table] {
+					pending = append(pending, file.table)
+				}
+			}
+			return nil, fmt.Errorf("fixtures have circular or missing references: %v", strings.Join(pending, ", "))
+		}
+	}
+	return sorted, nil
+}
+
+func fixtureDependenciesDone(file fixtureFile, done map[string]bool) bool {
+	for _, table := range file.dependsOn {
+		if !done[table] {
+			return false
+		}
+	}
+	for _, row := range file.rows {
+		for _, value := range row {
+			table, _, _, ok := fixtureReference(value)
+			if ok && table != file.table && !done[table] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func insertFixtureFile(ctx context.Context, q pg.Querier, file fixtureFile, fixtures Fixtures) error {
+	labels := make([]string, 0, len(file.rows))
+	for label := range file.rows {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	fixtures[file.table] = make(map[string]map[string]any)
+	for _, label := range labels {
+		row := file.rows[label]
+		columns := make([]string, 0, len(row))
+		for column := range row {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		values := make([]any, 0, len(columns))
+		for _, column := range columns {
+			value, err := resolveFixtureValue(row[column], fixtures)
+			if err != nil {
+				return fmt.Errorf("fixture %v.%v: %w", file.table, label, err)
+			}
+			values = append(values, value)
+		}
+		sql, args := pg.InsertInto(file.table).Columns(columns...).Values(values...).Build()
+		rows, err := q.Query(ctx, sql+" RETURNING *", args...)
+		if err != nil {
+			return fmt.Errorf("fixture %v.%v: %w", file.table, label, err)
+		}
+		inserted, err := pgx.CollectExactlyOneRow(rows, pgx.RowToMap)
+		if err != nil {
+			return fmt.Errorf("fixture %v.%v: %w", file.table, label, err)
+		}
+		fixtures[file.table][label] = inserted
+	}
+	return nil
+}
+
+func resolveFixtureValue(value any, fixtures Fixtures) (any, error) {
+	if s, ok := value.(string); ok && strings.HasPrefix(s, "@@") {
+		return strings.TrimPrefix(s, "@"), nil
+	}
+	table, label, column, ok := fixtureReference(value)
+	if !ok {
+		return value, nil
+	}
+	row, ok := fixtures[table][label]
+	if !ok {
+		return nil, fmt.Errorf("unknown fixture %v.%v", table, label)
+	}
+	resolved, ok := row[column]
+	if !ok {
+		return nil, fmt.Errorf("fixture %v.%v has no column %v", table, label, column)
+	}
+	return resolved, nil
+}
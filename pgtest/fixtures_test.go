@@ -0,0 +1,65 @@
+package pgtest
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"testing"
+)
+
+func TestFixtureReference(t *testing.T) {
+	table, label, column, ok := fixtureReference("@public.users.alice.id")
+	if !ok || table != "public.users" || label != "alice" || column != "id" {
+		t.Errorf("unexpected reference: %v %v %v %v", table, label, column, ok)
+	}
+	if _, _, _, ok = fixtureReference("@@escaped.value.here"); ok {
+		t.Error("escaped value should not be a reference")
+	}
+	if _, _, _, ok = fixtureReference("alice@example.com"); ok {
+		t.Error("plain string should not be a reference")
+	}
+}
+
+func TestSortFixtureFiles(t *testing.T) {
+	files := []fixtureFile{
+		{table: "comments", rows: map[string]map[string]any{"c": {"post_id": "@posts.p.id"}}},
+		{table: "posts", rows: map[string]map[string]any{"p": {"user_id": "@users.u.id"}}},
+		{table: "users", rows: map[string]map[string]any{"u": {"name": "u"}}},
+	}
+	sorted, err := sortFixtureFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := pg.Map(sorted, func(f fixtureFile) string { return f.table })
+	if tables[0] != "users" || tables[1] != "posts" || tables[2] != "comments" {
+		t.Errorf("unexpected order: %v", tables)
+	}
+	_, err = sortFixtureFiles([]fixtureFile{{table: "a", rows: map[string]map[string]any{"x": {"b": "@b.y.id"}}}})
+	if err == nil {
+		t.Error("missing reference should fail")
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	db := StartPostgres(t)
+	_, err := db.Pool.Exec(context.Background(), `
+		CREATE TABLE users (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE posts (id SERIAL PRIMARY KEY, user_id INT NOT NULL REFERENCES users (id), title TEXT NOT NULL);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := LoadFixtures(t, db.Pool, "testdata/fixtures")
+	if fixtures.Get("posts", "welcome", "user_id") != fixtures.Get("users", "alice", "id") {
+		t.Error("reference should resolve to the generated id")
+	}
+	if fixtures.Get("posts", "welcome", "title") != "@welcome" {
+		t.Error("escaped value should keep a single @")
+	}
+	count, err := pg.Count(context.Background(), db.Pool, "SELECT * FROM posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 posts, got %v", count)
+	}
+}
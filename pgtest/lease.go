@@ -0,0 +1,78 @@
+package pgtest
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// DatabasePool hands out pre-created, fully migrated databases living in a single server to
+// parallel tests. A leased database is recreated from the template when the test finishes, so the
+// next test leasing it starts from a clean state. A database that cannot be recreated is dropped
+// from the pool.
+type DatabasePool struct {
+	template  *Database
+	available chan *Database
+
+	mu sync.Mutex
+	// size is the number of databases the pool still holds, leased or available.
+	size int
+}
+
+// NewDatabasePool starts a server with opts, migrates a template database and clones size
+// databases from it. Everything is cleaned up when t finishes, so create the pool in a parent test
+// or in TestMain through a long-lived testing.TB.
+func NewDatabasePool(t testing.TB, size int, opts ...Option) *DatabasePool {
+	t.Helper()
+	template := StartPostgres(t, append(opts, WithTemplate())...)
+	p := &DatabasePool{
+		template:  template,
+		available: make(chan *Database, size),
+		size:      size,
+	}
+	for i := 0; i < size; i++ {
+		db, err := template.createClone(template.Configuration.Name + "_lease_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.available <- db
+	}
+	t.Cleanup(func() {
+		p.mu.Lock()
+		remaining := p.size
+		p.mu.Unlock()
+		for i := 0; i < remaining; i++ {
+			_ = template.dropClone(<-p.available)
+		}
+	})
+	return p
+}
+
+// Lease blocks until a database is available and returns it to the pool, reset, when t finishes.
+func (p *DatabasePool) Lease(t testing.TB) *Database {
+	t.Helper()
+	p.mu.Lock()
+	empty := p.size == 0
+	p.mu.Unlock()
+	if empty {
+		t.Fatal("no database left in the pool")
+	}
+	db := <-p.available
+	t.Cleanup(func() {
+		name := db.Configuration.Name
+		err := p.template.dropClone(db)
+		if err == nil {
+			db, err = p.template.createClone(name)
+		}
+		if err != nil {
+			// The pool of the dropped database is closed, so it cannot be leased again.
+			t.Errorf("resetting leased database %v: %v", name, err)
+			p.mu.Lock()
+			p.size--
+			p.mu.Unlock()
+			return
+		}
+		p.available <- db
+	})
+	return db
+}
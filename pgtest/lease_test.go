@@ -0,0 +1,29 @@
+package pgtest
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"strconv"
+	"testing"
+)
+
+func TestDatabasePool(t *testing.T) {
+	pool := NewDatabasePool(t, 2, WithMigrations("../testdb"))
+	for i := 0; i < 4; i++ {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Parallel()
+			db := pool.Lease(t)
+			count, err := pg.Count(context.Background(), db.Pool, "SELECT * FROM testtable")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 {
+				t.Errorf("leased database should be reset, got %v rows", count)
+			}
+			_, err = db.Pool.Exec(context.Background(), "INSERT INTO testtable (id, name) VALUES (2, 'name2')")
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package pgtest
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"testing"
+)
+
+type MigrationTestOptions struct {
+	// Down reverts all migrations with their down scripts after applying them and then applies
+	// them again, checking that the resulting schema is unchanged.
+	Down bool
+	// Options configure the database the migrations run against.
+	Options []Option
+}
+
+// TestMigrations applies the migrations in dir to a fresh database and fails the test with the
+// offending file and SQL error when one of them does not apply cleanly.
+func TestMigrations(t testing.TB, dir string, opts MigrationTestOptions) *Database {
+	t.Helper()
+	db := StartPostgres(t, opts.Options...)
+	c := db.Configuration
+	c.MigrationsDirectory = dir
+	err := pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatalf("applying migrations from %v: %v", dir, err)
+	}
+	if !opts.Down {
+		return db
+	}
+	schema, err := DescribeSchema(context.Background(), db.Pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.MigrateDown(db.Pool, c)
+	if err != nil {
+		t.Fatalf("reverting migrations from %v: %v", dir, err)
+	}
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatalf("reapplying migrations from %v: %v", dir, err)
+	}
+	reapplied, err := DescribeSchema(context.Background(), db.Pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reapplied != schema {
+		t.Errorf("schema differs after reverting and reapplying migrations from %v:\n%v", dir, lineDiff(schema, reapplied))
+	}
+	return db
+}
@@ -0,0 +1,7 @@
+package pgtest
+
+import "testing"
+
+func TestTestMigrations(t *testing.T) {
+	TestMigrations(t, "../testdb", MigrationTestOptions{Down: true})
+}
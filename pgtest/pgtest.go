@@ -0,0 +1,234 @@
+package pgtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	DefaultImage    = "postgres"
+	DefaultVersion  = "17"
+	DefaultUsername = "test_user"
+	DefaultPassword = "test_password"
+	DefaultDatabase = "test_db"
+)
+
+type options struct {
+	image               string
+	version             string
+	username            string
+	password            string
+	database            string
+	migrationsDirectory string
+	setEnv              bool
+	template            bool
+	backend             Backend
+	versionSet          bool
+	reuseLabel          string
+	settings            map[string]string
+}
+
+type Option func(*options)
+
+// WithVersion selects the postgres version. For the container backend it is the image tag, e.g.
+// "16" or "17-alpine"; for the embedded backend a major version such as "15" or a full release.
+func WithVersion(version string) Option {
+	return func(o *options) {
+		o.version = version
+		o.versionSet = true
+	}
+}
+
+// WithImage replaces the postgres image, e.g. with one bundling extensions.
+func WithImage(image string) Option {
+	return func(o *options) {
+		o.image = image
+	}
+}
+
+func WithCredentials(username string, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.password = password
+	}
+}
+
+func WithDatabase(name string) Option {
+	return func(o *options) {
+		o.database = name
+	}
+}
+
+// WithMigrations applies the migrations in directory before the pool is returned.
+func WithMigrations(directory string) Option {
+	return func(o *options) {
+		o.migrationsDirectory = directory
+	}
+}
+
+// WithSettings starts the server with the given configuration parameters, e.g.
+// {"shared_preload_libraries": "pg_stat_statements"} for settings that need a restart.
+func WithSettings(settings map[string]string) Option {
+	return func(o *options) {
+		o.settings = settings
+	}
+}
+
+// WithEnv exports the connection settings through the DB_* variables for the duration of the test,
+// so code calling pg.Connect picks up the test database.
+func WithEnv() Option {
+	return func(o *options) {
+		o.setEnv = true
+	}
+}
+
+type Database struct {
+	Configuration pg.Configuration
+	Pool          *pgxpool.Pool
+	// Container is nil for the embedded backend.
+	Container testcontainers.Container
+}
+
+// StartPostgres starts a postgres server on the selected backend and connects to it. The server
+// and pool are cleaned up when the test finishes. With the container backend the test is skipped
+// when Docker is not available.
+func StartPostgres(t testing.TB, opts ...Option) *Database {
+	t.Helper()
+	o := options{
+		backend:  BackendContainer,
+		image:    DefaultImage,
+		version:  DefaultVersion,
+		username: DefaultUsername,
+		password: DefaultPassword,
+		database: DefaultDatabase,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var address string
+	var container testcontainers.Container
+	if o.backend == BackendEmbedded {
+		address = startEmbedded(t, o)
+	} else {
+		address, container = startContainer(t, o)
+	}
+
+	c := pg.CreateConfigurationFromEnv()
+	c.Address = address
+	c.Username = o.username
+	c.Password = o.password
+	c.Name = o.database
+	c.MigrationsEnabled = o.migrationsDirectory != ""
+	if c.MigrationsEnabled {
+		c.MigrationsDirectory = o.migrationsDirectory
+	}
+	if o.setEnv {
+		setEnv(t, c)
+	}
+	if o.reuseLabel != "" {
+		ensureDatabase(t, c)
+	}
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	db := &Database{
+		Configuration: c,
+		Pool:          pool,
+		Container:     container,
+	}
+	if o.template {
+		db.makeTemplate(t)
+	}
+	return db
+}
+
+func startContainer(t testing.TB, o options) (string, testcontainers.Container) {
+	t.Helper()
+	skipIfDockerUnavailable(t)
+	containerRequest := testcontainers.ContainerRequest{
+		Image:        o.image + ":" + o.version,
+		ExposedPorts: []string{"5432/tcp"},
+		Env: map[string]string{
+			"POSTGRES_USER":     o.username,
+			"POSTGRES_PASSWORD": o.password,
+			"POSTGRES_DB":       o.database,
+		},
+		WaitingFor: wait.ForListeningPort("5432/tcp").WithPollInterval(time.Second),
+	}
+	if len(o.settings) > 0 {
+		containerRequest.Cmd = []string{"postgres"}
+		for _, name := range slices.Sorted(maps.Keys(o.settings)) {
+			containerRequest.Cmd = append(containerRequest.Cmd, "-c", name+"="+o.settings[name])
+		}
+	}
+	if o.reuseLabel != "" {
+		return startReusedContainer(t, o, containerRequest)
+	}
+	postgres, err := testcontainers.GenericContainer(context.Background(), testcontainers.GenericContainerRequest{
+		ContainerRequest: containerRequest,
+		Started:          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = postgres.Terminate(context.Background())
+	})
+	return containerAddress(t, postgres)
+}
+
+func containerAddress(t testing.TB, postgres testcontainers.Container) (string, testcontainers.Container) {
+	t.Helper()
+	host, err := postgres.Host(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := postgres.MappedPort(context.Background(), "5432")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%s:%s", host, port.Port()), postgres
+}
+
+func setEnv(t testing.TB, c pg.Configuration) {
+	t.Setenv(pg.EnvDatabaseAddress, c.Address)
+	t.Setenv(pg.EnvDatabaseUsername, c.Username)
+	t.Setenv(pg.EnvDatabasePassword, c.Password)
+	t.Setenv(pg.EnvDatabaseName, c.Name)
+	t.Setenv(pg.EnvMigrationsDirectory, c.MigrationsDirectory)
+}
+
+func skipIfDockerUnavailable(t testing.TB) {
+	t.Helper()
+	err := dockerHealth()
+	if err != nil {
+		t.Skipf("Docker is not available: %v", err)
+	}
+}
+
+func dockerHealth() (err error) {
+	// testcontainers panics instead of returning an error when it cannot locate a Docker host.
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return err
+	}
+	defer func(provider testcontainers.GenericProvider) {
+		_ = provider.Close()
+	}(provider)
+	return provider.Health(context.Background())
+}
@@ -0,0 +1,18 @@
+package pgtest
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStartPostgres(t *testing.T) {
+	db := StartPostgres(t, WithVersion("16"), WithMigrations("../testdb"))
+	var count int
+	err := db.Pool.QueryRow(context.Background(), "SELECT count(*) FROM testtable").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 row, got %v", count)
+	}
+}
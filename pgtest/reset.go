@@ -0,0 +1,93 @@
+package pgtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"strings"
+)
+
+type ResetOptions struct {
+	// Schemas whose tables are truncated. Defaults to the public schema.
+	Schemas []string
+	// Exclude lists further schema qualified tables to keep, e.g. "public.countries". Reset fails
+	// when a kept table references one that is truncated, rather than emptying it too.
+	Exclude []string
+	// Configuration identifies the changelog table, which is never truncated. Defaults to the
+	// configuration from the environment.
+	Configuration *pg.Configuration
+	// FixturesDirectory is loaded with LoadFixtures semantics after truncation when set.
+	FixturesDirectory string
+	// Seed runs after truncation and fixture loading when set.
+	Seed func(ctx context.Context, q pg.Querier) error
+}
+
+// Reset truncates all tables in the managed schemas except the changelogs, restarts their
+// identities and optionally re-seeds them, all in one transaction.
+func Reset(ctx context.Context, pool *pgxpool.Pool, opts ResetOptions) error {
+	schemas := opts.Schemas
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+	c := pg.CreateConfigurationFromEnv()
+	if opts.Configuration != nil {
+		c = *opts.Configuration
+	}
+	changelog := c.ChangelogSchema + "." + c.ChangelogTable
+	exclude := append([]string{changelog, changelog + "_repeatable"}, opts.Exclude...)
+	return pg.DoInTransactionNoResult(pool, func(tx pgx.Tx) error {
+		//goland:noinspection SqlResolve
+		rows, err := tx.Query(ctx, `
+			SELECT format('%I.%I', schemaname, tablename)::regclass::oid, schemaname, tablename FROM pg_tables
+			WHERE schemaname = ANY($1) AND NOT (schemaname || '.' || tablename = ANY($2))
+			ORDER BY schemaname, tablename
+		`, schemas, exclude)
+		if err != nil {
+			return err
+		}
+		var oids []uint32
+		tables, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (string, error) {
+			var oid uint32
+			var schema, table string
+			err := row.Scan(&oid, &schema, &table)
+			oids = append(oids, oid)
+			return pgx.Identifier{schema, table}.Sanitize(), err
+		})
+		if err != nil {
+			return err
+		}
+		var referencing, referenced string
+		err = tx.QueryRow(ctx, `
+			SELECT conrelid::regclass::text, confrelid::regclass::text FROM pg_constraint
+			WHERE contype = 'f' AND confrelid = ANY($1) AND NOT conrelid = ANY($1)
+			ORDER BY 1, 2 LIMIT 1
+		`, oids).Scan(&referencing, &referenced)
+		if err == nil {
+			return fmt.Errorf("kept table %v references %v, exclude both or neither", referencing, referenced)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if len(tables) > 0 {
+			// All tables are truncated by one statement, so references among them need no CASCADE,
+			// which would also empty the kept tables.
+			_, err = tx.Exec(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY")
+			if err != nil {
+				return err
+			}
+		}
+		if opts.FixturesDirectory != "" {
+			_, err = loadFixtures(ctx, tx, opts.FixturesDirectory)
+			if err != nil {
+				return err
+			}
+		}
+		if opts.Seed != nil {
+			return opts.Seed(ctx, tx)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package pgtest
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	db := StartPostgres(t, WithMigrations("../testdb"))
+	err := Reset(context.Background(), db.Pool, ResetOptions{Configuration: &db.Configuration})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := pg.Count(context.Background(), db.Pool, "SELECT * FROM testtable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected empty table, got %v rows", count)
+	}
+	count, err = pg.Count(context.Background(), db.Pool, "SELECT * FROM "+db.Configuration.ChangelogSchema+"."+db.Configuration.ChangelogTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Error("changelog should not be truncated")
+	}
+}
+
+func TestResetKeepsExcludedTables(t *testing.T) {
+	db := StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE countries (code TEXT PRIMARY KEY);
+		CREATE TABLE cities (name TEXT, country TEXT REFERENCES countries);
+		INSERT INTO countries VALUES ('CH');
+		INSERT INTO cities VALUES ('Bern', 'CH');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Reset(ctx, db.Pool, ResetOptions{Configuration: &db.Configuration, Exclude: []string{"public.countries"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertRowCount(t, db.Pool, "countries", 1)
+	AssertRowCount(t, db.Pool, "cities", 0)
+
+	_, err = db.Pool.Exec(ctx, "INSERT INTO cities VALUES ('Bern', 'CH')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Reset(ctx, db.Pool, ResetOptions{Configuration: &db.Configuration, Exclude: []string{"public.cities"}})
+	if err == nil {
+		t.Error("expected a kept table referencing a truncated one to fail")
+	}
+	AssertRowCount(t, db.Pool, "cities", 1)
+	AssertRowCount(t, db.Pool, "countries", 1)
+}
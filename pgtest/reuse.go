@@ -0,0 +1,65 @@
+package pgtest
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	pg "github.com/msumera/pgutils"
+	"github.com/testcontainers/testcontainers-go"
+	"sync"
+	"testing"
+)
+
+const (
+	reusedContainerPrefix = "pgtest-"
+	duplicateDatabase     = "42P04"
+)
+
+var reuseLocks sync.Map
+
+// WithReuse shares one long-lived container between all tests, packages and runs using the same
+// label instead of starting a container per test. The container is not terminated when the test
+// finishes; to keep it across runs disable the testcontainers reaper with
+// TESTCONTAINERS_RYUK_DISABLED=true. Since tests share the server, give each package its own
+// database with WithDatabase, which is created on first use, or isolate tests with WithTemplate
+// and Database.Clone.
+func WithReuse(label string) Option {
+	return func(o *options) {
+		o.reuseLabel = label
+	}
+}
+
+func startReusedContainer(t testing.TB, o options, request testcontainers.ContainerRequest) (string, testcontainers.Container) {
+	t.Helper()
+	// testcontainers resolves name conflicts between processes; the lock keeps concurrent tests in
+	// this process from racing on the creation in the first place.
+	lock, _ := reuseLocks.LoadOrStore(o.reuseLabel, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+	request.Name = reusedContainerPrefix + o.reuseLabel
+	postgres, err := testcontainers.GenericContainer(context.Background(), testcontainers.GenericContainerRequest{
+		ContainerRequest: request,
+		Started:          true,
+		Reuse:            true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return containerAddress(t, postgres)
+}
+
+// ensureDatabase creates the configured database unless it exists, as a reused container only
+// created the database requested by whoever started it.
+func ensureDatabase(t testing.TB, c pg.Configuration) {
+	t.Helper()
+	db := &Database{Configuration: c}
+	err := db.maintenanceExec(context.Background(), "CREATE DATABASE "+pgx.Identifier{c.Name}.Sanitize())
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == duplicateDatabase {
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
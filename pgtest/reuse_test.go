@@ -0,0 +1,14 @@
+package pgtest
+
+import "testing"
+
+func TestReuse(t *testing.T) {
+	first := StartPostgres(t, WithReuse("pgtest-reuse-test"), WithDatabase("first"))
+	second := StartPostgres(t, WithReuse("pgtest-reuse-test"), WithDatabase("second"))
+	if first.Container.GetContainerID() != second.Container.GetContainerID() {
+		t.Error("containers with the same label should be shared")
+	}
+	if first.Configuration.Name == second.Configuration.Name {
+		t.Error("each database should be created separately")
+	}
+}
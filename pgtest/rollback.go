@@ -0,0 +1,32 @@
+package pgtest
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"testing"
+)
+
+// WithRollback runs fn inside a transaction that is always rolled back, keeping tests isolated
+// without truncating tables.
+func WithRollback(t testing.TB, pool *pgxpool.Pool, fn func(tx pgx.Tx)) {
+	t.Helper()
+	tx, err := pool.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	fn(tx)
+}
+
+// WithRollbackQuerier is WithRollback for code written against pg.Querier. The context carries the
+// transaction, so repositories embedding pg.Repository join it too.
+func WithRollbackQuerier(t testing.TB, pool *pgxpool.Pool, fn func(ctx context.Context, q pg.Querier)) {
+	t.Helper()
+	WithRollback(t, pool, func(tx pgx.Tx) {
+		fn(pg.ContextWithTx(context.Background(), tx), tx)
+	})
+}
@@ -0,0 +1,27 @@
+package pgtest
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"testing"
+)
+
+func TestWithRollback(t *testing.T) {
+	db := StartPostgres(t, WithMigrations("../testdb"))
+	WithRollback(t, db.Pool, func(tx pgx.Tx) {
+		_, err := tx.Exec(context.Background(), "INSERT INTO testtable (id, name) VALUES (2, 'name2')")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	WithRollbackQuerier(t, db.Pool, func(ctx context.Context, q pg.Querier) {
+		count, err := pg.Count(ctx, q, "SELECT * FROM testtable")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("insert should have been rolled back, got %v rows", count)
+		}
+	})
+}
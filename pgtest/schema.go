@@ -0,0 +1,112 @@
+package pgtest
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// EnvUpdateGolden makes AssertSchema rewrite the golden file instead of comparing against it.
+const EnvUpdateGolden = "PGTEST_UPDATE_GOLDEN"
+
+const userTablesFilter = "c.relkind IN ('r', 'p') AND n.nspname NOT LIKE 'pg\\_%' AND n.nspname <> 'information_schema'"
+
+// AssertSchema compares the tables, columns, constraints and indexes of the database with
+// goldenFile and fails with a line diff when they differ. Run the test with PGTEST_UPDATE_GOLDEN=true
+// to write the current schema to the golden file.
+func AssertSchema(t testing.TB, q pg.Querier, goldenFile string) {
+	t.Helper()
+	actual, err := DescribeSchema(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, _ := strconv.ParseBool(os.Getenv(EnvUpdateGolden))
+	if update {
+		err = os.MkdirAll(filepath.Dir(goldenFile), 0o755)
+		if err == nil {
+			err = os.WriteFile(goldenFile, []byte(actual), 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(goldenFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden file %v does not exist, run with %v=true to create it", goldenFile, EnvUpdateGolden)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != actual {
+		t.Errorf("schema does not match %v, run with %v=true to update it:\n%v", goldenFile, EnvUpdateGolden, lineDiff(string(expected), actual))
+	}
+}
+
+// DescribeSchema renders the user tables of the database in a stable, diff friendly text form.
+func DescribeSchema(ctx context.Context, q pg.Querier) (string, error) {
+	tables := make(map[string][]string)
+	queries := []string{
+		//goland:noinspection SqlResolve
+		`SELECT n.nspname || '.' || c.relname,
+			'column ' || a.attname || ' ' || format_type(a.atttypid, a.atttypmod)
+				|| CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END
+				|| COALESCE(' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attnum > 0 AND NOT a.attisdropped AND ` + userTablesFilter + `
+		ORDER BY 1, a.attnum`,
+		//goland:noinspection SqlResolve
+		`SELECT n.nspname || '.' || c.relname, 'constraint ' || k.conname || ' ' || pg_get_constraintdef(k.oid)
+		FROM pg_constraint k
+			JOIN pg_class c ON c.oid = k.conrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE ` + userTablesFilter + `
+		ORDER BY 1, k.conname`,
+		//goland:noinspection SqlResolve
+		`SELECT n.nspname || '.' || c.relname, 'index ' || i.relname || ' ' || pg_get_indexdef(i.oid)
+		FROM pg_index x
+			JOIN pg_class c ON c.oid = x.indrelid
+			JOIN pg_class i ON i.oid = x.indexrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE ` + userTablesFilter + `
+		ORDER BY 1, i.relname`,
+	}
+	for _, query := range queries {
+		rows, err := q.Query(ctx, query)
+		if err != nil {
+			return "", err
+		}
+		var table, line string
+		_, err = pgx.ForEachRow(rows, []any{&table, &line}, func() error {
+			tables[table] = append(tables[table], line)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString("table " + name + "\n")
+		for _, line := range tables[name] {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,8 @@
+package pgtest
+
+import "testing"
+
+func TestAssertSchema(t *testing.T) {
+	db := StartPostgres(t, WithMigrations("../testdb"))
+	AssertSchema(t, db.Pool, "testdata/schema.golden")
+}
@@ -0,0 +1,106 @@
+package pgtest
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"strings"
+	"testing"
+)
+
+// Ref refers to a column of another seeded row, typically its generated id.
+type Ref struct {
+	Table  string
+	Label  string
+	Column string
+}
+
+// Seeder collects rows to insert with a fluent API:
+//
+//	seeded := pgtest.Seed(t, q).
+//		Table("posts").Row("welcome", map[string]any{"user_id": pgtest.Ref{"users", "alice", "id"}}).
+//		Table("users").Row("alice", map[string]any{"name": "Alice"}).
+//		Insert()
+//	userId := seeded.Get("users", "alice", "id")
+//
+// Tables are inserted in an order satisfying references and foreign keys.
+type Seeder struct {
+	t      testing.TB
+	q      pg.Querier
+	tables []*SeedTable
+}
+
+type SeedTable struct {
+	seeder *Seeder
+	name   string
+	rows   map[string]map[string]any
+}
+
+func Seed(t testing.TB, q pg.Querier) *Seeder {
+	return &Seeder{t: t, q: q}
+}
+
+// Table returns the rows builder of a table, creating it on first use.
+func (s *Seeder) Table(name string) *SeedTable {
+	for _, table := range s.tables {
+		if table.name == name {
+			return table
+		}
+	}
+	table := &SeedTable{seeder: s, name: name, rows: make(map[string]map[string]any)}
+	s.tables = append(s.tables, table)
+	return table
+}
+
+// Row adds a row under label, which Ref values of other rows and Fixtures.Get use to find it.
+func (st *SeedTable) Row(label string, values map[string]any) *SeedTable {
+	st.rows[label] = values
+	return st
+}
+
+// Table switches to another table, so rows of several tables can be added in one chain.
+func (st *SeedTable) Table(name string) *SeedTable {
+	return st.seeder.Table(name)
+}
+
+// Insert inserts the collected rows and returns them including generated columns.
+func (st *SeedTable) Insert() Fixtures {
+	st.seeder.t.Helper()
+	return st.seeder.Insert()
+}
+
+// Insert inserts the collected rows and returns them including generated columns.
+func (s *Seeder) Insert() Fixtures {
+	s.t.Helper()
+	files := pg.Map(s.tables, func(table *SeedTable) fixtureFile {
+		rows := make(map[string]map[string]any, len(table.rows))
+		for label, values := range table.rows {
+			rows[label] = seedValues(values)
+		}
+		return fixtureFile{table: table.name, rows: rows}
+	})
+	fixtures, err := insertFixtureFiles(context.Background(), s.q, files)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return fixtures
+}
+
+// seedValues converts seed values to fixture values, turning Ref into a fixture reference and
+// escaping strings that would otherwise be read as one.
+func seedValues(values map[string]any) map[string]any {
+	converted := make(map[string]any, len(values))
+	for column, value := range values {
+		switch v := value.(type) {
+		case Ref:
+			converted[column] = "@" + v.Table + "." + v.Label + "." + v.Column
+		case string:
+			if strings.HasPrefix(v, "@") {
+				v = "@" + v
+			}
+			converted[column] = v
+		default:
+			converted[column] = value
+		}
+	}
+	return converted
+}
@@ -0,0 +1,36 @@
+package pgtest
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSeedValues(t *testing.T) {
+	values := seedValues(map[string]any{"user_id": Ref{"users", "alice", "id"}, "handle": "@alice", "age": 3})
+	if values["user_id"] != "@users.alice.id" || values["handle"] != "@@alice" || values["age"] != 3 {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	db := StartPostgres(t)
+	_, err := db.Pool.Exec(context.Background(), `
+		CREATE TABLE users (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE audits (id SERIAL PRIMARY KEY, user_id INT REFERENCES users (id));
+		CREATE TABLE posts (id SERIAL PRIMARY KEY, user_id INT NOT NULL REFERENCES users (id), title TEXT NOT NULL);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeded := Seed(t, db.Pool).
+		Table("posts").Row("welcome", map[string]any{"user_id": Ref{"users", "alice", "id"}, "title": "@hello"}).
+		Table("audits").Row("empty", map[string]any{"user_id": nil}).
+		Table("users").Row("alice", map[string]any{"name": "Alice"}).
+		Insert()
+	if seeded.Get("posts", "welcome", "user_id") != seeded.Get("users", "alice", "id") {
+		t.Error("reference should resolve to the generated id")
+	}
+	if seeded.Get("posts", "welcome", "title") != "@hello" {
+		t.Error("strings should be inserted verbatim")
+	}
+}
@@ -0,0 +1,40 @@
+package pgtest
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+)
+
+func (d *Database) snapshotName(name string) string {
+	return d.Configuration.Name + "_snapshot_" + name
+}
+
+// Snapshot saves the current state of the database under name as a template database, so a
+// scenario can branch from it repeatedly with Restore. Taking a snapshot replaces an earlier one
+// with the same name. The pool's connections are closed and transparently reopened, so no
+// connection may be held while the snapshot is taken.
+func (d *Database) Snapshot(ctx context.Context, name string) error {
+	snapshot := pgx.Identifier{d.snapshotName(name)}.Sanitize()
+	err := d.maintenanceExec(ctx, "DROP DATABASE IF EXISTS "+snapshot)
+	if err != nil {
+		return err
+	}
+	d.Pool.Reset()
+	return d.maintenanceExec(ctx, "CREATE DATABASE "+snapshot+" TEMPLATE "+pgx.Identifier{d.Configuration.Name}.Sanitize())
+}
+
+// Restore replaces the database with the snapshot taken under name. Connections to the database
+// are terminated; the pool reconnects on next use.
+func (d *Database) Restore(ctx context.Context, name string) error {
+	database := pgx.Identifier{d.Configuration.Name}.Sanitize()
+	d.Pool.Reset()
+	err := d.maintenanceExec(ctx, "DROP DATABASE "+database+" WITH (FORCE)")
+	if err != nil {
+		return err
+	}
+	return d.maintenanceExec(ctx, "CREATE DATABASE "+database+" TEMPLATE "+pgx.Identifier{d.snapshotName(name)}.Sanitize())
+}
+
+func (d *Database) DropSnapshot(ctx context.Context, name string) error {
+	return d.maintenanceExec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{d.snapshotName(name)}.Sanitize())
+}
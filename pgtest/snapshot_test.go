@@ -0,0 +1,37 @@
+package pgtest
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	db := StartPostgres(t, WithMigrations("../testdb"))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "INSERT INTO testtable (id, name) VALUES (2, 'name2')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Snapshot(ctx, "prepared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err = db.Pool.Exec(ctx, "DELETE FROM testtable")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.Restore(ctx, "prepared")
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err := pg.Count(ctx, db.Pool, "SELECT * FROM testtable")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Errorf("expected restored rows, got %v", count)
+		}
+	}
+}
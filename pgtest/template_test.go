@@ -0,0 +1,28 @@
+package pgtest
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	template := StartPostgres(t, WithMigrations("../testdb"), WithTemplate())
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			db := template.Clone(t)
+			_, err := db.Pool.Exec(context.Background(), "DELETE FROM testtable")
+			if err != nil {
+				t.Fatal(err)
+			}
+			count, err := pg.Count(context.Background(), db.Pool, "SELECT * FROM testtable")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 0 {
+				t.Errorf("expected empty table, got %v rows", count)
+			}
+		})
+	}
+}
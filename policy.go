@@ -0,0 +1,233 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+)
+
+// DestructivePolicy is how a MigrationPolicy treats scripts that drop or truncate tables or
+// delete without WHERE.
+type DestructivePolicy string
+
+const (
+	DestructiveAllow DestructivePolicy = "allow"
+	// DestructiveGuard requires them to be acknowledged, as Configuration.DestructiveGuard does.
+	DestructiveGuard DestructivePolicy = "guard"
+	// DestructiveForbid rejects them even when acknowledged.
+	DestructiveForbid DestructivePolicy = "forbid"
+)
+
+var (
+	ErrApprovalRequired    = errors.New("the migration policy requires an approver")
+	ErrOutOfOrderMigration = errors.New("migration is older than the latest applied one")
+	ErrCleanForbidden      = errors.New("the migration policy does not allow cleaning")
+)
+
+// MigrationPolicy bundles the safeguards of the migrator for an environment. It is selected by
+// Configuration.Environment from Configuration.PolicyFile or the built-in ProductionPolicy and
+// DevelopmentPolicy.
+type MigrationPolicy struct {
+	// RequireApproval fails migrating when no Configuration.Approver is set.
+	RequireApproval bool              `yaml:"require_approval"`
+	Destructive     DestructivePolicy `yaml:"destructive"`
+	// AllowOutOfOrder applies pending migrations older than the latest applied one; they fail the
+	// run otherwise. Without a policy they are applied.
+	AllowOutOfOrder bool `yaml:"allow_out_of_order"`
+	// AllowClean permits Clean.
+	AllowClean bool `yaml:"allow_clean"`
+	// AutoBaseline records all migrations as applied instead of running them when the changelog is
+	// empty but its schema already holds tables, see Baseline. The bookkeeping tables of this
+	// package and the tables of extensions do not count. DevelopmentPolicy sets it.
+	AutoBaseline bool `yaml:"auto_baseline"`
+}
+
+var (
+	ProductionPolicy = MigrationPolicy{
+		RequireApproval: true,
+		Destructive:     DestructiveForbid,
+	}
+	DevelopmentPolicy = MigrationPolicy{
+		Destructive:     DestructiveAllow,
+		AllowOutOfOrder: true,
+		AllowClean:      true,
+		AutoBaseline:    true,
+	}
+)
+
+// builtinPolicies are used for environments the policy file does not name.
+var builtinPolicies = map[string]MigrationPolicy{
+	"production":  ProductionPolicy,
+	"prod":        ProductionPolicy,
+	"development": DevelopmentPolicy,
+	"dev":         DevelopmentPolicy,
+}
+
+// ParsePolicyFile parses policies keyed by environment, e.g.
+//
+//	production:
+//	  require_approval: true
+//	  destructive: forbid
+//	staging:
+//	  destructive: guard
+func ParsePolicyFile(data []byte) (map[string]MigrationPolicy, error) {
+	var policies map[string]MigrationPolicy
+	err := yaml.Unmarshal(data, &policies)
+	if err != nil {
+		return nil, err
+	}
+	for environment, policy := range policies {
+		switch policy.Destructive {
+		case "", DestructiveAllow, DestructiveGuard, DestructiveForbid:
+		default:
+			return nil, fmt.Errorf("environment %v: unknown destructive policy %q", environment, policy.Destructive)
+		}
+	}
+	return policies, nil
+}
+
+// resolvePolicy returns c.Policy, or the policy of c.Environment, or nil without an environment.
+func (c Configuration) resolvePolicy(ctx context.Context) (*MigrationPolicy, error) {
+	if c.Policy != nil || c.Environment == "" {
+		return c.Policy, nil
+	}
+	if c.PolicyFile != "" {
+		data, err := c.source().Read(ctx, c.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		policies, err := ParsePolicyFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", c.PolicyFile, err)
+		}
+		if policy, ok := policies[c.Environment]; ok {
+			return &policy, nil
+		}
+	}
+	if policy, ok := builtinPolicies[c.Environment]; ok {
+		return &policy, nil
+	}
+	return nil, fmt.Errorf("no migration policy for environment %q", c.Environment)
+}
+
+// initPolicy resolves the policy and checks the requirements that do not depend on the scripts.
+func (dbm *databaseMigrator) initPolicy(ctx context.Context) error {
+	policy, err := dbm.Configuration.resolvePolicy(ctx)
+	if err != nil {
+		return err
+	}
+	dbm.policy = policy
+	if policy != nil && policy.RequireApproval && dbm.Configuration.Approver == nil {
+		return fmt.Errorf("%w in environment %v", ErrApprovalRequired, dbm.Configuration.Environment)
+	}
+	return nil
+}
+
+// latestApplied returns the version of the latest completed migration, or nil.
+func (dbm *databaseMigrator) latestApplied(tx pgx.Tx) ([]int, error) {
+	//goland:noinspection SqlResolve
+	rows, err := dbm.audited(tx).Query(context.Background(), dbm.replaceEnv("SELECT id FROM {SCHEMA_TABLE} WHERE status = $1"), statusCompleted)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	var latest []int
+	for _, id := range ids {
+		if version := versionParts(id); latest == nil || compareMigrationIds(version, latest) > 0 {
+			latest = version
+		}
+	}
+	return latest, nil
+}
+
+// checkOrder fails pending migrations older than the latest applied one unless the policy allows
+// them.
+func (dbm *databaseMigrator) checkOrder(migration migration) error {
+	if dbm.policy == nil || dbm.policy.AllowOutOfOrder || dbm.latest == nil || compareMigrationIds(migration.Id, dbm.latest) > 0 {
+		return nil
+	}
+	return fmt.Errorf("migration %v: %w, %v", migration.Filename, ErrOutOfOrderMigration, strings.Join(Map(dbm.latest, strconv.Itoa), "."))
+}
+
+// ownTables are the tables of this package that may exist in the changelog schema before the
+// first migration, e.g. the idempotency table created ahead of a run that then failed.
+func (c Configuration) ownTables() []string {
+	return []string{
+		c.ChangelogTable,
+		c.ChangelogTable + "_repeatable",
+		c.IdempotencyTable,
+		DefaultApprovalTable,
+		DefaultBackfillTable,
+		DefaultSizeSnapshotTable,
+	}
+}
+
+// autoBaseline baselines all migrations when the policy asks for it, the changelog is empty and
+// its schema holds other tables, apart from those of this package and of extensions.
+func (dbm *databaseMigrator) autoBaseline() error {
+	if dbm.policy == nil || !dbm.policy.AutoBaseline {
+		return nil
+	}
+	var adopt bool
+	//goland:noinspection SqlResolve
+	err := dbm.queryRow(dbm.PgxPool, dbm.replaceEnv(`
+		SELECT NOT EXISTS (SELECT FROM {SCHEMA_TABLE})
+			AND EXISTS (
+				SELECT FROM pg_class c
+				JOIN pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname = $1
+					AND c.relkind IN ('r', 'p')
+					AND c.relname <> ALL ($2)
+					AND NOT EXISTS (
+						SELECT FROM pg_depend d
+						WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e'))`),
+		dbm.Configuration.ChangelogSchema, dbm.Configuration.ownTables()).Scan(&adopt)
+	if err != nil || !adopt {
+		return err
+	}
+	recorded, err := dbm.Baseline("")
+	if err != nil {
+		return err
+	}
+	log.Infof("Baselined %v migrations of the existing schema %v", recorded, dbm.Configuration.ChangelogSchema)
+	return nil
+}
+
+// Clean drops the schemas, the changelog schema by default, with everything in them and creates
+// them again empty, e.g. to rebuild a development database from its migrations. It fails with
+// ErrCleanForbidden unless the policy of c allows it.
+func Clean(ctx context.Context, pool *pgxpool.Pool, c Configuration, schemas ...string) error {
+	policy, err := c.resolvePolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if policy == nil || !policy.AllowClean {
+		return fmt.Errorf("%w in environment %q", ErrCleanForbidden, c.Environment)
+	}
+	if len(schemas) == 0 {
+		schemas = []string{c.ChangelogSchema}
+	}
+	return DoInTransactionNoResult(pool, func(tx pgx.Tx) error {
+		for _, schema := range schemas {
+			log.Warnf("Cleaning schema %v", schema)
+			_, err := tx.Exec(ctx, "DROP SCHEMA IF EXISTS "+QuoteIdentifier(schema)+" CASCADE")
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, "CREATE SCHEMA "+QuoteIdentifier(schema))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,117 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrationPolicies(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	c := db.Configuration
+	c.MigrationsDirectory = "testdb"
+	c.Environment = "production"
+	err := pg.Migrate(db.Pool, c)
+	if !errors.Is(err, pg.ErrApprovalRequired) {
+		t.Errorf("expected ErrApprovalRequired, got %v", err)
+	}
+	err = pg.Clean(ctx, db.Pool, c)
+	if !errors.Is(err, pg.ErrCleanForbidden) {
+		t.Errorf("expected ErrCleanForbidden, got %v", err)
+	}
+
+	c.Environment = "development"
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM changelog WHERE status <> 'COMPLETED'")
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT tablename FROM pg_tables WHERE tablename = 'testtable'", [][]any{{"testtable"}})
+
+	err = pg.Clean(ctx, db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM pg_tables WHERE schemaname = 'public'")
+
+	// Development databases holding tables but no changelog are baselined.
+	_, err = db.Pool.Exec(ctx, "CREATE TABLE existing (id INT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM changelog WHERE status <> 'COMPLETED'")
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM pg_tables WHERE tablename = 'testtable'")
+}
+
+func TestAutoBaseline(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	c := db.Configuration
+	c.MigrationsDirectory = "testdb"
+	c.Policy = &pg.MigrationPolicy{AutoBaseline: true}
+	c.IdempotencyTable = "idempotency"
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE idempotency (key TEXT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT tablename FROM pg_tables WHERE tablename = 'testtable'", [][]any{{"testtable"}})
+
+	other := pgtest.StartPostgres(t)
+	c = other.Configuration
+	c.MigrationsDirectory = "testdb"
+	c.Policy = &pg.MigrationPolicy{AutoBaseline: true}
+	_, err = other.Pool.Exec(ctx, "CREATE TABLE existing (id INT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.Migrate(other.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertNoRows(t, other.Pool, "SELECT FROM changelog WHERE status <> 'COMPLETED'")
+	pgtest.AssertNoRows(t, other.Pool, "SELECT FROM pg_tables WHERE tablename = 'testtable'")
+}
+
+func TestOutOfOrderPolicy(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	directory := t.TempDir()
+	write := func(name string, script string) {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(script), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("1_create.sql", "CREATE TABLE a (id INT);")
+	write("3_alter.sql", "ALTER TABLE a ADD name TEXT;")
+	c := db.Configuration
+	c.MigrationsDirectory = directory
+	c.Policy = &pg.MigrationPolicy{Destructive: pg.DestructiveGuard}
+	err := pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("2_late.sql", "CREATE TABLE b (id INT);")
+	err = pg.Migrate(db.Pool, c)
+	if !errors.Is(err, pg.ErrOutOfOrderMigration) {
+		t.Errorf("expected ErrOutOfOrderMigration, got %v", err)
+	}
+	c.Policy.AllowOutOfOrder = true
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id FROM changelog ORDER BY id", [][]any{{"1"}, {"2"}, {"3"}})
+}
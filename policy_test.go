@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolvePolicy(t *testing.T) {
+	ctx := context.Background()
+	source := FSSource{FS: fstest.MapFS{
+		"policy.yaml": {Data: []byte("staging:\n  destructive: guard\n  allow_out_of_order: true\nproduction:\n  require_approval: true\n")},
+		"bad.yaml":    {Data: []byte("staging:\n  destructive: sometimes\n")},
+	}}
+	policy, err := Configuration{}.resolvePolicy(ctx)
+	if err != nil || policy != nil {
+		t.Errorf("expected no policy without an environment, got %v, %v", policy, err)
+	}
+	policy, err = Configuration{Environment: "prod"}.resolvePolicy(ctx)
+	if err != nil || *policy != ProductionPolicy {
+		t.Errorf("expected the production policy, got %v, %v", policy, err)
+	}
+	c := Configuration{Environment: "staging", PolicyFile: "policy.yaml", MigrationsSource: source}
+	policy, err = c.resolvePolicy(ctx)
+	if err != nil || *policy != (MigrationPolicy{Destructive: DestructiveGuard, AllowOutOfOrder: true}) {
+		t.Errorf("unexpected staging policy %v, %v", policy, err)
+	}
+	c.Environment = "production"
+	policy, err = c.resolvePolicy(ctx)
+	if err != nil || *policy != (MigrationPolicy{RequireApproval: true}) {
+		t.Errorf("expected the file to override the built-in policy, got %v, %v", policy, err)
+	}
+	c.Environment = "development"
+	policy, err = c.resolvePolicy(ctx)
+	if err != nil || *policy != DevelopmentPolicy {
+		t.Errorf("expected the built-in development policy, got %v, %v", policy, err)
+	}
+	c.Environment = "qa"
+	_, err = c.resolvePolicy(ctx)
+	if err == nil {
+		t.Error("expected an unknown environment to fail")
+	}
+	c.Environment, c.PolicyFile = "staging", "bad.yaml"
+	_, err = c.resolvePolicy(ctx)
+	if err == nil {
+		t.Error("expected an unknown destructive policy to fail")
+	}
+}
+
+func TestCheckDestructiveForbidden(t *testing.T) {
+	dbm := createDatabaseMigrator(nil, Configuration{Environment: "production"})
+	dbm.policy = &ProductionPolicy
+	err := dbm.checkDestructive("1_drop.sql", "1", allowDestructiveDirective+"\nDROP TABLE a;")
+	if !errors.Is(err, ErrDestructiveMigration) {
+		t.Errorf("expected an acknowledged drop to be forbidden, got %v", err)
+	}
+	dbm.policy = &DevelopmentPolicy
+	err = dbm.checkDestructive("1_drop.sql", "1", "DROP TABLE a;")
+	if err != nil {
+		t.Errorf("expected the drop to be allowed, got %v", err)
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	dbm := createDatabaseMigrator(nil, Configuration{})
+	dbm.latest = []int{2}
+	old := migration{Id: []int{1, 5}, Filename: "1_5_late.sql"}
+	if err := dbm.checkOrder(old); err != nil {
+		t.Errorf("expected no check without a policy, got %v", err)
+	}
+	dbm.policy = &ProductionPolicy
+	if err := dbm.checkOrder(old); !errors.Is(err, ErrOutOfOrderMigration) {
+		t.Errorf("expected ErrOutOfOrderMigration, got %v", err)
+	}
+	if err := dbm.checkOrder(migration{Id: []int{3}}); err != nil {
+		t.Errorf("expected a newer migration to pass, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PreflightCheck is a requirement verified before migrations run. Run returns an error describing
+// what is missing.
+type PreflightCheck struct {
+	Name string
+	Run  func(ctx context.Context, q Querier) error
+}
+
+// PreflightFailure is a check that did not pass.
+type PreflightFailure struct {
+	Check string
+	Err   error
+}
+
+// PreflightError reports all failed checks at once, so every missing requirement can be fixed
+// before the next attempt.
+type PreflightError struct {
+	Failures []PreflightFailure
+}
+
+func (e *PreflightError) Error() string {
+	lines := Map(e.Failures, func(f PreflightFailure) string {
+		return "\n  " + f.Check + ": " + f.Err.Error()
+	})
+	return fmt.Sprintf("%v preflight checks failed:%v", len(e.Failures), strings.Join(lines, ""))
+}
+
+func (e *PreflightError) Unwrap() []error {
+	return Map(e.Failures, func(f PreflightFailure) error { return f.Err })
+}
+
+// RunPreflightChecks runs all checks and returns a *PreflightError listing the failed ones.
+func RunPreflightChecks(ctx context.Context, q Querier, checks ...PreflightCheck) error {
+	var failures []PreflightFailure
+	for _, check := range checks {
+		err := check.Run(ctx, q)
+		if err != nil {
+			failures = append(failures, PreflightFailure{Check: check.Name, Err: err})
+		}
+	}
+	if len(failures) > 0 {
+		return &PreflightError{Failures: failures}
+	}
+	return nil
+}
+
+// RequireExtension checks that an extension is installed in the database.
+func RequireExtension(name string) PreflightCheck {
+	return PreflightCheck{
+		Name: "extension " + name,
+		Run: func(ctx context.Context, q Querier) error {
+			var installed bool
+			err := q.QueryRow(ctx, "SELECT EXISTS (SELECT FROM pg_extension WHERE extname = $1)", name).Scan(&installed)
+			if err == nil && !installed {
+				err = fmt.Errorf("extension %v is not installed", name)
+			}
+			return err
+		},
+	}
+}
+
+// RequireRole checks that a role exists, e.g. one the migrations grant privileges to.
+func RequireRole(name string) PreflightCheck {
+	return PreflightCheck{
+		Name: "role " + name,
+		Run: func(ctx context.Context, q Querier) error {
+			var exists bool
+			err := q.QueryRow(ctx, "SELECT EXISTS (SELECT FROM pg_roles WHERE rolname = $1)", name).Scan(&exists)
+			if err == nil && !exists {
+				err = fmt.Errorf("role %v does not exist", name)
+			}
+			return err
+		},
+	}
+}
+
+// MaxDatabaseSize checks that the database is smaller than maxBytes, e.g. to keep room for
+// migrations that rewrite large tables.
+func MaxDatabaseSize(maxBytes int64) PreflightCheck {
+	return PreflightCheck{
+		Name: "database size",
+		Run: func(ctx context.Context, q Querier) error {
+			var size int64
+			err := q.QueryRow(ctx, "SELECT pg_database_size(current_database())").Scan(&size)
+			if err == nil && size > maxBytes {
+				err = fmt.Errorf("database uses %v bytes, more than the allowed %v", size, maxBytes)
+			}
+			return err
+		},
+	}
+}
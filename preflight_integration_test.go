@@ -0,0 +1,30 @@
+package pg_test
+
+import (
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestPreflightChecks(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	c := db.Configuration
+	c.MigrationsDirectory = "testdb"
+	c.PreflightChecks = []pg.PreflightCheck{
+		pg.RequireExtension("plpgsql"),
+		pg.RequireExtension("pgcrypto"),
+		pg.RequireRole(c.Username),
+		pg.RequireRole("pgutils_missing_role"),
+		pg.MaxDatabaseSize(1 << 40),
+	}
+	err := pg.Migrate(db.Pool, c)
+	var preflightError *pg.PreflightError
+	if !errors.As(err, &preflightError) {
+		t.Fatalf("expected a PreflightError, got %v", err)
+	}
+	if len(preflightError.Failures) != 2 || preflightError.Failures[0].Check != "extension pgcrypto" || preflightError.Failures[1].Check != "role pgutils_missing_role" {
+		t.Errorf("unexpected failures %v", err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM pg_tables WHERE tablename = 'testtable'")
+}
@@ -0,0 +1,29 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRunPreflightChecks(t *testing.T) {
+	missing := errors.New("missing")
+	passing := PreflightCheck{Name: "passing", Run: func(ctx context.Context, q Querier) error { return nil }}
+	failing := func(name string) PreflightCheck {
+		return PreflightCheck{Name: name, Run: func(ctx context.Context, q Querier) error { return missing }}
+	}
+	if err := RunPreflightChecks(context.Background(), nil, passing); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	err := RunPreflightChecks(context.Background(), nil, failing("first"), passing, failing("second"))
+	var preflightError *PreflightError
+	if !errors.As(err, &preflightError) || len(preflightError.Failures) != 2 {
+		t.Fatalf("expected both failures to be reported, got %v", err)
+	}
+	if err.Error() != "2 preflight checks failed:\n  first: missing\n  second: missing" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if !errors.Is(err, missing) {
+		t.Error("the error should wrap the check errors")
+	}
+}
@@ -0,0 +1,20 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier is the common subset of *pgxpool.Pool, *pgxpool.Conn, *pgx.Conn and pgx.Tx used by the helpers in this package.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// TxBeginner is implemented by *pgxpool.Pool and *pgx.Conn, which start a transaction, and by
+// pgx.Tx, which starts a savepoint.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
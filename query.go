@@ -0,0 +1,54 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+)
+
+// Exists reports whether the query returns at least one row. The statement is run as given, so
+// soft deleted rows count unless it excludes them; SelectBuilder.Exists applies the conditions of
+// the builder, such as NotDeleted.
+func Exists(ctx context.Context, q Querier, sql string, args ...any) (bool, error) {
+	var exists bool
+	err := q.QueryRow(ctx, "SELECT EXISTS ("+sql+")", args...).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// Count returns the number of rows the query returns, see SelectBuilder.Count for soft deleted
+// rows.
+func Count(ctx context.Context, q Querier, sql string, args ...any) (int64, error) {
+	var count int64
+	err := q.QueryRow(ctx, "SELECT count(*) FROM ("+sql+") AS counted", args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Scalar scans the single column of the first row. It returns pgx.ErrNoRows when the query
+// returns no rows.
+func Scalar[T any](ctx context.Context, q Querier, sql string, args ...any) (T, error) {
+	var value T
+	err := q.QueryRow(ctx, sql, args...).Scan(&value)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value, nil
+}
+
+// ScalarOptional is like Scalar but returns nil instead of an error when the query returns no rows.
+func ScalarOptional[T any](ctx context.Context, q Querier, sql string, args ...any) (*T, error) {
+	value, err := Scalar[T](ctx, q, sql, args...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
@@ -0,0 +1,47 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestQueryHelpers(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	exists, err := pg.Exists(ctx, db.Pool, "SELECT FROM testtable WHERE id = $1", 1)
+	if err != nil || !exists {
+		t.Errorf("expected the row to exist, got %v, %v", exists, err)
+	}
+	exists, err = pg.Exists(ctx, db.Pool, "SELECT FROM testtable WHERE id = $1", 2)
+	if err != nil || exists {
+		t.Errorf("expected no row, got %v, %v", exists, err)
+	}
+	count, err := pg.Count(ctx, db.Pool, "SELECT * FROM testtable")
+	if err != nil || count != 1 {
+		t.Errorf("unexpected count %v, %v", count, err)
+	}
+	name, err := pg.Scalar[string](ctx, db.Pool, "SELECT name FROM testtable WHERE id = $1", 1)
+	if err != nil || name != "name1" {
+		t.Errorf("unexpected name %q, %v", name, err)
+	}
+	_, err = pg.Scalar[string](ctx, db.Pool, "SELECT name FROM testtable WHERE id = $1", 2)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected pgx.ErrNoRows, got %v", err)
+	}
+	optional, err := pg.ScalarOptional[string](ctx, db.Pool, "SELECT name FROM testtable WHERE id = $1", 2)
+	if err != nil || optional != nil {
+		t.Errorf("expected nil, got %v, %v", optional, err)
+	}
+	optional, err = pg.ScalarOptional[string](ctx, db.Pool, "SELECT name FROM testtable WHERE id = $1", 1)
+	if err != nil || optional == nil || *optional != "name1" {
+		t.Errorf("unexpected name %v, %v", optional, err)
+	}
+	_, err = pg.ScalarOptional[string](ctx, db.Pool, "SELECT missing FROM testtable")
+	if err == nil {
+		t.Error("expected an invalid query to fail")
+	}
+}
@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"time"
+)
+
+type QueueStats struct {
+	Queue string
+	// Ready jobs are pending and due; Scheduled jobs are pending with a future run_at, including
+	// retries waiting out their backoff.
+	Ready     int64
+	Scheduled int64
+	Dead      int64
+	// OldestReady is the time the longest waiting due job became due, zero when nothing is ready.
+	OldestReady time.Time
+}
+
+// Stats summarises every queue in table.
+func Stats(ctx context.Context, q pg.Querier, table string) ([]QueueStats, error) {
+	//goland:noinspection SqlResolve
+	rows, err := q.Query(ctx, `
+		SELECT queue,
+			count(*) FILTER (WHERE status = $1 AND run_at <= now()),
+			count(*) FILTER (WHERE status = $1 AND run_at > now()),
+			count(*) FILTER (WHERE status = $2),
+			coalesce(min(run_at) FILTER (WHERE status = $1 AND run_at <= now()), 'epoch')
+		FROM `+pg.QuoteIdentifier(table)+`
+		GROUP BY queue
+		ORDER BY queue`, statusPending, statusDead)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (QueueStats, error) {
+		var s QueueStats
+		err := row.Scan(&s.Queue, &s.Ready, &s.Scheduled, &s.Dead, &s.OldestReady)
+		if s.OldestReady.Unix() == 0 {
+			s.OldestReady = time.Time{}
+		}
+		return s, err
+	})
+}
+
+// DeadJobs lists the most recently created dead-lettered jobs of queue.
+func DeadJobs(ctx context.Context, q pg.Querier, table string, queue string, limit int) ([]Job, error) {
+	//goland:noinspection SqlResolve
+	rows, err := q.Query(ctx, "SELECT id, queue, payload, priority, run_at, attempts, max_attempts, last_error, created_at FROM "+pg.QuoteIdentifier(table)+
+		" WHERE queue = $1 AND status = $2 ORDER BY created_at DESC, id DESC LIMIT $3", queue, statusDead, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Job, error) {
+		var job Job
+		err := row.Scan(&job.ID, &job.Queue, &job.Payload, &job.Priority, &job.RunAt, &job.Attempts, &job.MaxAttempts, &job.LastError, &job.CreatedAt)
+		return job, err
+	})
+}
+
+// RetryDead moves a dead-lettered job back to the queue with a fresh set of attempts. It reports
+// whether a dead job with that id existed.
+func RetryDead(ctx context.Context, q pg.Querier, table string, id int64) (bool, error) {
+	//goland:noinspection SqlResolve
+	tag, err := q.Exec(ctx, "UPDATE "+pg.QuoteIdentifier(table)+" SET status = $2, attempts = 0, run_at = now() WHERE id = $1 AND status = $3", id, statusPending, statusDead)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
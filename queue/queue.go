@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTable       = "jobs"
+	DefaultMaxAttempts = 5
+
+	statusPending = "pending"
+	statusDead    = "dead"
+)
+
+// Job is a unit of work on a named queue. Jobs with a higher Priority run first; among equal
+// priorities the earliest RunAt wins.
+type Job struct {
+	ID          int64
+	Queue       string
+	Payload     json.RawMessage
+	Priority    int
+	RunAt       time.Time
+	Attempts    int
+	MaxAttempts int
+	LastError   string
+	CreatedAt   time.Time
+}
+
+// Migration returns the script creating the jobs table, for inclusion in a migrations directory.
+func Migration(table string) string {
+	index := pgx.Identifier{strings.ReplaceAll(table, ".", "_") + "_fetch_idx"}.Sanitize()
+	return `
+		CREATE TABLE IF NOT EXISTS ` + pg.QuoteIdentifier(table) + `
+		(
+			id BIGSERIAL PRIMARY KEY NOT NULL,
+			queue TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			priority INTEGER NOT NULL DEFAULT 0,
+			run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			attempts INTEGER NOT NULL DEFAULT 0,
+			max_attempts INTEGER NOT NULL DEFAULT 5,
+			last_error TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'pending',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS ` + index + ` ON ` + pg.QuoteIdentifier(table) + ` (queue, priority DESC, run_at) WHERE status = 'pending';
+	`
+}
+
+// Migrate creates the jobs table if it does not exist yet.
+func Migrate(ctx context.Context, q pg.Querier, table string) error {
+	_, err := q.Exec(ctx, Migration(table))
+	return err
+}
+
+// Enqueue adds job to the default jobs table and returns its id. A zero RunAt runs the job as soon
+// as possible and a zero MaxAttempts uses DefaultMaxAttempts.
+func Enqueue(ctx context.Context, q pg.Querier, job Job) (int64, error) {
+	return EnqueueTo(ctx, q, DefaultTable, job)
+}
+
+func EnqueueTo(ctx context.Context, q pg.Querier, table string, job Job) (int64, error) {
+	runAt := job.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	maxAttempts := job.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	var id int64
+	//goland:noinspection SqlResolve
+	err := q.QueryRow(ctx, "INSERT INTO "+pg.QuoteIdentifier(table)+" (queue, payload, priority, run_at, max_attempts) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		job.Queue, job.Payload, job.Priority, runAt, maxAttempts).Scan(&id)
+	return id, err
+}
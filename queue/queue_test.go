@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	expected := map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 100: time.Hour}
+	for attempt, delay := range expected {
+		if ExponentialBackoff(attempt) != delay {
+			t.Errorf("attempt %v: expected %v, got %v", attempt, delay, ExponentialBackoff(attempt))
+		}
+	}
+}
+
+func TestWorker(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	err := Migrate(ctx, db.Pool, DefaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Enqueue(ctx, db.Pool, Job{Queue: "mail", Payload: json.RawMessage(`"low"`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Enqueue(ctx, db.Pool, Job{Queue: "mail", Payload: json.RawMessage(`"high"`), Priority: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Enqueue(ctx, db.Pool, Job{Queue: "mail", Payload: json.RawMessage(`"later"`), RunAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing, err := Enqueue(ctx, db.Pool, Job{Queue: "mail", Payload: json.RawMessage(`"fail"`), Priority: -1, MaxAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handled []string
+	worker := NewWorker(db.Pool, "mail", func(ctx context.Context, tx pgx.Tx, job Job) error {
+		var payload string
+		_ = json.Unmarshal(job.Payload, &payload)
+		if payload == "fail" {
+			return errors.New("smtp unavailable")
+		}
+		handled = append(handled, payload)
+		return nil
+	})
+	for {
+		processed, err := worker.ProcessNext(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !processed {
+			break
+		}
+	}
+	if len(handled) != 2 || handled[0] != "high" || handled[1] != "low" {
+		t.Errorf("expected due jobs by priority, got %v", handled)
+	}
+
+	stats, err := Stats(ctx, db.Pool, DefaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Ready != 0 || stats[0].Scheduled != 1 || stats[0].Dead != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	dead, err := DeadJobs(ctx, db.Pool, DefaultTable, "mail", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != failing || dead[0].LastError != "smtp unavailable" {
+		t.Errorf("unexpected dead jobs: %+v", dead)
+	}
+	retried, err := RetryDead(ctx, db.Pool, DefaultTable, failing)
+	if err != nil || !retried {
+		t.Errorf("expected the dead job to be retried, got %v, %v", retried, err)
+	}
+}
+
+func TestWorkerFinishesRunningJobsOnShutdown(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	err := Migrate(ctx, db.Pool, DefaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Pool.Exec(ctx, "CREATE TABLE sent (payload TEXT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range []string{`"first"`, `"second"`} {
+		_, err = Enqueue(ctx, db.Pool, Job{Queue: "mail", Payload: json.RawMessage(payload)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	started := make(chan struct{})
+	worker := NewWorker(db.Pool, "mail", func(ctx context.Context, tx pgx.Tx, job Job) error {
+		_, err := tx.Exec(ctx, "INSERT INTO sent VALUES ($1)", string(job.Payload))
+		if err != nil {
+			return err
+		}
+		close(started)
+		// The shutdown arrives while the job is running.
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+		_, err = tx.Exec(ctx, "SELECT 1")
+		return err
+	})
+	done := make(chan error)
+	go func() { done <- worker.Run(runCtx) }()
+	<-started
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the worker did not stop")
+	}
+	pgtest.AssertRowCount(t, db.Pool, "sent", 1)
+	pgtest.AssertRowCount(t, db.Pool, DefaultTable, 1)
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM "+DefaultTable+" WHERE attempts > 0")
+}
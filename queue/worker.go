@@ -0,0 +1,151 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultConcurrency  = 1
+	DefaultPollInterval = time.Second
+
+	backoffBase = time.Second
+	backoffMax  = time.Hour
+)
+
+// Handler processes a job inside the transaction holding its row lock, so effects written through
+// tx commit together with the job's completion. Returning an error rolls those effects back and
+// schedules a retry, or dead-letters the job once it has used up its attempts.
+type Handler func(ctx context.Context, tx pgx.Tx, job Job) error
+
+// Worker runs Concurrency goroutines fetching due jobs from one queue with
+// SELECT ... FOR UPDATE SKIP LOCKED, so any number of workers can share a queue.
+type Worker struct {
+	pool    *pgxpool.Pool
+	queue   string
+	handler Handler
+
+	Table        string
+	Concurrency  int
+	PollInterval time.Duration
+	// Backoff returns the delay before the given retry attempt, starting at 1.
+	Backoff func(attempt int) time.Duration
+}
+
+func NewWorker(pool *pgxpool.Pool, queue string, handler Handler) *Worker {
+	return &Worker{
+		pool:         pool,
+		queue:        queue,
+		handler:      handler,
+		Table:        DefaultTable,
+		Concurrency:  DefaultConcurrency,
+		PollInterval: DefaultPollInterval,
+		Backoff:      ExponentialBackoff,
+	}
+}
+
+// ExponentialBackoff doubles the delay with every attempt, starting at a second and capped at an hour.
+func ExponentialBackoff(attempt int) time.Duration {
+	delay := backoffBase
+	for i := 1; i < attempt && delay < backoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, backoffMax)
+}
+
+// Run processes jobs until ctx is cancelled, which is treated as a graceful shutdown: no further
+// jobs are fetched, jobs already running are allowed to finish and commit, and nil is returned.
+func (w *Worker) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < max(w.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		processed, err := w.ProcessNext(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warnf("Error processing job from queue %v: %v", w.queue, err)
+		}
+		if processed && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+// ProcessNext runs the next due job, if any, and reports whether there was one. Handler failures
+// are recorded on the job and not returned. ctx only bounds fetching the job: once it is locked,
+// the handler, the bookkeeping and the commit run to completion even if ctx is cancelled, so a
+// shutdown does not roll back the effects the handler already wrote.
+func (w *Worker) ProcessNext(ctx context.Context) (bool, error) {
+	tx, err := w.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	table := pg.QuoteIdentifier(w.Table)
+	var job Job
+	//goland:noinspection SqlResolve
+	err = tx.QueryRow(ctx, "SELECT id, queue, payload, priority, run_at, attempts, max_attempts, last_error, created_at FROM "+table+
+		" WHERE queue = $1 AND status = $2 AND run_at <= now() ORDER BY priority DESC, run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED", w.queue, statusPending).
+		Scan(&job.ID, &job.Queue, &job.Payload, &job.Priority, &job.RunAt, &job.Attempts, &job.MaxAttempts, &job.LastError, &job.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	ctx = context.WithoutCancel(ctx)
+	handlerErr := pg.WithSavepoint(ctx, tx, func(tx pgx.Tx) error {
+		return w.handle(ctx, tx, job)
+	})
+	if handlerErr == nil {
+		//goland:noinspection SqlResolve
+		_, err = tx.Exec(ctx, "DELETE FROM "+table+" WHERE id = $1", job.ID)
+	} else if job.Attempts+1 >= job.MaxAttempts {
+		log.Warnf("Job %v on queue %v failed permanently: %v", job.ID, job.Queue, handlerErr)
+		//goland:noinspection SqlResolve
+		_, err = tx.Exec(ctx, "UPDATE "+table+" SET attempts = attempts + 1, last_error = $2, status = $3 WHERE id = $1", job.ID, handlerErr.Error(), statusDead)
+	} else {
+		log.Debugf("Job %v on queue %v failed, retrying: %v", job.ID, job.Queue, handlerErr)
+		//goland:noinspection SqlResolve
+		_, err = tx.Exec(ctx, "UPDATE "+table+" SET attempts = attempts + 1, last_error = $2, run_at = $3 WHERE id = $1", job.ID, handlerErr.Error(), time.Now().Add(w.Backoff(job.Attempts+1)))
+	}
+	if err != nil {
+		return true, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (w *Worker) handle(ctx context.Context, tx pgx.Tx, job Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return w.handler(ctx, tx, job)
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"time"
+)
+
+const DefaultTable = "rate_limits"
+
+// Migration returns the script creating the bucket table, for inclusion in a migrations directory.
+// The table is unlogged: losing buckets in a crash only resets the limits.
+func Migration(table string) string {
+	return `
+		CREATE UNLOGGED TABLE IF NOT EXISTS ` + pg.QuoteIdentifier(table) + `
+		(
+			key TEXT PRIMARY KEY NOT NULL,
+			tokens DOUBLE PRECISION NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		);
+	`
+}
+
+// Migrate creates the bucket table if it does not exist yet.
+func Migrate(ctx context.Context, q pg.Querier, table string) error {
+	_, err := q.Exec(ctx, Migration(table))
+	return err
+}
+
+// Limiter is a token bucket per key shared by every replica using the same table. A bucket holds
+// up to Burst tokens and refills at Rate tokens per second; each request takes one or more.
+type Limiter struct {
+	q     pg.Querier
+	Table string
+	Rate  float64
+	Burst float64
+}
+
+func NewLimiter(q pg.Querier, rate float64, burst float64) *Limiter {
+	return &Limiter{
+		q:     q,
+		Table: DefaultTable,
+		Rate:  rate,
+		Burst: burst,
+	}
+}
+
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN takes n tokens from key's bucket if it holds enough and reports whether it did. Refill
+// and withdrawal happen in a single statement, so concurrent callers cannot overdraw the bucket.
+// A denied request leaves the bucket untouched.
+func (l *Limiter) AllowN(ctx context.Context, key string, n float64) (bool, error) {
+	if n > l.Burst {
+		return false, nil
+	}
+	table := pg.QuoteIdentifier(l.Table)
+	refilled := "least($2::float8, b.tokens + extract(epoch FROM clock_timestamp() - b.updated_at) * $3::float8)"
+	var tokens float64
+	//goland:noinspection SqlResolve
+	err := l.q.QueryRow(ctx, "INSERT INTO "+table+" AS b (key, tokens, updated_at) VALUES ($1, $2::float8 - $4::float8, clock_timestamp()) "+
+		"ON CONFLICT (key) DO UPDATE SET tokens = "+refilled+" - $4, updated_at = clock_timestamp() "+
+		"WHERE "+refilled+" >= $4 RETURNING tokens", key, l.Burst, l.Rate, n).Scan(&tokens)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Sweep deletes buckets untouched for longer than olderThan. Such buckets are full anyway once
+// olderThan exceeds Burst / Rate seconds, so removing them does not change any limit.
+func (l *Limiter) Sweep(ctx context.Context, olderThan time.Duration) (int64, error) {
+	//goland:noinspection SqlResolve
+	tag, err := l.q.Exec(ctx, "DELETE FROM "+pg.QuoteIdentifier(l.Table)+" WHERE updated_at < $1", time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	err := Migrate(ctx, db.Pool, DefaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLimiter(db.Pool, 10, 3)
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		ok, err := l.Allow(ctx, "client-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("expected the burst to be allowed, got %v", allowed)
+	}
+	ok, err := l.Allow(ctx, "client-2")
+	if err != nil || !ok {
+		t.Errorf("keys should have separate buckets, got %v, %v", ok, err)
+	}
+	ok, err = l.AllowN(ctx, "client-3", 4)
+	if err != nil || ok {
+		t.Errorf("requests larger than the burst should be denied, got %v, %v", ok, err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	ok, err = l.Allow(ctx, "client-1")
+	if err != nil || !ok {
+		t.Errorf("the bucket should have refilled, got %v, %v", ok, err)
+	}
+	swept, err := l.Sweep(ctx, 0)
+	if err != nil || swept != 2 {
+		t.Errorf("expected both buckets to be swept, got %v, %v", swept, err)
+	}
+}
@@ -0,0 +1,71 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// repeatableTable returns the quoted table recording the checksums of the repeatable migrations,
+// next to the changelog.
+func (c Configuration) repeatableTable() string {
+	return QuoteIdentifier(c.ChangelogSchema) + "." + QuoteIdentifier(c.ChangelogTable+"_repeatable")
+}
+
+// applyRepeatableMigration runs the script when it is new or changed since it last ran.
+func (dbm *databaseMigrator) applyRepeatableMigration(migration migration, tx pgx.Tx) (migrationStatus, error) {
+	bytes, err := dbm.Configuration.source().Read(context.Background(), migration.Filename)
+	if err != nil {
+		return "", err
+	}
+	script := string(bytes)
+	checksum := scriptChecksum(bytes)
+	table := dbm.Configuration.repeatableTable()
+	_, err = dbm.exec(tx, `
+		CREATE TABLE IF NOT EXISTS `+table+`
+		(
+			filename TEXT PRIMARY KEY NOT NULL,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			timestamp TIMESTAMPTZ NOT NULL
+		)`)
+	if err != nil {
+		return "", err
+	}
+	var applied string
+	//goland:noinspection SqlResolve
+	err = dbm.queryRow(tx, "SELECT checksum FROM "+table+" WHERE filename = $1", migration.Filename).Scan(&applied)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+	if applied == checksum {
+		return statusSkipped, nil
+	}
+	approved, err := dbm.approve(context.Background(), tx, PendingMigration{
+		Name:     migration.Name,
+		Filename: migration.Filename,
+		Script:   script,
+		Checksum: checksum,
+	})
+	if err != nil || !approved {
+		return statusHeld, err
+	}
+	log.Printf("Applying repeatable migration %v", migration.Filename)
+	err = dbm.checkDestructive(migration.Filename, "", script)
+	if err != nil {
+		return "", err
+	}
+	_, err = dbm.exec(tx, script)
+	if err != nil {
+		return statusError, newMigrationError(migration.Filename, "", script, err)
+	}
+	//goland:noinspection SqlResolve
+	_, err = dbm.exec(tx, "INSERT INTO "+table+" (filename, name, checksum, timestamp) VALUES ($1, $2, $3, $4) ON CONFLICT (filename) DO UPDATE SET checksum = $3, timestamp = $4",
+		migration.Filename, migration.Name, checksum, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return statusCompleted, nil
+}
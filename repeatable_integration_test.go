@@ -0,0 +1,40 @@
+package pg_test
+
+import (
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlywayRepeatableMigrations(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	directory := t.TempDir()
+	write := func(name string, script string) {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(script), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("V1__create_notes.sql", "CREATE TABLE notes (value TEXT);")
+	write("R__notes_view.sql", "CREATE TABLE runs (id INT); CREATE VIEW notes_view AS SELECT value FROM notes;")
+	c := db.Configuration
+	c.MigrationsDirectory = directory
+	c.MigrationNaming = pg.MigrationNamingFlyway
+	for range 2 {
+		err := pg.Migrate(db.Pool, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id FROM changelog", [][]any{{"1"}})
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT filename FROM changelog_repeatable", [][]any{{"R__notes_view.sql"}})
+
+	write("R__notes_view.sql", "CREATE OR REPLACE VIEW notes_view AS SELECT value, length(value) AS length FROM notes;")
+	err := pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT count(*) FROM information_schema.columns WHERE table_name = 'notes_view'", [][]any{{int64(2)}})
+}
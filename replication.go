@@ -0,0 +1,72 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// ReplicaStatus is a standby connected to the primary, as seen in pg_stat_replication.
+type ReplicaStatus struct {
+	ApplicationName string
+	ClientAddress   string
+	State           string
+	SyncState       string
+	WriteLag        time.Duration
+	FlushLag        time.Duration
+	ReplayLag       time.Duration
+	// ReplayLagBytes is how far the standby's replay position is behind the primary's WAL.
+	ReplayLagBytes int64
+}
+
+// IsStandby reports whether the server is a standby in recovery.
+func IsStandby(ctx context.Context, q Querier) (bool, error) {
+	return Scalar[bool](ctx, q, "SELECT pg_is_in_recovery()")
+}
+
+// ReplicationLag returns how far a standby is behind its primary, measured as the age of the last
+// replayed transaction. It is zero on a primary and on a standby that has replayed everything it
+// received, so an idle primary does not make its standbys look lagging.
+func ReplicationLag(ctx context.Context, q Querier) (time.Duration, error) {
+	var seconds float64
+	err := q.QueryRow(ctx, `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END::float8
+	`).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// ReplicaStatuses lists the standbys streaming from the primary.
+func ReplicaStatuses(ctx context.Context, q Querier) ([]ReplicaStatus, error) {
+	//goland:noinspection SqlResolve
+	rows, err := q.Query(ctx, `
+		SELECT application_name, COALESCE(host(client_addr), ''), state, sync_state,
+			COALESCE(write_lag, '0'), COALESCE(flush_lag, '0'), COALESCE(replay_lag, '0'),
+			COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn), 0)::bigint
+		FROM pg_stat_replication
+		ORDER BY application_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ReplicaStatus, error) {
+		var s ReplicaStatus
+		err := row.Scan(&s.ApplicationName, &s.ClientAddress, &s.State, &s.SyncState, &s.WriteLag, &s.FlushLag, &s.ReplayLag, &s.ReplayLagBytes)
+		return s, err
+	})
+}
+
+// WithinReplicationLag reports whether the server's replication lag is at most maxLag, for health
+// checks and routing reads away from lagging standbys.
+func WithinReplicationLag(ctx context.Context, q Querier, maxLag time.Duration) (bool, error) {
+	lag, err := ReplicationLag(ctx, q)
+	if err != nil {
+		return false, err
+	}
+	return lag <= maxLag, nil
+}
@@ -0,0 +1,29 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestReplicationOnPrimary(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	standby, err := pg.IsStandby(ctx, db.Pool)
+	if err != nil || standby {
+		t.Errorf("expected a primary, got %v, %v", standby, err)
+	}
+	lag, err := pg.ReplicationLag(ctx, db.Pool)
+	if err != nil || lag != 0 {
+		t.Errorf("expected no lag on a primary, got %v, %v", lag, err)
+	}
+	within, err := pg.WithinReplicationLag(ctx, db.Pool, 0)
+	if err != nil || !within {
+		t.Errorf("expected a primary to be within any lag, got %v, %v", within, err)
+	}
+	replicas, err := pg.ReplicaStatuses(ctx, db.Pool)
+	if err != nil || len(replicas) != 0 {
+		t.Errorf("expected no replicas, got %v, %v", replicas, err)
+	}
+}
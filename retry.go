@@ -0,0 +1,43 @@
+package pg
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+
+	retryBaseDelay = 10 * time.Millisecond
+)
+
+// IsRetryable reports whether err is a serialization failure or deadlock, after which the whole
+// transaction can safely be run again.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
+
+// DoInTransactionWithRetry runs fn like DoInTransaction, running it again in a new transaction up
+// to attempts times in total when it fails with a retryable error.
+func DoInTransactionWithRetry[R any](pool *pgxpool.Pool, attempts int, fn func(tx pgx.Tx) (*R, error)) (*R, error) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		result, err := DoInTransaction(pool, fn)
+		if err == nil || attempt >= attempts || !IsRetryable(err) {
+			return result, err
+		}
+		log.Debugf("Retrying transaction after attempt %v: %v", attempt, err)
+		recordTxRetry()
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
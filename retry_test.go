@@ -0,0 +1,23 @@
+package pg
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	if !IsRetryable(fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: serializationFailure})) {
+		t.Error("serialization failure should be retryable")
+	}
+	if !IsRetryable(&pgconn.PgError{Code: deadlockDetected}) {
+		t.Error("deadlock should be retryable")
+	}
+	if IsRetryable(&pgconn.PgError{Code: "23505"}) {
+		t.Error("unique violation should not be retryable")
+	}
+	if IsRetryable(errors.New("boom")) {
+		t.Error("plain errors should not be retryable")
+	}
+}
@@ -0,0 +1,47 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"sort"
+)
+
+// WithRLSContext runs fn in a transaction with the given settings applied as SET LOCAL, e.g.
+// {"app.tenant_id": "42"} for policies using current_setting('app.tenant_id'). The settings end
+// with the transaction, so they never leak to other users of the pooled connection.
+func WithRLSContext(ctx context.Context, pool *pgxpool.Pool, settings map[string]string, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer trackTx(tx)()
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	err = SetLocal(ctx, tx, settings)
+	if err != nil {
+		return err
+	}
+	err = fn(ContextWithTx(ctx, tx), tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetLocal applies settings for the remainder of tx.
+func SetLocal(ctx context.Context, tx pgx.Tx, settings map[string]string) error {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", name, settings[name])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestWithRLSContext(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	// Superusers bypass row level security, so the policies are checked as another role.
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE documents (tenant TEXT, title TEXT);
+		INSERT INTO documents VALUES ('a', 'first'), ('a', 'second'), ('b', 'third');
+		ALTER TABLE documents ENABLE ROW LEVEL SECURITY;
+		CREATE POLICY tenant ON documents USING (tenant = current_setting('app.tenant_id'));
+		CREATE ROLE tenant_user;
+		GRANT SELECT, INSERT ON documents TO tenant_user;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := db.Pool.Config()
+	config.MaxConns = 1
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	settings := map[string]string{"app.tenant_id": "a"}
+	err = pg.WithRLSContext(ctx, pool, settings, func(ctx context.Context, tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "SET LOCAL ROLE tenant_user")
+		if err != nil {
+			return err
+		}
+		count, err := pg.Count(ctx, pg.QuerierFromContext(ctx, pool), "SELECT * FROM documents")
+		if err != nil || count != 2 {
+			t.Errorf("expected the rows of tenant a, got %v, %v", count, err)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setting, err := pg.Scalar[string](ctx, pool, "SELECT current_setting('app.tenant_id', true)")
+	if err != nil || setting != "" {
+		t.Errorf("expected the setting to end with the transaction, got %q, %v", setting, err)
+	}
+
+	failed := errors.New("failed")
+	err = pg.WithRLSContext(ctx, pool, settings, func(ctx context.Context, tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "INSERT INTO documents VALUES ('a', 'rolled back')")
+		if err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("expected the failure, got %v", err)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM documents WHERE title = 'rolled back'")
+}
@@ -0,0 +1,54 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrNoRole = errors.New("no role in context")
+
+type roleContextKey struct{}
+
+// ContextWithRole returns a context carrying the role WithContextRole switches to, e.g. derived
+// from the authenticated caller by a middleware.
+func ContextWithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleContextKey{}, role)
+}
+
+func RoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(roleContextKey{}).(string)
+	return role, ok && role != ""
+}
+
+// WithRole runs fn on a connection switched to role with SET ROLE, so an application connecting
+// as a user with few privileges gains those of role for one operation. The connecting user must
+// be a member of role. The role is reset before the connection goes back to the pool, which closes
+// the connection instead when the reset fails.
+func WithRole(ctx context.Context, pool *pgxpool.Pool, role string, fn func(ctx context.Context, conn *pgxpool.Conn) error) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, err := conn.Exec(context.Background(), "RESET ROLE")
+		if err != nil {
+			_ = conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}()
+	_, err = conn.Exec(ctx, "SELECT set_config('role', $1, false)", role)
+	if err != nil {
+		return err
+	}
+	return fn(ctx, conn)
+}
+
+// WithContextRole is WithRole for the role carried by ctx.
+func WithContextRole(ctx context.Context, pool *pgxpool.Pool, fn func(ctx context.Context, conn *pgxpool.Conn) error) error {
+	role, ok := RoleFromContext(ctx)
+	if !ok {
+		return ErrNoRole
+	}
+	return WithRole(ctx, pool, role, fn)
+}
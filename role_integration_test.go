@@ -0,0 +1,49 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestWithRole(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE ROLE reader;
+		CREATE TABLE secrets (value TEXT);
+		CREATE TABLE notes (value TEXT);
+		GRANT SELECT ON notes TO reader;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = pg.ContextWithRole(ctx, "reader")
+	err = pg.WithContextRole(ctx, db.Pool, func(ctx context.Context, conn *pgxpool.Conn) error {
+		pgtest.AssertQueryReturns(t, conn, "SELECT current_user::text", [][]any{{"reader"}})
+		pgtest.AssertNoRows(t, conn, "SELECT value FROM notes")
+		_, err := conn.Exec(ctx, "SELECT value FROM secrets")
+		if err == nil {
+			t.Error("expected the role to lack access to secrets")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range db.Pool.Stat().TotalConns() {
+		pgtest.AssertQueryReturns(t, db.Pool, "SELECT current_user::text", [][]any{{"postgres"}})
+	}
+
+	err = pg.WithRole(ctx, db.Pool, "missing", func(ctx context.Context, conn *pgxpool.Conn) error { return nil })
+	if err == nil {
+		t.Error("expected switching to a missing role to fail")
+	}
+	err = pg.WithContextRole(context.Background(), db.Pool, func(ctx context.Context, conn *pgxpool.Conn) error { return nil })
+	if !errors.Is(err, pg.ErrNoRole) {
+		t.Errorf("expected ErrNoRole, got %v", err)
+	}
+}
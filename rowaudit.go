@@ -0,0 +1,105 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"strings"
+)
+
+const (
+	// DefaultRowAuditTable is the central table the row audit triggers write to.
+	DefaultRowAuditTable = "row_audit"
+	// RowAuditActorSetting names who makes the changes of a transaction, see SetAuditActor. The
+	// session user is recorded when it is not set.
+	RowAuditActorSetting = "pgutils.actor"
+)
+
+// RowAudit configures the audit trigger of one table.
+type RowAudit struct {
+	Table string
+	// ExcludeColumns are left out of the recorded rows, e.g. password hashes. Updates touching only
+	// these columns are not recorded.
+	ExcludeColumns []string
+}
+
+func (a RowAudit) triggerName() string {
+	return QuoteIdentifier(lastPart(a.Table) + "_row_audit")
+}
+
+// RowAuditMigration returns the statements creating the audit table and trigger function, and
+// installing audit triggers on tables. Every insert, update and delete of an audited table records
+// the actor, time, operation, old and new row as JSONB and the changed columns. The statements are
+// idempotent, so put the output in a migration file and add tables in later migrations the same way.
+func RowAuditMigration(auditTable string, tables ...RowAudit) string {
+	if auditTable == "" {
+		auditTable = DefaultRowAuditTable
+	}
+	quoted := QuoteIdentifier(auditTable)
+	function := QuoteIdentifier(auditTable + "_trigger")
+	var sb strings.Builder
+	//goland:noinspection SqlResolve
+	sb.WriteString(`CREATE TABLE IF NOT EXISTS ` + quoted + ` (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    table_name TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    transaction_id BIGINT NOT NULL DEFAULT txid_current(),
+    old_row JSONB,
+    new_row JSONB,
+    changed_columns TEXT[]
+);
+CREATE INDEX IF NOT EXISTS ` + QuoteIdentifier(lastPart(auditTable)+"_table_name") + ` ON ` + quoted + ` (table_name, changed_at);
+CREATE OR REPLACE FUNCTION ` + function + `() RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+    -- TG_ARGV is NULL for triggers created without arguments.
+    excluded_columns TEXT[] := COALESCE(TG_ARGV, '{}');
+    old_data JSONB;
+    new_data JSONB;
+    changed TEXT[];
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        old_data := to_jsonb(OLD) - excluded_columns;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        new_data := to_jsonb(NEW) - excluded_columns;
+    END IF;
+    IF TG_OP = 'UPDATE' THEN
+        SELECT array_agg(n.key ORDER BY n.key) INTO changed
+        FROM jsonb_each(new_data) n
+        WHERE n.value IS DISTINCT FROM old_data -> n.key;
+        IF changed IS NULL THEN
+            RETURN NULL;
+        END IF;
+    END IF;
+    INSERT INTO ` + quoted + ` (table_name, operation, actor, old_row, new_row, changed_columns)
+    VALUES (TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME, TG_OP,
+        COALESCE(NULLIF(current_setting('` + RowAuditActorSetting + `', true), ''), session_user), old_data, new_data, changed);
+    RETURN NULL;
+END
+$$;
+`)
+	for _, table := range tables {
+		excluded := Map(table.ExcludeColumns, func(column string) string {
+			return "'" + strings.ReplaceAll(column, "'", "''") + "'"
+		})
+		sb.WriteString("DROP TRIGGER IF EXISTS " + table.triggerName() + " ON " + QuoteIdentifier(table.Table) + ";\n")
+		sb.WriteString("CREATE TRIGGER " + table.triggerName() + " AFTER INSERT OR UPDATE OR DELETE ON " + QuoteIdentifier(table.Table) +
+			" FOR EACH ROW EXECUTE FUNCTION " + function + "(" + strings.Join(excluded, ", ") + ");\n")
+	}
+	return sb.String()
+}
+
+// RowAuditDownMigration removes the audit triggers of tables, keeping the recorded changes.
+func RowAuditDownMigration(tables ...RowAudit) string {
+	var sb strings.Builder
+	for _, table := range tables {
+		sb.WriteString("DROP TRIGGER IF EXISTS " + table.triggerName() + " ON " + QuoteIdentifier(table.Table) + ";\n")
+	}
+	return sb.String()
+}
+
+// SetAuditActor records actor as the author of the changes made in the rest of tx.
+func SetAuditActor(ctx context.Context, tx pgx.Tx, actor string) error {
+	return SetLocal(ctx, tx, map[string]string{RowAuditActorSetting: actor})
+}
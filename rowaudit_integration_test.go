@@ -0,0 +1,77 @@
+package pg_test
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestRowAudit(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE users (id BIGINT PRIMARY KEY, name TEXT, password TEXT);"+
+		pg.RowAuditMigration("", pg.RowAudit{Table: "users", ExcludeColumns: []string{"password"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	err = pg.SetAuditActor(ctx, tx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO users (id, name, password) VALUES (1, 'a', 'secret');
+		UPDATE users SET password = 'changed';
+		UPDATE users SET name = 'b';
+		DELETE FROM users;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, `
+		SELECT operation, actor, new_row ->> 'name', new_row ? 'password', array_to_string(changed_columns, ',')
+		FROM row_audit ORDER BY id
+	`, [][]any{
+		{"INSERT", "alice", "a", false, nil},
+		{"UPDATE", "alice", "b", false, "name"},
+		{"DELETE", "alice", nil, nil, nil},
+	})
+}
+
+func TestRowAuditWithoutExclusions(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE notes (id BIGINT PRIMARY KEY, body TEXT);"+
+		pg.RowAuditMigration("", pg.RowAudit{Table: "notes"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO notes (id, body) VALUES (1, 'a');
+		UPDATE notes SET body = 'b';
+		DELETE FROM notes;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, `
+		SELECT operation, old_row ->> 'body', new_row ->> 'body', array_to_string(changed_columns, ',')
+		FROM row_audit ORDER BY id
+	`, [][]any{
+		{"INSERT", nil, "a", nil},
+		{"UPDATE", "a", "b", "body"},
+		{"DELETE", "b", nil, nil},
+	})
+}
@@ -0,0 +1,22 @@
+package pg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRowAuditMigration(t *testing.T) {
+	sql := RowAuditMigration("", RowAudit{Table: "app.users", ExcludeColumns: []string{"password"}})
+	for _, expected := range []string{
+		`CREATE TABLE IF NOT EXISTS "row_audit" (`,
+		`CREATE TRIGGER "users_row_audit" AFTER INSERT OR UPDATE OR DELETE ON "app"."users" FOR EACH ROW EXECUTE FUNCTION "row_audit_trigger"('password');`,
+	} {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("expected %v in %v", expected, sql)
+		}
+	}
+	down := RowAuditDownMigration(RowAudit{Table: "app.users"})
+	if down != `DROP TRIGGER IF EXISTS "users_row_audit" ON "app"."users";`+"\n" {
+		t.Errorf("unexpected down migration %v", down)
+	}
+}
@@ -0,0 +1,61 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"strconv"
+	"sync/atomic"
+)
+
+var savepointCounter atomic.Uint64
+
+type SavepointHandle struct {
+	tx   pgx.Tx
+	name string
+}
+
+// Savepoint creates a named savepoint in tx.
+func Savepoint(ctx context.Context, tx pgx.Tx, name string) (*SavepointHandle, error) {
+	_, err := tx.Exec(ctx, "SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+	if err != nil {
+		return nil, err
+	}
+	return &SavepointHandle{tx: tx, name: name}, nil
+}
+
+func (s *SavepointHandle) Name() string {
+	return s.name
+}
+
+// Release discards the savepoint, keeping the work done since it was created.
+func (s *SavepointHandle) Release(ctx context.Context) error {
+	_, err := s.tx.Exec(ctx, "RELEASE SAVEPOINT "+pgx.Identifier{s.name}.Sanitize())
+	return err
+}
+
+// RollbackTo undoes the work done since the savepoint was created. The savepoint stays valid.
+func (s *SavepointHandle) RollbackTo(ctx context.Context) error {
+	_, err := s.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+pgx.Identifier{s.name}.Sanitize())
+	return err
+}
+
+// WithSavepoint runs fn inside a savepoint, rolling back to it when fn fails or panics so the
+// surrounding transaction can continue.
+func WithSavepoint(ctx context.Context, tx pgx.Tx, fn func(tx pgx.Tx) error) error {
+	savepoint, err := Savepoint(ctx, tx, "pgutils_savepoint_"+strconv.FormatUint(savepointCounter.Add(1), 10))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = savepoint.RollbackTo(context.Background())
+			panic(p)
+		}
+	}()
+	err = fn(tx)
+	if err != nil {
+		_ = savepoint.RollbackTo(ctx)
+		return err
+	}
+	return savepoint.Release(ctx)
+}
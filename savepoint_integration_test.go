@@ -0,0 +1,76 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestSavepoints(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	insert := func(tx pgx.Tx, id int) {
+		t.Helper()
+		_, err := tx.Exec(ctx, "INSERT INTO testtable (id, name) VALUES ($1, 'savepoint')", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := pg.DoInTransactionNoResult(db.Pool, func(tx pgx.Tx) error {
+		savepoint, err := pg.Savepoint(ctx, tx, "first")
+		if err != nil {
+			return err
+		}
+		insert(tx, 2)
+		err = savepoint.RollbackTo(ctx)
+		if err != nil {
+			return err
+		}
+		// The savepoint stays valid after rolling back to it.
+		insert(tx, 3)
+		err = savepoint.Release(ctx)
+		if err != nil {
+			return err
+		}
+
+		failed := errors.New("failed")
+		err = pg.WithSavepoint(ctx, tx, func(tx pgx.Tx) error {
+			insert(tx, 4)
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("expected the failure, got %v", err)
+		}
+		err = pg.WithSavepoint(ctx, tx, func(tx pgx.Tx) error {
+			// The failed statement aborts the savepoint only, not the transaction.
+			_, err := tx.Exec(ctx, "INSERT INTO testtable (id, name) VALUES (1, 'duplicate')")
+			return err
+		})
+		if err == nil {
+			t.Error("expected the duplicate to fail")
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected the panic to be rethrown")
+				}
+			}()
+			_ = pg.WithSavepoint(ctx, tx, func(tx pgx.Tx) error {
+				insert(tx, 5)
+				panic("fn panicked")
+			})
+		}()
+		return pg.WithSavepoint(ctx, tx, func(tx pgx.Tx) error {
+			insert(tx, 6)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id FROM testtable WHERE name = 'savepoint' ORDER BY id",
+		[][]any{{int32(3)}, {int32(6)}})
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	start := time.Date(2024, 1, 31, 10, 7, 30, 0, time.UTC)
+	tests := map[string]time.Time{
+		"*/15 * * * *":   time.Date(2024, 1, 31, 10, 15, 0, 0, time.UTC),
+		"0 9 * * 1-5":    time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC),
+		"30 2 29 2 *":    time.Date(2024, 2, 29, 2, 30, 0, 0, time.UTC),
+		"5,10 10 * * *":  time.Date(2024, 1, 31, 10, 10, 0, 0, time.UTC),
+		"0 0 1 * 0":      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		"@every 1h":      time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC),
+		"10/20 10 * * *": time.Date(2024, 1, 31, 10, 10, 0, 0, time.UTC),
+	}
+	for spec, expected := range tests {
+		schedule, err := ParseSchedule(spec)
+		if err != nil {
+			t.Errorf("%v: %v", spec, err)
+			continue
+		}
+		if next := schedule.Next(start); !next.Equal(expected) {
+			t.Errorf("%v: expected %v, got %v", spec, expected, next)
+		}
+	}
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "@every 1ms", "a * * * *"} {
+		_, err := ParseSchedule(spec)
+		if err == nil {
+			t.Errorf("%v: expected an error", spec)
+		}
+	}
+}
+
+func TestMissedRuns(t *testing.T) {
+	schedule, _ := ParseSchedule("@every 1h")
+	nextRun := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := nextRun.Add(3*time.Hour + 30*time.Minute)
+	if runs := missedRuns(schedule, CatchUpAll, nextRun, now, time.Minute); len(runs) != 4 {
+		t.Errorf("expected every missed run, got %v", runs)
+	}
+	if runs := missedRuns(schedule, CatchUpOnce, nextRun, now, time.Minute); len(runs) != 1 {
+		t.Errorf("expected a single run, got %v", runs)
+	}
+	if runs := missedRuns(schedule, CatchUpSkip, nextRun, now, time.Minute); len(runs) != 0 {
+		t.Errorf("expected missed runs to be skipped, got %v", runs)
+	}
+	if runs := missedRuns(schedule, CatchUpSkip, nextRun, nextRun.Add(time.Second), time.Minute); len(runs) != 1 {
+		t.Errorf("expected an on-time run, got %v", runs)
+	}
+}
@@ -0,0 +1,205 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTable        = "schedules"
+	DefaultTickInterval = 10 * time.Second
+
+	// maxCatchUpRuns bounds CatchUpAll after a long outage.
+	maxCatchUpRuns = 1000
+)
+
+// CatchUpPolicy decides what happens to runs that were missed because no instance was up.
+type CatchUpPolicy int
+
+const (
+	// CatchUpSkip drops missed runs and waits for the next scheduled time.
+	CatchUpSkip CatchUpPolicy = iota
+	// CatchUpOnce runs the job once for any number of missed runs.
+	CatchUpOnce
+	// CatchUpAll runs the job once for every missed run, oldest first.
+	CatchUpAll
+)
+
+// JobFunc runs a job for the activation at scheduledAt.
+type JobFunc func(ctx context.Context, scheduledAt time.Time) error
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	policy   CatchUpPolicy
+	fn       JobFunc
+}
+
+// Scheduler fires registered jobs on their schedules. Every instance runs the same registrations;
+// schedule state lives in Table and each tick is guarded by an advisory lock, so only one instance
+// fires a given activation.
+type Scheduler struct {
+	pool *pgxpool.Pool
+	mu   sync.Mutex
+	jobs []*job
+
+	Table        string
+	TickInterval time.Duration
+}
+
+func New(pool *pgxpool.Pool) *Scheduler {
+	return &Scheduler{
+		pool:         pool,
+		Table:        DefaultTable,
+		TickInterval: DefaultTickInterval,
+	}
+}
+
+// Migration returns the script creating the schedules table, for inclusion in a migrations directory.
+func Migration(table string) string {
+	return `
+		CREATE TABLE IF NOT EXISTS ` + pg.QuoteIdentifier(table) + `
+		(
+			name TEXT PRIMARY KEY NOT NULL,
+			spec TEXT NOT NULL,
+			next_run TIMESTAMPTZ NOT NULL,
+			last_run TIMESTAMPTZ,
+			last_error TEXT NOT NULL DEFAULT ''
+		);
+	`
+}
+
+// Migrate creates the schedules table if it does not exist yet.
+func Migrate(ctx context.Context, q pg.Querier, table string) error {
+	_, err := q.Exec(ctx, Migration(table))
+	return err
+}
+
+// Register adds a job under a unique name with a schedule understood by ParseSchedule.
+func (s *Scheduler) Register(name string, spec string, policy CatchUpPolicy, fn JobFunc) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.name == name {
+			return fmt.Errorf("job %v is already registered", name)
+		}
+	}
+	s.jobs = append(s.jobs, &job{name: name, spec: spec, schedule: schedule, policy: policy, fn: fn})
+	return nil
+}
+
+func (s *Scheduler) registered() []*job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*job(nil), s.jobs...)
+}
+
+// Run ticks every TickInterval until ctx is cancelled, which is treated as a graceful shutdown
+// and returns nil.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.TickInterval)
+	defer ticker.Stop()
+	for {
+		err := s.Tick(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Warnf("Error running scheduler tick: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick fires the jobs that are due, unless another instance is ticking right now.
+func (s *Scheduler) Tick(ctx context.Context) error {
+	_, err := pg.TryAdvisoryLock(ctx, s.pool, "pgutils.scheduler."+s.Table, func(ctx context.Context) error {
+		var errs []error
+		for _, j := range s.registered() {
+			err := s.fire(ctx, j, time.Now())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("job %v: %w", j.name, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+	return err
+}
+
+func (s *Scheduler) fire(ctx context.Context, j *job, now time.Time) error {
+	table := pg.QuoteIdentifier(s.Table)
+	var nextRun time.Time
+	// A changed spec starts over from now rather than replaying the old schedule.
+	//goland:noinspection SqlResolve
+	err := s.pool.QueryRow(ctx, "INSERT INTO "+table+" AS s (name, spec, next_run) VALUES ($1, $2, $3) "+
+		"ON CONFLICT (name) DO UPDATE SET spec = EXCLUDED.spec, next_run = CASE WHEN s.spec = EXCLUDED.spec THEN s.next_run ELSE EXCLUDED.next_run END "+
+		"RETURNING next_run", j.name, j.spec, j.schedule.Next(now)).Scan(&nextRun)
+	if err != nil {
+		return err
+	}
+	if nextRun.After(now) {
+		return nil
+	}
+	var jobErrs []error
+	// An activation is only missed once it is older than a couple of ticks.
+	for _, scheduledAt := range missedRuns(j.schedule, j.policy, nextRun, now, 2*s.TickInterval) {
+		log.Debugf("Running scheduled job %v for %v", j.name, scheduledAt)
+		err := runJob(ctx, j, scheduledAt)
+		if err != nil {
+			log.Warnf("Scheduled job %v failed: %v", j.name, err)
+			jobErrs = append(jobErrs, err)
+		}
+	}
+	lastError := ""
+	if len(jobErrs) > 0 {
+		lastError = errors.Join(jobErrs...).Error()
+	}
+	//goland:noinspection SqlResolve
+	_, err = s.pool.Exec(ctx, "UPDATE "+table+" SET next_run = $2, last_run = $3, last_error = $4 WHERE name = $1", j.name, j.schedule.Next(now), now, lastError)
+	return err
+}
+
+// missedRuns lists the activations between nextRun and now to run according to policy. Activations
+// within grace of now are on time rather than missed.
+func missedRuns(schedule Schedule, policy CatchUpPolicy, nextRun time.Time, now time.Time, grace time.Duration) []time.Time {
+	runs := []time.Time{nextRun}
+	for t := schedule.Next(nextRun); !t.IsZero() && !t.After(now) && len(runs) < maxCatchUpRuns; t = schedule.Next(t) {
+		runs = append(runs, t)
+	}
+	switch policy {
+	case CatchUpAll:
+		return runs
+	case CatchUpOnce:
+		return runs[len(runs)-1:]
+	default:
+		last := runs[len(runs)-1]
+		if now.Sub(last) > grace {
+			return nil
+		}
+		return []time.Time{last}
+	}
+}
+
+func runJob(ctx context.Context, j *job, scheduledAt time.Time) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return j.fn(ctx, scheduledAt)
+}
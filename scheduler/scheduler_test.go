@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestTick(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	err := Migrate(ctx, db.Pool, DefaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := 0
+	s := New(db.Pool)
+	err = s.Register("cleanup", "@every 1h", CatchUpOnce, func(ctx context.Context, scheduledAt time.Time) error {
+		runs++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Tick(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 0 {
+		t.Fatalf("the job should wait for its first activation, ran %v times", runs)
+	}
+	_, err = db.Pool.Exec(ctx, "UPDATE schedules SET next_run = now() - interval '5 hours'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Tick(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Tick(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Errorf("missed runs should be caught up once, ran %v times", runs)
+	}
+}
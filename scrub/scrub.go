@@ -0,0 +1,190 @@
+// Package scrub masks personal data in a copy of a database, e.g. to give staging a production-like
+// but anonymized dataset. Never point it at a database whose data must be kept.
+package scrub
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Strategy string
+
+const (
+	// Null clears the column.
+	Null Strategy = "null"
+	// Hash replaces text with the hex SHA-256 of the salted value, keeping equal values equal.
+	Hash Strategy = "hash"
+	// Fake replaces the value with a plausible one of the kind named by Rule.Fake, derived from
+	// the salted value, so equal values get equal replacements.
+	Fake Strategy = "fake"
+	// Fixed replaces the value with Rule.Value.
+	Fixed Strategy = "fixed"
+)
+
+// fakes render a fake value of a kind from an expression hashing the original value, h.
+var fakes = map[string]func(h string) string{
+	"email": func(h string) string { return `'user_' || left(` + h + `, 12) || '@example.com'` },
+	"name": func(h string) string {
+		return pick(h, firstNames) + ` || ' ' || ` + pick("md5("+h+")", lastNames)
+	},
+	"first_name": func(h string) string { return pick(h, firstNames) },
+	"last_name":  func(h string) string { return pick(h, lastNames) },
+	"phone": func(h string) string {
+		return `'+1555' || lpad((('x' || left(` + h + `, 8))::bit(32)::bigint % 10000000)::text, 7, '0')`
+	},
+	"text": func(h string) string { return `'Lorem ipsum ' || left(` + h + `, 8)` },
+	"uuid": func(h string) string { return `left(` + h + `, 32)::uuid` },
+}
+
+var (
+	firstNames = []string{"Alice", "Bob", "Carol", "Dave", "Erin", "Frank", "Grace", "Heidi", "Ivan", "Judy"}
+	lastNames  = []string{"Smith", "Jones", "Brown", "Taylor", "Wilson", "Davies", "Evans", "Thomas", "Roberts", "Walker"}
+)
+
+// pick chooses an element of list by the hash h.
+func pick(h string, list []string) string {
+	quoted := pg.Map(list, func(s string) string { return "'" + s + "'" })
+	return `(ARRAY[` + strings.Join(quoted, ", ") + `])[1 + ('x' || left(` + h + `, 8))::bit(32)::bigint % ` + strconv.Itoa(len(list)) + `]`
+}
+
+// Rule masks a column of a table.
+type Rule struct {
+	Table    string   `yaml:"table"`
+	Column   string   `yaml:"column"`
+	Strategy Strategy `yaml:"strategy"`
+	// Fake is the kind of fake value: email, name, first_name, last_name, phone, text or uuid.
+	Fake  string `yaml:"fake"`
+	Value string `yaml:"value"`
+}
+
+// Rules is a scrubbing configuration, see ParseRules.
+type Rules struct {
+	// Salt makes hashes and fake values impossible to reverse by hashing guessed values.
+	Salt  string `yaml:"salt"`
+	Rules []Rule `yaml:"rules"`
+	// DisableTriggers keeps triggers such as audit logs from firing while scrubbing. It needs
+	// superuser privileges.
+	DisableTriggers bool `yaml:"disable_triggers"`
+}
+
+// ParseRules reads rules from YAML:
+//
+//	salt: change-me
+//	rules:
+//	  - {table: users, column: email, strategy: fake, fake: email}
+//	  - {table: users, column: password_hash, strategy: fixed, value: "!"}
+//	  - {table: users, column: notes, strategy: null}
+func ParseRules(data []byte) (Rules, error) {
+	var r Rules
+	err := yaml.Unmarshal(data, &r)
+	return r, err
+}
+
+// Statement is the UPDATE scrubbing a table.
+type Statement struct {
+	Table string
+	SQL   string
+	Args  []any
+}
+
+// Statements returns one UPDATE per table, in table order, without running them.
+func (r Rules) Statements() ([]Statement, error) {
+	byTable := make(map[string][]Rule)
+	for _, rule := range r.Rules {
+		if rule.Table == "" || rule.Column == "" {
+			return nil, fmt.Errorf("rule %+v needs a table and a column", rule)
+		}
+		byTable[rule.Table] = append(byTable[rule.Table], rule)
+	}
+	tables := make([]string, 0, len(byTable))
+	for table := range byTable {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	statements := make([]Statement, 0, len(tables))
+	for _, table := range tables {
+		statement := Statement{Table: table}
+		// The salt is only bound when a rule hashes, as a parameter the statement does not use
+		// has no type and fails it.
+		var salt string
+		hash := func(column string) string {
+			if salt == "" {
+				statement.Args = append(statement.Args, r.Salt)
+				salt = "$" + strconv.Itoa(len(statement.Args))
+			}
+			return `encode(sha256(convert_to(` + salt + ` || ` + column + `::text, 'UTF8')), 'hex')`
+		}
+		assignments := make([]string, 0, len(byTable[table]))
+		for _, rule := range byTable[table] {
+			column := pg.QuoteIdentifier(rule.Column)
+			var value string
+			switch rule.Strategy {
+			case Null:
+				value = "NULL"
+			case Hash:
+				value = hash(column)
+			case Fake:
+				fake, ok := fakes[rule.Fake]
+				if !ok {
+					return nil, fmt.Errorf("%v.%v: unknown fake %q", table, rule.Column, rule.Fake)
+				}
+				value = fake(hash(column))
+			case Fixed:
+				statement.Args = append(statement.Args, rule.Value)
+				value = "$" + strconv.Itoa(len(statement.Args))
+			default:
+				return nil, fmt.Errorf("%v.%v: unknown strategy %q", table, rule.Column, rule.Strategy)
+			}
+			if rule.Strategy != Null {
+				// NULLs stay NULL, so masked data keeps its shape.
+				value = "CASE WHEN " + column + " IS NULL THEN NULL ELSE " + value + " END"
+			}
+			assignments = append(assignments, column+" = "+value)
+		}
+		statement.SQL = "UPDATE " + pg.QuoteIdentifier(table) + " SET " + strings.Join(assignments, ", ")
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+// Result tells how many rows of a table were scrubbed.
+type Result struct {
+	Table string
+	Rows  int64
+}
+
+// Scrub applies rules in a single transaction, so a failing rule leaves the data untouched.
+func Scrub(ctx context.Context, db pg.TxBeginner, rules Rules) ([]Result, error) {
+	statements, err := rules.Statements()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	if rules.DisableTriggers {
+		_, err = tx.Exec(ctx, "SET LOCAL session_replication_role = replica")
+		if err != nil {
+			return nil, err
+		}
+	}
+	results := make([]Result, 0, len(statements))
+	for _, statement := range statements {
+		tag, err := tx.Exec(ctx, statement.SQL, statement.Args...)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", statement.Table, err)
+		}
+		results = append(results, Result{Table: statement.Table, Rows: tag.RowsAffected()})
+	}
+	return results, tx.Commit(ctx)
+}
@@ -0,0 +1,93 @@
+package scrub
+
+import (
+	"context"
+	"github.com/msumera/pgutils/pgtest"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+salt: pepper
+rules:
+  - {table: testtable, column: name, strategy: fake, fake: email}
+  - {table: testtable, column: description, strategy: fixed, value: redacted}
+  - {table: other.users, column: ssn, strategy: "null"}
+`
+
+func TestStatements(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements, err := rules.Statements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 2 || statements[0].SQL != `UPDATE "other"."users" SET "ssn" = NULL` || len(statements[0].Args) != 0 {
+		t.Fatalf("unexpected statements %+v", statements)
+	}
+	sql := statements[1].SQL
+	if !strings.HasPrefix(sql, `UPDATE "testtable" SET "name" = CASE WHEN "name" IS NULL THEN NULL ELSE 'user_' || left(`) ||
+		!strings.HasSuffix(sql, `"description" = CASE WHEN "description" IS NULL THEN NULL ELSE $2 END`) {
+		t.Errorf("unexpected statement %v", sql)
+	}
+	if len(statements[1].Args) != 2 || statements[1].Args[0] != "pepper" || statements[1].Args[1] != "redacted" {
+		t.Errorf("unexpected args %v", statements[1].Args)
+	}
+
+	for _, rule := range []Rule{
+		{Table: "users", Column: "email", Strategy: "shuffle"},
+		{Table: "users", Column: "email", Strategy: Fake, Fake: "credit_card"},
+		{Table: "users", Strategy: Null},
+	} {
+		if _, err = (Rules{Rules: []Rule{rule}}).Statements(); err == nil {
+			t.Errorf("expected %+v to be rejected", rule)
+		}
+	}
+}
+
+func TestScrub(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("../testdb"))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "INSERT INTO testtable (id, name) VALUES (2, 'name1'), (3, 'name3')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := Rules{Salt: "pepper", Rules: []Rule{
+		{Table: "testtable", Column: "name", Strategy: Fake, Fake: "name"},
+		{Table: "testtable", Column: "description", Strategy: Hash},
+	}}
+	results, err := Scrub(ctx, db.Pool, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Rows != 3 {
+		t.Errorf("unexpected results %+v", results)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT 1 FROM testtable WHERE name LIKE 'name%' OR description = 'name1'")
+	// Equal values get equal replacements, so joins on them still match.
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT count(DISTINCT name) FROM testtable WHERE id IN (1, 2)", [][]any{{int64(1)}})
+}
+
+func TestScrubWithoutHashing(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("../testdb"))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE secrets (id INT, token TEXT); INSERT INTO secrets VALUES (1, 'a'), (2, NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := Rules{Salt: "pepper", Rules: []Rule{
+		{Table: "testtable", Column: "description", Strategy: Null},
+		{Table: "secrets", Column: "token", Strategy: Fixed, Value: "redacted"},
+	}}
+	results, err := Scrub(ctx, db.Pool, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Table != "secrets" || results[0].Rows != 2 || results[1].Rows != 1 {
+		t.Errorf("unexpected results %+v", results)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT 1 FROM testtable WHERE description IS NOT NULL")
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT id, token FROM secrets ORDER BY id", [][]any{{int32(1), "redacted"}, {int32(2), nil}})
+}
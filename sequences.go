@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+)
+
+// SequenceFix reports the value a sequence was moved to by FixSequences.
+type SequenceFix struct {
+	Sequence string
+	Table    string
+	Column   string
+	// Value is the greater of the maximum of the column and the last value drawn from the
+	// sequence, so the next value drawn follows it; sequences are never moved backwards. When there
+	// is neither, Value is the minimum value of the sequence, which is drawn next. Descending
+	// sequences use the minimum of the column and their maximum value instead.
+	Value int64
+}
+
+// SequenceValue returns the last value drawn from sequence, or its start value when none was.
+func SequenceValue(ctx context.Context, q Querier, sequence string) (int64, error) {
+	var value int64
+	//goland:noinspection SqlResolve
+	err := q.QueryRow(ctx, "SELECT last_value FROM "+QuoteIdentifier(sequence)).Scan(&value)
+	return value, err
+}
+
+// SetSequence makes next the value the next nextval call on sequence returns.
+func SetSequence(ctx context.Context, q Querier, sequence string, next int64) error {
+	_, err := q.Exec(ctx, "SELECT setval($1::regclass, $2, false)", QuoteIdentifier(sequence), next)
+	return err
+}
+
+// FixSequences moves the serial and identity sequences of all tables in schema past the values
+// stored in their columns, leaving those already past them, as needed after bulk imports or
+// restores that set the ids explicitly.
+func FixSequences(ctx context.Context, q Querier, schema string) ([]SequenceFix, error) {
+	return fixSequences(ctx, q, "n.nspname = $1", schema)
+}
+
+// FixTableSequences is FixSequences for the sequences owned by the columns of table.
+func FixTableSequences(ctx context.Context, q Querier, table string) ([]SequenceFix, error) {
+	return fixSequences(ctx, q, "t.oid = $1::regclass", QuoteIdentifier(table))
+}
+
+func fixSequences(ctx context.Context, q Querier, condition string, arg any) ([]SequenceFix, error) {
+	rows, err := q.Query(ctx, `
+		SELECT format('%I.%I', sn.nspname, s.relname), format('%I.%I', n.nspname, t.relname), a.attname
+		FROM pg_depend d
+			JOIN pg_class s ON s.oid = d.objid AND s.relkind = 'S'
+			JOIN pg_namespace sn ON sn.oid = s.relnamespace
+			JOIN pg_class t ON t.oid = d.refobjid
+			JOIN pg_namespace n ON n.oid = t.relnamespace
+			JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+		WHERE d.classid = 'pg_class'::regclass AND d.refclassid = 'pg_class'::regclass
+			AND d.deptype IN ('a', 'i') AND `+condition+`
+		ORDER BY 2, 3
+	`, arg)
+	if err != nil {
+		return nil, err
+	}
+	fixes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SequenceFix, error) {
+		var fix SequenceFix
+		err := row.Scan(&fix.Sequence, &fix.Table, &fix.Column)
+		return fix, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range fixes {
+		fix := &fixes[i]
+		column := pgx.Identifier{fix.Column}.Sanitize()
+		//goland:noinspection SqlResolve
+		err = q.QueryRow(ctx, `
+			SELECT setval(p.seqrelid, CASE WHEN x.past THEN t.v ELSE t.first END, x.past)
+			FROM pg_sequence p,
+				LATERAL (
+					SELECT CASE WHEN p.seqincrement > 0 THEN GREATEST(c.hi, s.l) ELSE LEAST(c.lo, s.l) END AS v,
+						CASE WHEN p.seqincrement > 0 THEN p.seqmin ELSE p.seqmax END AS first
+					FROM (SELECT max(`+column+`) AS hi, min(`+column+`) AS lo FROM `+fix.Table+`) c,
+						(SELECT CASE WHEN is_called THEN last_value END AS l FROM `+fix.Sequence+`) s
+				) t,
+				LATERAL (
+					SELECT (CASE WHEN p.seqincrement > 0 THEN t.v >= t.first ELSE t.v <= t.first END) IS TRUE AS past
+				) x
+			WHERE p.seqrelid = $1::regclass
+		`, fix.Sequence).Scan(&fix.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fixes, nil
+}
@@ -0,0 +1,66 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestFixSequences(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE serials (id SERIAL PRIMARY KEY);
+		CREATE TABLE identities (id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY);
+		CREATE TABLE empty (id BIGSERIAL PRIMARY KEY);
+		INSERT INTO serials (id) VALUES (41), (42);
+		INSERT INTO identities (id) VALUES (7);
+		CREATE TABLE ahead (id SERIAL PRIMARY KEY);
+		SELECT setval('ahead_id_seq', 50);
+		INSERT INTO ahead (id) VALUES (5);
+		CREATE SEQUENCE bounded_seq MINVALUE 10;
+		CREATE TABLE bounded (id BIGINT DEFAULT nextval('bounded_seq'));
+		ALTER SEQUENCE bounded_seq OWNED BY bounded.id;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixes, err := pg.FixSequences(ctx, db.Pool, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 5 || fixes[0].Value != 50 || fixes[1].Value != 10 || fixes[2].Table != "public.empty" ||
+		fixes[2].Value != 1 || fixes[3].Value != 7 || fixes[4].Value != 42 {
+		t.Errorf("unexpected fixes %+v", fixes)
+	}
+	var id int64
+	err = db.Pool.QueryRow(ctx, "INSERT INTO serials DEFAULT VALUES RETURNING id").Scan(&id)
+	if err != nil || id != 43 {
+		t.Errorf("expected the next serial to follow the maximum, got %v, %v", id, err)
+	}
+	err = db.Pool.QueryRow(ctx, "INSERT INTO empty DEFAULT VALUES RETURNING id").Scan(&id)
+	if err != nil || id != 1 {
+		t.Errorf("expected an empty table to start at 1, got %v, %v", id, err)
+	}
+	err = db.Pool.QueryRow(ctx, "INSERT INTO ahead DEFAULT VALUES RETURNING id").Scan(&id)
+	if err != nil || id != 51 {
+		t.Errorf("expected the sequence not to move backwards, got %v, %v", id, err)
+	}
+	err = db.Pool.QueryRow(ctx, "INSERT INTO bounded DEFAULT VALUES RETURNING id").Scan(&id)
+	if err != nil || id != 10 {
+		t.Errorf("expected an empty table to start at the minimum value, got %v, %v", id, err)
+	}
+	err = pg.SetSequence(ctx, db.Pool, fixes[3].Sequence, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Pool.QueryRow(ctx, "INSERT INTO identities DEFAULT VALUES RETURNING id").Scan(&id)
+	if err != nil || id != 100 {
+		t.Errorf("expected the set value, got %v, %v", id, err)
+	}
+	value, err := pg.SequenceValue(ctx, db.Pool, fixes[3].Sequence)
+	if err != nil || value != 100 {
+		t.Errorf("unexpected sequence value %v, %v", value, err)
+	}
+}
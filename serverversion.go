@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// minVersionDirective marks a migration that needs at least the given server version, e.g.
+// "-- pg:min-version 15" on a line of its own.
+const minVersionDirective = "-- pg:min-version"
+
+var ErrServerVersion = errors.New("server version too old")
+
+// parseServerVersion converts a version like 15, 15.2 or 9.6 to the server_version_num form.
+func parseServerVersion(version string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	numbers := make([]int, 3)
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid server version %q", version)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid server version %q", version)
+		}
+		numbers[i] = n
+	}
+	if numbers[0] >= 10 {
+		// Since postgres 10 the second number is the minor version.
+		return numbers[0]*10000 + numbers[1], nil
+	}
+	return numbers[0]*10000 + numbers[1]*100 + numbers[2], nil
+}
+
+func formatServerVersion(num int) string {
+	if num >= 100000 {
+		return fmt.Sprintf("%v.%v", num/10000, num%10000)
+	}
+	return fmt.Sprintf("%v.%v.%v", num/10000, num/100%100, num%100)
+}
+
+// requiredServerVersion returns the version the script's min-version directive asks for, or 0.
+func requiredServerVersion(script string) (int, error) {
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if version, ok := strings.CutPrefix(line, minVersionDirective); ok {
+			return parseServerVersion(version)
+		}
+	}
+	return 0, nil
+}
+
+func (dbm *databaseMigrator) queryServerVersion() (int, error) {
+	var version int
+	err := dbm.queryRow(dbm.PgxPool, "SELECT current_setting('server_version_num')::int").Scan(&version)
+	return version, err
+}
+
+// checkServerVersion fails when the server is older than required, a server_version_num value.
+func checkServerVersion(server int, required int, what string) error {
+	if server >= required {
+		return nil
+	}
+	return fmt.Errorf("%w: %v needs %v, the server runs %v", ErrServerVersion, what,
+		formatServerVersion(required), formatServerVersion(server))
+}
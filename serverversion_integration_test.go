@@ -0,0 +1,44 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServerVersionGating(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	c := db.Configuration
+	c.MigrationsDirectory = "testdb"
+	c.MinServerVersion = "99"
+	err := pg.Migrate(db.Pool, c)
+	if !errors.Is(err, pg.ErrServerVersion) {
+		t.Errorf("expected ErrServerVersion, got %v", err)
+	}
+
+	directory := t.TempDir()
+	err = os.WriteFile(filepath.Join(directory, "0_future.sql"), []byte("-- pg:min-version 99\nCREATE TABLE future (id INT);"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(directory, "1_present.sql"), []byte("CREATE TABLE present (id INT);"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = db.Configuration
+	c.MigrationsDirectory = directory
+	c.SkipUnsupportedMigrations = true
+	err = pg.Migrate(db.Pool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := pg.Changelog(ctx, db.Pool, c, pg.ChangelogFilter{})
+	if err != nil || len(entries) != 1 || entries[0].Filename != "1_present.sql" {
+		t.Errorf("expected only the supported migration to be recorded, got %+v, %v", entries, err)
+	}
+}
@@ -0,0 +1,37 @@
+package pg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	for version, expected := range map[string]int{"15": 150000, "15.2": 150002, "9.6": 90600, "9.6.5": 90605} {
+		num, err := parseServerVersion(version)
+		if err != nil || num != expected {
+			t.Errorf("unexpected result for %v: %v, %v", version, num, err)
+		}
+	}
+	_, err := parseServerVersion("fifteen")
+	if err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+	if formatServerVersion(150002) != "15.2" || formatServerVersion(90605) != "9.6.5" {
+		t.Error("unexpected formatted versions")
+	}
+}
+
+func TestRequiredServerVersion(t *testing.T) {
+	required, err := requiredServerVersion("-- adds a MERGE based upsert\n-- pg:min-version 15\nMERGE INTO a USING b ON true WHEN MATCHED THEN DO NOTHING;")
+	if err != nil || required != 150000 {
+		t.Errorf("unexpected required version %v, %v", required, err)
+	}
+	required, err = requiredServerVersion("SELECT 1;")
+	if err != nil || required != 0 {
+		t.Errorf("scripts without a directive should not require a version, got %v, %v", required, err)
+	}
+	err = checkServerVersion(140005, 150000, "migration 1_merge.sql")
+	if !errors.Is(err, ErrServerVersion) || err.Error() != "server version too old: migration 1_merge.sql needs 15.0, the server runs 14.5" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+)
+
+// WithSettings runs fn in a transaction with settings applied as SET LOCAL, e.g.
+// {"work_mem": "256MB", "enable_seqscan": "off", "role": "reporting"}. The settings end with the
+// transaction, so they never leak to other users of a pooled connection. When db is a transaction,
+// fn runs in a savepoint and the previous values are restored after it.
+func WithSettings(ctx context.Context, db TxBeginner, settings map[string]string, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	outer, nested := db.(pgx.Tx)
+	var previous map[string]string
+	if nested {
+		var err error
+		previous, err = currentSettings(ctx, outer, settings)
+		if err != nil {
+			return err
+		}
+	}
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer trackTx(tx)()
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	err = SetLocal(ctx, tx, settings)
+	if err != nil {
+		return err
+	}
+	err = fn(ContextWithTx(ctx, tx), tx)
+	if err != nil {
+		return err
+	}
+	// Rolling back a savepoint restores the settings, releasing it does not.
+	err = SetLocal(ctx, tx, previous)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// currentSettings returns the values the names of settings have in q.
+func currentSettings(ctx context.Context, q Querier, settings map[string]string) (map[string]string, error) {
+	current := make(map[string]string, len(settings))
+	for name := range settings {
+		var value *string
+		err := q.QueryRow(ctx, "SELECT current_setting($1, true)", name).Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			current[name] = *value
+		}
+	}
+	return current, nil
+}
@@ -0,0 +1,63 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestWithSettings(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Release()
+	_, err = conn.Exec(ctx, "CREATE ROLE reporting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := map[string]string{"work_mem": "64MB", "enable_seqscan": "off", "role": "reporting"}
+	err = pg.WithSettings(ctx, conn.Conn(), settings, func(ctx context.Context, tx pgx.Tx) error {
+		pgtest.AssertQueryReturns(t, tx, "SELECT current_setting('work_mem'), current_setting('enable_seqscan'), current_user::text",
+			[][]any{{"64MB", "off", "reporting"}})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, conn, "SELECT current_setting('work_mem'), current_setting('enable_seqscan'), current_user::text",
+		[][]any{{"4MB", "on", "postgres"}})
+
+	failed := errors.New("failed")
+	err = pg.WithSettings(ctx, conn.Conn(), settings, func(ctx context.Context, tx pgx.Tx) error { return failed })
+	if !errors.Is(err, failed) {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+	pgtest.AssertQueryReturns(t, conn, "SELECT current_setting('work_mem')", [][]any{{"4MB"}})
+
+	// Inside a transaction only the savepoint sees the settings.
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	err = pg.SetLocal(ctx, tx, map[string]string{"work_mem": "8MB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.WithSettings(ctx, tx, map[string]string{"work_mem": "128MB"}, func(ctx context.Context, tx pgx.Tx) error {
+		pgtest.AssertQueryReturns(t, tx, "SELECT current_setting('work_mem')", [][]any{{"128MB"}})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgtest.AssertQueryReturns(t, tx, "SELECT current_setting('work_mem')", [][]any{{"8MB"}})
+}
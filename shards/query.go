@@ -0,0 +1,56 @@
+package shards
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// QueryAll runs the query on every shard, at most Parallelism at once, and returns the rows of all
+// shards in shard order. Rows are scanned into T by column name when T is a struct, and from the
+// single column otherwise. When some shards fail, the rows of the others are returned with the
+// joined *ShardError of the failed ones; see FailedShards.
+func QueryAll[T any](ctx context.Context, m *Manager, sql string, args ...any) ([]T, error) {
+	results := make(map[string][]T, len(m.names))
+	var mu sync.Mutex
+	err := m.ForAll(ctx, func(ctx context.Context, shard string, pool *pgxpool.Pool) error {
+		rows, err := pool.Query(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		collected, err := pgx.CollectRows(rows, rowTo[T]())
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		results[shard] = collected
+		return nil
+	})
+	var merged []T
+	for _, name := range m.names {
+		merged = append(merged, results[name]...)
+	}
+	return merged, err
+}
+
+// QueryAllSorted is QueryAll with the merged rows sorted by cmp, e.g. to apply the query's ORDER
+// BY across shards. Rows comparing equal keep their shard order.
+func QueryAllSorted[T any](ctx context.Context, m *Manager, cmp func(a, b T) int, sql string, args ...any) ([]T, error) {
+	rows, err := QueryAll[T](ctx, m, sql, args...)
+	slices.SortStableFunc(rows, cmp)
+	return rows, err
+}
+
+func rowTo[T any]() pgx.RowToFunc[T] {
+	t := reflect.TypeFor[T]()
+	if t.Kind() == reflect.Struct && t != reflect.TypeFor[time.Time]() && !reflect.PointerTo(t).Implements(reflect.TypeFor[sql.Scanner]()) {
+		return pgx.RowToStructByNameLax[T]
+	}
+	return pgx.RowTo[T]
+}
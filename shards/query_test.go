@@ -0,0 +1,64 @@
+package shards
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"reflect"
+	"testing"
+)
+
+func TestFailedShards(t *testing.T) {
+	err := errors.Join(&ShardError{Shard: "c", Err: errors.New("down")}, nil, &ShardError{Shard: "a", Err: errors.New("down")})
+	if failed := FailedShards(err); !reflect.DeepEqual(failed, []string{"a", "c"}) {
+		t.Errorf("unexpected failed shards %v", failed)
+	}
+	if failed := FailedShards(nil); failed != nil {
+		t.Errorf("expected no failed shards, got %v", failed)
+	}
+}
+
+type user struct {
+	ID   int64
+	Name string
+}
+
+func TestQueryAll(t *testing.T) {
+	ctx := context.Background()
+	configurations := make(map[string]pg.Configuration)
+	for i, name := range []string{"a", "b", "c"} {
+		db := pgtest.StartPostgres(t)
+		configurations[name] = db.Configuration
+		if name == "c" {
+			continue
+		}
+		_, err := db.Pool.Exec(ctx, "CREATE TABLE users (id BIGINT, name TEXT); INSERT INTO users VALUES ($1, $2), ($3, $4)",
+			i, "user"+name, i+10, "other"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := NewManager(configurations)
+	m.Parallelism = 2
+	defer m.Close()
+
+	users, err := QueryAllSorted(ctx, m, func(a, b user) int { return int(a.ID - b.ID) }, "SELECT id, name FROM users")
+	if failed := FailedShards(err); !reflect.DeepEqual(failed, []string{"c"}) {
+		t.Errorf("expected shard c to fail, got %v", err)
+	}
+	expected := []user{{0, "usera"}, {1, "userb"}, {10, "othera"}, {11, "otherb"}}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("unexpected users %v", users)
+	}
+
+	ids, err := QueryAll[int64](ctx, m, "SELECT 1")
+	if err != nil || len(ids) != 3 {
+		t.Errorf("unexpected ids %v, %v", ids, err)
+	}
+	err = m.ForAll(ctx, func(ctx context.Context, shard string, pool *pgxpool.Pool) error { return nil })
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,202 @@
+package shards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoShards     = errors.New("no shards configured")
+	ErrUnknownShard = errors.New("unknown shard")
+)
+
+// Manager keeps a pool per shard, each a database described by its Configuration, and routes
+// shard keys to them.
+type Manager struct {
+	configurations map[string]pg.Configuration
+	names          []string
+	mu             sync.Mutex
+	pools          map[string]*pgxpool.Pool
+
+	// Parallelism bounds the shards ForAll, QueryAll and Migrate work on at once; all of them when
+	// zero.
+	Parallelism int
+	// ShardFor maps a key to the name of its shard. By default the key is hashed onto the shard
+	// names in sorted order, which moves most keys when shards are added; set it to a directory
+	// lookup or consistent hash where that matters.
+	ShardFor func(key string) string
+}
+
+// NewManager returns a manager of the shards named by the keys of configurations. Pools are
+// opened on first use.
+func NewManager(configurations map[string]pg.Configuration) *Manager {
+	m := &Manager{
+		configurations: maps.Clone(configurations),
+		names:          slices.Sorted(maps.Keys(configurations)),
+		pools:          make(map[string]*pgxpool.Pool),
+	}
+	m.ShardFor = m.hashShard
+	return m
+}
+
+func (m *Manager) hashShard(key string) string {
+	if len(m.names) == 0 {
+		return ""
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return m.names[h.Sum32()%uint32(len(m.names))]
+}
+
+// Shards returns the names of the shards, sorted.
+func (m *Manager) Shards() []string {
+	return slices.Clone(m.names)
+}
+
+// Pool returns the pool of the named shard, connecting it when needed. Migrations are left to
+// Migrate, whatever the shard's MigrationsEnabled. Shards are connected without holding the lock,
+// so a slow or unreachable shard does not hold up the others.
+func (m *Manager) Pool(name string) (*pgxpool.Pool, error) {
+	c, ok := m.configurations[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownShard, name)
+	}
+	m.mu.Lock()
+	pool, ok := m.pools[name]
+	m.mu.Unlock()
+	if ok {
+		return pool, nil
+	}
+	c.MigrationsEnabled = false
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		return nil, fmt.Errorf("shard %v: %w", name, err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Another caller may have connected the shard meanwhile; its pool is kept.
+	if existing, ok := m.pools[name]; ok {
+		pool.Close()
+		return existing, nil
+	}
+	m.pools[name] = pool
+	return pool, nil
+}
+
+// ForKey returns the pool of the shard holding key.
+func (m *Manager) ForKey(ctx context.Context, key string) (*pgxpool.Pool, error) {
+	if len(m.names) == 0 {
+		return nil, ErrNoShards
+	}
+	return m.Pool(m.ShardFor(key))
+}
+
+// ShardError is the failure of a shard in ForAll, QueryAll and Migrate.
+type ShardError struct {
+	Shard string
+	Err   error
+}
+
+func (e *ShardError) Error() string {
+	return fmt.Sprintf("shard %v: %v", e.Shard, e.Err)
+}
+
+func (e *ShardError) Unwrap() error {
+	return e.Err
+}
+
+// FailedShards returns the shards whose errors err joins, sorted.
+func FailedShards(err error) []string {
+	var failed []string
+	var shardErr *ShardError
+	if errors.As(err, &shardErr) {
+		failed = append(failed, shardErr.Shard)
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			failed = append(failed, FailedShards(err)...)
+		}
+	}
+	slices.Sort(failed)
+	return slices.Compact(failed)
+}
+
+// each calls fn for every shard, at most Parallelism at once, and waits for all of them.
+func (m *Manager) each(fn func(i int, name string)) {
+	parallelism := m.Parallelism
+	if parallelism <= 0 {
+		parallelism = len(m.names)
+	}
+	slots := make(chan struct{}, max(parallelism, 1))
+	var wg sync.WaitGroup
+	for i, name := range m.names {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fn(i, name)
+		}()
+	}
+	wg.Wait()
+}
+
+// ForAll runs fn for every shard concurrently and waits for all of them. The errors are joined as
+// *ShardError.
+func (m *Manager) ForAll(ctx context.Context, fn func(ctx context.Context, shard string, pool *pgxpool.Pool) error) error {
+	errs := make([]error, len(m.names))
+	m.each(func(i int, name string) {
+		pool, err := m.Pool(name)
+		if err == nil {
+			err = fn(ctx, name, pool)
+		}
+		if err != nil {
+			errs[i] = &ShardError{Shard: name, Err: err}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// MigrationStatus is the outcome of migrating a shard.
+type MigrationStatus struct {
+	Shard    string
+	Duration time.Duration
+	Err      error
+}
+
+// Migrate applies the pending migrations of each shard's Configuration to all shards
+// concurrently. A failing shard does not stop the others; the statuses are in shard order and the
+// error joins those of the failed shards.
+func (m *Manager) Migrate(ctx context.Context) ([]MigrationStatus, error) {
+	statuses := make([]MigrationStatus, len(m.names))
+	m.each(func(i int, name string) {
+		start := time.Now()
+		pool, err := m.Pool(name)
+		if err == nil {
+			err = pg.Migrate(pool, m.configurations[name])
+		}
+		if err != nil {
+			err = &ShardError{Shard: name, Err: err}
+		}
+		statuses[i] = MigrationStatus{Shard: name, Duration: time.Since(start), Err: err}
+	})
+	return statuses, errors.Join(pg.Map(statuses, func(s MigrationStatus) error { return s.Err })...)
+}
+
+// Close closes the pools of all shards.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, pool := range m.pools {
+		pool.Close()
+		delete(m.pools, name)
+	}
+}
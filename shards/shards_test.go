@@ -0,0 +1,97 @@
+package shards
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"sync"
+	"testing"
+)
+
+func TestShardFor(t *testing.T) {
+	m := NewManager(map[string]pg.Configuration{"b": {}, "a": {}, "c": {}})
+	if shards := m.Shards(); len(shards) != 3 || shards[0] != "a" || shards[2] != "c" {
+		t.Errorf("unexpected shards %v", shards)
+	}
+	seen := make(map[string]bool)
+	for _, key := range []string{"user-1", "user-2", "user-3", "user-4", "user-5", "user-6", "user-7", "user-8"} {
+		shard := m.ShardFor(key)
+		if shard != m.ShardFor(key) {
+			t.Errorf("key %v moved between shards", key)
+		}
+		seen[shard] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected keys to spread over the shards, got %v", seen)
+	}
+	_, err := m.Pool("d")
+	if !errors.Is(err, ErrUnknownShard) {
+		t.Errorf("expected ErrUnknownShard, got %v", err)
+	}
+	_, err = NewManager(nil).ForKey(context.Background(), "user-1")
+	if !errors.Is(err, ErrNoShards) {
+		t.Errorf("expected ErrNoShards, got %v", err)
+	}
+}
+
+func TestMigrateAndForAll(t *testing.T) {
+	first := pgtest.StartPostgres(t)
+	second := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	configurations := make(map[string]pg.Configuration)
+	for name, db := range map[string]*pgtest.Database{"first": first, "second": second} {
+		c := db.Configuration
+		c.MigrationsDirectory = "../testdb"
+		configurations[name] = c
+	}
+	m := NewManager(configurations)
+	defer m.Close()
+	statuses, err := m.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].Shard != "first" || statuses[1].Shard != "second" {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+	var mu sync.Mutex
+	counts := make(map[string]int64)
+	err = m.ForAll(ctx, func(ctx context.Context, shard string, pool *pgxpool.Pool) error {
+		count, err := pg.Scalar[int64](ctx, pool, "SELECT count(*) FROM changelog")
+		mu.Lock()
+		defer mu.Unlock()
+		counts[shard] = count
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["first"] == 0 || counts["first"] != counts["second"] {
+		t.Errorf("unexpected changelog counts %v", counts)
+	}
+	pool, err := m.ForKey(ctx, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := m.Pool(m.ShardFor("user-1"))
+	if pool != expected {
+		t.Error("expected the pool of the key's shard")
+	}
+	m.Close()
+	pools := make([]*pgxpool.Pool, 4)
+	var wg sync.WaitGroup
+	for i := range pools {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pools[i], _ = m.Pool("first")
+		}()
+	}
+	wg.Wait()
+	for _, p := range pools {
+		if p == nil || p != pools[0] {
+			t.Fatalf("expected concurrent callers to share one pool, got %v", pools)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// DefaultSizeSnapshotTable records the table sizes taken by SnapshotSizes.
+const DefaultSizeSnapshotTable = "table_size_snapshots"
+
+// sizeSchemaFilter restricts size queries to the schemas in $1, or to all user schemas.
+const sizeSchemaFilter = "(cardinality($1::text[]) = 0 AND n.nspname NOT LIKE 'pg\\_%' AND n.nspname <> 'information_schema' OR n.nspname = ANY($1))"
+
+// TableSize holds the sizes of a table in bytes. Rows is the planner's estimate. Bloat is the
+// estimated fraction of the heap that VACUUM FULL would reclaim, judged from the average row width
+// of the statistics; it is 0 for tables never analyzed.
+type TableSize struct {
+	Schema     string
+	Table      string
+	Rows       int64
+	TableBytes int64
+	IndexBytes int64
+	ToastBytes int64
+	TotalBytes int64
+	BloatBytes int64
+	Bloat      float64
+}
+
+// TableSizes returns the sizes of the tables of schemas, or of all user schemas, largest first.
+// Unlike BloatedIndexes it needs no extension and reads only catalogs.
+func TableSizes(ctx context.Context, q Querier, schemas ...string) ([]TableSize, error) {
+	if schemas == nil {
+		schemas = []string{}
+	}
+	// A row takes its data, a 24 byte header and a 4 byte line pointer; pages are filled to the
+	// fillfactor, 100% by default for tables.
+	rows, err := q.Query(ctx, `
+		SELECT schema_name, table_name, rows, table_bytes, index_bytes, toast_bytes, total_bytes,
+			GREATEST(0, table_bytes - expected_bytes)::bigint,
+			CASE WHEN table_bytes > 0 THEN GREATEST(0, 1 - expected_bytes / table_bytes) ELSE 0 END
+		FROM (
+			SELECT n.nspname AS schema_name, c.relname AS table_name, GREATEST(c.reltuples, 0)::bigint AS rows,
+				pg_relation_size(c.oid) AS table_bytes, pg_indexes_size(c.oid) AS index_bytes,
+				COALESCE(pg_total_relation_size(NULLIF(c.reltoastrelid, 0)), 0) AS toast_bytes,
+				pg_total_relation_size(c.oid) AS total_bytes,
+				COALESCE(ceil(GREATEST(c.reltuples, 0) * (28 + w.width) /
+					(current_setting('block_size')::float8 * COALESCE((SELECT split_part(o, '=', 2)::float8 FROM unnest(c.reloptions) o WHERE o LIKE 'fillfactor=%'), 100) / 100))
+					* current_setting('block_size')::float8, pg_relation_size(c.oid)) AS expected_bytes
+			FROM pg_class c
+				JOIN pg_namespace n ON n.oid = c.relnamespace
+				LEFT JOIN (
+					SELECT schemaname, tablename, sum(avg_width) AS width FROM pg_stats GROUP BY 1, 2
+				) w ON w.schemaname = n.nspname AND w.tablename = c.relname
+			WHERE c.relkind IN ('r', 'p', 'm') AND `+sizeSchemaFilter+`
+		) t
+		ORDER BY total_bytes DESC, 1, 2`, schemas)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (TableSize, error) {
+		var s TableSize
+		err := row.Scan(&s.Schema, &s.Table, &s.Rows, &s.TableBytes, &s.IndexBytes, &s.ToastBytes, &s.TotalBytes, &s.BloatBytes, &s.Bloat)
+		return s, err
+	})
+}
+
+// IndexSize is the size of an index in bytes with the number of scans since the statistics were
+// last reset.
+type IndexSize struct {
+	Schema string
+	Table  string
+	Index  string
+	Bytes  int64
+	Scans  int64
+	Unique bool
+}
+
+// IndexSizes returns the indexes of schemas, or of all user schemas, largest first.
+func IndexSizes(ctx context.Context, q Querier, schemas ...string) ([]IndexSize, error) {
+	if schemas == nil {
+		schemas = []string{}
+	}
+	rows, err := q.Query(ctx, `
+		SELECT n.nspname, t.relname, i.relname, pg_relation_size(i.oid), COALESCE(s.idx_scan, 0), x.indisunique
+		FROM pg_index x
+			JOIN pg_class i ON i.oid = x.indexrelid
+			JOIN pg_class t ON t.oid = x.indrelid
+			JOIN pg_namespace n ON n.oid = t.relnamespace
+			LEFT JOIN pg_stat_all_indexes s ON s.indexrelid = x.indexrelid
+		WHERE `+sizeSchemaFilter+`
+		ORDER BY 4 DESC, 1, 2, 3`, schemas)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (IndexSize, error) {
+		var s IndexSize
+		err := row.Scan(&s.Schema, &s.Table, &s.Index, &s.Bytes, &s.Scans, &s.Unique)
+		return s, err
+	})
+}
+
+// SizeSnapshotMigration returns the script creating the table SnapshotSizes records into, for
+// inclusion in a migrations directory.
+func SizeSnapshotMigration(table string) string {
+	return `
+		CREATE TABLE IF NOT EXISTS ` + QuoteIdentifier(table) + `
+		(
+			taken_at TIMESTAMPTZ NOT NULL,
+			schema_name TEXT NOT NULL,
+			table_name TEXT NOT NULL,
+			rows BIGINT NOT NULL,
+			table_bytes BIGINT NOT NULL,
+			index_bytes BIGINT NOT NULL,
+			total_bytes BIGINT NOT NULL,
+			PRIMARY KEY (schema_name, table_name, taken_at)
+		);
+		CREATE INDEX IF NOT EXISTS ` + QuoteIdentifier(lastPart(table)+"_taken_at") + ` ON ` + QuoteIdentifier(table) + ` (taken_at);
+	`
+}
+
+// SnapshotSizes records the current sizes of the tables of schemas, or of all user schemas, in
+// table, e.g. daily from a scheduler job, and returns the number of tables recorded.
+func SnapshotSizes(ctx context.Context, q Querier, table string, schemas ...string) (int64, error) {
+	sizes, err := TableSizes(ctx, q, schemas...)
+	if err != nil {
+		return 0, err
+	}
+	takenAt := time.Now()
+	rows := Map(sizes, func(s TableSize) []any {
+		return []any{takenAt, s.Schema, s.Table, s.Rows, s.TableBytes, s.IndexBytes, s.TotalBytes}
+	})
+	columns := []string{"taken_at", "schema_name", "table_name", "rows", "table_bytes", "index_bytes", "total_bytes"}
+	var recorded int64
+	for _, chunk := range Chunk(rows, maxQueryParameters/len(columns)) {
+		b := InsertInto(table).Columns(columns...)
+		for _, row := range chunk {
+			b.Values(row...)
+		}
+		sql, args := b.Build()
+		tag, err := q.Exec(ctx, sql, args...)
+		if err != nil {
+			return recorded, err
+		}
+		recorded += tag.RowsAffected()
+	}
+	return recorded, nil
+}
+
+// TableGrowth compares the first snapshot of a table in a period with the last one.
+type TableGrowth struct {
+	Schema    string
+	Table     string
+	From      time.Time
+	To        time.Time
+	FromBytes int64
+	ToBytes   int64
+	FromRows  int64
+	ToRows    int64
+}
+
+// Growth is the change in total bytes, negative for tables that shrank.
+func (g TableGrowth) Growth() int64 {
+	return g.ToBytes - g.FromBytes
+}
+
+// BytesPerDay extrapolates the growth to a day, 0 when both snapshots are the same.
+func (g TableGrowth) BytesPerDay() float64 {
+	elapsed := g.To.Sub(g.From)
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(g.Growth()) / elapsed.Hours() * 24
+}
+
+// SizeGrowth returns the growth of every table snapshotted in table since the given time, fastest
+// growing first.
+func SizeGrowth(ctx context.Context, q Querier, table string, since time.Time) ([]TableGrowth, error) {
+	//goland:noinspection SqlResolve
+	rows, err := q.Query(ctx, `
+		SELECT * FROM (
+			SELECT schema_name, table_name, min(taken_at), max(taken_at),
+				(array_agg(total_bytes ORDER BY taken_at))[1] AS from_bytes,
+				(array_agg(total_bytes ORDER BY taken_at DESC))[1] AS to_bytes,
+				(array_agg(rows ORDER BY taken_at))[1], (array_agg(rows ORDER BY taken_at DESC))[1]
+			FROM `+QuoteIdentifier(table)+`
+			WHERE taken_at >= $1
+			GROUP BY 1, 2
+		) g
+		ORDER BY to_bytes - from_bytes DESC, 1, 2`, since)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (TableGrowth, error) {
+		var g TableGrowth
+		err := row.Scan(&g.Schema, &g.Table, &g.From, &g.To, &g.FromBytes, &g.ToBytes, &g.FromRows, &g.ToRows)
+		return g, err
+	})
+}
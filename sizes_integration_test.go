@@ -0,0 +1,62 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestTableSizes(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE small (id INT PRIMARY KEY);
+		CREATE TABLE events (id INT PRIMARY KEY, payload TEXT);
+		INSERT INTO events SELECT i, repeat('x', 100) FROM generate_series(1, 10000) i;
+		ANALYZE events;
+	`+pg.SizeSnapshotMigration(pg.DefaultSizeSnapshotTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes, err := pg.TableSizes(ctx, db.Pool, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 3 || sizes[0].Table != "events" || sizes[0].Rows != 10000 || sizes[0].IndexBytes == 0 ||
+		sizes[0].TotalBytes < sizes[0].TableBytes+sizes[0].IndexBytes {
+		t.Fatalf("unexpected sizes %+v", sizes)
+	}
+	if sizes[0].Bloat > 0.2 {
+		t.Errorf("expected a fresh table to have little bloat, got %+v", sizes[0])
+	}
+	indexes, err := pg.IndexSizes(ctx, db.Pool, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 4 || indexes[0].Index != "events_pkey" || !indexes[0].Unique || indexes[0].Bytes == 0 {
+		t.Errorf("unexpected index sizes %+v", indexes)
+	}
+
+	start := time.Now()
+	recorded, err := pg.SnapshotSizes(ctx, db.Pool, pg.DefaultSizeSnapshotTable, "public")
+	if err != nil || recorded != 3 {
+		t.Fatalf("expected 3 recorded tables, got %v: %v", recorded, err)
+	}
+	_, err = db.Pool.Exec(ctx, "INSERT INTO small SELECT generate_series(1, 10000); ANALYZE small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pg.SnapshotSizes(ctx, db.Pool, pg.DefaultSizeSnapshotTable, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	growth, err := pg.SizeGrowth(ctx, db.Pool, pg.DefaultSizeSnapshotTable, start.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(growth) == 0 || growth[0].Table != "small" || growth[0].Growth() <= 0 || growth[0].ToRows != 10000 {
+		t.Errorf("unexpected growth %+v", growth)
+	}
+}
@@ -0,0 +1,17 @@
+package pg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTableGrowth(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := TableGrowth{From: from, To: from.Add(48 * time.Hour), FromBytes: 1000, ToBytes: 5000}
+	if g.Growth() != 4000 || g.BytesPerDay() != 2000 {
+		t.Errorf("unexpected growth %v, %v per day", g.Growth(), g.BytesPerDay())
+	}
+	if g = (TableGrowth{From: from, To: from, FromBytes: 1000, ToBytes: 800}); g.Growth() != -200 || g.BytesPerDay() != 0 {
+		t.Errorf("unexpected growth %v, %v per day", g.Growth(), g.BytesPerDay())
+	}
+}
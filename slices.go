@@ -0,0 +1,76 @@
+package pg
+
+func Map[T, R any](list []T, fn func(T) R) []R {
+	result := make([]R, 0, len(list))
+	for _, t := range list {
+		result = append(result, fn(t))
+	}
+	return result
+}
+
+// Filter returns the elements of list for which keep returns true.
+func Filter[T any](list []T, keep func(T) bool) []T {
+	result := make([]T, 0, len(list))
+	for _, t := range list {
+		if keep(t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// Reduce folds list into a single value, starting from initial.
+func Reduce[T, R any](list []T, initial R, fn func(R, T) R) R {
+	result := initial
+	for _, t := range list {
+		result = fn(result, t)
+	}
+	return result
+}
+
+// GroupBy groups the elements of list by key, keeping their order within each group.
+func GroupBy[T any, K comparable](list []T, key func(T) K) map[K][]T {
+	result := make(map[K][]T)
+	for _, t := range list {
+		k := key(t)
+		result[k] = append(result[k], t)
+	}
+	return result
+}
+
+// Chunk splits list into consecutive slices of at most size elements, e.g. to batch statements.
+// The chunks share list's backing array. It panics when size is not positive.
+func Chunk[T any](list []T, size int) [][]T {
+	if size <= 0 {
+		panic("pg.Chunk: size must be positive")
+	}
+	result := make([][]T, 0, (len(list)+size-1)/size)
+	for start := 0; start < len(list); start += size {
+		end := min(start+size, len(list))
+		result = append(result, list[start:end:end])
+	}
+	return result
+}
+
+// Uniq returns list without duplicates, keeping the first occurrence of each element.
+func Uniq[T comparable](list []T) []T {
+	seen := make(map[T]struct{}, len(list))
+	result := make([]T, 0, len(list))
+	for _, t := range list {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		result = append(result, t)
+	}
+	return result
+}
+
+// ToMap indexes list by key. Later elements win when keys collide.
+func ToMap[T any, K comparable](list []T, key func(T) K) map[K]T {
+	result := make(map[K]T, len(list))
+	for _, t := range list {
+		result[key(t)] = t
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package pg
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	result := Map([]int{1, 2}, strconv.Itoa)
+	if !reflect.DeepEqual(result, []string{"1", "2"}) {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	result := Filter([]int{1, 2, 3, 4}, func(i int) bool { return i%2 == 0 })
+	if !reflect.DeepEqual(result, []int{2, 4}) {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	result := Reduce([]int{1, 2, 3}, "", func(s string, i int) string { return s + strconv.Itoa(i) })
+	if result != "123" {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	result := GroupBy([]string{"ab", "c", "de"}, func(s string) int { return len(s) })
+	if !reflect.DeepEqual(result, map[int][]string{1: {"c"}, 2: {"ab", "de"}}) {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	result := Chunk([]int{1, 2, 3, 4, 5}, 2)
+	if !reflect.DeepEqual(result, [][]int{{1, 2}, {3, 4}, {5}}) {
+		t.Errorf("unexpected result %v", result)
+	}
+	result[0] = append(result[0], 9)
+	if result[1][0] != 3 {
+		t.Error("appending to a chunk should not overwrite the next one")
+	}
+	if len(Chunk([]int{}, 3)) != 0 {
+		t.Error("an empty list should have no chunks")
+	}
+}
+
+func TestUniq(t *testing.T) {
+	result := Uniq([]string{"b", "a", "b", "c", "a"})
+	if !reflect.DeepEqual(result, []string{"b", "a", "c"}) {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestToMap(t *testing.T) {
+	type user struct {
+		id   int
+		name string
+	}
+	result := ToMap([]user{{1, "a"}, {2, "b"}, {1, "c"}}, func(u user) int { return u.id })
+	if len(result) != 2 || result[1].name != "c" || result[2].name != "b" {
+		t.Errorf("unexpected result %v", result)
+	}
+}
@@ -0,0 +1,214 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// explainTimeout bounds capturing the plan of a slow query.
+const explainTimeout = 5 * time.Second
+
+// SlowQuery describes a statement that took at least Configuration.SlowQueryThreshold.
+type SlowQuery struct {
+	SQL      string
+	Args     []any
+	Duration time.Duration
+	// Plan is captured with Configuration.SlowQueryPlans; PlanErr tells why it is missing otherwise.
+	Plan    *Plan
+	PlanErr error
+}
+
+type slowQueryContextKey struct{}
+
+type slowQueryStart struct {
+	sql   string
+	args  []any
+	start time.Time
+}
+
+type skipSlowQueryContextKey struct{}
+
+// slowQueryTracer reports statements exceeding the threshold. Plans are captured on a separate
+// pooled connection after the statement finished, as the statement's own connection is busy.
+type slowQueryTracer struct {
+	threshold  time.Duration
+	plans      bool
+	sampleRate float64
+	analyze    bool
+	report     func(SlowQuery)
+	poolRef    *atomic.Pointer[pgxpool.Pool]
+	random     func() float64
+}
+
+func (t *slowQueryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if ctx.Value(skipSlowQueryContextKey{}) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, slowQueryContextKey{}, slowQueryStart{sql: data.SQL, args: data.Args, start: time.Now()})
+}
+
+func (t *slowQueryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(slowQueryContextKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+	duration := time.Since(start.start)
+	if duration < t.threshold {
+		return
+	}
+	slow := SlowQuery{SQL: start.sql, Args: start.args, Duration: duration}
+	pool := t.poolRef.Load()
+	if !t.plans || pool == nil || !t.sampled() {
+		t.report(slow)
+		return
+	}
+	go func() {
+		explainCtx, cancel := context.WithTimeout(context.WithValue(context.Background(), skipSlowQueryContextKey{}, true), explainTimeout)
+		defer cancel()
+		if t.analyze {
+			slow.Plan, slow.PlanErr = explainAnalyzeReadOnly(explainCtx, pool, slow.SQL, slow.Args)
+		} else {
+			slow.Plan, slow.PlanErr = Explain(explainCtx, pool, slow.SQL, slow.Args...)
+		}
+		t.report(slow)
+	}()
+}
+
+// sampled decides whether the plan of a slow query is captured.
+func (t *slowQueryTracer) sampled() bool {
+	return t.sampleRate <= 0 || t.sampleRate >= 1 || t.random() < t.sampleRate
+}
+
+var (
+	lockingClause = regexp.MustCompile(`\bFOR (UPDATE|NO KEY UPDATE|SHARE|KEY SHARE)\b|\bINTO\b`)
+	functionCall  = regexp.MustCompile(`([A-Z_][A-Z0-9_$]*) ?\(`)
+)
+
+// harmlessVolatile are volatile functions that have no effects, so statements calling them can be
+// run again.
+var harmlessVolatile = []string{"pg_sleep", "random", "clock_timestamp", "timeofday", "gen_random_uuid"}
+
+// analyzeCandidate returns the functions the statement calls when it is a plain SELECT without
+// locking clause, the only statements that may be run again under EXPLAIN ANALYZE.
+func analyzeCandidate(sql string) ([]string, bool) {
+	statements := splitScript(sql)
+	if len(statements) != 1 {
+		return nil, false
+	}
+	normalized := statements[0].normalized
+	if !strings.HasPrefix(normalized, "SELECT ") || lockingClause.MatchString(normalized) {
+		return nil, false
+	}
+	var functions []string
+	for _, match := range functionCall.FindAllStringSubmatch(normalized, -1) {
+		functions = append(functions, strings.ToLower(match[1]))
+	}
+	return functions, true
+}
+
+// analyzable tells whether the statement is a plain SELECT calling no volatile function, such as
+// nextval or pg_advisory_lock, whose effects a read only transaction does not prevent.
+func analyzable(ctx context.Context, q Querier, sql string) (bool, error) {
+	functions, ok := analyzeCandidate(sql)
+	if !ok || len(functions) == 0 {
+		return ok, nil
+	}
+	var volatile bool
+	err := q.QueryRow(ctx, `SELECT EXISTS (
+		SELECT FROM pg_proc WHERE proname = ANY ($1) AND provolatile = 'v' AND NOT proname = ANY ($2))`,
+		functions, harmlessVolatile).Scan(&volatile)
+	return !volatile, err
+}
+
+// explainAnalyzeReadOnly runs the statement again under EXPLAIN ANALYZE in a read only transaction
+// that is rolled back, when it is analyzable. It falls back to EXPLAIN otherwise or when that
+// fails.
+func explainAnalyzeReadOnly(ctx context.Context, pool *pgxpool.Pool, sql string, args []any) (*Plan, error) {
+	ok, err := analyzable(ctx, pool, sql)
+	if err != nil || !ok {
+		return Explain(ctx, pool, sql, args...)
+	}
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	plan, err := ExplainAnalyze(ctx, tx, sql, args...)
+	_ = tx.Rollback(context.Background())
+	if err != nil {
+		return Explain(ctx, pool, sql, args...)
+	}
+	return plan, nil
+}
+
+// configureAutoExplain loads auto_explain on every new connection. Failing to load it, e.g. for
+// lack of privileges, is logged once and otherwise ignored.
+func configureAutoExplain(config *pgxpool.Config, c Configuration, logger log.FieldLogger) {
+	sampleRate := c.SlowQueryPlanSampleRate
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	minDuration := strconv.FormatInt(c.SlowQueryThreshold.Milliseconds(), 10)
+	var warned atomic.Bool
+	afterConnect := config.AfterConnect
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, "LOAD 'auto_explain'")
+		if err == nil {
+			_, err = conn.Exec(ctx, `SELECT set_config('auto_explain.log_min_duration', $1, false),
+				set_config('auto_explain.sample_rate', $2, false)`,
+				minDuration, strconv.FormatFloat(sampleRate, 'f', -1, 64))
+		}
+		if err != nil && !warned.Swap(true) {
+			logger.WithError(err).Warn("auto_explain is not available")
+		}
+		if afterConnect != nil {
+			return afterConnect(ctx, conn)
+		}
+		return nil
+	}
+}
+
+// configureSlowQueryLog installs the slow query tracer when c.SlowQueryThreshold is set. Slow
+// queries go to c.OnSlowQuery, or are logged as warnings through c.Logger.
+func configureSlowQueryLog(config *pgxpool.Config, c Configuration, poolRef *atomic.Pointer[pgxpool.Pool]) {
+	if c.SlowQueryThreshold <= 0 {
+		return
+	}
+	logger := c.Logger
+	if logger == nil {
+		logger = log.StandardLogger()
+	}
+	if c.SlowQueryAutoExplain {
+		configureAutoExplain(config, c, logger)
+	}
+	report := c.OnSlowQuery
+	if report == nil {
+		report = func(slow SlowQuery) {
+			fields := log.Fields{"sql": slow.SQL, "duration_ms": slow.Duration.Milliseconds()}
+			if slow.Plan != nil {
+				fields["plan_cost"] = slow.Plan.Root.TotalCost
+				fields["seq_scans"] = slow.Plan.SeqScans()
+				if slow.Plan.ExecutionTime > 0 {
+					fields["execution_ms"] = slow.Plan.ExecutionTime
+				}
+			}
+			logger.WithFields(fields).Warn("Slow query")
+		}
+	}
+	addQueryTracer(config, &slowQueryTracer{
+		threshold:  c.SlowQueryThreshold,
+		plans:      c.SlowQueryPlans,
+		sampleRate: c.SlowQueryPlanSampleRate,
+		analyze:    c.SlowQueryAnalyze,
+		report:     report,
+		poolRef:    poolRef,
+		random:     rand.Float64,
+	})
+}
@@ -0,0 +1,73 @@
+package pg
+
+import (
+	"context"
+	"time"
+)
+
+// SoftDeleteColumn is the timestamp column marking a row as logically deleted.
+const SoftDeleteColumn = "deleted_at"
+
+// NotDeleted matches rows that have not been soft deleted.
+func NotDeleted() Condition {
+	return IsNull(SoftDeleteColumn)
+}
+
+// Deleted matches rows that have been soft deleted.
+func Deleted() Condition {
+	return IsNotNull(SoftDeleteColumn)
+}
+
+// NotDeleted restricts the query to rows that have not been soft deleted. The restriction holds
+// for every read of b: Build, Exists, Count and SelectScalar.
+func (b *SelectBuilder) NotDeleted() *SelectBuilder {
+	return b.Where(NotDeleted())
+}
+
+// Exists reports whether the query of b returns at least one row.
+func (b *SelectBuilder) Exists(ctx context.Context, q Querier) (bool, error) {
+	sql, args := b.Build()
+	return Exists(ctx, q, sql, args...)
+}
+
+// Count returns the number of rows the query of b returns.
+func (b *SelectBuilder) Count(ctx context.Context, q Querier) (int64, error) {
+	sql, args := b.Build()
+	return Count(ctx, q, sql, args...)
+}
+
+// SelectScalar is Scalar for the query of b, with its conditions such as NotDeleted.
+func SelectScalar[T any](ctx context.Context, q Querier, b *SelectBuilder) (T, error) {
+	sql, args := b.Build()
+	return Scalar[T](ctx, q, sql, args...)
+}
+
+// SoftDelete marks the matching rows as deleted. Rows already deleted keep their original timestamp.
+func SoftDelete(ctx context.Context, q Querier, table string, conditions ...Condition) (int64, error) {
+	sql, args := Update(table).Set(SoftDeleteColumn, time.Now()).Where(NotDeleted()).Where(conditions...).Build()
+	tag, err := q.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Restore clears the deletion mark of the matching rows.
+func Restore(ctx context.Context, q Querier, table string, conditions ...Condition) (int64, error) {
+	sql, args := Update(table).Set(SoftDeleteColumn, nil).Where(Deleted()).Where(conditions...).Build()
+	tag, err := q.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeDeleted permanently removes rows that were soft deleted more than olderThan ago.
+func PurgeDeleted(ctx context.Context, q Querier, table string, olderThan time.Duration, conditions ...Condition) (int64, error) {
+	sql, args := DeleteFrom(table).Where(Lt(SoftDeleteColumn, time.Now().Add(-olderThan))).Where(conditions...).Build()
+	tag, err := q.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,38 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestSoftDeleteReads(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `CREATE TABLE items (id INT, name TEXT, deleted_at TIMESTAMPTZ);
+		INSERT INTO items (id, name) VALUES (1, 'a'), (2, 'b')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := pg.SoftDelete(ctx, db.Pool, "items", pg.Eq("id", 1))
+	if err != nil || deleted != 1 {
+		t.Fatalf("unexpected soft delete %v, %v", deleted, err)
+	}
+	count, err := pg.Select().From("items").NotDeleted().Count(ctx, db.Pool)
+	if err != nil || count != 1 {
+		t.Errorf("unexpected count %v, %v", count, err)
+	}
+	exists, err := pg.Select().From("items").Where(pg.Eq("id", 1)).NotDeleted().Exists(ctx, db.Pool)
+	if err != nil || exists {
+		t.Errorf("expected the deleted row not to exist, got %v, %v", exists, err)
+	}
+	name, err := pg.SelectScalar[string](ctx, db.Pool, pg.Select("name").From("items").NotDeleted().OrderBy("id"))
+	if err != nil || name != "b" {
+		t.Errorf("unexpected name %q, %v", name, err)
+	}
+	count, err = pg.Select().From("items").Count(ctx, db.Pool)
+	if err != nil || count != 2 {
+		t.Errorf("expected deleted rows to count without the filter, got %v, %v", count, err)
+	}
+}
@@ -0,0 +1,13 @@
+package pg
+
+import "testing"
+
+func TestSelectNotDeleted(t *testing.T) {
+	sql, args := Select("id").From("users").Where(Eq("name", "a")).NotDeleted().Build()
+	if sql != `SELECT "id" FROM "users" WHERE ("name" = $1 AND "deleted_at" IS NULL)` {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if len(args) != 1 {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
@@ -0,0 +1,254 @@
+package pg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Source provides the migration scripts. Read must return an error wrapping fs.ErrNotExist for
+// missing files, so absent down scripts are reported as such.
+type Source interface {
+	// List returns the file names of the scripts, including down scripts.
+	List(ctx context.Context) ([]string, error)
+	Read(ctx context.Context, name string) ([]byte, error)
+}
+
+// source returns c.MigrationsSource, falling back to c.MigrationsDirectory.
+func (c Configuration) source() Source {
+	if c.MigrationsSource != nil {
+		return c.MigrationsSource
+	}
+	return DirectorySource(c.MigrationsDirectory)
+}
+
+// DirectorySource reads the scripts from a directory on disk.
+type DirectorySource string
+
+func (d DirectorySource) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return nil, err
+	}
+	return fileNames(entries), nil
+}
+
+func (d DirectorySource) Read(ctx context.Context, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(d), name))
+}
+
+// FSSource reads the scripts from the root of a file system, e.g. an embed.FS narrowed with
+// fs.Sub, so migrations can be shipped inside the binary.
+type FSSource struct {
+	FS fs.FS
+}
+
+func (s FSSource) List(ctx context.Context) ([]string, error) {
+	entries, err := fs.ReadDir(s.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+	return fileNames(entries), nil
+}
+
+func (s FSSource) Read(ctx context.Context, name string) ([]byte, error) {
+	return fs.ReadFile(s.FS, name)
+}
+
+func fileNames(entries []fs.DirEntry) []string {
+	return Map(Filter(entries, func(e fs.DirEntry) bool { return !e.IsDir() }), fs.DirEntry.Name)
+}
+
+// HTTPIndexFile is the file listing the scripts of an HTTPSource, one name per line.
+const HTTPIndexFile = "index.txt"
+
+// HTTPSource downloads the scripts from BaseURL, which serves HTTPIndexFile and the scripts next
+// to it. Responses are cached by ETag, so repeated runs only transfer changed files. A missing
+// index is an error rather than an empty source.
+type HTTPSource struct {
+	BaseURL string
+	// Client defaults to http.DefaultClient; configure authentication through its transport.
+	Client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]httpCacheEntry
+}
+
+type httpCacheEntry struct {
+	etag string
+	body []byte
+}
+
+func NewHTTPSource(baseURL string) *HTTPSource {
+	return &HTTPSource{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (s *HTTPSource) List(ctx context.Context) ([]string, error) {
+	body, err := s.get(ctx, HTTPIndexFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Without the index nothing can be migrated, which must not pass for a source without
+		// migrations.
+		return nil, fmt.Errorf("the index of the migrations is missing: %v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Filter(Map(strings.Split(string(body), "\n"), strings.TrimSpace), func(name string) bool {
+		return name != "" && !strings.HasPrefix(name, "#")
+	}), nil
+}
+
+func (s *HTTPSource) Read(ctx context.Context, name string) ([]byte, error) {
+	return s.get(ctx, name)
+}
+
+func (s *HTTPSource) get(ctx context.Context, name string) ([]byte, error) {
+	url := s.BaseURL + "/" + name
+	s.mu.Lock()
+	cached, ok := s.cache[url]
+	s.mu.Unlock()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		request.Header.Set("If-None-Match", cached.etag)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(response.Body)
+	switch {
+	case response.StatusCode == http.StatusNotModified && ok:
+		return cached.body, nil
+	case response.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%v: %w", url, fs.ErrNotExist)
+	case response.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%v: unexpected status %v", url, response.Status)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if etag := response.Header.Get("ETag"); etag != "" {
+		s.mu.Lock()
+		if s.cache == nil {
+			s.cache = make(map[string]httpCacheEntry)
+		}
+		s.cache[url] = httpCacheEntry{etag: etag, body: body}
+		s.mu.Unlock()
+	}
+	return body, nil
+}
+
+// Bucket is the part of an object store client a BucketSource needs. Adapt an S3, GCS or Azure
+// client to it; GetObject must return an error wrapping fs.ErrNotExist for missing keys.
+type Bucket interface {
+	// ListObjects returns the keys starting with prefix.
+	ListObjects(ctx context.Context, prefix string) ([]string, error)
+	GetObject(ctx context.Context, key string) ([]byte, error)
+}
+
+// BucketSource reads the scripts stored under Prefix in an object store bucket.
+type BucketSource struct {
+	Bucket Bucket
+	// Prefix is the "directory" of the scripts, e.g. "migrations/".
+	Prefix string
+}
+
+func (s BucketSource) List(ctx context.Context) ([]string, error) {
+	keys, err := s.Bucket.ListObjects(ctx, s.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	names := Map(keys, func(key string) string { return strings.TrimPrefix(key, s.Prefix) })
+	// Objects in nested "directories" are not migrations of this source.
+	return Filter(names, func(name string) bool { return name != "" && !strings.Contains(name, "/") }), nil
+}
+
+func (s BucketSource) Read(ctx context.Context, name string) ([]byte, error) {
+	return s.Bucket.GetObject(ctx, s.Prefix+name)
+}
+
+// GitSource reads the scripts from a directory at a revision of a git repository, without
+// checking it out. Read checks names against the listing of the last List, so a run lists the
+// directory once rather than for every script.
+type GitSource struct {
+	// Repository is the path of a local clone.
+	Repository string
+	// Ref is a branch, tag or commit; HEAD by default.
+	Ref  string
+	Path string
+	// Git is the git executable, found on PATH by default.
+	Git string
+
+	mu    sync.Mutex
+	names []string
+}
+
+func (s *GitSource) treeish(name string) string {
+	ref := s.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	return ref + ":" + path.Join(s.Path, name)
+}
+
+func (s *GitSource) run(ctx context.Context, args ...string) ([]byte, error) {
+	executable := s.Git
+	if executable == "" {
+		executable = "git"
+	}
+	cmd := exec.CommandContext(ctx, executable, append([]string{"-C", s.Repository}, args...)...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err := runTool(cmd)
+	return stdout.Bytes(), err
+}
+
+func (s *GitSource) List(ctx context.Context) ([]string, error) {
+	output, err := s.run(ctx, "ls-tree", "--name-only", s.treeish(""))
+	if err != nil {
+		return nil, err
+	}
+	names := Filter(strings.Split(string(output), "\n"), func(name string) bool { return name != "" })
+	s.mu.Lock()
+	s.names = names
+	s.mu.Unlock()
+	return names, nil
+}
+
+func (s *GitSource) Read(ctx context.Context, name string) ([]byte, error) {
+	s.mu.Lock()
+	names := s.names
+	s.mu.Unlock()
+	if names == nil {
+		var err error
+		names, err = s.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !slices.Contains(names, name) {
+		return nil, fmt.Errorf("%v: %w", s.treeish(name), fs.ErrNotExist)
+	}
+	return s.run(ctx, "show", s.treeish(name))
+}
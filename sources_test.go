@@ -0,0 +1,168 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDirectoryAndFSSources(t *testing.T) {
+	ctx := context.Background()
+	for _, source := range []Source{
+		DirectorySource("testdb"),
+		FSSource{FS: os.DirFS("testdb")},
+	} {
+		names, err := source.List(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(names, "0_init.sql") || !slices.Contains(names, "0_init.down.sql") {
+			t.Errorf("unexpected names %v", names)
+		}
+		_, err = source.Read(ctx, "missing.sql")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected fs.ErrNotExist, got %v", err)
+		}
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	ctx := context.Background()
+	transfers := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/migrations/" + HTTPIndexFile:
+			_, _ = w.Write([]byte("# scripts\n0_init.sql\n\n"))
+		case "/migrations/0_init.sql":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			transfers++
+			_, _ = w.Write([]byte("CREATE TABLE a (id INT);"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	source := NewHTTPSource(server.URL + "/migrations/")
+	names, err := source.List(ctx)
+	if err != nil || !reflect.DeepEqual(names, []string{"0_init.sql"}) {
+		t.Errorf("unexpected names %v, %v", names, err)
+	}
+	for i := 0; i < 2; i++ {
+		script, err := source.Read(ctx, "0_init.sql")
+		if err != nil || string(script) != "CREATE TABLE a (id INT);" {
+			t.Errorf("unexpected script %q, %v", script, err)
+		}
+	}
+	if transfers != 1 {
+		t.Errorf("expected the cached script to be reused, got %v transfers", transfers)
+	}
+	_, err = source.Read(ctx, "0_init.down.sql")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+	_, err = NewHTTPSource(server.URL + "/missing/").List(ctx)
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing index to fail, got %v", err)
+	}
+}
+
+type mapBucket fstest.MapFS
+
+func (b mapBucket) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for key := range b {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (b mapBucket) GetObject(ctx context.Context, key string) ([]byte, error) {
+	return fs.ReadFile(fstest.MapFS(b), key)
+}
+
+func TestBucketSource(t *testing.T) {
+	bucket := mapBucket{
+		"migrations/0_init.sql":     {Data: []byte("SELECT 1")},
+		"migrations/old/0_init.sql": {Data: []byte("SELECT 2")},
+		"other/1_other.sql":         {Data: []byte("SELECT 3")},
+	}
+	source := BucketSource{Bucket: bucket, Prefix: "migrations/"}
+	names, err := source.List(context.Background())
+	if err != nil || !reflect.DeepEqual(names, []string{"0_init.sql"}) {
+		t.Errorf("unexpected names %v, %v", names, err)
+	}
+	script, err := source.Read(context.Background(), "0_init.sql")
+	if err != nil || string(script) != "SELECT 1" {
+		t.Errorf("unexpected script %q, %v", script, err)
+	}
+}
+
+func TestGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repository := t.TempDir()
+	err := os.MkdirAll(filepath.Join(repository, "db"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(repository, "db", "0_init.sql"), []byte("SELECT 1"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-qm", "init"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", repository}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+	// The working tree is not read.
+	err = os.WriteFile(filepath.Join(repository, "db", "0_init.sql"), []byte("SELECT 2"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The wrapper logs the git commands run.
+	commands := filepath.Join(t.TempDir(), "commands")
+	wrapper := filepath.Join(t.TempDir(), "git")
+	err = os.WriteFile(wrapper, []byte("#!/bin/sh\necho \"$3\" >> "+commands+"\nexec git \"$@\"\n"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &GitSource{Repository: repository, Path: "db", Git: wrapper}
+	names, err := source.List(context.Background())
+	if err != nil || !reflect.DeepEqual(names, []string{"0_init.sql"}) {
+		t.Errorf("unexpected names %v, %v", names, err)
+	}
+	script, err := source.Read(context.Background(), "0_init.sql")
+	if err != nil || string(script) != "SELECT 1" {
+		t.Errorf("unexpected script %q, %v", script, err)
+	}
+	_, err = source.Read(context.Background(), "0_init.down.sql")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+	logged, err := os.ReadFile(commands)
+	if err != nil || string(logged) != "ls-tree\nshow\n" {
+		t.Errorf("expected the directory to be listed once, got %q, %v", logged, err)
+	}
+}
@@ -0,0 +1,115 @@
+package pg
+
+import (
+	"regexp"
+	"strings"
+)
+
+// scriptStatement is a statement of a migration script as split by splitScript.
+type scriptStatement struct {
+	// SQL is the statement as written, without the terminating semicolon.
+	SQL string
+	// Line is the line of its first token, counting from 1.
+	Line int
+	// Comments are the comments between the previous statement and the end of this one.
+	Comments []string
+	// normalized is the statement in upper case with comments removed, whitespace collapsed and
+	// literals and dollar quoted bodies emptied, for matching keywords.
+	normalized string
+}
+
+var dollarTag = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// splitScript splits a script at the semicolons outside of literals, quoted identifiers, dollar
+// quoted bodies and comments.
+func splitScript(script string) []scriptStatement {
+	var statements []scriptStatement
+	var current scriptStatement
+	var normalized strings.Builder
+	start, line := 0, 1
+	finish := func(end int) {
+		current.normalized = strings.Join(strings.Fields(strings.ToUpper(normalized.String())), " ")
+		if current.normalized != "" {
+			current.SQL = strings.TrimSpace(script[start:end])
+			statements = append(statements, current)
+			current = scriptStatement{}
+		}
+		normalized.Reset()
+		start = end + 1
+	}
+	token := func() {
+		if current.Line == 0 {
+			current.Line = line
+		}
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\n':
+			line++
+			normalized.WriteByte(' ')
+		case c == ';':
+			finish(i)
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			current.Comments = append(current.Comments, strings.TrimSpace(script[i+2:i+end]))
+			normalized.WriteByte(' ')
+			i += end - 1
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			depth, j := 1, i+2
+			for ; j < len(script) && depth > 0; j++ {
+				switch {
+				case strings.HasPrefix(script[j:], "/*"):
+					depth++
+					j++
+				case strings.HasPrefix(script[j:], "*/"):
+					depth--
+					j++
+				case script[j] == '\n':
+					line++
+				}
+			}
+			current.Comments = append(current.Comments, strings.TrimSpace(strings.TrimSuffix(script[i+2:j], "*/")))
+			normalized.WriteByte(' ')
+			i = j - 1
+		case c == '\'' || c == '"':
+			token()
+			backslash := c == '\'' && i > 0 && (script[i-1] == 'E' || script[i-1] == 'e')
+			j := i + 1
+			for ; j < len(script) && script[j] != c; j++ {
+				if backslash && script[j] == '\\' {
+					j++
+				}
+				if j < len(script) && script[j] == '\n' {
+					line++
+				}
+			}
+			if c == '"' {
+				normalized.WriteString(script[i:min(j+1, len(script))])
+			} else {
+				normalized.WriteString("''")
+			}
+			i = j
+		case c == '$' && dollarTag.MatchString(script[i:]):
+			token()
+			tag := dollarTag.FindString(script[i:])
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				end = len(script) - i - len(tag)
+			}
+			line += strings.Count(script[i:i+len(tag)+end], "\n")
+			normalized.WriteString("$$")
+			i += len(tag) + end + len(tag) - 1
+		default:
+			if c != ' ' && c != '\t' && c != '\r' {
+				token()
+			}
+			normalized.WriteByte(c)
+		}
+	}
+	finish(len(script))
+	return statements
+}
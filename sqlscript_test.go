@@ -0,0 +1,30 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitScript(t *testing.T) {
+	script := `-- users
+CREATE TABLE users (id INT, note TEXT DEFAULT 'a;b');
+/* nested /* block */ comment */ INSERT INTO "odd;name" VALUES (E'it\'s;');
+
+CREATE FUNCTION f() RETURNS INT AS $body$ SELECT 1; $body$ LANGUAGE sql;
+  -- trailing comment`
+	statements := splitScript(script)
+	if len(statements) != 3 {
+		t.Fatalf("expected 3 statements, got %+v", statements)
+	}
+	if statements[0].SQL != "-- users\nCREATE TABLE users (id INT, note TEXT DEFAULT 'a;b')" || statements[0].Line != 2 ||
+		!reflect.DeepEqual(statements[0].Comments, []string{"users"}) {
+		t.Errorf("unexpected statement %+v", statements[0])
+	}
+	if statements[1].normalized != `INSERT INTO "ODD;NAME" VALUES (E'')` || statements[1].Line != 3 ||
+		!reflect.DeepEqual(statements[1].Comments, []string{"nested /* block */ comment"}) {
+		t.Errorf("unexpected statement %+v", statements[1])
+	}
+	if statements[2].normalized != "CREATE FUNCTION F() RETURNS INT AS $$ LANGUAGE SQL" || statements[2].Line != 5 {
+		t.Errorf("unexpected statement %+v", statements[2])
+	}
+}
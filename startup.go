@@ -0,0 +1,83 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// MigrationCounts tells how many migrations a run applied, skipped as already applied, failed,
+// and held for approval. A failed run rolls back, so it applied none.
+type MigrationCounts struct {
+	Applied int
+	Skipped int
+	Failed  int
+	Held    int
+}
+
+func (m *MigrationCounts) add(status migrationStatus) {
+	switch status {
+	case statusCompleted:
+		m.Applied++
+	case statusSkipped:
+		m.Skipped++
+	case statusError:
+		m.Failed++
+	case statusHeld:
+		m.Held++
+	}
+}
+
+// StartupSummary describes the pool ConnectWithConfig created, to tell environments apart when
+// something only fails in one of them.
+type StartupSummary struct {
+	ServerVersion   string
+	SearchPath      string
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+	Migrations      MigrationCounts
+	// Duration is the time from parsing the configuration until the migrations finished.
+	Duration time.Duration
+	// Err is set when the server settings could not be read.
+	Err error
+}
+
+func reportStartup(pool *pgxpool.Pool, c Configuration, migrations MigrationCounts, start time.Time) {
+	config := pool.Config()
+	summary := StartupSummary{
+		MaxConns:        config.MaxConns,
+		MinConns:        config.MinConns,
+		MaxConnLifetime: config.MaxConnLifetime,
+		MaxConnIdleTime: config.MaxConnIdleTime,
+		Migrations:      migrations,
+	}
+	summary.Err = pool.QueryRow(context.Background(), "SELECT current_setting('server_version'), current_setting('search_path')").
+		Scan(&summary.ServerVersion, &summary.SearchPath)
+	summary.Duration = time.Since(start)
+	if c.OnStartup != nil {
+		c.OnStartup(summary)
+		return
+	}
+	logger := c.Logger
+	if logger == nil {
+		logger = log.StandardLogger()
+	}
+	fields := log.Fields{
+		"server_version":     summary.ServerVersion,
+		"search_path":        summary.SearchPath,
+		"max_conns":          summary.MaxConns,
+		"min_conns":          summary.MinConns,
+		"migrations_applied": migrations.Applied,
+		"migrations_skipped": migrations.Skipped,
+		"migrations_failed":  migrations.Failed,
+		"migrations_held":    migrations.Held,
+		"duration_ms":        summary.Duration.Milliseconds(),
+	}
+	if summary.Err != nil {
+		fields["error"] = summary.Err.Error()
+	}
+	logger.WithFields(fields).Info("Connected to database")
+}
@@ -0,0 +1,64 @@
+package pg_test
+
+import (
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStartupSummary(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	c := db.Configuration
+	var summaries []pg.StartupSummary
+	c.OnStartup = func(summary pg.StartupSummary) {
+		summaries = append(summaries, summary)
+	}
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if len(summaries) != 1 {
+		t.Fatalf("expected one summary, got %v", len(summaries))
+	}
+	summary := summaries[0]
+	if summary.Err != nil || summary.ServerVersion == "" || !strings.Contains(summary.SearchPath, "public") {
+		t.Errorf("unexpected server settings %+v", summary)
+	}
+	if summary.Migrations != (pg.MigrationCounts{Skipped: 3}) {
+		t.Errorf("expected the applied migrations to be skipped, got %+v", summary.Migrations)
+	}
+	if summary.MaxConns != pool.Config().MaxConns {
+		t.Errorf("unexpected pool settings %+v", summary)
+	}
+}
+
+func TestStartupSummaryOfFailedRun(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	directory := t.TempDir()
+	for name, script := range map[string]string{
+		"0_create.sql": "CREATE TABLE a (id INT);",
+		"1_broken.sql": "INSERT INTO missing VALUES (1);",
+	} {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(script), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := db.Configuration
+	c.MigrationsEnabled = true
+	c.MigrationsDirectory = directory
+	var summary pg.StartupSummary
+	c.OnStartup = func(s pg.StartupSummary) { summary = s }
+	_, err := pg.ConnectWithConfig(c)
+	if err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+	// The run rolled back, so the first migration was not applied after all.
+	if summary.Migrations != (pg.MigrationCounts{Failed: 1}) {
+		t.Errorf("unexpected counts %+v", summary.Migrations)
+	}
+}
@@ -0,0 +1,13 @@
+package pg
+
+import "testing"
+
+func TestMigrationCounts(t *testing.T) {
+	var counts MigrationCounts
+	for _, status := range []migrationStatus{statusCompleted, statusSkipped, statusSkipped, statusError, statusHeld} {
+		counts.add(status)
+	}
+	if counts != (MigrationCounts{Applied: 1, Skipped: 2, Failed: 1, Held: 1}) {
+		t.Errorf("unexpected counts %+v", counts)
+	}
+}
@@ -0,0 +1,65 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type StatementOrder string
+
+const (
+	ByTotalTime StatementOrder = "total_exec_time"
+	ByMeanTime  StatementOrder = "mean_exec_time"
+	ByCalls     StatementOrder = "calls"
+)
+
+// StatementStats is a row of pg_stat_statements.
+type StatementStats struct {
+	QueryID   int64
+	Query     string
+	Calls     int64
+	Rows      int64
+	TotalTime time.Duration
+	MeanTime  time.Duration
+	MinTime   time.Duration
+	MaxTime   time.Duration
+}
+
+// TopStatements returns the limit statements ranking highest by order. It requires the
+// pg_stat_statements extension of PostgreSQL 13 or later.
+func TopStatements(ctx context.Context, q Querier, order StatementOrder, limit int) ([]StatementStats, error) {
+	if order != ByTotalTime && order != ByMeanTime && order != ByCalls {
+		return nil, fmt.Errorf("unsupported statement order %q", order)
+	}
+	//goland:noinspection SqlResolve
+	rows, err := q.Query(ctx, `
+		SELECT queryid, query, calls, rows, total_exec_time, mean_exec_time, min_exec_time, max_exec_time
+		FROM pg_stat_statements
+		ORDER BY `+string(order)+` DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (StatementStats, error) {
+		var s StatementStats
+		var total, mean, minTime, maxTime float64
+		err := row.Scan(&s.QueryID, &s.Query, &s.Calls, &s.Rows, &total, &mean, &minTime, &maxTime)
+		s.TotalTime = millisToDuration(total)
+		s.MeanTime = millisToDuration(mean)
+		s.MinTime = millisToDuration(minTime)
+		s.MaxTime = millisToDuration(maxTime)
+		return s, err
+	})
+}
+
+// ResetStatementStats discards the statistics gathered by pg_stat_statements.
+func ResetStatementStats(ctx context.Context, q Querier) error {
+	_, err := q.Exec(ctx, "SELECT pg_stat_statements_reset()")
+	return err
+}
+
+func millisToDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
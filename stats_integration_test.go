@@ -0,0 +1,49 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"strings"
+	"testing"
+)
+
+func TestTopStatements(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"),
+		pgtest.WithSettings(map[string]string{"shared_preload_libraries": "pg_stat_statements"}))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE EXTENSION pg_stat_statements")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.ResetStatementStats(ctx, db.Pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		_, err = pg.Count(ctx, db.Pool, "SELECT * FROM testtable WHERE id > $1", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	statements, err := pg.TopStatements(ctx, db.Pool, pg.ByCalls, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 1 || !strings.Contains(statements[0].Query, "testtable") || statements[0].Calls != 5 {
+		t.Fatalf("unexpected statements %+v", statements)
+	}
+	if statements[0].Rows != 5 || statements[0].TotalTime <= 0 || statements[0].MaxTime < statements[0].MinTime {
+		t.Errorf("unexpected statistics %+v", statements[0])
+	}
+	for _, order := range []pg.StatementOrder{pg.ByTotalTime, pg.ByMeanTime} {
+		statements, err = pg.TopStatements(ctx, db.Pool, order, 10)
+		if err != nil || len(statements) == 0 {
+			t.Errorf("%v: unexpected statements %v, %v", order, statements, err)
+		}
+	}
+	_, err = pg.TopStatements(ctx, db.Pool, "query", 10)
+	if err == nil {
+		t.Error("expected an unsupported order to fail")
+	}
+}
@@ -0,0 +1,233 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrStatementCacheStatsDisabled = errors.New("statement cache statistics are not enabled for this pool")
+
+// StatementCacheCounters describe the statement cache of pgx; with QueryExecModeCacheDescribe it
+// is the description cache. Hits are derived from the statements run, so statements that bypass
+// the cache, e.g. with an explicit QueryExecMode argument, count as hits as well.
+type StatementCacheCounters struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
+
+// ConnStatementCacheStats is the cache of a single connection.
+type ConnStatementCacheStats struct {
+	PID uint32 `json:"pid"`
+	StatementCacheCounters
+	Size int `json:"size"`
+}
+
+// StatementCacheStats sums the caches of the pool's connections, including the counters of closed
+// connections.
+type StatementCacheStats struct {
+	Mode     string `json:"mode"`
+	Capacity int    `json:"capacity"`
+	StatementCacheCounters
+	Size        int                       `json:"size"`
+	Connections []ConnStatementCacheStats `json:"connections"`
+}
+
+type connStatementCache struct {
+	queries int64
+	misses  int64
+	evicted int64
+	size    int
+}
+
+func (c *connStatementCache) counters() StatementCacheCounters {
+	return StatementCacheCounters{Hits: c.queries - c.misses, Misses: c.misses, Evictions: c.evicted}
+}
+
+type prepareNameContextKey struct{}
+
+// statementCacheTracer mirrors the statement cache of every connection: pgx prepares a statement
+// on each cache miss and evicts the least recently used entry when the cache is full.
+type statementCacheTracer struct {
+	mode     pgx.QueryExecMode
+	capacity int
+	mu       sync.Mutex
+	conns    map[*pgx.Conn]*connStatementCache
+	closed   StatementCacheCounters
+}
+
+func newStatementCacheTracer(config *pgx.ConnConfig) *statementCacheTracer {
+	capacity := config.StatementCacheCapacity
+	if config.DefaultQueryExecMode == pgx.QueryExecModeCacheDescribe {
+		capacity = config.DescriptionCacheCapacity
+	}
+	return &statementCacheTracer{mode: config.DefaultQueryExecMode, capacity: capacity, conns: make(map[*pgx.Conn]*connStatementCache)}
+}
+
+func (t *statementCacheTracer) caching() bool {
+	return t.capacity > 0 && (t.mode == pgx.QueryExecModeCacheStatement || t.mode == pgx.QueryExecModeCacheDescribe)
+}
+
+func (t *statementCacheTracer) conn(conn *pgx.Conn) *connStatementCache {
+	c, ok := t.conns[conn]
+	if !ok {
+		c = &connStatementCache{}
+		t.conns[conn] = c
+	}
+	return c
+}
+
+func (t *statementCacheTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !t.caching() {
+		return ctx
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conn(conn).queries++
+	return ctx
+}
+
+func (t *statementCacheTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+}
+
+func (t *statementCacheTracer) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	return context.WithValue(ctx, prepareNameContextKey{}, data.Name)
+}
+
+func (t *statementCacheTracer) TracePrepareEnd(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareEndData) {
+	name, _ := ctx.Value(prepareNameContextKey{}).(string)
+	cached := (t.mode == pgx.QueryExecModeCacheStatement && strings.HasPrefix(name, "stmtcache_")) ||
+		(t.mode == pgx.QueryExecModeCacheDescribe && name == "")
+	if !t.caching() || !cached || data.Err != nil || data.AlreadyPrepared {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.conn(conn)
+	c.misses++
+	if c.size >= t.capacity {
+		c.evicted++
+	} else {
+		c.size++
+	}
+}
+
+// forget moves the counters of a closing connection to the totals.
+func (t *statementCacheTracer) forget(conn *pgx.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.conns[conn]
+	if !ok {
+		return
+	}
+	counters := c.counters()
+	t.closed.Hits += counters.Hits
+	t.closed.Misses += counters.Misses
+	t.closed.Evictions += counters.Evictions
+	delete(t.conns, conn)
+}
+
+func (t *statementCacheTracer) stats() StatementCacheStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := StatementCacheStats{Mode: t.mode.String(), Capacity: t.capacity, StatementCacheCounters: t.closed}
+	for conn, c := range t.conns {
+		counters := c.counters()
+		stats.Hits += counters.Hits
+		stats.Misses += counters.Misses
+		stats.Evictions += counters.Evictions
+		stats.Size += c.size
+		stats.Connections = append(stats.Connections, ConnStatementCacheStats{PID: conn.PgConn().PID(), StatementCacheCounters: counters, Size: c.size})
+	}
+	sort.Slice(stats.Connections, func(i, j int) bool { return stats.Connections[i].PID < stats.Connections[j].PID })
+	return stats
+}
+
+// configureStatementCacheStats installs the statement cache tracer when c.StatementCacheStats is
+// set and reports it as pgutils.stmtcache metrics.
+func configureStatementCacheStats(config *pgxpool.Config, c Configuration) {
+	if !c.StatementCacheStats {
+		return
+	}
+	tracer := newStatementCacheTracer(config.ConnConfig)
+	addQueryTracer(config, tracer)
+	beforeClose := config.BeforeClose
+	config.BeforeClose = func(conn *pgx.Conn) {
+		tracer.forget(conn)
+		if beforeClose != nil {
+			beforeClose(conn)
+		}
+	}
+	registerStatementCacheMetrics(tracer)
+}
+
+func registerStatementCacheMetrics(tracer *statementCacheTracer) {
+	meterProviderMu.Lock()
+	mp := meterProvider
+	meterProviderMu.Unlock()
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(meterName)
+	// As with the transaction metrics, failing to create an instrument leaves a usable no-op.
+	hits, _ := meter.Int64ObservableCounter("pgutils.stmtcache.hits", metric.WithDescription("Statements run with a cached statement"))
+	misses, _ := meter.Int64ObservableCounter("pgutils.stmtcache.misses", metric.WithDescription("Statements prepared because they were not cached"))
+	evictions, _ := meter.Int64ObservableCounter("pgutils.stmtcache.evictions", metric.WithDescription("Cached statements evicted to make room"))
+	size, _ := meter.Int64ObservableGauge("pgutils.stmtcache.size", metric.WithDescription("Statements cached by all connections"))
+	_, _ = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		stats := tracer.stats()
+		o.ObserveInt64(hits, stats.Hits)
+		o.ObserveInt64(misses, stats.Misses)
+		o.ObserveInt64(evictions, stats.Evictions)
+		o.ObserveInt64(size, int64(stats.Size))
+		return nil
+	}, hits, misses, evictions, size)
+}
+
+// findStatementCacheTracer looks for the tracer among the tracers configured for the pool.
+func findStatementCacheTracer(tracer pgx.QueryTracer) *statementCacheTracer {
+	switch tracer := tracer.(type) {
+	case *statementCacheTracer:
+		return tracer
+	case *multitracer.Tracer:
+		for _, t := range tracer.QueryTracers {
+			if found := findStatementCacheTracer(t); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// GetStatementCacheStats returns the statement cache statistics of a pool created by
+// ConnectWithConfig with Configuration.StatementCacheStats.
+func GetStatementCacheStats(pool *pgxpool.Pool) (StatementCacheStats, error) {
+	tracer := findStatementCacheTracer(pool.Config().ConnConfig.Tracer)
+	if tracer == nil {
+		return StatementCacheStats{}, ErrStatementCacheStatsDisabled
+	}
+	return tracer.stats(), nil
+}
+
+// StatementCacheHandler serves GetStatementCacheStats as JSON, e.g. on a debug endpoint.
+func StatementCacheHandler(pool *pgxpool.Pool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats, err := GetStatementCacheStats(pool)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(stats)
+	})
+}
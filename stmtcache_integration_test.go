@@ -0,0 +1,53 @@
+package pg_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatementCacheStats(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	if _, err := pg.GetStatementCacheStats(db.Pool); !errors.Is(err, pg.ErrStatementCacheStatsDisabled) {
+		t.Errorf("expected statistics to be disabled, got %v", err)
+	}
+	c := db.Configuration
+	c.MigrationsEnabled = false
+	c.StatementCacheStats = true
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	before, err := pg.GetStatementCacheStats(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sql := range []string{"SELECT 1 FROM testtable", "SELECT 1 FROM testtable", "SELECT 2 FROM testtable"} {
+		_, err = conn.Exec(context.Background(), sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Release()
+
+	recorder := httptest.NewRecorder()
+	pg.StatementCacheHandler(pool).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/stmtcache", nil))
+	var after pg.StatementCacheStats
+	err = json.NewDecoder(recorder.Body).Decode(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Hits-before.Hits != 1 || after.Misses-before.Misses != 2 || after.Size < 2 || len(after.Connections) == 0 {
+		t.Errorf("unexpected statistics %+v after %+v", after, before)
+	}
+}
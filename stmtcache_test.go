@@ -0,0 +1,58 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"testing"
+)
+
+func TestStatementCacheTracer(t *testing.T) {
+	tracer := newStatementCacheTracer(&pgx.ConnConfig{DefaultQueryExecMode: pgx.QueryExecModeCacheStatement, StatementCacheCapacity: 2})
+	conn := &pgx.Conn{}
+	run := func(sql string, prepare bool) {
+		tracer.TraceQueryStart(context.Background(), conn, pgx.TraceQueryStartData{SQL: sql})
+		if prepare {
+			ctx := tracer.TracePrepareStart(context.Background(), conn, pgx.TracePrepareStartData{Name: "stmtcache_" + sql, SQL: sql})
+			tracer.TracePrepareEnd(ctx, conn, pgx.TracePrepareEndData{})
+		}
+	}
+	run("a", true)
+	run("a", false)
+	run("b", true)
+	run("c", true)
+	ctx := tracer.TracePrepareStart(context.Background(), conn, pgx.TracePrepareStartData{Name: "named"})
+	tracer.TracePrepareEnd(ctx, conn, pgx.TracePrepareEndData{})
+	ctx = tracer.TracePrepareStart(context.Background(), conn, pgx.TracePrepareStartData{Name: "stmtcache_d"})
+	tracer.TracePrepareEnd(ctx, conn, pgx.TracePrepareEndData{Err: errors.New("syntax error")})
+
+	c := tracer.conns[conn]
+	expected := StatementCacheCounters{Hits: 1, Misses: 3, Evictions: 1}
+	if c.counters() != expected || c.size != 2 {
+		t.Errorf("expected %+v with 2 cached, got %+v with %v cached", expected, c.counters(), c.size)
+	}
+	tracer.forget(conn)
+	stats := tracer.stats()
+	if stats.StatementCacheCounters != expected || stats.Size != 0 || len(stats.Connections) != 0 {
+		t.Errorf("expected the counters of closed connections to be kept, got %+v", stats)
+	}
+}
+
+func TestStatementCacheTracerWithoutCache(t *testing.T) {
+	tracer := newStatementCacheTracer(&pgx.ConnConfig{DefaultQueryExecMode: pgx.QueryExecModeSimpleProtocol, StatementCacheCapacity: 2})
+	tracer.TraceQueryStart(context.Background(), &pgx.Conn{}, pgx.TraceQueryStartData{SQL: "a"})
+	if len(tracer.conns) != 0 {
+		t.Error("expected statements not to be counted without a cache")
+	}
+}
+
+func TestFindStatementCacheTracer(t *testing.T) {
+	tracer := newStatementCacheTracer(&pgx.ConnConfig{})
+	if findStatementCacheTracer(multitracer.New(&slowQueryTracer{}, tracer)) != tracer {
+		t.Error("expected the tracer to be found among multiple tracers")
+	}
+	if findStatementCacheTracer(&slowQueryTracer{}) != nil || findStatementCacheTracer(nil) != nil {
+		t.Error("expected no tracer")
+	}
+}
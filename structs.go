@@ -0,0 +1,104 @@
+package pg
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// structField maps a struct field to the column it is stored in. The column is taken from the
+// db tag, falling back to the snake_cased field name; fields tagged db:"-" are skipped. Untagged
+// embedded structs are flattened, as pgx does when scanning.
+type structField struct {
+	Column string
+	Index  []int
+}
+
+func structFields(t reflect.Type) ([]structField, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%v is not a struct", t)
+	}
+	return appendStructFields(make([]structField, 0, t.NumField()), t, nil), nil
+}
+
+func appendStructFields(fields []structField, t reflect.Type, index []int) []structField {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("db"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		fieldIndex := append(slices.Clone(index), field.Index...)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && tag == "" {
+			fields = appendStructFields(fields, field.Type, fieldIndex)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = toSnakeCase(field.Name)
+		}
+		fields = append(fields, structField{Column: tag, Index: fieldIndex})
+	}
+	return fields
+}
+
+func structValues(v reflect.Value, fields []structField) []any {
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	return Map(fields, func(f structField) any {
+		return v.FieldByIndex(f.Index).Interface()
+	})
+}
+
+func toSnakeCase(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func structPointers(v reflect.Value, fields []structField) []any {
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	return Map(fields, func(f structField) any {
+		return v.FieldByIndex(f.Index).Addr().Interface()
+	})
+}
+
+// StructColumns returns the exported fields of struct type t keyed by the column each maps to,
+// following the same rules as the struct helpers.
+func StructColumns(t reflect.Type) (map[string]reflect.StructField, error) {
+	fields, err := structFields(t)
+	if err != nil {
+		return nil, err
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	columns := make(map[string]reflect.StructField, len(fields))
+	for _, f := range fields {
+		columns[f.Column] = t.FieldByIndex(f.Index)
+	}
+	return columns, nil
+}
@@ -0,0 +1,222 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SyncResult struct {
+	Inserted int64
+	Updated  int64
+	Deleted  int64
+}
+
+// SyncRows makes the contents of table equal to desired in a single transaction: rows missing from
+// the table are inserted, rows whose non-key columns differ are updated and rows absent from
+// desired are deleted. Rows are matched on keyColumns, which must name struct columns and be unique
+// in desired. Key columns must not be NULL, in desired nor in the table.
+func SyncRows[T any](ctx context.Context, q TxBeginner, table string, desired []T, keyColumns ...string) (SyncResult, error) {
+	result := SyncResult{}
+	fields, err := structFields(reflect.TypeFor[T]())
+	if err != nil {
+		return result, err
+	}
+	keyIndexes, err := syncKeyIndexes(fields, keyColumns)
+	if err != nil {
+		return result, err
+	}
+	tx, err := q.Begin(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+
+	existing, err := syncExistingRows[T](ctx, tx, table, fields, keyIndexes)
+	if err != nil {
+		return result, err
+	}
+	missing := make([]T, 0)
+	seen := make(map[string]bool, len(desired))
+	for _, row := range desired {
+		values := structValues(reflect.ValueOf(row), fields)
+		if hasNullSyncKey(values, keyIndexes) {
+			return result, fmt.Errorf("null key %v in the rows to sync into %v", syncKeyValues(values, keyIndexes), table)
+		}
+		key := syncKey(values, keyIndexes)
+		if seen[key] {
+			return result, fmt.Errorf("duplicate key %v in the rows to sync into %v", syncKeyValues(values, keyIndexes), table)
+		}
+		seen[key] = true
+		current, ok := existing[key]
+		if !ok {
+			missing = append(missing, row)
+			continue
+		}
+		delete(existing, key)
+		if syncValuesEqual(current, values) {
+			continue
+		}
+		update := Update(table).Where(syncKeyConditions(fields, keyIndexes, values)...)
+		for i, field := range fields {
+			if !isSyncKey(i, keyIndexes) {
+				update.Set(field.Column, values[i])
+			}
+		}
+		sql, args := update.Build()
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return result, err
+		}
+		result.Updated += tag.RowsAffected()
+	}
+	for _, values := range existing {
+		sql, args := DeleteFrom(table).Where(syncKeyConditions(fields, keyIndexes, values)...).Build()
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return result, err
+		}
+		result.Deleted += tag.RowsAffected()
+	}
+	result.Inserted, err = InsertRows(ctx, tx, table, missing)
+	if err != nil {
+		return result, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func syncKeyIndexes(fields []structField, keyColumns []string) ([]int, error) {
+	if len(keyColumns) == 0 {
+		return nil, fmt.Errorf("at least one key column is required")
+	}
+	indexes := make([]int, 0, len(keyColumns))
+	for _, column := range keyColumns {
+		index := -1
+		for i, field := range fields {
+			if field.Column == column {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("key column %v is not a struct column", column)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+func syncExistingRows[T any](ctx context.Context, tx pgx.Tx, table string, fields []structField, keyIndexes []int) (map[string][]any, error) {
+	sql, args := Select(Map(fields, func(f structField) string { return f.Column })...).From(table).Build()
+	rows, err := tx.Query(ctx, sql+" FOR UPDATE", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	existing := make(map[string][]any)
+	for rows.Next() {
+		var row T
+		err = rows.Scan(structPointers(reflect.ValueOf(&row), fields)...)
+		if err != nil {
+			return nil, err
+		}
+		values := structValues(reflect.ValueOf(row), fields)
+		if hasNullSyncKey(values, keyIndexes) {
+			return nil, fmt.Errorf("null key %v in %v", syncKeyValues(values, keyIndexes), table)
+		}
+		key := syncKey(values, keyIndexes)
+		if _, ok := existing[key]; ok {
+			return nil, fmt.Errorf("duplicate key %v in %v", syncKeyValues(values, keyIndexes), table)
+		}
+		existing[key] = values
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// syncKey encodes the key columns of a row with their types and quoted values, so that distinct
+// keys never encode alike and pointers are matched by what they point to.
+func syncKey(values []any, keyIndexes []int) string {
+	var sb strings.Builder
+	for _, value := range syncKeyValues(values, keyIndexes) {
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		sb.WriteString(strconv.Quote(fmt.Sprintf("%T", value)))
+		sb.WriteString(strconv.Quote(fmt.Sprint(value)))
+	}
+	return sb.String()
+}
+
+// hasNullSyncKey reports whether a key column is NULL, as no condition on the key could then single
+// out the row: "= NULL" matches nothing.
+func hasNullSyncKey(values []any, keyIndexes []int) bool {
+	for _, value := range syncKeyValues(values, keyIndexes) {
+		if value == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func syncKeyValues(values []any, keyIndexes []int) []any {
+	return Map(keyIndexes, func(i int) any { return syncIndirect(values[i]) })
+}
+
+// syncIndirect dereferences pointers, returning nil for nil ones.
+func syncIndirect(value any) any {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// syncValuesEqual compares rows column by column, times by the instant they denote, as the ones
+// read back are in the local time zone.
+func syncValuesEqual(a []any, b []any) bool {
+	for i := range a {
+		x, y := syncIndirect(a[i]), syncIndirect(b[i])
+		if t, ok := x.(time.Time); ok {
+			if u, ok := y.(time.Time); !ok || !t.Equal(u) {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+func syncKeyConditions(fields []structField, keyIndexes []int, values []any) []Condition {
+	return Map(keyIndexes, func(i int) Condition { return Eq(fields[i].Column, values[i]) })
+}
+
+func isSyncKey(index int, keyIndexes []int) bool {
+	for _, i := range keyIndexes {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
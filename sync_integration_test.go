@@ -0,0 +1,61 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+type syncTestEvent struct {
+	Tenant string
+	Code   *string
+	At     time.Time
+	Note   string
+}
+
+func TestSyncRows(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE sync_test_event (tenant TEXT, code TEXT, at TIMESTAMPTZ, note TEXT, PRIMARY KEY (tenant, code))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := func(s string) *string { return &s }
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	// Both keys print as [a b c] but must be told apart.
+	desired := []syncTestEvent{
+		{Tenant: "a b", Code: code("c"), At: at, Note: "first"},
+		{Tenant: "a", Code: code("b c"), At: at, Note: "second"},
+	}
+	result, err := pg.SyncRows(ctx, db.Pool, "sync_test_event", desired, "tenant", "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (pg.SyncResult{Inserted: 2}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// Pointer keys match the rows read back, and times read back in another zone are unchanged.
+	desired[1].Note = "changed"
+	result, err = pg.SyncRows(ctx, db.Pool, "sync_test_event", desired, "tenant", "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (pg.SyncResult{Updated: 1}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT note FROM sync_test_event ORDER BY note", [][]any{{"changed"}, {"first"}})
+
+	_, err = pg.SyncRows(ctx, db.Pool, "sync_test_event", append(desired, desired[0]), "tenant", "code")
+	if err == nil {
+		t.Error("expected duplicate keys to be rejected")
+	}
+
+	_, err = pg.SyncRows(ctx, db.Pool, "sync_test_event", append(desired, syncTestEvent{Tenant: "a"}), "tenant", "code")
+	if err == nil {
+		t.Error("expected null keys to be rejected")
+	}
+	pgtest.AssertRowCount(t, db.Pool, "sync_test_event", 2)
+}
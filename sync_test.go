@@ -0,0 +1,56 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSyncKeyIndexes(t *testing.T) {
+	fields, _ := structFields(reflect.TypeFor[insertTestRow]())
+	indexes, err := syncKeyIndexes(fields, []string{"first_name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indexes, []int{1}) {
+		t.Errorf("unexpected indexes: %v", indexes)
+	}
+	_, err = syncKeyIndexes(fields, []string{"missing"})
+	if err == nil {
+		t.Error("unknown key column should fail")
+	}
+	_, err = syncKeyIndexes(fields, nil)
+	if err == nil {
+		t.Error("missing key columns should fail")
+	}
+}
+
+func TestSyncKey(t *testing.T) {
+	code := "b"
+	if syncKey([]any{"a b", "c"}, []int{0, 1}) == syncKey([]any{"a", "b c"}, []int{0, 1}) {
+		t.Error("expected distinct keys not to collide")
+	}
+	if syncKey([]any{1}, []int{0}) == syncKey([]any{"1"}, []int{0}) {
+		t.Error("expected keys of different types not to collide")
+	}
+	if syncKey([]any{&code}, []int{0}) != syncKey([]any{"b"}, []int{0}) {
+		t.Error("expected pointers to be matched by their value")
+	}
+	now := time.Now()
+	if syncKey([]any{now}, []int{0}) != syncKey([]any{now.UTC()}, []int{0}) {
+		t.Error("expected times to be matched by their instant")
+	}
+	if !syncValuesEqual([]any{now, &code}, []any{now.In(time.UTC), &code}) || syncValuesEqual([]any{&code}, []any{(*string)(nil)}) {
+		t.Error("unexpected comparison of values")
+	}
+}
+
+func TestHasNullSyncKey(t *testing.T) {
+	code := "b"
+	if hasNullSyncKey([]any{"a", &code}, []int{0, 1}) || !hasNullSyncKey([]any{"a", (*string)(nil)}, []int{0, 1}) {
+		t.Error("unexpected detection of null keys")
+	}
+	if hasNullSyncKey([]any{"a", nil}, []int{0}) {
+		t.Error("expected only key columns to be checked")
+	}
+}
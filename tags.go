@@ -0,0 +1,84 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/trace"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type queryTagsContextKey struct{}
+
+// WithQueryTags returns a context carrying tags, e.g. {"service": "orders", "route": "/checkout"},
+// that TaggedQuerier appends to queries. Tags already on ctx are kept unless overridden.
+func WithQueryTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string)
+	if existing, ok := ctx.Value(queryTagsContextKey{}).(map[string]string); ok {
+		for k, v := range existing {
+			merged[k] = v
+		}
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, queryTagsContextKey{}, merged)
+}
+
+// TagQuery appends the tags on ctx to sql as a trailing comment following the sqlcommenter
+// convention. Tags should take few distinct values, as each one makes for another statement to
+// prepare and cache.
+func TagQuery(ctx context.Context, sql string) string {
+	return tagQuery(ctx, sql, false)
+}
+
+// tagQuery appends the tags on ctx, and the W3C traceparent of the current span too if traceparent
+// is set and there is one.
+func tagQuery(ctx context.Context, sql string, traceparent bool) string {
+	tags, _ := ctx.Value(queryTagsContextKey{}).(map[string]string)
+	spanContext := trace.SpanContextFromContext(ctx)
+	traced := traceparent && spanContext.IsValid()
+	if len(tags) == 0 && !traced {
+		return sql
+	}
+	pairs := make([]string, 0, len(tags)+1)
+	for k, v := range tags {
+		pairs = append(pairs, sqlCommenterEscape(k)+"='"+sqlCommenterEscape(v)+"'")
+	}
+	if traced {
+		value := "00-" + spanContext.TraceID().String() + "-" + spanContext.SpanID().String() + "-" + spanContext.TraceFlags().String()
+		pairs = append(pairs, "traceparent='"+sqlCommenterEscape(value)+"'")
+	}
+	sort.Strings(pairs)
+	return strings.TrimRight(sql, " \t\n;") + " /*" + strings.Join(pairs, ",") + "*/"
+}
+
+func sqlCommenterEscape(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	// Keep the comment from being closed early by the escaped value.
+	return strings.ReplaceAll(s, "*", "%2A")
+}
+
+// TaggedQuerier tags every statement with TagQuery before passing it on, so DBAs can map
+// pg_stat_activity entries back to application endpoints. Traceparent adds the W3C traceparent of
+// the current span as well; as it makes every statement text unique, it defeats the statement
+// cache and should only be set on pools using QueryExecModeExec or QueryExecModeSimpleProtocol.
+type TaggedQuerier struct {
+	Querier     Querier
+	Traceparent bool
+}
+
+func (t TaggedQuerier) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return t.Querier.Exec(ctx, tagQuery(ctx, sql, t.Traceparent), arguments...)
+}
+
+func (t TaggedQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return t.Querier.Query(ctx, tagQuery(ctx, sql, t.Traceparent), args...)
+}
+
+func (t TaggedQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return t.Querier.QueryRow(ctx, tagQuery(ctx, sql, t.Traceparent), args...)
+}
@@ -0,0 +1,37 @@
+package pg
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestTagQuery(t *testing.T) {
+	ctx := WithQueryTags(context.Background(), map[string]string{"service": "orders"})
+	ctx = WithQueryTags(ctx, map[string]string{"route": "/checkout */ DROP"})
+	tagged := TagQuery(ctx, "SELECT 1;")
+	expected := "SELECT 1 /*route='%2Fcheckout%20%2A%2F%20DROP',service='orders'*/"
+	if tagged != expected {
+		t.Errorf("unexpected tagged query: %v", tagged)
+	}
+	if TagQuery(context.Background(), "SELECT 1") != "SELECT 1" {
+		t.Error("queries without tags should be unchanged")
+	}
+}
+
+func TestTagQueryTraceparent(t *testing.T) {
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+	if TagQuery(ctx, "SELECT 1") != "SELECT 1" {
+		t.Error("expected the traceparent to be opt-in")
+	}
+	tagged := tagQuery(ctx, "SELECT 1", true)
+	expected := "SELECT 1 /*traceparent='00-01000000000000000000000000000000-0200000000000000-01'*/"
+	if tagged != expected {
+		t.Errorf("unexpected tagged query: %v", tagged)
+	}
+}
@@ -0,0 +1,35 @@
+package pg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var ErrUnresolvedPlaceholder = errors.New("unresolved placeholder")
+
+var templatePlaceholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)}`)
+
+// ExpandTemplate replaces the {NAME} placeholders of s with the environment variables of the same
+// name, e.g. {SERVICE}_changelog becomes billing_changelog with SERVICE=billing, so services
+// sharing a database keep separate changelogs. Placeholders of unset or empty variables fail with
+// ErrUnresolvedPlaceholder. CreateConfigurationFromEnv expands the changelog schema and table and
+// the idempotency table.
+func ExpandTemplate(s string) (string, error) {
+	var unresolved []string
+	expanded := templatePlaceholder.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			unresolved = append(unresolved, placeholder)
+			return placeholder
+		}
+		return value
+	})
+	if len(unresolved) > 0 {
+		return "", fmt.Errorf("%w %v in %q, the environment variables are not set", ErrUnresolvedPlaceholder, strings.Join(unresolved, ", "), s)
+	}
+	return expanded, nil
+}
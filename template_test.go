@@ -0,0 +1,44 @@
+package pg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	t.Setenv("SERVICE", "billing")
+	t.Setenv("REGION", "")
+	for template, expected := range map[string]string{
+		"changelog":           "changelog",
+		"{SERVICE}_changelog": "billing_changelog",
+		"{SERVICE}_{SERVICE}": "billing_billing",
+		"{not a placeholder}": "{not a placeholder}",
+	} {
+		actual, err := ExpandTemplate(template)
+		if err != nil || actual != expected {
+			t.Errorf("expected %q to expand to %q, got %q, %v", template, expected, actual, err)
+		}
+	}
+	for _, template := range []string{"{REGION}_changelog", "{SERVICE}_{PGUTILS_UNSET_VAR}"} {
+		_, err := ExpandTemplate(template)
+		if !errors.Is(err, ErrUnresolvedPlaceholder) {
+			t.Errorf("expected %q to fail with ErrUnresolvedPlaceholder, got %v", template, err)
+		}
+	}
+}
+
+func TestCreateConfigurationFromEnvExpandsTemplates(t *testing.T) {
+	t.Setenv("SERVICE", "billing")
+	t.Setenv(EnvChangelogTable, "{SERVICE}_changelog")
+	t.Setenv(EnvIdempotencyTable, "{SERVICE}_idempotency")
+	c := CreateConfigurationFromEnv()
+	if c.ChangelogTable != "billing_changelog" || c.IdempotencyTable != "billing_idempotency" || c.envErr != nil {
+		t.Errorf("unexpected tables %q and %q, %v", c.ChangelogTable, c.IdempotencyTable, c.envErr)
+	}
+
+	t.Setenv(EnvChangelogTable, "{PGUTILS_UNSET_VAR}_changelog")
+	_, err := ConnectWithConfig(CreateConfigurationFromEnv())
+	if !errors.Is(err, ErrUnresolvedPlaceholder) {
+		t.Errorf("expected ErrUnresolvedPlaceholder, got %v", err)
+	}
+}
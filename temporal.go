@@ -0,0 +1,83 @@
+package pg
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// ValidFromColumn is added to versioned tables and holds the time the row version was written.
+	ValidFromColumn = "valid_from"
+	// ValidToColumn is added to history tables and holds the time the row version was replaced.
+	ValidToColumn = "valid_to"
+	HistorySuffix = "_history"
+)
+
+// HistoryTable returns the name of the table holding the previous row versions of table.
+func HistoryTable(table string) string {
+	return table + HistorySuffix
+}
+
+// TemporalMigration returns the statements that version table: a valid_from column, a history
+// table with the same columns plus valid_to, and a trigger copying the old row version there on
+// every update and delete. Put the output in a migration after the table is created. Versions are
+// stamped with the transaction time, so a row changed twice in one transaction keeps one version.
+// Columns added to table later must be added to the history table at the same position.
+func TemporalMigration(table string) string {
+	quoted := QuoteIdentifier(table)
+	history := QuoteIdentifier(HistoryTable(table))
+	function := QuoteIdentifier(table + "_versioning")
+	trigger := QuoteIdentifier(lastPart(table) + "_versioning")
+	//goland:noinspection SqlResolve
+	return `ALTER TABLE ` + quoted + ` ADD COLUMN IF NOT EXISTS ` + ValidFromColumn + ` TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE TABLE IF NOT EXISTS ` + history + ` (LIKE ` + quoted + `);
+ALTER TABLE ` + history + ` ADD COLUMN IF NOT EXISTS ` + ValidToColumn + ` TIMESTAMPTZ NOT NULL;
+CREATE INDEX IF NOT EXISTS ` + QuoteIdentifier(lastPart(HistoryTable(table))+"_validity") + ` ON ` + history + ` (` + ValidToColumn + `, ` + ValidFromColumn + `);
+CREATE OR REPLACE FUNCTION ` + function + `() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    IF OLD.` + ValidFromColumn + ` < now() THEN
+        INSERT INTO ` + history + ` SELECT OLD.*, now();
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    NEW.` + ValidFromColumn + ` := now();
+    RETURN NEW;
+END
+$$;
+DROP TRIGGER IF EXISTS ` + trigger + ` ON ` + quoted + `;
+CREATE TRIGGER ` + trigger + ` BEFORE UPDATE OR DELETE ON ` + quoted + ` FOR EACH ROW EXECUTE FUNCTION ` + function + `();
+`
+}
+
+// TemporalDownMigration reverts TemporalMigration, dropping the history.
+func TemporalDownMigration(table string) string {
+	quoted := QuoteIdentifier(table)
+	//goland:noinspection SqlResolve
+	return `DROP TRIGGER IF EXISTS ` + QuoteIdentifier(lastPart(table)+"_versioning") + ` ON ` + quoted + `;
+DROP FUNCTION IF EXISTS ` + QuoteIdentifier(table+"_versioning") + `();
+DROP TABLE IF EXISTS ` + QuoteIdentifier(HistoryTable(table)) + `;
+ALTER TABLE ` + quoted + ` DROP COLUMN IF EXISTS ` + ValidFromColumn + `;
+`
+}
+
+func lastPart(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// AsOf reads a table versioned by TemporalMigration as it was at the given time. The rows carry a
+// valid_to column, which is NULL for versions that are still current.
+func (b *SelectBuilder) AsOf(at time.Time) *SelectBuilder {
+	b.asOf = &at
+	return b
+}
+
+// temporalSource renders the FROM source of a query reading table as of at.
+func temporalSource(table string, at time.Time, args *queryArgs) string {
+	at1, at2, at3 := args.add(at), args.add(at), args.add(at)
+	//goland:noinspection SqlResolve
+	return "(SELECT *, NULL::TIMESTAMPTZ AS " + ValidToColumn + " FROM " + QuoteIdentifier(table) +
+		" WHERE " + ValidFromColumn + " <= " + at1 + " UNION ALL SELECT * FROM " + QuoteIdentifier(HistoryTable(table)) +
+		" WHERE " + ValidFromColumn + " <= " + at2 + " AND " + ValidToColumn + " > " + at3 + ") AS " +
+		QuoteIdentifier(lastPart(table))
+}
@@ -0,0 +1,55 @@
+package pg_test
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestTemporalTable(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, "CREATE TABLE items (id BIGINT PRIMARY KEY, name TEXT);"+pg.TemporalMigration("items"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Pool.Exec(ctx, "INSERT INTO items (id, name) VALUES (1, 'first'), (2, 'deleted')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var before time.Time
+	err = db.Pool.QueryRow(ctx, "SELECT now()").Scan(&before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Pool.Exec(ctx, "UPDATE items SET name = 'second' WHERE id = 1; DELETE FROM items WHERE id = 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(at time.Time) []string {
+		sql, args := pg.Select("name").From("items").AsOf(at).OrderBy("id").Build()
+		rows, err := db.Pool.Query(ctx, sql, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	if result := names(before); len(result) != 2 || result[0] != "first" || result[1] != "deleted" {
+		t.Errorf("unexpected rows before the changes %v", result)
+	}
+	if result := names(time.Now().Add(time.Minute)); len(result) != 1 || result[0] != "second" {
+		t.Errorf("unexpected current rows %v", result)
+	}
+	pgtest.AssertRowCount(t, db.Pool, pg.HistoryTable("items"), 2)
+	_, err = db.Pool.Exec(ctx, pg.TemporalDownMigration("items"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,32 @@
+package pg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelectAsOf(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sql, args := Select("id").From("app.items").AsOf(at).Where(Eq("name", "a")).Build()
+	expected := `SELECT "id" FROM (SELECT *, NULL::TIMESTAMPTZ AS valid_to FROM "app"."items" WHERE valid_from <= $1 ` +
+		`UNION ALL SELECT * FROM "app"."items_history" WHERE valid_from <= $2 AND valid_to > $3) AS "items" WHERE "name" = $4`
+	if sql != expected {
+		t.Errorf("unexpected sql: %v", sql)
+	}
+	if len(args) != 4 || args[0] != at || args[3] != "a" {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestTemporalMigration(t *testing.T) {
+	sql := TemporalMigration("app.items")
+	for _, expected := range []string{
+		`CREATE TABLE IF NOT EXISTS "app"."items_history" (LIKE "app"."items");`,
+		`CREATE TRIGGER "items_versioning" BEFORE UPDATE OR DELETE ON "app"."items" FOR EACH ROW EXECUTE FUNCTION "app"."items_versioning"();`,
+	} {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("expected %v in %v", expected, sql)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"regexp"
+	"strings"
+)
+
+const DefaultPrefix = "tenant_"
+
+var (
+	ErrNoTenant      = errors.New("no tenant in context")
+	ErrInvalidTenant = errors.New("tenant ids may only contain lowercase letters, digits and underscores")
+
+	tenantPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+type tenantContextKey struct{}
+
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// Manager handles the lifecycle of tenants that each live in their own schema, named Prefix
+// followed by the tenant id.
+type Manager struct {
+	pool *pgxpool.Pool
+	// Configuration is used to migrate new tenants; each tenant schema gets its own changelog.
+	Configuration pg.Configuration
+	Prefix        string
+	// Partitions, when set, caps the connections each tenant may hold; tenants are its labels.
+	Partitions *pg.PartitionedPool
+}
+
+func NewManager(pool *pgxpool.Pool, c pg.Configuration) *Manager {
+	return &Manager{
+		pool:          pool,
+		Configuration: c,
+		Prefix:        DefaultPrefix,
+	}
+}
+
+// Schema returns the schema holding tenant's data.
+func (m *Manager) Schema(tenant string) (string, error) {
+	if !tenantPattern.MatchString(tenant) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTenant, tenant)
+	}
+	schema := m.Prefix + tenant
+	_, err := pg.SanitizeIdentifier(schema)
+	if err != nil {
+		return "", err
+	}
+	return schema, nil
+}
+
+// Create creates the tenant schema and, when Configuration.MigrationsEnabled is set, applies the
+// migrations to it.
+func (m *Manager) Create(ctx context.Context, tenant string) error {
+	schema, err := m.Schema(tenant)
+	if err != nil {
+		return err
+	}
+	_, err = m.pool.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pg.QuoteIdentifier(schema))
+	if err != nil {
+		return err
+	}
+	if !m.Configuration.MigrationsEnabled {
+		return nil
+	}
+	return m.Migrate(ctx, tenant)
+}
+
+// Migrate applies pending migrations to the tenant schema. The migrations run with search_path
+// set to the schema, which also holds the tenant's changelog.
+func (m *Manager) Migrate(ctx context.Context, tenant string) error {
+	schema, err := m.Schema(tenant)
+	if err != nil {
+		return err
+	}
+	config, err := pgxpool.ParseConfig(pg.ConnectionString(m.Configuration))
+	if err != nil {
+		return err
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = pg.QuoteIdentifier(schema)
+	config.MaxConns = 1
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	c := m.Configuration
+	c.ChangelogSchema = schema
+	return pg.Migrate(pool, c)
+}
+
+// Drop removes the tenant schema with all its data.
+func (m *Manager) Drop(ctx context.Context, tenant string) error {
+	schema, err := m.Schema(tenant)
+	if err != nil {
+		return err
+	}
+	_, err = m.pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+pg.QuoteIdentifier(schema)+" CASCADE")
+	return err
+}
+
+// List returns the ids of all tenants, sorted.
+func (m *Manager) List(ctx context.Context) ([]string, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(m.Prefix) + "%"
+	rows, err := m.pool.Query(ctx, "SELECT nspname FROM pg_namespace WHERE nspname LIKE $1 ORDER BY nspname", pattern)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	return pg.Map(schemas, func(schema string) string {
+		return strings.TrimPrefix(schema, m.Prefix)
+	}), nil
+}
+
+// Conn is a pooled connection pinned to a tenant schema.
+type Conn struct {
+	*pgxpool.Conn
+	release func()
+}
+
+// Release resets search_path before returning the connection to the pool, so the next user does
+// not see the tenant's schema.
+func (c *Conn) Release() {
+	_, err := c.Conn.Exec(context.Background(), "RESET search_path")
+	if err != nil {
+		_ = c.Conn.Conn().Close(context.Background())
+	}
+	c.release()
+}
+
+// Acquire returns a connection with search_path set to tenant's schema. It must be released with
+// Conn.Release.
+func (m *Manager) Acquire(ctx context.Context, tenant string) (*Conn, error) {
+	schema, err := m.Schema(tenant)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := m.acquire(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "SELECT set_config('search_path', $1, false)", pg.QuoteIdentifier(schema))
+	if err != nil {
+		conn.release()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (m *Manager) acquire(ctx context.Context, tenant string) (*Conn, error) {
+	if m.Partitions != nil {
+		conn, err := m.Partitions.Acquire(ctx, tenant)
+		if err != nil {
+			return nil, err
+		}
+		return &Conn{Conn: conn.Conn, release: conn.Release}, nil
+	}
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn, release: conn.Release}, nil
+}
+
+// AcquireFromContext is Acquire for the tenant carried by ctx.
+func (m *Manager) AcquireFromContext(ctx context.Context) (*Conn, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+	return m.Acquire(ctx, tenant)
+}
+
+// WithTenant runs fn in a transaction whose search_path is pinned to the tenant carried by ctx.
+// The setting ends with the transaction.
+func (m *Manager) WithTenant(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+	schema, err := m.Schema(tenant)
+	if err != nil {
+		return err
+	}
+	return pg.WithRLSContext(ctx, m.pool, map[string]string{"search_path": pg.QuoteIdentifier(schema)}, fn)
+}
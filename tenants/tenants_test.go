@@ -0,0 +1,86 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	m := &Manager{Prefix: DefaultPrefix}
+	schema, err := m.Schema("acme_1")
+	if err != nil || schema != "tenant_acme_1" {
+		t.Errorf("unexpected schema %v, %v", schema, err)
+	}
+	for _, tenant := range []string{"", "Acme", "a;b", "a.b"} {
+		_, err = m.Schema(tenant)
+		if !errors.Is(err, ErrInvalidTenant) {
+			t.Errorf("%q: expected ErrInvalidTenant, got %v", tenant, err)
+		}
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	_, ok := TenantFromContext(context.Background())
+	if ok {
+		t.Error("expected no tenant")
+	}
+	tenant, ok := TenantFromContext(ContextWithTenant(context.Background(), "acme"))
+	if !ok || tenant != "acme" {
+		t.Errorf("unexpected tenant %v", tenant)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	db := pgtest.StartPostgres(t)
+	ctx := context.Background()
+	c := db.Configuration
+	c.MigrationsEnabled = true
+	c.MigrationsDirectory = "../testdb"
+	m := NewManager(db.Pool, c)
+	for _, tenant := range []string{"acme", "globex"} {
+		err := m.Create(ctx, tenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := m.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0] != "acme" || list[1] != "globex" {
+		t.Errorf("unexpected tenants %v", list)
+	}
+
+	conn, err := m.Acquire(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Exec(ctx, "INSERT INTO testtable (id, name) VALUES (2, 'acme')")
+	conn.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.WithTenant(ContextWithTenant(ctx, "globex"), func(ctx context.Context, tx pgx.Tx) error {
+		count, err := pg.Count(ctx, tx, "SELECT * FROM testtable")
+		if err == nil && count != 1 {
+			t.Errorf("tenants should be isolated, got %v rows", count)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Drop(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err = m.List(ctx)
+	if err != nil || len(list) != 1 {
+		t.Errorf("unexpected tenants after drop %v, %v", list, err)
+	}
+}
@@ -0,0 +1 @@
+DELETE FROM testtable WHERE id = 1;
@@ -0,0 +1 @@
+ALTER TABLE testtable DROP COLUMN description;
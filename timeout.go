@@ -0,0 +1,107 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+var ErrQueryTimeout = errors.New("query timed out")
+
+type queryTimeoutContextKey struct{}
+
+// ContextWithQueryTimeout overrides the default timeout of a TimeoutQuerier for the statements run
+// with ctx; zero disables it.
+func ContextWithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutContextKey{}, timeout)
+}
+
+// TimeoutQuerier bounds every statement by a timeout, so call sites need no context.WithTimeout of
+// their own. Statements exceeding it fail with an error matching ErrQueryTimeout; deadlines of the
+// caller's context are reported as they are.
+type TimeoutQuerier struct {
+	Querier Querier
+	Timeout time.Duration
+}
+
+func NewTimeoutQuerier(q Querier, timeout time.Duration) *TimeoutQuerier {
+	return &TimeoutQuerier{Querier: q, Timeout: timeout}
+}
+
+// withTimeout returns the context of a statement and the function mapping its error.
+func (t *TimeoutQuerier) withTimeout(ctx context.Context) (context.Context, context.CancelFunc, func(error) error) {
+	timeout := t.Timeout
+	if override, ok := ctx.Value(queryTimeoutContextKey{}).(time.Duration); ok {
+		timeout = override
+	}
+	if timeout <= 0 {
+		return ctx, func() {}, func(err error) error { return err }
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	return queryCtx, cancel, func(err error) error {
+		if err != nil && errors.Is(queryCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Errorf("%w after %v: %w", ErrQueryTimeout, timeout, err)
+		}
+		return err
+	}
+}
+
+func (t *TimeoutQuerier) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	ctx, cancel, mapErr := t.withTimeout(ctx)
+	defer cancel()
+	tag, err := t.Querier.Exec(ctx, sql, arguments...)
+	return tag, mapErr(err)
+}
+
+// Query bounds reading the rows as well; the timeout ends when they are closed or exhausted.
+func (t *TimeoutQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	ctx, cancel, mapErr := t.withTimeout(ctx)
+	rows, err := t.Querier.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+		return nil, mapErr(err)
+	}
+	return &timeoutRows{Rows: rows, cancel: cancel, mapErr: mapErr}, nil
+}
+
+func (t *TimeoutQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	ctx, cancel, mapErr := t.withTimeout(ctx)
+	return &timeoutRow{row: t.Querier.QueryRow(ctx, sql, args...), cancel: cancel, mapErr: mapErr}
+}
+
+type timeoutRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+	mapErr func(error) error
+}
+
+func (r *timeoutRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.cancel()
+	return false
+}
+
+func (r *timeoutRows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+func (r *timeoutRows) Err() error {
+	return r.mapErr(r.Rows.Err())
+}
+
+type timeoutRow struct {
+	row    pgx.Row
+	cancel context.CancelFunc
+	mapErr func(error) error
+}
+
+func (r *timeoutRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.mapErr(r.row.Scan(dest...))
+}
@@ -0,0 +1,37 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutQuerier(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	q := pg.NewTimeoutQuerier(db.Pool, 50*time.Millisecond)
+	ctx := context.Background()
+	_, err := q.Exec(ctx, "SELECT pg_sleep(1)")
+	if !errors.Is(err, pg.ErrQueryTimeout) {
+		t.Fatalf("expected a query timeout, got %v", err)
+	}
+	var name string
+	err = q.QueryRow(ctx, "SELECT name FROM testtable WHERE id = 1").Scan(&name)
+	if err != nil || name != "name1" {
+		t.Errorf("unexpected result %v, %v", name, err)
+	}
+	rows, err := q.Query(ctx, "SELECT id FROM generate_series(1, 3) id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if rows.Err() != nil || count != 3 {
+		t.Errorf("unexpected rows %v, %v", count, rows.Err())
+	}
+}
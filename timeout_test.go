@@ -0,0 +1,57 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"testing"
+	"time"
+)
+
+// blockingQuerier waits until the statement's context is done.
+type blockingQuerier struct {
+	deadlines []time.Time
+}
+
+func (q *blockingQuerier) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	deadline, _ := ctx.Deadline()
+	q.deadlines = append(q.deadlines, deadline)
+	<-ctx.Done()
+	return pgconn.CommandTag{}, ctx.Err()
+}
+
+func (q *blockingQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (q *blockingQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return nil
+}
+
+func TestTimeoutQuerier(t *testing.T) {
+	inner := &blockingQuerier{}
+	q := NewTimeoutQuerier(inner, 10*time.Millisecond)
+	_, err := q.Exec(context.Background(), "SELECT pg_sleep(1)")
+	if !errors.Is(err, ErrQueryTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a query timeout, got %v", err)
+	}
+	_, err = q.Query(ContextWithQueryTimeout(context.Background(), time.Millisecond), "SELECT pg_sleep(1)")
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("expected a query timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = q.Exec(ctx, "SELECT pg_sleep(1)")
+	if errors.Is(err, ErrQueryTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the caller's deadline to be reported as is, got %v", err)
+	}
+	ctx, cancel = context.WithCancel(ContextWithQueryTimeout(context.Background(), 0))
+	cancel()
+	_, _ = q.Exec(ctx, "SELECT 1")
+	if !inner.deadlines[len(inner.deadlines)-1].IsZero() {
+		t.Error("expected no deadline when the timeout is disabled")
+	}
+}
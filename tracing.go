@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tracerName = "github.com/msumera/pgutils"
+
+func (dbm *databaseMigrator) tracer() trace.Tracer {
+	tp := dbm.Configuration.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+func (dbm *databaseMigrator) applyMigrationTraced(ctx context.Context, migration migration, tx pgx.Tx) error {
+	_, span := dbm.tracer().Start(ctx, "pgutils.migration", trace.WithAttributes(
+		attribute.String("db.migration.file", migration.Filename),
+		attribute.String("db.migration.version", strings.Join(Map(migration.Id, strconv.Itoa), ".")),
+	))
+	start := time.Now()
+	status, err := dbm.applyMigration(migration, tx)
+	if err != nil {
+		status = statusError
+	}
+	dbm.counts.add(status)
+	span.SetAttributes(
+		attribute.String("db.migration.outcome", status),
+		attribute.Int64("db.migration.duration_ms", time.Since(start).Milliseconds()),
+	)
+	endSpan(span, err)
+	return err
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,139 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// Repository is meant to be embedded by repositories so their queries join the transaction
+// carried by the context, falling back to the pool outside of one.
+type Repository struct {
+	Pool *pgxpool.Pool
+}
+
+func (r Repository) Querier(ctx context.Context) Querier {
+	return QuerierFromContext(ctx, r.Pool)
+}
+
+// UnitOfWork collects operations and runs them in a single transaction on Commit. After-commit
+// callbacks only run once the transaction has been committed.
+type UnitOfWork struct {
+	pool        *pgxpool.Pool
+	mu          sync.Mutex
+	operations  []func(ctx context.Context) error
+	afterCommit []func(ctx context.Context)
+}
+
+func NewUnitOfWork(pool *pgxpool.Pool) *UnitOfWork {
+	return &UnitOfWork{pool: pool}
+}
+
+// Add defers an operation until Commit. The context it receives carries the transaction, so
+// repositories embedding Repository take part in it.
+func (u *UnitOfWork) Add(operation func(ctx context.Context) error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.operations = append(u.operations, operation)
+}
+
+func (u *UnitOfWork) AfterCommit(fn func(ctx context.Context)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.afterCommit = append(u.afterCommit, fn)
+}
+
+// Commit runs the pending operations in a transaction. If ctx already carries a transaction the
+// work is nested in a savepoint of it. Nested in the transaction of another UnitOfWork, the
+// callbacks run after that one commits and are dropped when it rolls back; nested in a transaction
+// begun elsewhere, whose commit is not seen, they run once the savepoint is released. Pending
+// operations and callbacks are cleared either way.
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	u.mu.Lock()
+	operations := u.operations
+	afterCommit := u.afterCommit
+	u.operations = nil
+	u.afterCommit = nil
+	u.mu.Unlock()
+
+	var tx pgx.Tx
+	var err error
+	var outerQueue *afterCommitQueue
+	if outer, ok := TxFromContext(ctx); ok {
+		if queue, ok := ctx.Value(afterCommitContextKey{}).(*afterCommitQueue); ok && queue.tx == outer {
+			outerQueue = queue
+		}
+		tx, err = outer.Begin(ctx)
+	} else {
+		tx, err = u.pool.Begin(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(tx, context.Background())
+	queue := &afterCommitQueue{tx: tx, callbacks: afterCommit}
+	txCtx := context.WithValue(ContextWithTx(ctx, tx), afterCommitContextKey{}, queue)
+	for _, operation := range operations {
+		err = operation(txCtx)
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	if outerQueue != nil {
+		outerQueue.add(queue.take())
+		return nil
+	}
+	for _, fn := range queue.take() {
+		runAfterCommit(ctx, fn)
+	}
+	return nil
+}
+
+type afterCommitContextKey struct{}
+
+// afterCommitQueue collects the callbacks of a UnitOfWork's transaction, including those of units
+// nested in it, until it commits.
+type afterCommitQueue struct {
+	tx        pgx.Tx
+	mu        sync.Mutex
+	callbacks []func(ctx context.Context)
+}
+
+func (q *afterCommitQueue) add(callbacks []func(ctx context.Context)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.callbacks = append(q.callbacks, callbacks...)
+}
+
+func (q *afterCommitQueue) take() []func(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	callbacks := q.callbacks
+	q.callbacks = nil
+	return callbacks
+}
+
+// Rollback discards the pending operations and callbacks without touching the database.
+func (u *UnitOfWork) Rollback() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.operations = nil
+	u.afterCommit = nil
+}
+
+func runAfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Errorf("After-commit callback panicked: %v", p)
+		}
+	}()
+	fn(ctx)
+}
@@ -0,0 +1,57 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+)
+
+func TestNestedUnitOfWork(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	var called []string
+	run := func(fail bool) error {
+		outer := pg.NewUnitOfWork(db.Pool)
+		outer.Add(func(ctx context.Context) error {
+			inner := pg.NewUnitOfWork(db.Pool)
+			inner.Add(func(ctx context.Context) error {
+				_, err := pg.QuerierFromContext(ctx, db.Pool).Exec(ctx, "INSERT INTO testtable (id, name) VALUES (2, 'inner')")
+				return err
+			})
+			inner.AfterCommit(func(ctx context.Context) { called = append(called, "inner") })
+			err := inner.Commit(ctx)
+			if err != nil {
+				return err
+			}
+			if len(called) != 0 {
+				t.Error("expected the callback to wait for the outer commit")
+			}
+			if fail {
+				return errors.New("outer failed")
+			}
+			return nil
+		})
+		outer.AfterCommit(func(ctx context.Context) { called = append(called, "outer") })
+		return outer.Commit(ctx)
+	}
+
+	err := run(true)
+	if err == nil {
+		t.Fatal("expected the outer unit of work to fail")
+	}
+	if len(called) != 0 {
+		t.Errorf("expected no callbacks after the rollback, got %v", called)
+	}
+	pgtest.AssertNoRows(t, db.Pool, "SELECT FROM testtable WHERE id = 2")
+
+	err = run(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(called) != 2 || called[0] != "outer" || called[1] != "inner" {
+		t.Errorf("unexpected callbacks %v", called)
+	}
+	pgtest.AssertQueryReturns(t, db.Pool, "SELECT name FROM testtable WHERE id = 2", [][]any{{"inner"}})
+}
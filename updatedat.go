@@ -0,0 +1,71 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"strings"
+)
+
+const (
+	// UpdatedAtColumn is the column the updated_at triggers maintain.
+	UpdatedAtColumn = "updated_at"
+	// UpdatedAtFunction is the trigger function installed by UpdatedAtMigration.
+	UpdatedAtFunction = "set_updated_at"
+)
+
+func updatedAtTrigger(table string) string {
+	return QuoteIdentifier(lastPart(table) + "_" + UpdatedAtFunction)
+}
+
+// UpdatedAtMigration returns the statements creating the set_updated_at() trigger function and
+// attaching it to tables, so every update sets their updated_at column to the time of the
+// transaction. The statements are idempotent; add tables in later migrations the same way.
+func UpdatedAtMigration(tables ...string) string {
+	var sb strings.Builder
+	sb.WriteString(`CREATE OR REPLACE FUNCTION ` + UpdatedAtFunction + `() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.` + UpdatedAtColumn + ` := now();
+    RETURN NEW;
+END
+$$;
+`)
+	for _, table := range tables {
+		sb.WriteString("DROP TRIGGER IF EXISTS " + updatedAtTrigger(table) + " ON " + QuoteIdentifier(table) + ";\n")
+		sb.WriteString("CREATE TRIGGER " + updatedAtTrigger(table) + " BEFORE UPDATE ON " + QuoteIdentifier(table) +
+			" FOR EACH ROW EXECUTE FUNCTION " + UpdatedAtFunction + "();\n")
+	}
+	return sb.String()
+}
+
+// UpdatedAtDownMigration removes the updated_at triggers of tables, keeping the function.
+func UpdatedAtDownMigration(tables ...string) string {
+	var sb strings.Builder
+	for _, table := range tables {
+		sb.WriteString("DROP TRIGGER IF EXISTS " + updatedAtTrigger(table) + " ON " + QuoteIdentifier(table) + ";\n")
+	}
+	return sb.String()
+}
+
+// MissingUpdatedAtTriggers returns the tables of schemas, or of all user schemas, that have an
+// updated_at column but no trigger calling set_updated_at(), schema qualified.
+func MissingUpdatedAtTriggers(ctx context.Context, q Querier, schemas ...string) ([]string, error) {
+	if schemas == nil {
+		schemas = []string{}
+	}
+	rows, err := q.Query(ctx, `
+		SELECT n.nspname || '.' || c.relname
+		FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE a.attname = $1 AND a.attnum > 0 AND NOT a.attisdropped AND c.relkind IN ('r', 'p')
+			AND (cardinality($2::text[]) = 0 AND n.nspname NOT LIKE 'pg\_%' AND n.nspname <> 'information_schema' OR n.nspname = ANY($2))
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_trigger t JOIN pg_proc p ON p.oid = t.tgfoid
+				WHERE t.tgrelid = c.oid AND NOT t.tgisinternal AND p.proname = $3
+			)
+		ORDER BY 1`, UpdatedAtColumn, schemas, UpdatedAtFunction)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
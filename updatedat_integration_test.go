@@ -0,0 +1,42 @@
+package pg_test
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"testing"
+	"time"
+)
+
+func TestUpdatedAtTriggers(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, `
+		ALTER TABLE testtable ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT '2000-01-01';
+		CREATE TABLE other (id INT PRIMARY KEY, updated_at TIMESTAMPTZ);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, err := pg.MissingUpdatedAtTriggers(ctx, db.Pool)
+	if err != nil || len(missing) != 2 || missing[0] != "public.other" {
+		t.Fatalf("expected both tables to miss the trigger, got %v, %v", missing, err)
+	}
+	_, err = db.Pool.Exec(ctx, pg.UpdatedAtMigration("testtable"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, err = pg.MissingUpdatedAtTriggers(ctx, db.Pool, "public")
+	if err != nil || len(missing) != 1 || missing[0] != "public.other" {
+		t.Errorf("expected only other to miss the trigger, got %v, %v", missing, err)
+	}
+	_, err = db.Pool.Exec(ctx, "UPDATE testtable SET name = 'changed' WHERE id = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updatedAt time.Time
+	err = db.Pool.QueryRow(ctx, "SELECT updated_at FROM testtable WHERE id = 1").Scan(&updatedAt)
+	if err != nil || time.Since(updatedAt) > time.Minute {
+		t.Errorf("expected updated_at to be set, got %v, %v", updatedAt, err)
+	}
+}
@@ -0,0 +1,23 @@
+package pg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpdatedAtMigration(t *testing.T) {
+	sql := UpdatedAtMigration("app.users")
+	for _, expected := range []string{
+		"CREATE OR REPLACE FUNCTION set_updated_at() RETURNS trigger",
+		`DROP TRIGGER IF EXISTS "users_set_updated_at" ON "app"."users";`,
+		`CREATE TRIGGER "users_set_updated_at" BEFORE UPDATE ON "app"."users" FOR EACH ROW EXECUTE FUNCTION set_updated_at();`,
+	} {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("expected %v in %v", expected, sql)
+		}
+	}
+	down := UpdatedAtDownMigration("app.users")
+	if down != `DROP TRIGGER IF EXISTS "users_set_updated_at" ON "app"."users";`+"\n" {
+		t.Errorf("unexpected down migration %v", down)
+	}
+}
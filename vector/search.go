@@ -0,0 +1,110 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	pg "github.com/msumera/pgutils"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metric is a pgvector distance function.
+type Metric string
+
+const (
+	L2           Metric = "l2"
+	Cosine       Metric = "cosine"
+	InnerProduct Metric = "ip"
+)
+
+// Operator returns the distance operator of m, e.g. <-> for L2.
+func (m Metric) Operator() string {
+	switch m {
+	case Cosine:
+		return "<=>"
+	case InnerProduct:
+		return "<#>"
+	}
+	return "<->"
+}
+
+// OperatorClass returns the index operator class supporting m.
+func (m Metric) OperatorClass() string {
+	return "vector_" + string(m) + "_ops"
+}
+
+type IndexType string
+
+const (
+	HNSW    IndexType = "hnsw"
+	IVFFlat IndexType = "ivfflat"
+)
+
+// ExtensionMigration installs pgvector; include it in a migration before the other statements.
+const ExtensionMigration = "CREATE EXTENSION IF NOT EXISTS vector;"
+
+// ColumnMigration returns the statement adding a vector column of the given dimensions to table.
+func ColumnMigration(table string, column string, dimensions int) string {
+	return "ALTER TABLE " + pg.QuoteIdentifier(table) + " ADD COLUMN IF NOT EXISTS " + pg.QuoteIdentifier(column) + " vector(" + strconv.Itoa(dimensions) + ");"
+}
+
+// IndexMigration returns the statement creating an approximate nearest neighbour index on column
+// for metric. params are the index storage parameters, e.g. {"m": 16, "ef_construction": 64} for
+// HNSW or {"lists": 100} for IVFFlat.
+func IndexMigration(table string, column string, indexType IndexType, metric Metric, params map[string]int) string {
+	name := strings.ReplaceAll(table, ".", "_") + "_" + column + "_" + string(indexType) + "_idx"
+	sql := "CREATE INDEX IF NOT EXISTS " + pgx.Identifier{name}.Sanitize() + " ON " + pg.QuoteIdentifier(table) +
+		" USING " + string(indexType) + " (" + pg.QuoteIdentifier(column) + " " + metric.OperatorClass() + ")"
+	if len(params) > 0 {
+		keys := make([]string, 0, len(params))
+		for key := range params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		sql += " WITH (" + strings.Join(pg.Map(keys, func(key string) string {
+			return key + " = " + strconv.Itoa(params[key])
+		}), ", ") + ")"
+	}
+	return sql + ";"
+}
+
+// Neighbor is a row returned by Nearest with its distance to the query vector.
+type Neighbor[T any] struct {
+	Row      T
+	Distance float64
+}
+
+// Nearest returns the limit rows of table closest to query by metric, scanning the columns into
+// the fields of T like the other struct helpers. For inner product the distance is negated, as
+// pgvector does, so smaller is always closer.
+func Nearest[T any](ctx context.Context, q pg.Querier, table string, column string, query Vector, metric Metric, limit int) ([]Neighbor[T], error) {
+	fields, err := pg.StructColumns(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0, len(fields))
+	for name := range fields {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	sql := fmt.Sprintf("SELECT %v, %v %v $1 AS distance FROM %v ORDER BY distance LIMIT $2",
+		strings.Join(pg.Map(columns, pg.QuoteIdentifier), ", "), pg.QuoteIdentifier(column), metric.Operator(), pg.QuoteIdentifier(table))
+	rows, err := q.Query(ctx, sql, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Neighbor[T], error) {
+		var n Neighbor[T]
+		v := reflect.ValueOf(&n.Row).Elem()
+		targets := make([]any, 0, len(columns)+1)
+		for _, name := range columns {
+			targets = append(targets, v.FieldByIndex(fields[name].Index).Addr().Interface())
+		}
+		targets = append(targets, &n.Distance)
+		err := row.Scan(targets...)
+		return n, err
+	})
+}
@@ -0,0 +1,140 @@
+package vector
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"strconv"
+	"strings"
+)
+
+// Vector is a pgvector value. A nil Vector is NULL.
+type Vector []float32
+
+func (v Vector) String() string {
+	return string(v.appendText(nil))
+}
+
+func (v Vector) appendText(buf []byte) []byte {
+	buf = append(buf, '[')
+	for i, f := range v {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendFloat(buf, float64(f), 'g', -1, 32)
+	}
+	return append(buf, ']')
+}
+
+// Parse reads the text representation of a vector, e.g. "[1,2.5,3]".
+func Parse(s string) (Vector, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("invalid vector %q", s)
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	if s == "" {
+		return Vector{}, nil
+	}
+	parts := strings.Split(s, ",")
+	v := make(Vector, len(parts))
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector %q: %w", s, err)
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
+}
+
+// Codec encodes Vector and []float32 in the text format of the vector type.
+type Codec struct{}
+
+func (Codec) FormatSupported(format int16) bool {
+	return format == pgtype.TextFormatCode
+}
+
+func (Codec) PreferredFormat() int16 {
+	return pgtype.TextFormatCode
+}
+
+type encodePlan struct{}
+
+func (encodePlan) Encode(value any, buf []byte) ([]byte, error) {
+	var v Vector
+	switch value := value.(type) {
+	case Vector:
+		v = value
+	case []float32:
+		v = value
+	}
+	if v == nil {
+		return nil, nil
+	}
+	return v.appendText(buf), nil
+}
+
+func (Codec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value any) pgtype.EncodePlan {
+	switch value.(type) {
+	case Vector, []float32:
+		return encodePlan{}
+	}
+	return nil
+}
+
+type scanPlan struct{}
+
+func (scanPlan) Scan(src []byte, target any) error {
+	var v Vector
+	if src != nil {
+		var err error
+		v, err = Parse(string(src))
+		if err != nil {
+			return err
+		}
+	}
+	switch target := target.(type) {
+	case *Vector:
+		*target = v
+	case *[]float32:
+		*target = v
+	}
+	return nil
+}
+
+func (Codec) PlanScan(m *pgtype.Map, oid uint32, format int16, target any) pgtype.ScanPlan {
+	switch target.(type) {
+	case *Vector, *[]float32:
+		return scanPlan{}
+	}
+	return nil
+}
+
+func (Codec) DecodeDatabaseSQLValue(m *pgtype.Map, oid uint32, format int16, src []byte) (driver.Value, error) {
+	if src == nil {
+		return nil, nil
+	}
+	return string(src), nil
+}
+
+func (Codec) DecodeValue(m *pgtype.Map, oid uint32, format int16, src []byte) (any, error) {
+	if src == nil {
+		return nil, nil
+	}
+	return Parse(string(src))
+}
+
+// RegisterTypes registers the vector type of the pgvector extension on conn. Use it as the
+// AfterConnect hook of a pool, e.g. through pg.Configuration.AfterConnect.
+func RegisterTypes(ctx context.Context, conn *pgx.Conn) error {
+	var oid uint32
+	err := conn.QueryRow(ctx, "SELECT 'vector'::regtype::oid").Scan(&oid)
+	if err != nil {
+		return fmt.Errorf("looking up the vector type, is the extension installed? %w", err)
+	}
+	conn.TypeMap().RegisterType(&pgtype.Type{Name: "vector", OID: oid, Codec: Codec{}})
+	return nil
+}
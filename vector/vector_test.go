@@ -0,0 +1,78 @@
+package vector
+
+import (
+	"context"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("[1,2.5,-3]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, Vector{1, 2.5, -3}) {
+		t.Errorf("unexpected vector %v", v)
+	}
+	if v.String() != "[1,2.5,-3]" {
+		t.Errorf("unexpected text %v", v.String())
+	}
+	_, err = Parse("1,2")
+	if err == nil {
+		t.Error("expected an error without brackets")
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	sql := ColumnMigration("app.items", "embedding", 3)
+	if sql != `ALTER TABLE "app"."items" ADD COLUMN IF NOT EXISTS "embedding" vector(3);` {
+		t.Errorf("unexpected column migration %v", sql)
+	}
+	sql = IndexMigration("items", "embedding", HNSW, Cosine, map[string]int{"m": 16, "ef_construction": 64})
+	if sql != `CREATE INDEX IF NOT EXISTS "items_embedding_hnsw_idx" ON "items" USING hnsw ("embedding" vector_cosine_ops) WITH (ef_construction = 64, m = 16);` {
+		t.Errorf("unexpected index migration %v", sql)
+	}
+}
+
+type item struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestNearest(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithImage("pgvector/pgvector"), pgtest.WithVersion("pg16"))
+	ctx := context.Background()
+	_, err := db.Pool.Exec(ctx, ExtensionMigration+"CREATE TABLE items (id BIGINT PRIMARY KEY, name TEXT);"+
+		ColumnMigration("items", "embedding", 2)+IndexMigration("items", "embedding", HNSW, L2, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := db.Configuration
+	c.MigrationsEnabled = false
+	c.AfterConnect = RegisterTypes
+	pool, err := pg.ConnectWithConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	for i, v := range []Vector{{0, 0}, {1, 1}, {5, 5}} {
+		_, err = pool.Exec(ctx, "INSERT INTO items (id, name, embedding) VALUES ($1, $2, $3)", i, v.String(), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	neighbors, err := Nearest[item](ctx, pool, "items", "embedding", Vector{4, 4}, L2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbors) != 2 || neighbors[0].Row.ID != 2 || neighbors[1].Row.ID != 1 {
+		t.Errorf("unexpected neighbours %+v", neighbors)
+	}
+	var stored Vector
+	err = pool.QueryRow(ctx, "SELECT embedding FROM items WHERE id = 2").Scan(&stored)
+	if err != nil || !reflect.DeepEqual(stored, Vector{5, 5}) {
+		t.Errorf("unexpected stored vector %v, %v", stored, err)
+	}
+}
@@ -0,0 +1,193 @@
+package pg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultTxWatchdogThreshold = time.Minute
+	DefaultTxWatchdogInterval  = 10 * time.Second
+)
+
+// LongTransaction is a transaction open for longer than TxWatchdog.Threshold.
+type LongTransaction struct {
+	PID             uint32
+	State           string
+	ApplicationName string
+	// Query is the statement running, or the last one run when the session is idle.
+	Query   string
+	Started time.Time
+	// Stack is where the transaction was begun, for transactions of the helpers of this package in
+	// this process.
+	Stack      string
+	Terminated bool
+}
+
+func (t LongTransaction) Duration() time.Duration {
+	return time.Since(t.Started)
+}
+
+type trackedTx struct {
+	pid   uint32
+	start time.Time
+	stack string
+}
+
+// trackedTxs holds the transactions begun by the helpers while a watchdog runs; capturing stacks
+// is skipped otherwise.
+var trackedTxs = struct {
+	mu       sync.Mutex
+	next     uint64
+	txs      map[uint64]trackedTx
+	watchers atomic.Int32
+}{txs: make(map[uint64]trackedTx)}
+
+// trackTx records tx for the watchdogs and returns the function ending the record.
+func trackTx(tx pgx.Tx) func() {
+	if trackedTxs.watchers.Load() == 0 {
+		return func() {}
+	}
+	tracked := trackedTx{pid: tx.Conn().PgConn().PID(), start: time.Now(), stack: string(debug.Stack())}
+	trackedTxs.mu.Lock()
+	defer trackedTxs.mu.Unlock()
+	trackedTxs.next++
+	id := trackedTxs.next
+	trackedTxs.txs[id] = tracked
+	return func() {
+		trackedTxs.mu.Lock()
+		defer trackedTxs.mu.Unlock()
+		delete(trackedTxs.txs, id)
+	}
+}
+
+// trackedStacks returns the stacks of the tracked transactions begun before since, by backend.
+func trackedStacks(since time.Time) map[uint32]string {
+	trackedTxs.mu.Lock()
+	defer trackedTxs.mu.Unlock()
+	stacks := make(map[uint32]string)
+	for _, tx := range trackedTxs.txs {
+		if tx.start.Before(since) {
+			stacks[tx.pid] = tx.stack
+		}
+	}
+	return stacks
+}
+
+// TxWatchdog reports transactions held open longer than Threshold: sessions of the database idle
+// in a transaction, and transactions of the helpers of this package in this process, with the
+// stack that began them.
+type TxWatchdog struct {
+	pool      *pgxpool.Pool
+	Threshold time.Duration
+	Interval  time.Duration
+	// Terminate terminates the backends of the long transactions of this process, rolling them
+	// back: those begun by the helpers and, when the pool sets application_name, the sessions with
+	// the same application_name. Other sessions are only reported.
+	Terminate bool
+	// OnLongTransaction receives every transaction once; they are logged as warnings when it is
+	// nil.
+	OnLongTransaction func(LongTransaction)
+
+	reported map[uint32]time.Time
+}
+
+func NewTxWatchdog(pool *pgxpool.Pool) *TxWatchdog {
+	return &TxWatchdog{
+		pool:      pool,
+		Threshold: DefaultTxWatchdogThreshold,
+		Interval:  DefaultTxWatchdogInterval,
+		reported:  make(map[uint32]time.Time),
+	}
+}
+
+// Run checks every Interval until ctx is cancelled, which returns nil.
+func (w *TxWatchdog) Run(ctx context.Context) error {
+	trackedTxs.watchers.Add(1)
+	defer trackedTxs.watchers.Add(-1)
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		_, err := w.Check(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Warnf("Error checking for long transactions: %v", err)
+		}
+	}
+}
+
+// Check reports the transactions that became too old since the last check and returns all that
+// are too old now. It must not be called concurrently.
+func (w *TxWatchdog) Check(ctx context.Context) ([]LongTransaction, error) {
+	since := time.Now().Add(-w.Threshold)
+	stacks := trackedStacks(since)
+	pids := make([]int32, 0, len(stacks))
+	for pid := range stacks {
+		pids = append(pids, int32(pid))
+	}
+	rows, err := w.pool.Query(ctx, `
+		SELECT pid, COALESCE(state, ''), application_name, query, xact_start
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND pid <> pg_backend_pid() AND xact_start < $1
+		AND (state LIKE 'idle in transaction%' OR pid = ANY($2))
+		ORDER BY xact_start`, since, pids)
+	if err != nil {
+		return nil, err
+	}
+	long, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (LongTransaction, error) {
+		var t LongTransaction
+		err := row.Scan(&t.PID, &t.State, &t.ApplicationName, &t.Query, &t.Started)
+		t.Stack = stacks[t.PID]
+		return t, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	applicationName := w.pool.Config().ConnConfig.RuntimeParams["application_name"]
+	current := make(map[uint32]time.Time, len(long))
+	for i := range long {
+		_, tracked := stacks[long[i].PID]
+		own := tracked || applicationName != "" && long[i].ApplicationName == applicationName
+		if w.Terminate && own {
+			err = w.pool.QueryRow(ctx, "SELECT pg_terminate_backend($1)", long[i].PID).Scan(&long[i].Terminated)
+			if err != nil {
+				return long, err
+			}
+		}
+		current[long[i].PID] = long[i].Started
+		if reported, ok := w.reported[long[i].PID]; !ok || !reported.Equal(long[i].Started) {
+			w.report(long[i])
+		}
+	}
+	w.reported = current
+	return long, nil
+}
+
+func (w *TxWatchdog) report(t LongTransaction) {
+	if w.OnLongTransaction != nil {
+		w.OnLongTransaction(t)
+		return
+	}
+	entry := log.WithFields(log.Fields{
+		"pid":         t.PID,
+		"state":       t.State,
+		"application": t.ApplicationName,
+		"query":       t.Query,
+		"duration_ms": t.Duration().Milliseconds(),
+		"terminated":  t.Terminated,
+	})
+	if t.Stack != "" {
+		entry = entry.WithField("stack", t.Stack)
+	}
+	entry.Warn("Long transaction")
+}
@@ -0,0 +1,103 @@
+package pg_test
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg "github.com/msumera/pgutils"
+	"github.com/msumera/pgutils/pgtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTxWatchdog(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := pg.NewTxWatchdog(db.Pool)
+	w.Threshold = 50 * time.Millisecond
+	w.Interval = time.Hour
+	var reported []pg.LongTransaction
+	w.OnLongTransaction = func(long pg.LongTransaction) { reported = append(reported, long) }
+	go func() { _ = w.Run(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+
+	var long []pg.LongTransaction
+	err := pg.DoInTransactionNoResult(db.Pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "SELECT 1 FROM testtable")
+		if err != nil {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+		long, err = w.Check(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = w.Check(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(long) != 1 || !strings.HasPrefix(long[0].State, "idle in transaction") || long[0].Query != "SELECT 1 FROM testtable" {
+		t.Fatalf("expected the idle transaction, got %+v", long)
+	}
+	if !strings.Contains(long[0].Stack, "TestTxWatchdog") || long[0].Terminated {
+		t.Errorf("expected the stack of the transaction, got %+v", long[0])
+	}
+	if len(reported) != 1 {
+		t.Errorf("expected the transaction to be reported once, got %v", len(reported))
+	}
+}
+
+func TestTxWatchdogTerminate(t *testing.T) {
+	db := pgtest.StartPostgres(t, pgtest.WithMigrations("testdb"))
+	ctx := context.Background()
+	config, err := pgxpool.ParseConfig(pg.ConnectionString(db.Configuration) + "&application_name=watchdog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	begin := func(q interface {
+		Begin(ctx context.Context) (pgx.Tx, error)
+	}) pgx.Tx {
+		tx, err := q.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tx.Exec(ctx, "SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	own := begin(pool)
+	// Sessions of other processes are reported but left alone.
+	other := begin(db.Pool)
+	defer func() { _ = other.Rollback(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+	w := pg.NewTxWatchdog(pool)
+	w.Threshold = 50 * time.Millisecond
+	w.Terminate = true
+	w.OnLongTransaction = func(pg.LongTransaction) {}
+	long, err := w.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(long) != 2 || !long[0].Terminated || long[0].ApplicationName != "watchdog" || long[1].Terminated {
+		t.Fatalf("expected only the own transaction to be terminated, got %+v", long)
+	}
+	_, err = own.Exec(ctx, "SELECT 1")
+	if err == nil {
+		t.Error("expected the terminated transaction to fail")
+	}
+	_, err = other.Exec(ctx, "SELECT 1")
+	if err != nil {
+		t.Errorf("expected the other transaction to go on, got %v", err)
+	}
+}